@@ -0,0 +1,163 @@
+package main
+
+import (
+	"backend/database"
+	"backend/migrations"
+	"backend/services"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runCommand 执行命令行子命令，返回进程退出码
+func runCommand(args []string) int {
+	switch args[0] {
+	case "import-taxonomy":
+		return importTaxonomyCommand(args[1:])
+	case "migrate":
+		return migrateCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "可用命令: import-taxonomy, migrate")
+		return 2
+	}
+}
+
+// importTaxonomyCommand 按Source关联来源生物，并从本地NCBI taxonomy数据补全分类谱系
+// 用法: mnplib-backend import-taxonomy -dump ./new_taxdump
+func importTaxonomyCommand(args []string) int {
+	fs := flag.NewFlagSet("import-taxonomy", flag.ContinueOnError)
+	dumpDir := fs.String("dump", "./new_taxdump", "NCBI new_taxdump 解压目录（包含 rankedlineage.dmp 和 nodes.dmp）")
+	skipLink := fs.Bool("skip-link", false, "跳过按Source关联来源生物的步骤")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if !*skipLink {
+		linkStats, err := services.LinkOrganismsFromSource()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "关联来源生物失败: %v\n", err)
+			return 1
+		}
+		fmt.Printf("新建来源生物 %d 个，关联化合物 %d 个\n", linkStats.Created, linkStats.Linked)
+	}
+
+	stats, err := services.ImportTaxonomyDump(*dumpDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导入分类谱系失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("来源生物 %d 个：精确匹配 %d 个，属匹配 %d 个，未匹配 %d 个（扫描 %d 行）\n",
+		stats.Organisms, stats.Matched, stats.GenusMatched, stats.Unmatched, stats.ScannedLines)
+	return 0
+}
+
+// migrateCommand 执行或回滚数据库迁移，或查看迁移状态
+// 用法: mnplib-backend migrate up [-to 版本]
+//
+//	mnplib-backend migrate down [-steps 1 | -to 版本]
+//	mnplib-backend migrate status
+func migrateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "用法: migrate up|down|status")
+		return 2
+	}
+	db := database.GetDB()
+	if db == nil {
+		fmt.Fprintln(os.Stderr, "数据库未连接")
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		fs := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+		to := fs.Int("to", 0, "执行到的版本（含），默认为最新版本")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		ran, err := migrations.Up(db, *to)
+		for _, m := range ran {
+			fmt.Printf("已执行 %03d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		if len(ran) == 0 {
+			fmt.Println("数据库结构已是最新版本")
+		}
+		return 0
+
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := fs.Int("steps", 1, "回滚的版本数")
+		to := fs.Int("to", -1, "回滚到的版本（不含该版本之前的迁移），0 表示全部回滚，设置后忽略 -steps")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		target := *to
+		if target < 0 {
+			statuses, err := migrations.Status(db)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return 1
+			}
+			target = downTarget(statuses, *steps)
+		}
+		ran, err := migrations.Down(db, target)
+		for _, m := range ran {
+			fmt.Printf("已回滚 %03d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		if len(ran) == 0 {
+			fmt.Println("没有需要回滚的迁移")
+		}
+		return 0
+
+	case "status":
+		statuses, err := migrations.Status(db)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		for _, s := range statuses {
+			state := "未执行"
+			if s.AppliedAt != nil {
+				state = "已执行 " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if !s.Known {
+				state += "（本程序中不存在该版本）"
+			}
+			fmt.Printf("%03d_%-30s %s\n", s.Version, s.Name, state)
+		}
+		if err := migrations.Check(db); err != nil {
+			fmt.Println(err)
+		}
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "未知的 migrate 子命令: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "用法: migrate up|down|status")
+		return 2
+	}
+}
+
+// downTarget 返回回滚最近 steps 个已执行版本后的目标版本
+func downTarget(statuses []migrations.MigrationStatus, steps int) int {
+	var done []int
+	for _, s := range statuses {
+		if s.AppliedAt != nil {
+			done = append(done, s.Version)
+		}
+	}
+	if steps < 0 {
+		steps = 0
+	}
+	if steps >= len(done) {
+		return 0
+	}
+	return done[len(done)-steps-1]
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"testing"
 
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
@@ -15,8 +17,12 @@ func init() {
 	Config.AddConfigPath(".")
 	Config.WatchConfig() // 自动将配置读入Config变量
 
-	// 读取配置文件
+	// 读取配置文件。测试时没有配置文件，由测试代码设置所需的配置
 	err := Config.ReadInConfig()
+	var notFound viper.ConfigFileNotFoundError
+	if errors.As(err, &notFound) && testing.Testing() {
+		return
+	}
 	if err != nil {
 		logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
 		logger.Fatal().Msgf("配置文件读取错误: %s", err)
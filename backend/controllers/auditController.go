@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseAuditFilter 解析审计日志查询条件，出错时写入错误响应并返回 false
+func parseAuditFilter(c *gin.Context) (services.AuditFilter, bool) {
+	filter := services.AuditFilter{
+		Passkey:      c.Query("passkey"),
+		PasskeyLabel: c.Query("passkey_label"),
+		Action:       c.Query("action"),
+		ResourceID:   c.Query("resource_id"),
+		Outcome:      c.Query("outcome"),
+		ClientIP:     c.Query("client_ip"),
+	}
+
+	// 字段按 LIKE 匹配，只接受可授权的保护字段，避免 % 和 _ 匹配到其他记录
+	if field := c.Query("field"); field != "" {
+		for _, f := range models.GrantFields {
+			if strings.EqualFold(field, f) {
+				filter.Field = f
+				break
+			}
+		}
+		if filter.Field == "" {
+			utils.JsonErrorResponse(c, utils.CodeInvalidChoice, gin.H{"param": "field", "choices": strings.Join(models.GrantFields, ", ")})
+			return filter, false
+		}
+	}
+
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		s := c.Query(name)
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			// 也接受日期，如 2025-01-31
+			t, err = time.ParseInLocation("2006-01-02", s, time.Local)
+		}
+		if err != nil {
+			utils.JsonErrorResponse(c, utils.CodeInvalidTime, gin.H{"param": name})
+			return filter, false
+		}
+		*target = &t
+	}
+	return filter, true
+}
+
+// GetAuditEvents 查询审计日志
+// @Summary 查询审计日志
+// @Description 按操作者、动作、化合物、字段、结果、IP 和时间范围分页查询保护数据访问记录，按时间倒序排列，需要 audit:read 权限（超级管理员）
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param passkey query string false "操作者 passkey，按其 SHA-256 前 12 位匹配"
+// @Param passkey_label query string false "操作者 passkey 的 SHA-256 前 12 位，即记录中的 passkey_label"
+// @Param action query string false "动作，如 data.read-protected、data.read-ms2-full、bioactivity.read、bioactivity.filter"
+// @Param resource_id query string false "化合物ID"
+// @Param field query string false "返回的字段中包含该字段：MS2、MS2_full、Bioactivity、NMR_13C_data，不区分大小写"
+// @Param outcome query string false "结果：success、denied、not_found、invalid、error"
+// @Param client_ip query string false "客户端IP"
+// @Param from query string false "起始时间（含），RFC 3339 时间或 YYYY-MM-DD"
+// @Param to query string false "结束时间（不含），RFC 3339 时间或 YYYY-MM-DD"
+// @Param limit query int false "返回的记录数量，默认为50，最大500"
+// @Param offset query int false "从第几条记录开始，默认为0"
+// @Success 200 {object} utils.JSONResponse{data=utils.Page{data=[]models.AuditEvent}}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/audit/events [get]
+func GetAuditEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "limit"})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidNonNegativeInteger, gin.H{"param": "offset"})
+		return
+	}
+
+	// 限制最大查询数量
+	if limit > 500 {
+		limit = 500
+	}
+
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+	filter.Limit = limit
+	filter.Offset = offset
+
+	events, total, err := services.QueryAuditEvents(filter)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	response := utils.NewPage(events, total, limit, offset)
+
+	utils.JsonSuccessResponse(c, response)
+}
+
+// ExportAuditEvents 导出审计日志
+// @Summary 导出审计日志
+// @Description 按与查询相同的条件导出审计日志为 CSV（按时间倒序，最多 audit.export_limit 条），需要 audit:read 权限（超级管理员）。导出操作本身也会记录到审计日志
+// @Tags audit
+// @Security BearerAuth
+// @Produce text/csv
+// @Param passkey query string false "操作者 passkey，按其 SHA-256 前 12 位匹配"
+// @Param passkey_label query string false "操作者 passkey 的 SHA-256 前 12 位，即记录中的 passkey_label"
+// @Param action query string false "动作"
+// @Param resource_id query string false "化合物ID"
+// @Param field query string false "返回的字段中包含该字段：MS2、MS2_full、Bioactivity、NMR_13C_data，不区分大小写"
+// @Param outcome query string false "结果"
+// @Param client_ip query string false "客户端IP"
+// @Param from query string false "起始时间（含）"
+// @Param to query string false "结束时间（不含）"
+// @Success 200 {string} string "CSV 文件"
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/audit/events/export [get]
+func ExportAuditEvents(c *gin.Context) {
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("audit-events-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(200)
+	// UTF-8 BOM，便于 Excel 正确识别中文
+	c.Writer.WriteString("\xEF\xBB\xBF")
+
+	// 表头写出后无法再返回 JSON 错误，出错时只记录日志，文件在出错处截断
+	if _, err := services.ExportAuditEventsCSV(c.Writer, filter); err != nil {
+		utils.LogError(err)
+	}
+}
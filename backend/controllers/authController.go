@@ -2,14 +2,14 @@ package controllers
 
 import (
 	"backend/config"
-	"backend/database"
 	"backend/models"
+	"backend/repository"
+	"backend/services"
 	"backend/utils"
+	"errors"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"gorm.io/gorm"
 )
 
 // LoginRequest 登录请求结构
@@ -22,11 +22,36 @@ type LoginResponse struct {
 	Operator    string `json:"operator"`
 	Token       string `json:"token"`
 	Description string `json:"description"`
+	Role        string `json:"role"`
+	ExpiresAt   int64  `json:"expires_at"` // 访问令牌过期时间（Unix 秒）
+	// 刷新令牌，访问令牌过期前使用 /api/auth/refresh 换取新令牌，每次刷新后旧的刷新令牌作废
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresAt int64  `json:"refresh_expires_at"` // 刷新令牌过期时间（Unix 秒），每次刷新后顺延
+	SessionID        string `json:"session_id"`
+}
+
+// RefreshRequest 刷新令牌请求结构
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// newLoginResponse 由令牌和 passkey 生成登录响应
+func newLoginResponse(p *models.Passkey, pair *services.TokenPair) LoginResponse {
+	return LoginResponse{
+		Token:            pair.AccessToken,
+		Operator:         p.Operator,
+		Description:      p.Description,
+		Role:             p.Role,
+		ExpiresAt:        pair.AccessExpiresAt.Unix(),
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresAt: pair.RefreshExpiresAt.Unix(),
+		SessionID:        pair.SessionID,
+	}
 }
 
 // Login 登录API
 // @Summary 用户登录
-// @Description 使用passkey进行登录，返回JWT token
+// @Description 使用passkey进行登录，创建会话并返回短期访问令牌和刷新令牌。会检查passkey的有效期和剩余登录次数，会话有效期由passkey设置决定且不超过其失效时间
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -34,67 +59,108 @@ type LoginResponse struct {
 // @Success 200 {object} utils.JSONResponse{data=LoginResponse}
 // @Failure 400 {object} utils.JSONResponse
 // @Failure 401 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
 // @Failure 500 {object} utils.JSONResponse
 // @Router /api/auth/login [post]
 func Login(c *gin.Context) {
 	// 解析请求体
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.JsonErrorResponse(c, 200400, "请求参数错误")
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
 		return
 	}
 
 	// 验证passkey是否为空
 	if req.Passkey == "" {
-		utils.JsonErrorResponse(c, 200400, "passkey不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "passkey"})
 		return
 	}
 
 	// 查询数据库验证passkey
-	var passkey models.Passkey
-	db := database.GetDB()
-
-	result := db.Table("passkeys").Where("Passkey = ? AND Is_Active = ?", req.Passkey, true).First(&passkey)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			utils.JsonErrorResponse(c, 200401, "无效的passkey或已禁用")
+	passkey, err := passkeyRepo.Find(req.Passkey)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.JsonErrorResponse(c, utils.CodeInvalidPasskey)
 		} else {
-			utils.JsonErrorResponse(c, 200500, "数据库查询失败")
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 		}
 		return
 	}
+	if !passkey.IsActive {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPasskey)
+		return
+	}
 
-	// 创建JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"passkey":     req.Passkey,
-		"operator":    passkey.Operator,
-		"description": passkey.Description,
-		"exp":         time.Now().Add(time.Hour * 24 * 3).Unix(), // 3天过期
-		"iat":         time.Now().Unix(),
-	})
+	// 统一身份认证账户只能通过 IdP 登录
+	if passkey.OIDCSubject != nil {
+		utils.JsonErrorResponse(c, utils.CodeOIDCAccount)
+		return
+	}
+
+	// 绑定安全密钥后 passkey UUID 作为注册码已失效；开启 webauthn.require_enrollment 后只能用于注册
+	if passkey.EnrolledAt != nil {
+		utils.JsonErrorResponse(c, utils.CodePasskeyEnrolled)
+		return
+	}
+	if config.Config.GetBool("webauthn.require_enrollment") {
+		utils.JsonErrorResponse(c, utils.CodeEnrollmentRequired)
+		return
+	}
 
-	// 获取JWT secret
-	secret := config.Config.GetString("jwt.secret")
-	if secret == "" {
-		utils.JsonErrorResponse(c, 200500, "服务器配置错误")
+	// 检查JWT secret
+	if config.Config.GetString("jwt.secret") == "" {
+		utils.JsonErrorResponse(c, utils.CodeServerMisconfigured)
 		return
 	}
 
-	// 签名token
-	tokenString, err := token.SignedString([]byte(secret))
+	// 检查有效期和登录次数，并记录本次登录
+	now := time.Now()
+	if err := services.RecordLogin(passkey, now); err != nil {
+		serviceError(c, err, utils.CodeDatabaseError)
+		return
+	}
+
+	// 创建会话并签发令牌
+	pair, err := services.StartSession(passkey, c.ClientIP(), c.Request.UserAgent(), now)
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, "生成token失败")
+		serviceError(c, err, utils.CodeInternalError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, newLoginResponse(passkey, pair))
+}
+
+// RefreshToken 刷新令牌API
+// @Summary 刷新令牌
+// @Description 使用刷新令牌换取新的访问令牌和刷新令牌，并顺延会话有效期。旧的刷新令牌随即作废，再次使用会注销整个会话
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "刷新请求"
+// @Success 200 {object} utils.JSONResponse{data=LoginResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/auth/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
 		return
 	}
 
-	// 返回token
-	response := LoginResponse{
-		Token:       tokenString,
-		Operator:    passkey.Operator,
-		Description: passkey.Description,
+	pair, passkey, err := services.RefreshSession(req.RefreshToken, time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrPasskeyNotFound) {
+			utils.JsonErrorResponse(c, utils.CodeRefreshTokenInvalid)
+		} else {
+			serviceError(c, err, utils.CodeInternalError)
+		}
+		return
 	}
 
-	utils.JsonSuccessResponse(c, response)
+	utils.JsonSuccessResponse(c, newLoginResponse(passkey, pair))
 }
 
 // VerifyLoginStatus 验证登录状态
@@ -105,6 +171,7 @@ func Login(c *gin.Context) {
 // @Produce json
 // @Success 200 {object} utils.JSONResponse
 // @Failure 401 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
 // @Router /api/auth/verify [get]
 func VerifyLoginStatus(c *gin.Context) {
 	// 中间件已经验证了JWT token，这里只需要返回成功状态
@@ -113,15 +180,65 @@ func VerifyLoginStatus(c *gin.Context) {
 
 // VerifyPasskeyModifiable 验证是否可以修改passkey
 // @Summary 验证是否可以修改passkey
-// @Description 验证当前用户是否有权限修改passkey（中间件已验证Extends字段为空），返回成功状态
+// @Description 验证当前用户是否有权限管理passkey（中间件已验证角色拥有 passkeys:manage 权限），返回成功状态
 // @Tags auth
 // @Security BearerAuth
 // @Produce json
 // @Success 200 {object} utils.JSONResponse
 // @Failure 401 {object} utils.JSONResponse
 // @Failure 403 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
 // @Router /api/auth/verify-passkey-modifiable [get]
 func VerifyPasskeyModifiable(c *gin.Context) {
-	// 中间件已经验证了JWT token和Extends字段为空，这里只需要返回成功状态
+	// 中间件已经验证了JWT token和角色权限，这里只需要返回成功状态
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// Logout 注销当前令牌
+// @Summary 退出登录
+// @Description 注销当前使用的令牌及其所属会话，其他设备上的登录不受影响
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/auth/logout [post]
+func Logout(c *gin.Context) {
+	expiresAt, _ := c.Get("token_expires_at")
+	expiresAtTime, _ := expiresAt.(time.Time)
+
+	if err := services.RevokeToken(c.GetString("jti"), c.GetString("passkey"), expiresAtTime); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	// 同时注销会话，使刷新令牌失效
+	if sessionID := c.GetString("session_id"); sessionID != "" {
+		if _, err := services.RevokeSession(c.GetString("passkey"), sessionID, models.SessionRevokeLogout); err != nil {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+			return
+		}
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// LogoutEverywhere 在所有设备上退出登录
+// @Summary 在所有设备上退出登录
+// @Description 使当前 passkey 此前签发的全部令牌失效并结束全部会话，包括当前令牌
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/auth/logout-all [post]
+func LogoutEverywhere(c *gin.Context) {
+	if err := services.RevokeAllTokens(c.GetString("passkey")); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
 	utils.JsonSuccessResponse(c, nil)
 }
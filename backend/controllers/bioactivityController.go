@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BioactivityRequest 活性记录请求结构
+type BioactivityRequest struct {
+	AssayType string   `json:"assay_type"`
+	Target    string   `json:"target"`
+	CellLine  string   `json:"cell_line"`
+	Organism  string   `json:"organism"`
+	Endpoint  string   `json:"endpoint" binding:"required"`
+	Qualifier string   `json:"qualifier"`
+	Value     *float64 `json:"value"`
+	Units     string   `json:"units"`
+	Reference string   `json:"reference"`
+}
+
+// apply 将请求内容写入活性记录
+func (req BioactivityRequest) apply(record *models.Bioactivity) {
+	record.AssayType = req.AssayType
+	record.Target = req.Target
+	record.CellLine = req.CellLine
+	record.Organism = req.Organism
+	record.Endpoint = req.Endpoint
+	record.Qualifier = req.Qualifier
+	record.Value = req.Value
+	record.Units = req.Units
+	record.Reference = req.Reference
+}
+
+// bioactivityValidationError 将 serviceErrorCodes 中的校验错误转换为错误响应，返回是否已处理
+func bioactivityValidationError(c *gin.Context, err error) bool {
+	code := serviceErrorCode(err, "")
+	if code == "" {
+		return false
+	}
+	utils.JsonErrorResponse(c, code)
+	return true
+}
+
+// GetCompoundBioactivity 获取化合物的结构化活性记录（保护数据）
+// @Summary 获取化合物活性记录
+// @Description 返回指定化合物的全部结构化活性记录，需要 data:read-protected 权限或包含 Bioactivity 字段的数据授权
+// @Tags bioactivity
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "数据ID"
+// @Success 200 {object} utils.JSONResponse{data=[]models.Bioactivity}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/{id}/bioactivity [get]
+func GetCompoundBioactivity(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
+		return
+	}
+
+	fields, ok := grantedFields(c, id)
+	if !ok {
+		return
+	}
+	if !containsField(fields, models.GrantFieldBioactivity) {
+		utils.JsonErrorResponse(c, utils.CodeNoDataGrant)
+		return
+	}
+
+	records, err := services.GetBioactivityByDataID(id)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	c.Set("audit_fields", []string{models.GrantFieldBioactivity})
+	utils.JsonSuccessResponse(c, records)
+}
+
+// CreateCompoundBioactivity 为化合物新增活性记录
+// @Summary 新增活性记录
+// @Description 为指定化合物新增一条结构化活性记录，需要 data:read-protected 和 data:write 权限
+// @Tags bioactivity
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "数据ID"
+// @Param request body BioactivityRequest true "活性记录"
+// @Success 200 {object} utils.JSONResponse{data=models.Bioactivity}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/{id}/bioactivity [post]
+func CreateCompoundBioactivity(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
+		return
+	}
+
+	var req BioactivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	// 确认化合物存在
+	exists, err := compoundRepo.Exists(id)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if !exists {
+		utils.JsonErrorResponse(c, utils.CodeDataNotFound)
+		return
+	}
+
+	record := models.Bioactivity{DataID: id}
+	req.apply(&record)
+	if err := services.CreateBioactivity(&record); err != nil {
+		if !bioactivityValidationError(c, err) {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	utils.JsonSuccessResponse(c, record)
+}
+
+// UpdateBioactivity 更新活性记录
+// @Summary 更新活性记录
+// @Description 更新指定的结构化活性记录，需要 data:read-protected 和 data:write 权限
+// @Tags bioactivity
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param bid path int true "活性记录ID"
+// @Param request body BioactivityRequest true "活性记录"
+// @Success 200 {object} utils.JSONResponse{data=models.Bioactivity}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/bioactivity/{bid} [put]
+func UpdateBioactivity(c *gin.Context) {
+	bid, err := strconv.ParseUint(c.Param("bid"), 10, 64)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "bid"})
+		return
+	}
+
+	var req BioactivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	record, err := services.GetBioactivityByID(uint(bid))
+	if err != nil {
+		serviceError(c, err, utils.CodeDatabaseError)
+		return
+	}
+
+	req.apply(record)
+	if err := services.UpdateBioactivity(record); err != nil {
+		if !bioactivityValidationError(c, err) {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	utils.JsonSuccessResponse(c, record)
+}
+
+// DeleteBioactivity 删除活性记录
+// @Summary 删除活性记录
+// @Description 删除指定的结构化活性记录，需要 data:read-protected 和 data:write 权限
+// @Tags bioactivity
+// @Security BearerAuth
+// @Produce json
+// @Param bid path int true "活性记录ID"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/bioactivity/{bid} [delete]
+func DeleteBioactivity(c *gin.Context) {
+	bid, err := strconv.ParseUint(c.Param("bid"), 10, 64)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "bid"})
+		return
+	}
+
+	affected, err := services.DeleteBioactivity(uint(bid))
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if affected == 0 {
+		utils.JsonErrorResponse(c, utils.CodeBioactivityNotFound)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// FilterBioactivity 按活性阈值筛选
+// @Summary 按活性阈值筛选
+// @Description 按终点类型、测定类型、靶点/细胞系/物种和数值阈值筛选活性记录，例如 endpoint=IC50&cell_line=HeLa&max_value=10&units=µM。
+// @Description 每返回一条记录计一次保护数据读取配额，剩余配额少于 limit 时只返回剩余配额数量的记录
+// @Tags bioactivity
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "返回的记录数量，默认为10"
+// @Param offset query int false "从第几条记录开始，默认为0"
+// @Param endpoint query []string false "活性终点数组，如IC50、MIC、EC50、INHIBITION" collectionFormat(multi)
+// @Param assay_type query []string false "测定类型数组" collectionFormat(multi)
+// @Param target query string false "靶点、细胞系或物种（模糊匹配）"
+// @Param cell_line query string false "细胞系（模糊匹配）"
+// @Param organism query string false "物种（模糊匹配）"
+// @Param min_value query number false "数值下限"
+// @Param max_value query number false "数值上限"
+// @Param units query string false "阈值单位，使用min_value或max_value时必填"
+// @Success 200 {object} utils.JSONResponse{data=utils.Page{data=[]services.BioactivityHit}}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/bioactivity/filter [get]
+func FilterBioactivity(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "limit"})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidNonNegativeInteger, gin.H{"param": "offset"})
+		return
+	}
+
+	// 限制最大查询数量
+	if limit > 100 {
+		limit = 100
+	}
+	// 每条记录计一次配额，不返回超出剩余配额的记录
+	limit, ok := protectedRecordLimit(c, limit)
+	if !ok {
+		return
+	}
+
+	filter := services.BioactivityFilter{
+		Endpoints:  c.QueryArray("endpoint"),
+		AssayTypes: c.QueryArray("assay_type"),
+		Target:     c.Query("target"),
+		CellLine:   c.Query("cell_line"),
+		Organism:   c.Query("organism"),
+		Units:      c.Query("units"),
+		Limit:      limit,
+		Offset:     offset,
+	}
+
+	if s := c.Query("min_value"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		// ParseFloat 接受 NaN 和 Inf，NaN 与任何值比较都不成立，阈值只接受有限数
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			utils.JsonErrorResponse(c, utils.CodeInvalidNumber, gin.H{"param": "min_value"})
+			return
+		}
+		filter.MinValue = &v
+	}
+	if s := c.Query("max_value"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			utils.JsonErrorResponse(c, utils.CodeInvalidNumber, gin.H{"param": "max_value"})
+			return
+		}
+		filter.MaxValue = &v
+	}
+	if (filter.MinValue != nil || filter.MaxValue != nil) && filter.Units == "" {
+		utils.JsonErrorResponse(c, utils.CodeParameterRequiredWith, gin.H{"param": "units", "with": "min_value/max_value"})
+		return
+	}
+
+	hits, totalCount, err := services.FilterBioactivity(filter)
+	if err != nil {
+		if !bioactivityValidationError(c, err) {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	if !chargeProtectedRecords(c, len(hits)) {
+		return
+	}
+	response := utils.NewPage(hits, totalCount, limit, offset)
+
+	c.Set("audit_fields", []string{models.GrantFieldBioactivity})
+	utils.JsonSuccessResponse(c, response)
+}
+
+// ImportBioactivity 从文本列导入结构化活性记录
+// @Summary 导入活性记录
+// @Description 解析 data.Bioactivity 文本列并写入 bioactivity 表，需要 data:read-protected 和 data:write 权限。overwrite=true 时覆盖已有记录
+// @Tags bioactivity
+// @Security BearerAuth
+// @Produce json
+// @Param overwrite query bool false "是否覆盖已有结构化记录，默认为false"
+// @Success 200 {object} utils.JSONResponse{data=services.BioactivityImportResult}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/bioactivity/import [post]
+func ImportBioactivity(c *gin.Context) {
+	overwrite := c.DefaultQuery("overwrite", "false") == "true"
+
+	stats, err := services.ImportBioactivityFromText(overwrite)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, stats)
+}
@@ -1,27 +1,17 @@
 package controllers
 
 import (
-	"backend/database"
 	"backend/models"
+	"backend/repository"
 	"backend/services"
 	"backend/utils"
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetDataRecords 获取指定条记录，支持从指定条目开始计数
-// @Summary 获取数据记录
-// @Description 返回指定数量的记录，支持从指定偏移量开始
-// @Tags data
-// @Accept json
-// @Produce json
-// @Param limit query int false "返回的记录数量，默认为10"
-// @Param offset query int false "从第几条记录开始，默认为0"
-// @Success 200 {object} utils.JSONResponse{data=[]models.Data}
-// @Failure 400 {object} utils.JSONResponse
-// @Failure 500 {object} utils.JSONResponse
-// @Router /api/data [get]
+// GetDataRecords 获取指定条记录，支持从指定条目开始计数（当前未注册路由）
 func GetDataRecords(c *gin.Context) {
 	// 获取查询参数
 	limitStr := c.DefaultQuery("limit", "10")
@@ -30,13 +20,13 @@ func GetDataRecords(c *gin.Context) {
 	// 转换参数为整数
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit < 1 {
-		utils.JsonErrorResponse(c, 200400, "参数limit必须是正整数")
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "limit"})
 		return
 	}
 
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil || offset < 0 {
-		utils.JsonErrorResponse(c, 200400, "参数offset必须是非负整数")
+		utils.JsonErrorResponse(c, utils.CodeInvalidNonNegativeInteger, gin.H{"param": "offset"})
 		return
 	}
 
@@ -45,28 +35,14 @@ func GetDataRecords(c *gin.Context) {
 		limit = 100
 	}
 
-	var data []models.Data
-	db := database.GetDB()
-
-	// 查询数据
-	result := db.Offset(offset).Limit(limit).Find(&data)
-	if result.Error != nil {
-		utils.JsonErrorResponse(c, 200500, "查询数据失败")
+	// 查询数据和总记录数
+	data, totalCount, err := compoundRepo.List(offset, limit)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 		return
 	}
 
-	// 获取总记录数
-	var totalCount int64
-	db.Model(&models.Data{}).Count(&totalCount)
-
-	response := map[string]interface{}{
-		"data":        data,
-		"total":       totalCount,
-		"limit":       limit,
-		"offset":      offset,
-		"has_more":    offset+limit < int(totalCount),
-		"next_offset": offset + limit,
-	}
+	response := utils.NewPage(data, totalCount, limit, offset)
 
 	utils.JsonSuccessResponse(c, response)
 }
@@ -78,7 +54,7 @@ func GetDataRecords(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "数据ID"
-// @Success 200 {object} utils.JSONResponse{data=models.Data}
+// @Success 200 {object} utils.JSONResponse{data=models.PublicData}
 // @Failure 400 {object} utils.JSONResponse
 // @Failure 404 {object} utils.JSONResponse
 // @Failure 500 {object} utils.JSONResponse
@@ -87,63 +63,81 @@ func GetDataByID(c *gin.Context) {
 	// 获取路径参数
 	id := c.Param("id")
 	if id == "" {
-		utils.JsonErrorResponse(c, 200400, "参数id不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
 		return
 	}
 
-	var data models.PublicData
-	db := database.GetDB()
-
 	// 查询数据
-	result := db.Table("data").Where("ID = ?", id).First(&data)
-	if result.Error != nil {
-		if result.Error.Error() == "record not found" {
-			utils.JsonErrorResponse(c, 200404, "数据不存在")
-		} else {
-			utils.JsonErrorResponse(c, 200500, "查询数据失败")
-		}
+	data, err := compoundRepo.FindPublic(id)
+	if err != nil {
+		compoundLookupError(c, err)
+		return
+	}
+
+	// 附带别名
+	synonyms, err := services.GetSynonymsByDataID(id)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	data.Synonyms = synonyms
+
+	// 附带引用文献
+	references, err := services.GetReferencesByDataIDs([]string{id})
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 		return
 	}
+	data.References = references
 
 	utils.JsonSuccessResponse(c, data)
 }
 
+// DataStatistics 数据统计信息
+type DataStatistics struct {
+	// 化合物数量
+	TotalCompounds int64 `json:"total_compounds"`
+	// 来源物种数量
+	TotalSpecies int64 `json:"total_species"`
+	// 有活性数据的化合物数量
+	BioactivityData int64 `json:"bioactivity_data"`
+	// 有质谱数据的化合物数量
+	MSData int64 `json:"ms_data"`
+	// 有核磁数据的化合物数量
+	NMRData int64 `json:"nmr_data"`
+}
+
 // GetDataStatistics 获取数据统计信息
 // @Summary 获取数据统计
 // @Description 返回化合物数量、物种数量、活性数据量、质谱数据量、核磁数据量等统计信息
 // @Tags data
 // @Accept json
 // @Produce json
-// @Success 200 {object} utils.JSONResponse{data=map[string]interface{}}
+// @Success 200 {object} utils.JSONResponse{data=DataStatistics}
 // @Failure 500 {object} utils.JSONResponse
 // @Router /api/data/statistics [get]
 func GetDataStatistics(c *gin.Context) {
-	db := database.GetDB()
+	var stats DataStatistics
 
-	// 定义统计结果结构
-	var stats struct {
-		TotalCompounds  int64 `json:"total_compounds"`
-		TotalSpecies    int64 `json:"total_species"`
-		BioactivityData int64 `json:"bioactivity_data"`
-		MSData          int64 `json:"ms_data"`
-		NMRData         int64 `json:"nmr_data"`
+	// 获取总化合物数量（总记录数）、活性数据量（Bioactivity不为空）、
+	// 质谱数据量（MS1_H或MS2不为空）和核磁数据量（NMR_13C_data不为空）
+	counts, err := compoundRepo.Statistics()
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
 	}
+	stats.TotalCompounds = counts.Total
+	stats.BioactivityData = counts.Bioactivity
+	stats.MSData = counts.MS
+	stats.NMRData = counts.NMR
 
-	// 获取总化合物数量（总记录数）
-	db.Model(&models.Data{}).Count(&stats.TotalCompounds)
-
-	// // 获取物种数量（固定值，这里假设是ItemType不为空的记录数）
-	// db.Model(&models.Data{}).Where("ItemType IS NOT NULL AND ItemType != ''").Count(&stats.TotalSpecies)
-	stats.TotalSpecies = 500
-
-	// 获取活性数据量（Bioactivity不为空的数量）
-	db.Model(&models.Data{}).Where("Bioactivity IS NOT NULL AND Bioactivity != ''").Count(&stats.BioactivityData)
-
-	// 获取质谱数据量（MS1或MS2不为空的数量）
-	db.Model(&models.Data{}).Where("MS1_H IS NOT NULL OR MS2 IS NOT NULL AND MS2 != ''").Count(&stats.MSData)
-
-	// 获取核磁数据量（NMR_13C_data不为空的数量）
-	db.Model(&models.Data{}).Where("NMR_13C_data IS NOT NULL AND NMR_13C_data != ''").Count(&stats.NMRData)
+	// 获取物种数量（按来源生物的分类信息统计）
+	totalSpecies, err := services.CountSpecies()
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	stats.TotalSpecies = totalSpecies
 
 	utils.JsonSuccessResponse(c, stats)
 }
@@ -161,7 +155,11 @@ func GetDataStatistics(c *gin.Context) {
 // @Param max_weight query number false "最大分子量"
 // @Param description query []string false "Description描述数组" collectionFormat(multi)
 // @Param source query []string false "Source来源数组" collectionFormat(multi)
-// @Success 200 {object} utils.JSONResponse{data=[]models.Data}
+// @Param taxon_rank query string false "来源生物分类阶元，如genus"
+// @Param taxon query string false "来源生物分类单元名称，如Streptomyces"
+// @Success 200 {object} utils.JSONResponse{data=utils.Page{data=[]services.CompoundSummary}}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
 // @Failure 500 {object} utils.JSONResponse
 // @Router /api/data/filter [get]
 func FilterCompounds(c *gin.Context) {
@@ -173,17 +171,19 @@ func FilterCompounds(c *gin.Context) {
 	maxWeightStr := c.Query("max_weight")
 	descriptions := c.QueryArray("description")
 	sources := c.QueryArray("source")
+	taxonRank := c.Query("taxon_rank")
+	taxon := c.Query("taxon")
 
 	// 转换参数为整数
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit < 1 {
-		utils.JsonErrorResponse(c, 200400, "参数limit必须是正整数")
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "limit"})
 		return
 	}
 
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil || offset < 0 {
-		utils.JsonErrorResponse(c, 200400, "参数offset必须是非负整数")
+		utils.JsonErrorResponse(c, utils.CodeInvalidNonNegativeInteger, gin.H{"param": "offset"})
 		return
 	}
 
@@ -198,7 +198,7 @@ func FilterCompounds(c *gin.Context) {
 	if minWeightStr != "" {
 		minWeight, err = strconv.ParseFloat(minWeightStr, 64)
 		if err != nil {
-			utils.JsonErrorResponse(c, 200400, "参数min_weight必须是数字")
+			utils.JsonErrorResponse(c, utils.CodeInvalidNumber, gin.H{"param": "min_weight"})
 			return
 		}
 	}
@@ -206,26 +206,28 @@ func FilterCompounds(c *gin.Context) {
 	if maxWeightStr != "" {
 		maxWeight, err = strconv.ParseFloat(maxWeightStr, 64)
 		if err != nil {
-			utils.JsonErrorResponse(c, 200400, "参数max_weight必须是数字")
+			utils.JsonErrorResponse(c, utils.CodeInvalidNumber, gin.H{"param": "max_weight"})
 			return
 		}
 	}
 
+	if taxonRank != "" && taxon == "" {
+		utils.JsonErrorResponse(c, utils.CodeParameterRequiredWith, gin.H{"param": "taxon", "with": "taxon_rank"})
+		return
+	}
+
 	// 调用筛选服务，传入分页参数和数组参数
-	compounds, totalCount, err := services.FilterCompounds(itemTypes, minWeight, maxWeight, descriptions, sources, limit, offset)
+	compounds, totalCount, err := services.FilterCompounds(itemTypes, minWeight, maxWeight, descriptions, sources, taxonRank, taxon, limit, offset)
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, "筛选化合物失败")
+		if errors.Is(err, services.ErrInvalidRank) {
+			utils.JsonErrorResponse(c, utils.CodeUnsupportedRank)
+		} else {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
 		return
 	}
 
-	response := map[string]interface{}{
-		"data":        compounds,
-		"total":       totalCount,
-		"limit":       limit,
-		"offset":      offset,
-		"has_more":    offset+limit < int(totalCount),
-		"next_offset": offset + limit,
-	}
+	response := utils.NewPage(compounds, totalCount, limit, offset)
 
 	utils.JsonSuccessResponse(c, response)
 }
@@ -242,7 +244,7 @@ func FilterCompounds(c *gin.Context) {
 func GetItemTypes(c *gin.Context) {
 	itemTypes, err := services.GetItemTypes()
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, "获取ItemType分类失败")
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 		return
 	}
 
@@ -261,7 +263,7 @@ func GetItemTypes(c *gin.Context) {
 func GetDescriptions(c *gin.Context) {
 	descriptions, err := services.GetDescriptions()
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, "获取Description分类失败")
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 		return
 	}
 
@@ -270,73 +272,106 @@ func GetDescriptions(c *gin.Context) {
 
 // GetDataByIDFull 根据ID获取单条数据记录（保护数据）
 // @Summary 根据ID获取数据（保护数据）
-// @Description 根据数据ID返回MS2、Bioactivity和NMR_13C_data等保护数据
+// @Description 根据数据ID返回MS2、Bioactivity和NMR_13C_data等保护数据。拥有 data:read-protected 权限时返回全部字段，否则只返回数据授权允许的字段
 // @Tags data
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "数据ID"
-// @Success 200 {object} utils.JSONResponse{data=map[string]interface{}}
+// @Success 200 {object} utils.JSONResponse{data=models.ProtectedData}
 // @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
 // @Failure 404 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
 // @Failure 500 {object} utils.JSONResponse
-// @Router /api/data/{id}/full [get]
+// @Router /api/data/{id}/protected [get]
 func GetDataByIDFull(c *gin.Context) {
 	// 获取路径参数
 	id := c.Param("id")
 	if id == "" {
-		utils.JsonErrorResponse(c, 200400, "参数id不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
 		return
 	}
 
-	var data models.ProtectedData
-	db := database.GetDB()
-
-	// 查询数据，只选择需要的字段
-	result := db.Table("data").
-		Select("MS2, Bioactivity, NMR_13C_data").
-		Where("ID = ?", id).
-		First(&data)
-	if result.Error != nil {
-		if result.Error.Error() == "record not found" {
-			utils.JsonErrorResponse(c, 200404, "数据不存在")
-		} else {
-			utils.JsonErrorResponse(c, 200500, "查询数据失败")
+	// 按角色和数据授权确定可返回的字段（MS2_full 由单独的接口返回）
+	fields, ok := grantedFields(c, id)
+	if !ok {
+		return
+	}
+	var columns []string
+	for _, field := range fields {
+		if field != models.GrantFieldMS2Full {
+			columns = append(columns, field)
 		}
+	}
+	if len(columns) == 0 {
+		utils.JsonErrorResponse(c, utils.CodeNoDataGrant)
 		return
 	}
 
+	// 查询数据，只选择允许的字段
+	data, err := compoundRepo.FindProtected(id, columns)
+	if err != nil {
+		compoundLookupError(c, err)
+		return
+	}
+
+	// 有活性字段权限时附带结构化活性记录
+	if containsField(columns, models.GrantFieldBioactivity) {
+		records, err := services.GetBioactivityByDataID(id)
+		if err != nil {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+			return
+		}
+		data.BioactivityRecords = records
+	}
+	data.GrantedFields = fields
+
+	c.Set("audit_fields", columns)
 	utils.JsonSuccessResponse(c, data)
 }
 
+// GetMS2FullByID 根据ID获取完整MS2数据（保护数据）
+// @Summary 获取完整MS2数据
+// @Description 返回化合物的完整MS2数据，需要 data:read-protected 权限或包含 MS2_full 字段的数据授权
+// @Tags data
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "数据ID"
+// @Success 200 {object} utils.JSONResponse{data=string}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/{id}/ms2-full [get]
 func GetMS2FullByID(c *gin.Context) {
 	// 获取路径参数
 	id := c.Param("id")
 	if id == "" {
-		utils.JsonErrorResponse(c, 200400, "参数id不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
 		return
 	}
 
-	var data struct {
-		MS2_full string
+	fields, ok := grantedFields(c, id)
+	if !ok {
+		return
+	}
+	if !containsField(fields, models.GrantFieldMS2Full) {
+		utils.JsonErrorResponse(c, utils.CodeNoDataGrant)
+		return
 	}
-	db := database.GetDB()
 
-	// 查询数据，只选择需要的字段
-	result := db.Table("data").
-		Select("MS2_full").
-		Where("ID = ?", id).
-		Order("ID").
-		First(&data)
-	if result.Error != nil {
-		if result.Error.Error() == "record not found" {
-			utils.JsonErrorResponse(c, 200404, "数据不存在")
-		} else {
-			utils.JsonErrorResponse(c, 200500, "查询数据失败")
-		}
+	ms2Full, err := compoundRepo.FindMS2Full(id)
+	if err != nil {
+		compoundLookupError(c, err)
 		return
 	}
 
-	utils.JsonSuccessResponse(c, data.MS2_full)
+	c.Set("audit_fields", []string{models.GrantFieldMS2Full})
+	utils.JsonSuccessResponse(c, ms2Full)
 }
 
 // GetSources 获取所有Source分类
@@ -351,7 +386,7 @@ func GetMS2FullByID(c *gin.Context) {
 func GetSources(c *gin.Context) {
 	sources, err := services.GetSources()
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, "获取Source分类失败")
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 		return
 	}
 
@@ -359,41 +394,47 @@ func GetSources(c *gin.Context) {
 }
 
 // GetStructure 获取指定id的structure数据
-// @Summary 获取指定id的structure数据
-// @Description 获取指定id的structure数据
+// @Summary 获取化合物结构
+// @Description 返回化合物的 Structure 字段（MOL 格式的结构数据）
 // @Tags data
-// @Accept json
 // @Produce json
-// @Param id string
+// @Param id path string true "数据ID"
 // @Success 200 {object} utils.JSONResponse{data=string}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
 // @Failure 500 {object} utils.JSONResponse
-// @Router /api/data/sources [get]
+// @Router /api/data/{id}/structure [get]
 func GetStructure(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		utils.JsonErrorResponse(c, 200400, "参数id不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
 		return
 	}
-	// 定义只包含Structure的结构
 
-	var data struct {
-		Structure string
+	structure, err := compoundRepo.FindStructure(id)
+	if err != nil {
+		compoundLookupError(c, err)
+		return
 	}
-	db := database.GetDB()
 
-	// 查询数据，只选择需要的字段
-	result := db.Table("data").
-		Select("Structure").
-		Where("ID = ?", id).
-		First(&data)
-	if result.Error != nil {
-		if result.Error.Error() == "record not found" {
-			utils.JsonErrorResponse(c, 200404, "数据不存在")
-		} else {
-			utils.JsonErrorResponse(c, 200500, "查询数据失败")
-		}
-		return
+	utils.JsonSuccessResponse(c, structure)
+}
+
+// compoundLookupError 按查询化合物的错误类型写入响应
+func compoundLookupError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.JsonErrorResponse(c, utils.CodeDataNotFound)
+	} else {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 	}
+}
 
-	utils.JsonSuccessResponse(c, data.Structure)
+// containsField 判断字段列表中是否包含指定字段
+func containsField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"backend/docs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOpenAPISpec 获取 OpenAPI 文档
+// @Summary 获取 OpenAPI 文档
+// @Description 返回由接口注释生成的 OpenAPI 3 文档
+// @Tags meta
+// @Produce json
+// @Success 200 {object} object "OpenAPI 3 文档"
+// @Router /api/openapi.json [get]
+func GetOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", docs.OpenAPI)
+}
+
+// GetAPIDocs 接口文档页面
+// @Summary 接口文档页面
+// @Description 使用 Swagger UI 展示 /api/openapi.json
+// @Tags meta
+// @Produce html
+// @Success 200 {string} string "HTML 页面"
+// @Router /api/docs [get]
+func GetAPIDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docs.UI)
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"backend/services"
+	"backend/utils"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serviceErrorCodes 服务层返回的错误对应的错误代码，按顺序使用 errors.Is 匹配
+var serviceErrorCodes = []struct {
+	err  error
+	code utils.ErrorCode
+}{
+	{services.ErrDataNotFound, utils.CodeDataNotFound},
+	{services.ErrBioactivityNotFound, utils.CodeBioactivityNotFound},
+	{services.ErrOrganismNotFound, utils.CodeOrganismNotFound},
+	{services.ErrReferenceNotFound, utils.CodeReferenceNotFound},
+
+	{services.ErrInvalidRank, utils.CodeUnsupportedRank},
+	{services.ErrUnknownAdduct, utils.CodeUnsupportedAdduct},
+	{services.ErrInvalidFormula, utils.CodeInvalidFormula},
+	{services.ErrUnknownElement, utils.CodeUnknownElement},
+	{services.ErrAdductIncomplete, utils.CodeAdductIncomplete},
+	{services.ErrInvalidEndpoint, utils.CodeInvalidEndpoint},
+	{services.ErrInvalidQualifier, utils.CodeInvalidQualifier},
+	{services.ErrInvalidUnits, utils.CodeInvalidUnits},
+	{services.ErrInvalidGrantField, utils.CodeInvalidGrantField},
+	{services.ErrInvalidGrantScope, utils.CodeInvalidGrantScope},
+	{services.ErrEmptyGrantFields, utils.CodeEmptyGrantFields},
+	{services.ErrEmptyGrantScope, utils.CodeEmptyGrantScope},
+	{services.ErrInvalidSynonymType, utils.CodeInvalidSynonymType},
+	{services.ErrEmptySynonym, utils.CodeEmptySynonym},
+	{services.ErrDuplicateSynonym, utils.CodeDuplicateSynonym},
+	{services.ErrInvalidReferenceField, utils.CodeInvalidReferenceField},
+	{services.ErrInvalidDOI, utils.CodeInvalidDOI},
+	{services.ErrInvalidPubMedID, utils.CodeInvalidPubMedID},
+	{services.ErrDuplicateReference, utils.CodeDuplicateReference},
+	{services.ErrInvalidValidity, utils.CodeInvalidValidity},
+	{services.ErrInvalidMaxLogins, utils.CodeInvalidMaxLogins},
+	{services.ErrInvalidTokenLifetime, utils.CodeInvalidTokenLifetime},
+	{services.ErrNegativeLimit, utils.CodeNegativeLimit},
+	{services.ErrExceedsParentValidity, utils.CodeExceedsParentValidity},
+	{services.ErrExceedsParentLimit, utils.CodeExceedsParentLimit},
+	{services.ErrDescendantExceeds, utils.CodeDescendantExceeds},
+	{services.ErrOutsideDelegationScope, utils.CodeOutsideDelegationScope},
+
+	{services.ErrTokenRevoked, utils.CodeTokenRevoked},
+	{services.ErrSessionRevoked, utils.CodeSessionRevoked},
+	{services.ErrSessionExpired, utils.CodeSessionExpired},
+	{services.ErrRefreshTokenInvalid, utils.CodeRefreshTokenInvalid},
+	{services.ErrRefreshTokenReused, utils.CodeRefreshTokenReused},
+	{services.ErrPasskeyInactive, utils.CodePasskeyInactive},
+	{services.ErrPasskeyNotYetValid, utils.CodePasskeyNotYetValid},
+	{services.ErrPasskeyExpired, utils.CodePasskeyExpired},
+	{services.ErrLoginLimitReached, utils.CodeLoginLimitReached},
+	{services.ErrPasskeyEnrolled, utils.CodePasskeyEnrolled},
+	{services.ErrEnrollmentCodeConsumed, utils.CodeEnrollmentCodeConsumed},
+	{services.ErrCeremonyNotFound, utils.CodeCeremonyNotFound},
+	{services.ErrWebAuthnFailed, utils.CodeWebAuthnFailed},
+	{services.ErrCredentialCloned, utils.CodeCredentialCloned},
+	{services.ErrCredentialExists, utils.CodeCredentialExists},
+	{services.ErrCredentialNotFound, utils.CodeCredentialNotFound},
+	{services.ErrLastCredential, utils.CodeLastCredential},
+	{services.ErrOIDCStateInvalid, utils.CodeOIDCStateInvalid},
+	{services.ErrOIDCFailed, utils.CodeOIDCFailed},
+	{services.ErrOIDCEmailNotVerified, utils.CodeOIDCEmailNotVerified},
+	{services.ErrOIDCNoRole, utils.CodeOIDCNoRole},
+	{services.ErrOIDCCodeInvalid, utils.CodeOIDCCodeInvalid},
+
+	{services.ErrJWTSecretMissing, utils.CodeServerMisconfigured},
+	{services.ErrWebAuthnNotConfigured, utils.CodeWebAuthnNotConfigured},
+	{services.ErrOIDCNotConfigured, utils.CodeOIDCNotConfigured},
+	{services.ErrRdkitUnavailable, utils.CodeChemistryUnavailable},
+	{utils.ErrPythonTimeout, utils.CodeChemistryUnavailable},
+	{utils.ErrPythonClosed, utils.CodeChemistryUnavailable},
+}
+
+// serviceErrorCode 返回服务层错误对应的错误代码，没有对应的错误代码时返回 fallback
+func serviceErrorCode(err error, fallback utils.ErrorCode) utils.ErrorCode {
+	for _, item := range serviceErrorCodes {
+		if errors.Is(err, item.err) {
+			return item.code
+		}
+	}
+	return fallback
+}
+
+// serviceError 按服务层返回的错误响应，没有对应的错误代码时使用 fallback
+func serviceError(c *gin.Context, err error, fallback utils.ErrorCode) {
+	code := serviceErrorCode(err, fallback)
+	if code == utils.CodeInvalidTokenLifetime {
+		utils.JsonErrorResponse(c, code, gin.H{"min": services.MinTokenLifetime, "max": services.MaxTokenLifetime})
+		return
+	}
+	utils.JsonErrorResponse(c, code)
+}
+
+// GetErrorCatalogue 获取错误代码列表
+// @Summary 获取错误代码列表
+// @Description 返回全部错误代码（响应体的 error 字段）及其 HTTP 状态码和消息。消息中的 {name} 由响应的 details 中同名字段填充。
+// @Description 消息语言由查询参数 lang 或请求头 Accept-Language 决定，支持 zh-CN（默认）和 en
+// @Tags meta
+// @Produce json
+// @Param lang query string false "消息语言：zh-CN 或 en"
+// @Param Accept-Language header string false "消息语言，lang 为空时使用"
+// @Success 200 {object} utils.JSONResponse{data=[]utils.ErrorDefinition}
+// @Router /api/errors [get]
+func GetErrorCatalogue(c *gin.Context) {
+	lang := utils.NegotiateLanguage(c)
+	c.Header("Content-Language", lang)
+	c.Header("Vary", "Accept-Language")
+	utils.JsonSuccessResponse(c, utils.ErrorCatalogue(lang))
+}
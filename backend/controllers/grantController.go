@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DataGrantRequest 数据授权请求结构
+type DataGrantRequest struct {
+	Fields      []string `json:"fields" binding:"required"` // MS2、MS2_full、Bioactivity、NMR_13C_data
+	ScopeType   string   `json:"scope_type"`                // all、source、tag、ids，默认为 all
+	ScopeValues []string `json:"scope_values"`              // 来源、标签或化合物ID
+	Note        string   `json:"note"`
+}
+
+// DataGrantResponse 数据授权响应结构
+type DataGrantResponse struct {
+	models.DataGrant
+	FieldList []string `json:"field_list"`
+	ValueList []string `json:"value_list"`
+}
+
+// newDataGrantResponse 将授权记录转换为响应格式
+func newDataGrantResponse(grant models.DataGrant) DataGrantResponse {
+	values := services.SplitGrantValues(grant.ScopeValues)
+	if values == nil {
+		values = []string{}
+	}
+	return DataGrantResponse{
+		DataGrant: grant,
+		FieldList: services.SplitGrantValues(grant.Fields),
+		ValueList: values,
+	}
+}
+
+// grantedFields 获取当前用户对化合物可访问的保护字段，出错时写入错误响应并返回 false
+func grantedFields(c *gin.Context, id string) ([]string, bool) {
+	fields, err := services.GrantedFields(c.GetString("passkey"), c.GetString("role"), id)
+	if err != nil {
+		serviceError(c, err, utils.CodeDatabaseError)
+		return nil, false
+	}
+	return fields, true
+}
+
+// findManageablePasskey 查找路径参数中的 passkey 并检查当前用户能否管理，出错时写入错误响应
+func findManageablePasskey(c *gin.Context) (*models.Passkey, bool) {
+	passkeyID := c.Param("passkey")
+	if passkeyID == "" {
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "passkey"})
+		return nil, false
+	}
+
+	passkey, err := passkeyRepo.Find(passkeyID)
+	if err != nil {
+		passkeyLookupError(c, err)
+		return nil, false
+	}
+
+	if !checkManageable(c, passkey) {
+		return nil, false
+	}
+	return passkey, true
+}
+
+// GetMyDataGrants 获取当前用户的数据授权
+// @Summary 获取当前用户的数据授权
+// @Description 返回当前用户的全部数据授权，拥有 data:read-protected 权限的角色可访问全部保护数据，无需授权
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=[]DataGrantResponse}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/auth/grants [get]
+func GetMyDataGrants(c *gin.Context) {
+	grants, err := services.GetDataGrants(c.GetString("passkey"))
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	response := make([]DataGrantResponse, 0, len(grants))
+	for _, grant := range grants {
+		response = append(response, newDataGrantResponse(grant))
+	}
+
+	utils.JsonSuccessResponse(c, response)
+}
+
+// GetPasskeyDataGrants 获取 passkey 的数据授权
+// @Summary 获取 passkey 的数据授权
+// @Description 返回指定 passkey 的全部数据授权
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Success 200 {object} utils.JSONResponse{data=[]DataGrantResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/grants [get]
+func GetPasskeyDataGrants(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	grants, err := services.GetDataGrants(passkey.Passkey)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	response := make([]DataGrantResponse, 0, len(grants))
+	for _, grant := range grants {
+		response = append(response, newDataGrantResponse(grant))
+	}
+
+	utils.JsonSuccessResponse(c, response)
+}
+
+// CreatePasskeyDataGrant 为 passkey 新增数据授权
+// @Summary 新增数据授权
+// @Description 授权 passkey 访问部分化合物（按来源、标签或ID）的部分保护字段，多条授权取并集
+// @Tags passkey
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Param request body DataGrantRequest true "授权信息"
+// @Success 200 {object} utils.JSONResponse{data=DataGrantResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/grants [post]
+func CreatePasskeyDataGrant(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	var req DataGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	// 创建者名称，与 Extends 一致，不记录创建者的 passkey
+	createdBy := c.GetString("operator")
+	grant, err := services.NewDataGrant(passkey.Passkey, req.Fields, req.ScopeType, req.ScopeValues, req.Note, createdBy)
+	if err != nil {
+		serviceError(c, err, utils.CodeInvalidRequest)
+		return
+	}
+
+	if err := services.CreateDataGrant(grant); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, newDataGrantResponse(*grant))
+}
+
+// DeletePasskeyDataGrant 删除 passkey 的数据授权
+// @Summary 删除数据授权
+// @Description 删除指定 passkey 的一条数据授权
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Param gid path int true "授权ID"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/grants/{gid} [delete]
+func DeletePasskeyDataGrant(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	gid, err := strconv.ParseUint(c.Param("gid"), 10, 64)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "gid"})
+		return
+	}
+
+	affected, err := services.DeleteDataGrant(passkey.Passkey, uint(gid))
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if affected == 0 {
+		utils.JsonErrorResponse(c, utils.CodeGrantNotFound)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
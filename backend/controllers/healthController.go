@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"backend/metrics"
+	"backend/services"
+	"backend/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var metricsHandler = metrics.Handler()
+
+// GetHealthz 存活检查
+// @Summary 存活检查
+// @Description 进程能够处理请求时返回 200，不检查数据库和RDKit
+// @Tags meta
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=string} "data 为 ok"
+// @Router /healthz [get]
+func GetHealthz(c *gin.Context) {
+	utils.JsonSuccessResponse(c, "ok")
+}
+
+// GetReadyz 就绪检查
+// @Summary 就绪检查
+// @Description 在 health.timeout 秒内检查数据库连接和RDKit进程的响应，结果缓存 health.cache_ttl 秒（默认 5 秒）。
+// @Description strict 模式下任一依赖不可用时返回 503；degraded 模式下只有数据库不可用时返回 503，RDKit不可用时 degraded 为 true
+// @Tags meta
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=services.Readiness}
+// @Failure 503 {object} utils.JSONResponse "details 中的 failed 为检查失败的依赖，checks 为全部检查结果"
+// @Router /readyz [get]
+func GetReadyz(c *gin.Context) {
+	readiness := services.CachedReadiness(c.Request.Context())
+	if !readiness.Ready {
+		utils.JsonErrorResponse(c, utils.CodeNotReady, gin.H{
+			"failed": strings.Join(readiness.Failed(), ", "),
+			"checks": readiness.Checks,
+		})
+		return
+	}
+	utils.JsonSuccessResponse(c, readiness)
+}
+
+// GetMetrics 获取 Prometheus 指标
+// @Summary 获取 Prometheus 指标
+// @Description 以 Prometheus 文本格式返回请求数和耗时、数据库操作耗时、RDKit 进程的队列和请求耗时、化合物总数和各 passkey 的保护数据读取次数。
+// @Description 需要在 Authorization 头中以 Bearer 方式提供 metrics.token 配置的令牌，未配置时返回 503
+// @Tags meta
+// @Produce plain
+// @Success 200 {string} string "Prometheus 文本格式的指标"
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /metrics [get]
+func GetMetrics(c *gin.Context) {
+	metricsHandler.ServeHTTP(c.Writer, c.Request)
+}
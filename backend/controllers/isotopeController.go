@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"backend/services"
+	"backend/utils"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetIsotopePattern 模拟化合物的理论同位素分布
+// @Summary 模拟同位素分布
+// @Description 根据化合物分子式和加合离子类型，计算指定分辨率下的理论同位素峰（用于HRMS同位素簇比对）
+// @Tags data
+// @Accept json
+// @Produce json
+// @Param id path string true "数据ID"
+// @Param adduct query string false "加合离子类型，默认为[M+H]+"
+// @Param resolution query number false "分辨率（FWHM），默认为30000，0表示按整数质量合并"
+// @Param min_intensity query number false "最小相对强度（%），默认为0.1"
+// @Success 200 {object} utils.JSONResponse{data=services.IsotopePattern}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/{id}/isotope-pattern [get]
+func GetIsotopePattern(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
+		return
+	}
+
+	adduct := c.DefaultQuery("adduct", "[M+H]+")
+
+	resolution, err := strconv.ParseFloat(c.DefaultQuery("resolution", "30000"), 64)
+	// ParseFloat 接受 NaN 和 Inf，二者与任何数比较都不会落入范围检查，需单独排除
+	if err != nil || math.IsNaN(resolution) || math.IsInf(resolution, 0) || resolution < 0 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidNonNegativeNumber, gin.H{"param": "resolution"})
+		return
+	}
+
+	minIntensity, err := strconv.ParseFloat(c.DefaultQuery("min_intensity", "0.1"), 64)
+	if err != nil || math.IsNaN(minIntensity) || minIntensity < 0 || minIntensity > 100 {
+		utils.JsonErrorResponse(c, utils.CodeNumberOutOfRange, gin.H{"param": "min_intensity", "min": 0, "max": 100})
+		return
+	}
+
+	pattern, err := services.GetCompoundIsotopePattern(id, adduct, resolution, minIntensity)
+	if err != nil {
+		serviceError(c, err, utils.CodeInternalError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, pattern)
+}
+
+// GetAdducts 获取支持的加合离子类型
+// @Summary 获取加合离子类型
+// @Description 返回同位素分布模拟支持的加合离子类型及其电荷
+// @Tags data
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=[]services.Adduct}
+// @Router /api/data/adducts [get]
+func GetAdducts(c *gin.Context) {
+	utils.JsonSuccessResponse(c, services.GetAdducts())
+}
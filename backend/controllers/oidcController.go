@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"backend/config"
+	"backend/services"
+	"backend/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCConfigResponse 统一身份认证配置响应结构，前端据此显示登录按钮
+type OIDCConfigResponse struct {
+	Enabled     bool   `json:"enabled"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// OIDCExchangeRequest 领取登录结果的请求结构
+type OIDCExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// oidcFrontendRedirect 生成登录完成后跳转到前端的地址，结果通过查询参数传递
+func oidcFrontendRedirect(path string, params url.Values) string {
+	base := strings.TrimRight(config.Config.GetString("oidc.frontend_url"), "/")
+	target, err := url.Parse(base + services.SafeRedirectPath(path))
+	if err != nil {
+		target, _ = url.Parse(base + "/")
+	}
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+// GetOIDCConfig 获取统一身份认证配置
+// @Summary 获取统一身份认证配置
+// @Description 返回是否启用统一身份认证（OpenID Connect）登录及登录按钮显示的名称
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=OIDCConfigResponse}
+// @Router /api/auth/oidc/config [get]
+func GetOIDCConfig(c *gin.Context) {
+	displayName := config.Config.GetString("oidc.display_name")
+	if displayName == "" {
+		displayName = "统一身份认证"
+	}
+
+	utils.JsonSuccessResponse(c, OIDCConfigResponse{
+		Enabled:     config.Config.GetBool("oidc.enabled"),
+		DisplayName: displayName,
+		LoginURL:    "/api/auth/oidc/login",
+	})
+}
+
+// BeginOIDCLogin 跳转到 IdP 登录
+// @Summary 统一身份认证登录
+// @Description 使用授权码模式（PKCE）跳转到 IdP 登录页面，登录完成后回到 /api/auth/oidc/callback
+// @Tags oidc
+// @Param redirect query string false "登录完成后前端跳转的站内路径"
+// @Success 302
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/auth/oidc/login [get]
+func BeginOIDCLogin(c *gin.Context) {
+	authURL, err := services.BeginOIDCLogin(c.Request.Context(), c.Query("redirect"), time.Now())
+	if err != nil {
+		serviceError(c, err, utils.CodeOIDCUnavailable)
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback IdP 登录回调
+// @Summary 统一身份认证回调
+// @Description 校验授权码和 ID 令牌，按声明映射角色并创建会话，然后带一次性登录码（oidc_code）或错误信息（oidc_error 和 oidc_error_code）跳转回前端
+// @Tags oidc
+// @Param code query string false "授权码"
+// @Param state query string true "state"
+// @Param error query string false "IdP 返回的错误"
+// @Success 302
+// @Router /api/auth/oidc/callback [get]
+func OIDCCallback(c *gin.Context) {
+	now := time.Now()
+	// 错误信息通过 oidc_error（消息）和 oidc_error_code（错误代码）传递给前端
+	failWithMessage := func(redirect string, code utils.ErrorCode, msg string) {
+		c.Redirect(http.StatusFound, oidcFrontendRedirect(redirect, url.Values{
+			"oidc_error":      {msg},
+			"oidc_error_code": {string(code)},
+		}))
+	}
+	fail := func(redirect string, err error, fallback utils.ErrorCode) {
+		code := serviceErrorCode(err, fallback)
+		failWithMessage(redirect, code, utils.LookupError(code, utils.NegotiateLanguage(c)).Message)
+	}
+
+	if idpError := c.Query("error"); idpError != "" {
+		// 用户在 IdP 取消登录等情况，同时丢弃对应的授权请求
+		failWithMessage(services.CancelOIDCLogin(c.Query("state")), utils.CodeOIDCFailed, utils.LookupError(utils.CodeOIDCFailed, utils.NegotiateLanguage(c)).Message+": "+idpError)
+		return
+	}
+
+	identity, redirect, err := services.CompleteOIDCLogin(c.Request.Context(), c.Query("state"), c.Query("code"), now)
+	if err != nil {
+		fail(redirect, err, utils.CodeOIDCFailed)
+		return
+	}
+
+	passkey, err := services.UpsertOIDCPasskey(identity)
+	if err != nil {
+		fail(redirect, err, utils.CodeDatabaseError)
+		return
+	}
+
+	pair, err := services.LoginPasskey(passkey, c.ClientIP(), c.Request.UserAgent(), now)
+	if err != nil {
+		fail(redirect, err, utils.CodeInternalError)
+		return
+	}
+
+	code := services.IssueOIDCLoginCode(passkey, pair, now)
+	c.Redirect(http.StatusFound, oidcFrontendRedirect(redirect, url.Values{"oidc_code": {code}}))
+}
+
+// ExchangeOIDCLoginCode 领取统一身份认证登录结果
+// @Summary 领取统一身份认证登录结果
+// @Description 使用回调跳转时附带的一次性登录码（1 分钟内有效）领取令牌，响应与 /api/auth/login 相同
+// @Tags oidc
+// @Accept json
+// @Produce json
+// @Param request body OIDCExchangeRequest true "登录码"
+// @Success 200 {object} utils.JSONResponse{data=LoginResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
+// @Router /api/auth/oidc/exchange [post]
+func ExchangeOIDCLoginCode(c *gin.Context) {
+	var req OIDCExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	passkey, pair, err := services.TakeOIDCLoginCode(req.Code, time.Now())
+	if err != nil {
+		serviceError(c, err, utils.CodeOIDCCodeInvalid)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, newLoginResponse(passkey, pair))
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"backend/services"
+	"backend/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrganisms 按分类阶元浏览来源生物
+// @Summary 浏览来源生物
+// @Description 返回来源生物及其分类谱系和化合物数量，可按任一分类阶元筛选，如 rank=genus&name=Streptomyces
+// @Tags organism
+// @Produce json
+// @Param limit query int false "返回的记录数量，默认为10"
+// @Param offset query int false "从第几条记录开始，默认为0"
+// @Param rank query string false "分类阶元：domain、kingdom、phylum、class、order、family、genus、species"
+// @Param name query string false "分类单元名称，使用rank时必填"
+// @Success 200 {object} utils.JSONResponse{data=utils.Page{data=[]services.OrganismSummary}}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/organisms [get]
+func GetOrganisms(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "limit"})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidNonNegativeInteger, gin.H{"param": "offset"})
+		return
+	}
+
+	// 限制最大查询数量
+	if limit > 100 {
+		limit = 100
+	}
+
+	rank := c.Query("rank")
+	name := c.Query("name")
+	if rank != "" && name == "" {
+		utils.JsonErrorResponse(c, utils.CodeParameterRequiredWith, gin.H{"param": "name", "with": "rank"})
+		return
+	}
+
+	organisms, totalCount, err := services.ListOrganisms(rank, name, limit, offset)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRank) {
+			utils.JsonErrorResponse(c, utils.CodeUnsupportedRank)
+		} else {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	response := utils.NewPage(organisms, totalCount, limit, offset)
+
+	utils.JsonSuccessResponse(c, response)
+}
+
+// GetTaxa 获取某一分类阶元下的全部分类单元
+// @Summary 获取分类单元
+// @Description 返回指定阶元下的分类单元及其来源生物数和化合物数，可用上级阶元限定，如 /api/organisms/ranks/phylum?parent_rank=domain&parent=Bacteria
+// @Tags organism
+// @Produce json
+// @Param rank path string true "分类阶元"
+// @Param parent_rank query string false "上级分类阶元"
+// @Param parent query string false "上级分类单元名称"
+// @Success 200 {object} utils.JSONResponse{data=[]services.TaxonCount}
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/organisms/ranks/{rank} [get]
+func GetTaxa(c *gin.Context) {
+	taxa, err := services.GetTaxa(c.Param("rank"), c.Query("parent_rank"), c.Query("parent"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRank) {
+			utils.JsonErrorResponse(c, utils.CodeUnsupportedRank)
+		} else {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	utils.JsonSuccessResponse(c, taxa)
+}
+
+// GetOrganismByID 获取单个来源生物及其分类谱系
+// @Summary 获取来源生物
+// @Description 根据ID返回来源生物及其分类谱系
+// @Tags organism
+// @Produce json
+// @Param oid path int true "来源生物ID"
+// @Success 200 {object} utils.JSONResponse{data=models.Organism}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/organisms/{oid} [get]
+func GetOrganismByID(c *gin.Context) {
+	oid, err := strconv.ParseUint(c.Param("oid"), 10, 64)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "oid"})
+		return
+	}
+
+	organism, err := services.GetOrganismByID(uint(oid))
+	if err != nil {
+		serviceError(c, err, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, organism)
+}
+
+// LinkOrganisms 根据Source关联来源生物
+// @Summary 关联来源生物
+// @Description 为尚未关联来源生物的化合物按Source创建并关联来源生物，需要 data:write 权限。分类谱系通过 import-taxonomy 命令从本地NCBI数据导入
+// @Tags organism
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=services.OrganismLinkResult}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/organisms/link [post]
+func LinkOrganisms(c *gin.Context) {
+	stats, err := services.LinkOrganismsFromSource()
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, stats)
+}
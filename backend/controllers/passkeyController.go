@@ -1,13 +1,16 @@
 package controllers
 
 import (
-	"backend/database"
 	"backend/models"
+	"backend/repository"
+	"backend/services"
 	"backend/utils"
+	"errors"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"github.com/google/uuid"
 )
 
 // PasskeyRequest passkey 请求结构
@@ -15,6 +18,99 @@ type PasskeyRequest struct {
 	Description string `json:"description"`
 	Operator    string `json:"operator" binding:"required"`
 	IsActive    bool   `json:"is_active"`
+	Role        string `json:"role"` // 为空时创建为 protected-reader，更新时保持不变
+	// 可选的有效期和使用限制，为空表示不限制
+	ValidFrom     *time.Time `json:"valid_from"`
+	ValidUntil    *time.Time `json:"valid_until"`
+	MaxLogins     *int       `json:"max_logins"`
+	TokenLifetime *int       `json:"token_lifetime"` // 令牌有效期（分钟）
+	// 频率限制和保护数据读取配额，为空时使用全局配置，0 表示不限制
+	RateLimit       *int `json:"rate_limit"`        // 每分钟请求数
+	DailyQuota      *int `json:"daily_quota"`       // 每日保护记录读取次数
+	MonthlyQuota    *int `json:"monthly_quota"`     // 每月保护记录读取次数
+	ResetLoginCount bool `json:"reset_login_count"` // 更新时清零已登录次数
+}
+
+// assignableRoles 返回当前用户可以分配的角色（不含超级管理员）
+func assignableRoles(c *gin.Context) []string {
+	actor := c.GetString("role")
+	var roles []string
+	for _, role := range models.Roles {
+		if role != models.RoleSuperadmin && models.CanAssignRole(actor, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// checkAssignable 检查当前用户能否分配目标角色，不能时写入错误响应并返回 false
+func checkAssignable(c *gin.Context, targetRole string) bool {
+	if !models.CanAssignRole(c.GetString("role"), targetRole) {
+		utils.JsonErrorResponse(c, utils.CodeRoleNotManageable)
+		return false
+	}
+	return true
+}
+
+// checkManageable 检查当前用户能否管理目标 passkey：角色必须低于自己，
+// 且除超级管理员外只能管理自己直接或间接创建的 passkey。不能时写入错误响应并返回 false
+func checkManageable(c *gin.Context, target *models.Passkey) bool {
+	if !checkAssignable(c, target.Role) {
+		return false
+	}
+	if c.GetString("role") == models.RoleSuperadmin {
+		return true
+	}
+	inScope, err := services.IsDescendant(c.GetString("passkey"), target.Passkey)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return false
+	}
+	if !inScope {
+		utils.JsonErrorResponse(c, utils.CodeOutsideDelegationScope)
+		return false
+	}
+	return true
+}
+
+// manageablePasskeys 返回当前用户可管理的全部 passkey：超级管理员为除超级管理员外的全部账户，
+// 其余角色为自己直接或间接创建、且角色低于自己的账户
+func manageablePasskeys(c *gin.Context) ([]models.Passkey, error) {
+	roles := assignableRoles(c)
+	if c.GetString("role") == models.RoleSuperadmin {
+		return passkeyRepo.ListByRoles(roles)
+	}
+
+	descendants, err := services.GetDescendantPasskeys(c.GetString("passkey"))
+	if err != nil {
+		return nil, err
+	}
+	passkeys := make([]models.Passkey, 0, len(descendants))
+	for _, p := range descendants {
+		if containsRole(roles, p.Role) {
+			passkeys = append(passkeys, p)
+		}
+	}
+	sort.SliceStable(passkeys, func(i, j int) bool { return passkeys[i].CreatedAt.After(passkeys[j].CreatedAt) })
+	return passkeys, nil
+}
+
+// passkeyLookupError 按查询 passkey 的错误类型写入响应
+func passkeyLookupError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		utils.JsonErrorResponse(c, utils.CodePasskeyNotFound)
+	} else {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+	}
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
 
 // PasskeyResponse passkey 响应结构
@@ -24,41 +120,87 @@ type PasskeyResponse struct {
 	Operator    string    `json:"operator"`
 	CreatedAt   time.Time `json:"created_at"`
 	IsActive    bool      `json:"is_active"`
-	Extends     string    `json:"extends"`
+	Extends     string    `json:"extends"`             // 创建者名称
+	ParentID    *string   `json:"parent_id,omitempty"` // 创建者的 passkey
+	Role        string    `json:"role"`
+	// 有效期和使用限制
+	ValidFrom        *time.Time `json:"valid_from,omitempty"`
+	ValidUntil       *time.Time `json:"valid_until,omitempty"`
+	MaxLogins        *int       `json:"max_logins,omitempty"`
+	LoginCount       int        `json:"login_count"`
+	LastLoginAt      *time.Time `json:"last_login_at,omitempty"`
+	TokenLifetime    int        `json:"token_lifetime"`              // 实际使用的令牌有效期（分钟）
+	Status           string     `json:"status"`                      // active、disabled、pending、expired、exhausted
+	RemainingSeconds *int64     `json:"remaining_seconds,omitempty"` // 距失效的剩余秒数，不限期时为空
+	RemainingLogins  *int       `json:"remaining_logins,omitempty"`  // 剩余登录次数，不限次数时为空
+	// passkey 自身的频率限制和配额设置，为空表示使用全局配置
+	RateLimit    *int `json:"rate_limit,omitempty"`
+	DailyQuota   *int `json:"daily_quota,omitempty"`
+	MonthlyQuota *int `json:"monthly_quota,omitempty"`
+}
+
+// newPasskeyResponse 将 passkey 转换为响应格式，并计算剩余有效期
+func newPasskeyResponse(p *models.Passkey, now time.Time) PasskeyResponse {
+	response := PasskeyResponse{
+		Passkey:       p.Passkey,
+		Description:   p.Description,
+		Operator:      p.Operator,
+		CreatedAt:     p.CreatedAt,
+		IsActive:      p.IsActive,
+		Extends:       p.Extends,
+		ParentID:      p.ParentID,
+		Role:          p.Role,
+		ValidFrom:     p.ValidFrom,
+		ValidUntil:    p.ValidUntil,
+		MaxLogins:     p.MaxLogins,
+		LoginCount:    p.LoginCount,
+		LastLoginAt:   p.LastLoginAt,
+		TokenLifetime: int(services.TokenLifetime(&models.Passkey{TokenLifetime: p.TokenLifetime}, now) / time.Minute),
+		Status:        services.PasskeyStatus(p, now),
+		RateLimit:     p.RateLimit,
+		DailyQuota:    p.DailyQuota,
+		MonthlyQuota:  p.MonthlyQuota,
+	}
+	if p.ValidUntil != nil {
+		remaining := int64(p.ValidUntil.Sub(now) / time.Second)
+		if remaining < 0 {
+			remaining = 0
+		}
+		response.RemainingSeconds = &remaining
+	}
+	if p.MaxLogins != nil {
+		remaining := *p.MaxLogins - p.LoginCount
+		if remaining < 0 {
+			remaining = 0
+		}
+		response.RemainingLogins = &remaining
+	}
+	return response
 }
 
 // GetAllPasskeys 获取所有 passkey
 // @Summary 获取所有 passkey
-// @Description 获取所有 passkey 列表，需要管理员权限。注意：不会返回超级管理员的passkey
+// @Description 获取当前用户可管理的 passkey 列表，需要 passkeys:manage 权限。超级管理员可以看到除超级管理员外的全部 passkey，其余管理员只能看到自己直接或间接创建的 passkey
 // @Tags passkey
 // @Security BearerAuth
 // @Produce json
 // @Success 200 {object} utils.JSONResponse{data=[]PasskeyResponse}
 // @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
 // @Failure 500 {object} utils.JSONResponse
 // @Router /api/passkeys [get]
 func GetAllPasskeys(c *gin.Context) {
-	var passkeys []models.Passkey
-	db := database.GetDB()
-
-	// 查询所有 passkey，排除超级管理员
-	result := db.Table("passkeys").Where("Extends != ''").Order("Created_At DESC").Find(&passkeys)
-	if result.Error != nil {
-		utils.JsonErrorResponse(c, 200500, "数据库查询失败")
+	passkeys, err := manageablePasskeys(c)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 		return
 	}
 
 	// 转换为响应格式
 	var response []PasskeyResponse
-	for _, p := range passkeys {
-		response = append(response, PasskeyResponse{
-			Passkey:     p.Passkey,
-			Description: p.Description,
-			Operator:    p.Operator,
-			CreatedAt:   p.CreatedAt,
-			IsActive:    p.IsActive,
-			Extends:     p.Extends,
-		})
+	now := time.Now()
+	for i := range passkeys {
+		response = append(response, newPasskeyResponse(&passkeys[i], now))
 	}
 
 	utils.JsonSuccessResponse(c, response)
@@ -66,144 +208,229 @@ func GetAllPasskeys(c *gin.Context) {
 
 // CreatePasskey 创建新的 passkey
 // @Summary 创建 passkey
-// @Description 创建新的 passkey，系统会自动生成 UUID。创建者的 operator(passkey) 信息会被存储在 Extends 字段中
+// @Description 创建新的 passkey，系统会自动生成 UUID。创建者记录在 Parent_ID 中，创建者名称记录在 Extends 中。只能分配低于自己的角色（超级管理员除外），有效期不能超出创建者自身的有效期
 // @Tags passkey
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param request body PasskeyRequest true "passkey 信息"
-// @Success 201 {object} utils.JSONResponse{data=PasskeyResponse}
+// @Success 200 {object} utils.JSONResponse{data=PasskeyResponse}
 // @Failure 400 {object} utils.JSONResponse
 // @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
 // @Failure 500 {object} utils.JSONResponse
 // @Router /api/passkeys [post]
 func CreatePasskey(c *gin.Context) {
 	var req PasskeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.JsonErrorResponse(c, 200400, "请求参数错误")
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
 		return
 	}
 
-	// 从上下文中获取创建者的 operator 和 passkey 信息
-	creatorOperator, exists := c.Get("operator")
-	if !exists {
-		creatorOperator = "未知"
+	if req.Role == "" {
+		req.Role = models.RoleProtectedReader
 	}
-	creatorOperatorStr, _ := creatorOperator.(string)
-
-	creatorPasskey, exists := c.Get("passkey")
-	if !exists {
-		creatorPasskey = "未知"
+	if models.RoleLevel(req.Role) < 0 {
+		utils.JsonErrorResponse(c, utils.CodeUnsupportedRole)
+		return
+	}
+	if !checkAssignable(c, req.Role) {
+		return
 	}
-	creatorPasskeyStr, _ := creatorPasskey.(string)
 
-	// 构建 Extends 信息，格式为 "operator(passkey)"
-	extendsInfo := creatorOperatorStr + "(" + creatorPasskeyStr + ")"
+	// 创建者即新 passkey 的父级
+	creator, err := passkeyRepo.Find(c.GetString("passkey"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.JsonErrorResponse(c, utils.CodeUnauthenticated)
+		} else {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
 
-	// 创建 passkey（数据库会自动生成 UUID）
+	// 创建 passkey
 	passkey := models.Passkey{
-		Description: req.Description, // description 可以为空
-		Operator:    req.Operator,
-		IsActive:    req.IsActive,
-		Extends:     extendsInfo,
+		Passkey:       uuid.NewString(),
+		Description:   req.Description, // description 可以为空
+		Operator:      req.Operator,
+		IsActive:      req.IsActive,
+		Extends:       creator.Operator,
+		ParentID:      &creator.Passkey,
+		Role:          req.Role,
+		ValidFrom:     req.ValidFrom,
+		ValidUntil:    req.ValidUntil,
+		MaxLogins:     req.MaxLogins,
+		TokenLifetime: req.TokenLifetime,
+		RateLimit:     req.RateLimit,
+		DailyQuota:    req.DailyQuota,
+		MonthlyQuota:  req.MonthlyQuota,
 	}
-
-	db := database.GetDB()
-	result := db.Table("passkeys").Create(&passkey)
-	if result.Error != nil {
-		utils.JsonErrorResponse(c, 200500, "创建 passkey 失败")
+	if err := services.ConstrainToParent(&passkey, creator); err != nil {
+		serviceError(c, err, utils.CodeInvalidRequest)
+		return
+	}
+	if err := services.ValidatePasskeyLimits(&passkey); err != nil {
+		serviceError(c, err, utils.CodeInvalidRequest)
 		return
 	}
 
-	response := PasskeyResponse{
-		Passkey:     passkey.Passkey,
-		Description: passkey.Description,
-		Operator:    passkey.Operator,
-		CreatedAt:   passkey.CreatedAt,
-		IsActive:    passkey.IsActive,
-		Extends:     passkey.Extends,
+	if err := passkeyRepo.Create(&passkey); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
 	}
 
+	response := newPasskeyResponse(&passkey, time.Now())
+
 	utils.JsonSuccessResponse(c, response)
 }
 
+// passkeySettingColumns 修改 passkey 时写入的列。Token_Version 和 Login_Count 由注销和登录并发更新，不在其中
+var passkeySettingColumns = []string{
+	"Description", "Operator", "Is_Active", "Role", "Valid_From", "Valid_Until",
+	"Max_Logins", "Token_Lifetime", "Rate_Limit", "Daily_Quota", "Monthly_Quota",
+}
+
 // UpdatePasskey 更新 passkey
 // @Summary 更新 passkey
-// @Description 更新指定 passkey 的信息。注意：不能修改自己的 passkey
+// @Description 更新指定 passkey 的信息，有效期、最大登录次数、令牌有效期、频率限制和配额同样整体替换（为空表示不限制或使用全局配置）。
+// @Description 下级的角色、有效期或限制超出修改后的范围时返回 DESCENDANT_EXCEEDS，未设置有效期和限制的下级沿用新的范围。
+// @Description 禁用时与切换状态接口相同，cascade=true 会同时禁用其全部下级。注意：不能修改自己的 passkey
 // @Tags passkey
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param passkey path string true "passkey UUID"
 // @Param request body PasskeyRequest true "更新信息"
+// @Param cascade query bool false "禁用时是否同时禁用全部下级"
 // @Success 200 {object} utils.JSONResponse{data=PasskeyResponse}
 // @Failure 400 {object} utils.JSONResponse
 // @Failure 401 {object} utils.JSONResponse
 // @Failure 403 {object} utils.JSONResponse
 // @Failure 404 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
 // @Failure 500 {object} utils.JSONResponse
 // @Router /api/passkeys/{passkey} [put]
 func UpdatePasskey(c *gin.Context) {
 	passkeyID := c.Param("passkey")
 	if passkeyID == "" {
-		utils.JsonErrorResponse(c, 200400, "passkey 参数不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "passkey"})
 		return
 	}
 
 	// 获取当前登录用户的 passkey
 	currentPasskey, exists := c.Get("passkey")
 	if !exists {
-		utils.JsonErrorResponse(c, 200401, "未获取到用户信息")
+		utils.JsonErrorResponse(c, utils.CodeUnauthenticated)
 		return
 	}
 	currentPasskeyStr, _ := currentPasskey.(string)
 
 	// 检查是否尝试修改自己的 passkey
 	if passkeyID == currentPasskeyStr {
-		utils.JsonErrorResponse(c, 200403, "不能修改自己的 passkey")
+		utils.JsonErrorResponse(c, utils.CodeSelfModification)
 		return
 	}
 
 	var req PasskeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.JsonErrorResponse(c, 200400, "请求参数错误")
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
 		return
 	}
 
-	db := database.GetDB()
-	var passkey models.Passkey
-
 	// 查找 passkey
-	result := db.Table("passkeys").Where("Passkey = ?", passkeyID).First(&passkey)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			utils.JsonErrorResponse(c, 200404, "passkey 不存在")
-		} else {
-			utils.JsonErrorResponse(c, 200500, "数据库查询失败")
-		}
+	passkey, err := passkeyRepo.Find(passkeyID)
+	if err != nil {
+		passkeyLookupError(c, err)
 		return
 	}
 
+	if !checkManageable(c, passkey) {
+		return
+	}
+	previousRole := passkey.Role
+	wasActive := passkey.IsActive
+	if req.Role != "" {
+		if models.RoleLevel(req.Role) < 0 {
+			utils.JsonErrorResponse(c, utils.CodeUnsupportedRole)
+			return
+		}
+		if !checkAssignable(c, req.Role) {
+			return
+		}
+		passkey.Role = req.Role
+	}
+
 	// 更新信息
 	passkey.Description = req.Description
 	passkey.Operator = req.Operator
 	passkey.IsActive = req.IsActive
+	passkey.ValidFrom = req.ValidFrom
+	passkey.ValidUntil = req.ValidUntil
+	passkey.MaxLogins = req.MaxLogins
+	passkey.TokenLifetime = req.TokenLifetime
+	passkey.RateLimit = req.RateLimit
+	passkey.DailyQuota = req.DailyQuota
+	passkey.MonthlyQuota = req.MonthlyQuota
+	if req.ResetLoginCount {
+		passkey.LoginCount = 0
+	}
+	if passkey.ParentID != nil {
+		parent, err := passkeyRepo.Find(*passkey.ParentID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+			return
+		}
+		if parent != nil {
+			if err := services.ConstrainToParent(passkey, parent); err != nil {
+				serviceError(c, err, utils.CodeInvalidRequest)
+				return
+			}
+		}
+	}
+	if err := services.ValidatePasskeyLimits(passkey); err != nil {
+		serviceError(c, err, utils.CodeInvalidRequest)
+		return
+	}
+	// 下级须仍在修改后的范围内，未设置有效期和限制的下级沿用新的范围
+	inherited, err := services.ConstrainDescendants(passkey)
+	if err != nil {
+		serviceError(c, err, utils.CodeDatabaseError)
+		return
+	}
 
-	result = db.Table("passkeys").Save(&passkey)
-	if result.Error != nil {
-		utils.JsonErrorResponse(c, 200500, "更新 passkey 失败")
+	columns := passkeySettingColumns
+	if req.ResetLoginCount {
+		columns = append(columns[:len(columns):len(columns)], "Login_Count")
+	}
+	if err := passkeyRepo.UpdateColumns(passkey, columns...); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 		return
 	}
 
-	response := PasskeyResponse{
-		Passkey:     passkey.Passkey,
-		Description: passkey.Description,
-		Operator:    passkey.Operator,
-		CreatedAt:   passkey.CreatedAt,
-		IsActive:    passkey.IsActive,
-		Extends:     passkey.Extends,
+	if err := services.SaveInheritedLimits(inherited); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
 	}
 
+	// 禁用与 TogglePasskeyStatus 相同：令已签发的令牌失效，按需级联禁用下级
+	if wasActive && !passkey.IsActive {
+		if _, err := services.DisablePasskeyTree(passkey.Passkey, c.Query("cascade") == "true"); err != nil {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+			return
+		}
+	} else if passkey.Role != previousRole {
+		// 角色写在令牌中，角色变化时令已签发的令牌失效
+		if err := services.RevokeAllTokens(passkey.Passkey); err != nil {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+			return
+		}
+	}
+	services.InvalidatePasskeyCache(passkey.Passkey)
+
+	response := newPasskeyResponse(passkey, time.Now())
+
 	utils.JsonSuccessResponse(c, response)
 }
 
@@ -224,33 +451,64 @@ func UpdatePasskey(c *gin.Context) {
 func DeletePasskey(c *gin.Context) {
 	passkeyID := c.Param("passkey")
 	if passkeyID == "" {
-		utils.JsonErrorResponse(c, 200400, "passkey 参数不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "passkey"})
 		return
 	}
 
 	// 获取当前登录用户的 passkey
 	currentPasskey, exists := c.Get("passkey")
 	if !exists {
-		utils.JsonErrorResponse(c, 200401, "未获取到用户信息")
+		utils.JsonErrorResponse(c, utils.CodeUnauthenticated)
 		return
 	}
 	currentPasskeyStr, _ := currentPasskey.(string)
 
 	// 检查是否尝试删除自己的 passkey
 	if passkeyID == currentPasskeyStr {
-		utils.JsonErrorResponse(c, 200403, "不能删除自己的 passkey")
+		utils.JsonErrorResponse(c, utils.CodeSelfModification)
 		return
 	}
 
-	db := database.GetDB()
-	result := db.Table("passkeys").Where("Passkey = ?", passkeyID).Delete(&models.Passkey{})
-	if result.Error != nil {
-		utils.JsonErrorResponse(c, 200500, "删除 passkey 失败")
+	// 查找 passkey
+	passkey, err := passkeyRepo.Find(passkeyID)
+	if err != nil {
+		passkeyLookupError(c, err)
+		return
+	}
+
+	if !checkManageable(c, passkey) {
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		utils.JsonErrorResponse(c, 200404, "passkey 不存在")
+	if err := passkeyRepo.Delete(passkeyID); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	// 已签发的令牌和会话随 passkey 删除立即失效
+	services.InvalidatePasskeyCache(passkeyID)
+	if err := services.RevokeSessionsByPasskey(passkeyID, models.SessionRevokeDelete); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	// 下级 passkey 移交给被删除 passkey 的创建者
+	if err := services.ReparentChildren(passkeyID, passkey.ParentID); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	// 同时删除该 passkey 的数据授权和安全密钥
+	if err := services.DeleteDataGrantsByPasskey(passkeyID); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if err := services.DeleteWebAuthnCredentialsByPasskey(passkeyID); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if err := services.DeleteQuotaUsageByPasskey(passkeyID); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
 		return
 	}
 
@@ -267,49 +525,41 @@ func DeletePasskey(c *gin.Context) {
 // @Success 200 {object} utils.JSONResponse{data=PasskeyResponse}
 // @Failure 400 {object} utils.JSONResponse
 // @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
 // @Failure 404 {object} utils.JSONResponse
 // @Failure 500 {object} utils.JSONResponse
 // @Router /api/passkeys/{passkey} [get]
 func GetPasskeyByID(c *gin.Context) {
 	passkeyID := c.Param("passkey")
 	if passkeyID == "" {
-		utils.JsonErrorResponse(c, 200400, "passkey 参数不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "passkey"})
 		return
 	}
 
-	db := database.GetDB()
-	var passkey models.Passkey
-
 	// 查找 passkey
-	result := db.Table("passkeys").Where("Passkey = ?", passkeyID).First(&passkey)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			utils.JsonErrorResponse(c, 200404, "passkey 不存在")
-		} else {
-			utils.JsonErrorResponse(c, 200500, "数据库查询失败")
-		}
+	passkey, err := passkeyRepo.Find(passkeyID)
+	if err != nil {
+		passkeyLookupError(c, err)
 		return
 	}
 
-	response := PasskeyResponse{
-		Passkey:     passkey.Passkey,
-		Description: passkey.Description,
-		Operator:    passkey.Operator,
-		CreatedAt:   passkey.CreatedAt,
-		IsActive:    passkey.IsActive,
-		Extends:     passkey.Extends,
+	if !checkManageable(c, passkey) {
+		return
 	}
 
+	response := newPasskeyResponse(passkey, time.Now())
+
 	utils.JsonSuccessResponse(c, response)
 }
 
 // TogglePasskeyStatus 切换 passkey 状态
 // @Summary 切换 passkey 状态
-// @Description 启用或禁用指定的 passkey。注意：不能修改自己的 passkey 状态
+// @Description 启用或禁用指定的 passkey。禁用时 cascade=true 会同时禁用其全部下级 passkey，启用时不会级联。注意：不能修改自己的 passkey 状态
 // @Tags passkey
 // @Security BearerAuth
 // @Produce json
 // @Param passkey path string true "passkey UUID"
+// @Param cascade query bool false "禁用时是否同时禁用全部下级"
 // @Success 200 {object} utils.JSONResponse{data=PasskeyResponse}
 // @Failure 400 {object} utils.JSONResponse
 // @Failure 401 {object} utils.JSONResponse
@@ -320,55 +570,156 @@ func GetPasskeyByID(c *gin.Context) {
 func TogglePasskeyStatus(c *gin.Context) {
 	passkeyID := c.Param("passkey")
 	if passkeyID == "" {
-		utils.JsonErrorResponse(c, 200400, "passkey 参数不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "passkey"})
 		return
 	}
 
 	// 获取当前登录用户的 passkey
 	currentPasskey, exists := c.Get("passkey")
 	if !exists {
-		utils.JsonErrorResponse(c, 200401, "未获取到用户信息")
+		utils.JsonErrorResponse(c, utils.CodeUnauthenticated)
 		return
 	}
 	currentPasskeyStr, _ := currentPasskey.(string)
 
 	// 检查是否尝试修改自己的 passkey
 	if passkeyID == currentPasskeyStr {
-		utils.JsonErrorResponse(c, 200403, "不能修改自己的 passkey 状态")
+		utils.JsonErrorResponse(c, utils.CodeSelfModification)
 		return
 	}
 
-	db := database.GetDB()
-	var passkey models.Passkey
-
 	// 查找 passkey
-	result := db.Table("passkeys").Where("Passkey = ?", passkeyID).First(&passkey)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			utils.JsonErrorResponse(c, 200404, "passkey 不存在")
-		} else {
-			utils.JsonErrorResponse(c, 200500, "数据库查询失败")
-		}
+	passkey, err := passkeyRepo.Find(passkeyID)
+	if err != nil {
+		passkeyLookupError(c, err)
 		return
 	}
 
-	// 切换状态
-	passkey.IsActive = !passkey.IsActive
-
-	result = db.Table("passkeys").Save(&passkey)
-	if result.Error != nil {
-		utils.JsonErrorResponse(c, 200500, "更新 passkey 状态失败")
+	if !checkManageable(c, passkey) {
 		return
 	}
 
-	response := PasskeyResponse{
-		Passkey:     passkey.Passkey,
-		Description: passkey.Description,
-		Operator:    passkey.Operator,
-		CreatedAt:   passkey.CreatedAt,
-		IsActive:    passkey.IsActive,
-		Extends:     passkey.Extends,
+	// 禁用时令已签发的令牌立即失效，按需级联禁用下级
+	if passkey.IsActive {
+		if _, err := services.DisablePasskeyTree(passkey.Passkey, c.Query("cascade") == "true"); err != nil {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+			return
+		}
+		passkey.IsActive = false
+	} else {
+		passkey.IsActive = true
+		if err := passkeyRepo.UpdateColumns(passkey, "Is_Active"); err != nil {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+			return
+		}
+		services.InvalidatePasskeyCache(passkey.Passkey)
 	}
 
+	response := newPasskeyResponse(passkey, time.Now())
+
 	utils.JsonSuccessResponse(c, response)
 }
+
+// RoleInfo 角色及其权限范围
+type RoleInfo struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	Assignable  bool     `json:"assignable"`
+}
+
+// GetRoles 获取全部角色及其权限
+// @Summary 获取角色列表
+// @Description 返回全部角色及其权限范围，assignable 表示当前用户能否分配该角色
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=[]RoleInfo}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/roles [get]
+func GetRoles(c *gin.Context) {
+	actor := c.GetString("role")
+	roles := make([]RoleInfo, 0, len(models.Roles))
+	for _, role := range models.Roles {
+		permissions := models.RolePermissions[role]
+		if permissions == nil {
+			permissions = []string{}
+		}
+		roles = append(roles, RoleInfo{
+			Role:        role,
+			Permissions: permissions,
+			Assignable:  models.CanAssignRole(actor, role),
+		})
+	}
+
+	utils.JsonSuccessResponse(c, roles)
+}
+
+// LogoutPasskeyEverywhere 使指定 passkey 在所有设备上退出登录
+// @Summary 使 passkey 在所有设备上退出登录
+// @Description 使指定 passkey 此前签发的全部令牌立即失效，passkey 本身保持可用
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/logout-all [post]
+func LogoutPasskeyEverywhere(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	if err := services.RevokeAllTokens(passkey.Passkey); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// PasskeyTreeResponse 授权树节点
+type PasskeyTreeResponse struct {
+	PasskeyResponse
+	Children []PasskeyTreeResponse `json:"children"`
+}
+
+func newPasskeyTreeResponse(nodes []*services.PasskeyTreeNode, now time.Time) []PasskeyTreeResponse {
+	response := make([]PasskeyTreeResponse, 0, len(nodes))
+	for _, node := range nodes {
+		response = append(response, PasskeyTreeResponse{
+			PasskeyResponse: newPasskeyResponse(&node.Passkey, now),
+			Children:        newPasskeyTreeResponse(node.Children, now),
+		})
+	}
+	return response
+}
+
+// GetPasskeyTree 获取 passkey 授权树
+// @Summary 获取 passkey 授权树
+// @Description 按创建关系返回当前用户可管理的 passkey，展示每个 passkey 由谁创建。创建者不可见（如超级管理员或当前用户本人）的 passkey 作为根节点
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=[]PasskeyTreeResponse}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/tree [get]
+func GetPasskeyTree(c *gin.Context) {
+	passkeys, err := manageablePasskeys(c)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	// 同级按创建时间升序排列
+	sort.SliceStable(passkeys, func(i, j int) bool { return passkeys[i].CreatedAt.Before(passkeys[j].CreatedAt) })
+	utils.JsonSuccessResponse(c, newPasskeyTreeResponse(services.BuildPasskeyTree(passkeys), time.Now()))
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaUsageResponse passkey 的频率限制和配额使用情况
+type QuotaUsageResponse struct {
+	Passkey   string                 `json:"passkey"`
+	Operator  string                 `json:"operator"`
+	Role      string                 `json:"role"`
+	RateLimit int                    `json:"rate_limit"` // 实际使用的每分钟请求数，0 表示不限制
+	Exempt    bool                   `json:"exempt"`     // 角色是否不受配额限制
+	Quotas    []services.QuotaStatus `json:"quotas"`     // 每日和每月配额
+}
+
+func newQuotaUsageResponse(p *models.Passkey, quotas []services.QuotaStatus) QuotaUsageResponse {
+	return QuotaUsageResponse{
+		Passkey:   p.Passkey,
+		Operator:  p.Operator,
+		Role:      p.Role,
+		RateLimit: services.PasskeyRateLimit(p),
+		Exempt:    services.IsQuotaExempt(p.Role),
+		Quotas:    quotas,
+	}
+}
+
+// GetMyQuota 获取当前用户的配额使用情况
+// @Summary 获取当前用户的配额使用情况
+// @Description 返回当前 passkey 的频率限制，以及今日和本月保护记录读取配额的使用情况
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=QuotaUsageResponse}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/auth/quota [get]
+func GetMyQuota(c *gin.Context) {
+	passkey, err := services.GetPasskey(c.GetString("passkey"))
+	if err != nil {
+		if errors.Is(err, services.ErrPasskeyNotFound) {
+			utils.JsonErrorResponse(c, utils.CodeUnauthenticated)
+		} else {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	quotas, err := services.GetQuotaUsage(passkey, time.Now())
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, newQuotaUsageResponse(passkey, quotas))
+}
+
+// GetPasskeyQuota 获取 passkey 的配额使用情况
+// @Summary 获取 passkey 的配额使用情况
+// @Description 返回指定 passkey 的频率限制，以及今日和本月保护记录读取配额的使用情况
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Success 200 {object} utils.JSONResponse{data=QuotaUsageResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/quota [get]
+func GetPasskeyQuota(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	quotas, err := services.GetQuotaUsage(passkey, time.Now())
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, newQuotaUsageResponse(passkey, quotas))
+}
+
+// GetPasskeysQuota 获取全部可管理 passkey 的配额使用情况
+// @Summary 获取全部 passkey 的配额使用情况
+// @Description 返回当前用户可管理的全部 passkey 的频率限制和配额使用情况，按本月已用次数降序排列
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=[]QuotaUsageResponse}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/quota [get]
+func GetPasskeysQuota(c *gin.Context) {
+	passkeys, err := manageablePasskeys(c)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	usage, err := services.GetQuotaUsageBatch(passkeys, time.Now())
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	response := make([]QuotaUsageResponse, 0, len(passkeys))
+	for i := range passkeys {
+		response = append(response, newQuotaUsageResponse(&passkeys[i], usage[passkeys[i].Passkey]))
+	}
+	sort.SliceStable(response, func(i, j int) bool {
+		return monthlyUsed(response[i]) > monthlyUsed(response[j])
+	})
+
+	utils.JsonSuccessResponse(c, response)
+}
+
+func monthlyUsed(r QuotaUsageResponse) int {
+	for _, q := range r.Quotas {
+		if q.Period == models.QuotaPeriodMonth {
+			return q.Used
+		}
+	}
+	return 0
+}
+
+// quotaReservation 返回 ProtectedQuota 为本次请求占用的配额，不受配额限制时为 nil
+func quotaReservation(c *gin.Context) *services.QuotaReservation {
+	value, _ := c.Get("quota_reservation")
+	reservation, _ := value.(*services.QuotaReservation)
+	return reservation
+}
+
+// protectedRecordLimit 返回本次请求最多可以返回的保护记录数。ProtectedQuota 已为第一条记录占用配额，
+// 剩余配额不足时将 limit 减少到剩余配额 + 1。查询配额失败时返回错误响应，第二个返回值为 false
+func protectedRecordLimit(c *gin.Context, limit int) (int, bool) {
+	remaining, limited, err := quotaReservation(c).Remaining()
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return 0, false
+	}
+	if limited && remaining+1 < limit {
+		limit = remaining + 1
+	}
+	return limit, true
+}
+
+// chargeProtectedRecords 按本次请求返回的保护记录数 n 占用配额，第一条已由 ProtectedQuota 占用，
+// 没有返回记录时退还。配额不足时返回 429 并返回 false
+func chargeProtectedRecords(c *gin.Context, n int) bool {
+	reservation := quotaReservation(c)
+	if reservation == nil {
+		return true
+	}
+	if n == 0 {
+		services.RefundQuota(reservation)
+		return true
+	}
+	now := time.Now()
+	if err := services.ExtendQuota(reservation, now, n-1); err != nil {
+		var exceeded *services.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			utils.JsonTooManyRequestsResponse(c, exceeded.ResetsAt.Sub(now), exceeded.ErrorCode(), gin.H{"limit": exceeded.Limit})
+		} else {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return false
+	}
+	return true
+}
@@ -3,113 +3,188 @@ package controllers
 import (
 	"backend/services"
 	"backend/utils"
-	"fmt"
 
 	"github.com/gin-gonic/gin"
 )
 
+// SimilaritySearch 相似度搜索
+// @Summary 相似度搜索
+// @Description 按分子指纹的 Tanimoto 相似度查找化合物
+// @Tags rdkit
+// @Produce json
+// @Param qfp query string true "查询结构的分子指纹，见 /api/rdkit/smiles-to-fingerprint"
+// @Param threshold query number false "相似度阈值" default(0.5)
+// @Success 200 {object} utils.JSONResponse{data=string} "data 为 JSON 编码的化合物ID数组"
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/rdkit/similarity [get]
 func SimilaritySearch(c *gin.Context) {
 	// 绑定请求参数
 	qfp := c.Query("qfp")
 	threshold := c.DefaultQuery("threshold", "0.5")
 	// 验证参数
 	if qfp == "" {
-		utils.JsonErrorResponse(c, 200400, "查询指纹qfp不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "qfp"})
 		return
 	}
 
 	result, err := services.SimilaritySearch(qfp, threshold)
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, fmt.Sprintf("相似度搜索失败: %v", err))
+		serviceError(c, err, utils.CodeChemistryFailed)
 		return
 	}
 	utils.JsonSuccessResponse(c, result)
 }
 
 // GetRdkitStatus 获取RDKit服务状态
+// @Summary 获取RDKit服务状态
+// @Description 返回RDKit进程是否已启动
+// @Tags rdkit
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=services.RdkitStatus}
+// @Router /api/rdkit/status [get]
 func GetRdkitStatus(c *gin.Context) {
 	status := services.GetRdkitStatus()
 	utils.JsonSuccessResponse(c, status)
 }
 
 // SmilesToFingerprint SMILES转指纹
+// @Summary SMILES转指纹
+// @Description 计算SMILES对应的分子指纹，用于相似度搜索
+// @Tags rdkit
+// @Produce json
+// @Param smiles query string true "SMILES"
+// @Success 200 {object} utils.JSONResponse{data=string}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/rdkit/smiles-to-fingerprint [get]
 func SmilesToFingerprint(c *gin.Context) {
 	smiles := c.Query("smiles")
 	if smiles == "" {
-		utils.JsonErrorResponse(c, 200400, "SMILES字符串不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "smiles"})
 		return
 	}
 
 	result, err := services.SmilesToFingerprint(smiles)
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, fmt.Sprintf("SMILES转指纹失败: %v", err))
+		serviceError(c, err, utils.CodeChemistryFailed)
 		return
 	}
 	utils.JsonSuccessResponse(c, result)
 }
 
 // SmilesToPDB SMILES转PDB文件
+// @Summary SMILES转PDB
+// @Description 生成SMILES对应的三维结构，以PDB格式返回
+// @Tags rdkit
+// @Produce json
+// @Param smiles query string true "SMILES"
+// @Success 200 {object} utils.JSONResponse{data=string}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/rdkit/smiles-to-pdb [get]
 func SmilesToPDB(c *gin.Context) {
 	smiles := c.Query("smiles")
 	if smiles == "" {
-		utils.JsonErrorResponse(c, 200400, "SMILES字符串不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "smiles"})
 		return
 	}
 
 	result, err := services.SmilesToPDB(smiles)
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, fmt.Sprintf("SMILES转PDB失败: %v", err))
+		serviceError(c, err, utils.CodeChemistryFailed)
 		return
 	}
 	utils.JsonSuccessResponse(c, result)
 }
 
+// SubstructureMatchResponse 子结构匹配结果
+type SubstructureMatchResponse struct {
+	IsSubstructure bool `json:"is_substructure"`
+}
+
 // IsSubstructure 子结构匹配
+// @Summary 子结构匹配
+// @Description 判断SMARTS模式是否为SMILES的子结构
+// @Tags rdkit
+// @Produce json
+// @Param smarts_pattern query string true "SMARTS模式"
+// @Param smiles query string true "SMILES"
+// @Success 200 {object} utils.JSONResponse{data=SubstructureMatchResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/rdkit/is-substructure [get]
 func IsSubstructure(c *gin.Context) {
 	smartsPattern := c.Query("smarts_pattern")
 	smiles := c.Query("smiles")
 	if smartsPattern == "" || smiles == "" {
-		utils.JsonErrorResponse(c, 200400, "SMARTS模式和SMILES字符串都不能为空")
+		param := "smarts_pattern"
+		if smartsPattern != "" {
+			param = "smiles"
+		}
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": param})
 		return
 	}
 
 	result, err := services.IsSubstructure(smartsPattern, smiles)
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, fmt.Sprintf("子结构匹配失败: %v", err))
+		serviceError(c, err, utils.CodeChemistryFailed)
 		return
 	}
-	utils.JsonSuccessResponse(c, map[string]interface{}{
-		"is_substructure": result,
-	})
+	utils.JsonSuccessResponse(c, SubstructureMatchResponse{IsSubstructure: result})
 }
 
 // SubstructureSearch 子结构搜索 - 根据SMARTS模式在数据库中查找所有匹配的化合物
+// @Summary 子结构搜索
+// @Description 查找包含SMARTS模式的全部化合物
+// @Tags rdkit
+// @Produce json
+// @Param smarts_pattern query string true "SMARTS模式"
+// @Success 200 {object} utils.JSONResponse{data=string} "data 为 JSON 编码的化合物ID数组"
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/rdkit/substructure-search [get]
 func SubstructureSearch(c *gin.Context) {
 	smartsPattern := c.Query("smarts_pattern")
 	if smartsPattern == "" {
-		utils.JsonErrorResponse(c, 200400, "SMARTS模式不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "smarts_pattern"})
 		return
 	}
 
 	result, err := services.SubstructureSearch(smartsPattern)
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, fmt.Sprintf("子结构搜索失败: %v", err))
+		serviceError(c, err, utils.CodeChemistryFailed)
 		return
 	}
 	utils.JsonSuccessResponse(c, result)
 }
 
 // ExactMatchSearch 精确匹配搜索 - 查找SMILES相同的结构并返回其ID
+// @Summary 精确匹配搜索
+// @Description 查找与SMILES结构相同的化合物
+// @Tags rdkit
+// @Produce json
+// @Param smiles query string true "SMILES"
+// @Success 200 {object} utils.JSONResponse{data=string} "data 为 JSON 编码的化合物ID数组"
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/rdkit/exact-match [get]
 func ExactMatchSearch(c *gin.Context) {
 	smiles := c.Query("smiles")
 	if smiles == "" {
-		utils.JsonErrorResponse(c, 200400, "SMILES字符串不能为空")
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "smiles"})
 		return
 	}
 
 	result, err := services.ExactMatchSearch(smiles)
 	if err != nil {
-		utils.JsonErrorResponse(c, 200500, fmt.Sprintf("精确匹配搜索失败: %v", err))
+		serviceError(c, err, utils.CodeChemistryFailed)
 		return
 	}
 	utils.JsonSuccessResponse(c, result)
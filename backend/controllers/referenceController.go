@@ -0,0 +1,416 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReferenceRequest 文献请求结构
+type ReferenceRequest struct {
+	DOI      *string `json:"doi"`
+	PubMedID *string `json:"pubmed_id"`
+	Title    string  `json:"title" binding:"required"`
+	Journal  string  `json:"journal"`
+	Year     *int    `json:"year"`
+	Volume   string  `json:"volume"`
+	Pages    string  `json:"pages"`
+	Authors  string  `json:"authors"` // 以"; "分隔
+}
+
+// apply 将请求内容写入文献
+func (req ReferenceRequest) apply(ref *models.Reference) {
+	ref.DOI = req.DOI
+	ref.PubMedID = req.PubMedID
+	ref.Title = req.Title
+	ref.Journal = req.Journal
+	ref.Year = req.Year
+	ref.Volume = req.Volume
+	ref.Pages = req.Pages
+	ref.Authors = req.Authors
+}
+
+// LinkReferenceRequest 化合物关联文献请求结构
+type LinkReferenceRequest struct {
+	ReferenceID uint     `json:"reference_id" binding:"required"`
+	Fields      []string `json:"fields"` // general、isolation、structure、bioactivity、nmr、ms2，为空时为 general
+}
+
+// ReferenceDetailResponse 文献及引用它的化合物
+type ReferenceDetailResponse struct {
+	Reference *models.Reference `json:"reference"`
+	// 引用该文献的化合物及其关联字段
+	Compounds []models.CompoundReference `json:"compounds"`
+}
+
+// referenceValidationError 将校验错误转换为错误响应，返回是否已处理
+func referenceValidationError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrInvalidDOI),
+		errors.Is(err, services.ErrInvalidPubMedID),
+		errors.Is(err, services.ErrInvalidReferenceField),
+		errors.Is(err, services.ErrDuplicateReference):
+		serviceError(c, err, utils.CodeInternalError)
+		return true
+	}
+	return false
+}
+
+// parseReferenceID 解析路径参数rid
+func parseReferenceID(c *gin.Context) (uint, bool) {
+	rid, err := strconv.ParseUint(c.Param("rid"), 10, 64)
+	if err != nil || rid == 0 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "rid"})
+		return 0, false
+	}
+	return uint(rid), true
+}
+
+// GetReferences 分页查询文献
+// @Summary 查询文献
+// @Description 按关键词（匹配标题、作者、期刊、DOI或PubMed ID）分页查询文献
+// @Tags reference
+// @Produce json
+// @Param q query string false "关键词"
+// @Param limit query int false "返回的记录数量，默认为10"
+// @Param offset query int false "从第几条记录开始，默认为0"
+// @Success 200 {object} utils.JSONResponse{data=utils.Page{data=[]models.Reference}}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/references [get]
+func GetReferences(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "limit"})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidNonNegativeInteger, gin.H{"param": "offset"})
+		return
+	}
+
+	// 限制最大查询数量
+	if limit > 100 {
+		limit = 100
+	}
+
+	keyword := strings.TrimSpace(c.Query("q"))
+	if strings.HasPrefix(keyword, "10.") || strings.HasPrefix(strings.ToLower(keyword), "doi:") || strings.Contains(keyword, "doi.org/") {
+		keyword = services.NormalizeDOI(keyword)
+	}
+
+	refs, totalCount, err := services.ListReferences(keyword, limit, offset)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	response := utils.NewPage(refs, totalCount, limit, offset)
+
+	utils.JsonSuccessResponse(c, response)
+}
+
+// GetReferenceByID 获取单篇文献及引用它的化合物
+// @Summary 获取文献
+// @Description 根据ID返回文献，compounds 为引用该文献的化合物及其关联字段
+// @Tags reference
+// @Produce json
+// @Param rid path int true "文献ID"
+// @Success 200 {object} utils.JSONResponse{data=ReferenceDetailResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/references/{rid} [get]
+func GetReferenceByID(c *gin.Context) {
+	rid, ok := parseReferenceID(c)
+	if !ok {
+		return
+	}
+
+	ref, err := services.GetReferenceByID(rid)
+	if err != nil {
+		serviceError(c, err, utils.CodeDatabaseError)
+		return
+	}
+
+	links, err := services.GetReferencedCompounds(rid)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, ReferenceDetailResponse{Reference: ref, Compounds: links})
+}
+
+// CreateReference 新增文献
+// @Summary 新增文献
+// @Description 新增一篇文献，DOI会统一为小写且去掉 https://doi.org/ 前缀，需要 data:write 权限
+// @Tags reference
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ReferenceRequest true "文献"
+// @Success 200 {object} utils.JSONResponse{data=models.Reference}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 409 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/references [post]
+func CreateReference(c *gin.Context) {
+	var req ReferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	var ref models.Reference
+	req.apply(&ref)
+	if err := services.CreateReference(&ref); err != nil {
+		if !referenceValidationError(c, err) {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	utils.JsonSuccessResponse(c, ref)
+}
+
+// UpdateReference 更新文献
+// @Summary 更新文献
+// @Description 更新指定文献，需要 data:write 权限
+// @Tags reference
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param rid path int true "文献ID"
+// @Param request body ReferenceRequest true "文献"
+// @Success 200 {object} utils.JSONResponse{data=models.Reference}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 409 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/references/{rid} [put]
+func UpdateReference(c *gin.Context) {
+	rid, ok := parseReferenceID(c)
+	if !ok {
+		return
+	}
+
+	var req ReferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	ref, err := services.GetReferenceByID(rid)
+	if err != nil {
+		serviceError(c, err, utils.CodeDatabaseError)
+		return
+	}
+
+	req.apply(ref)
+	if err := services.UpdateReference(ref); err != nil {
+		if !referenceValidationError(c, err) {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	utils.JsonSuccessResponse(c, ref)
+}
+
+// DeleteReference 删除文献
+// @Summary 删除文献
+// @Description 删除指定文献及其与化合物的全部关联，需要 data:write 权限
+// @Tags reference
+// @Security BearerAuth
+// @Produce json
+// @Param rid path int true "文献ID"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/references/{rid} [delete]
+func DeleteReference(c *gin.Context) {
+	rid, ok := parseReferenceID(c)
+	if !ok {
+		return
+	}
+
+	affected, err := services.DeleteReference(rid)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if affected == 0 {
+		utils.JsonErrorResponse(c, utils.CodeReferenceNotFound)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// ExportReferences 导出一组化合物的引用文献
+// @Summary 导出文献
+// @Description 以BibTeX或RIS格式导出一组化合物引用的文献（去重），如 /api/references/export?format=ris&id=MNP000001&id=MNP000002
+// @Tags reference
+// @Produce application/x-bibtex,application/x-research-info-systems
+// @Param id query []string true "数据ID，可重复，最多100个" collectionFormat(multi)
+// @Param format query string false "导出格式" Enums(bibtex,ris) default(bibtex)
+// @Success 200 {string} string "文献文件"
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/references/export [get]
+func ExportReferences(c *gin.Context) {
+	var ids []string
+	for _, id := range c.QueryArray("id") {
+		// 同时支持 id=A,B 的写法
+		for _, part := range strings.Split(id, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				ids = append(ids, part)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
+		return
+	}
+	if len(ids) > 100 {
+		utils.JsonErrorResponse(c, utils.CodeTooManyExportIDs, gin.H{"max": 100})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "bibtex"))
+	if format != "bibtex" && format != "ris" {
+		utils.JsonErrorResponse(c, utils.CodeInvalidChoice, gin.H{"param": "format", "choices": "bibtex, ris"})
+		return
+	}
+
+	refs, err := services.GetReferencesByDataIDs(ids)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	if format == "ris" {
+		c.Header("Content-Disposition", `attachment; filename="references.ris"`)
+		c.Data(200, "application/x-research-info-systems; charset=utf-8", []byte(services.FormatRIS(refs)))
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="references.bib"`)
+	c.Data(200, "application/x-bibtex; charset=utf-8", []byte(services.FormatBibTeX(refs)))
+}
+
+// LinkCompoundReference 将文献关联到化合物
+// @Summary 关联文献
+// @Description 将文献关联到化合物的若干数据字段（如 nmr、ms2、bioactivity），已存在的关联会被忽略，需要 data:write 权限
+// @Tags reference
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "数据ID"
+// @Param request body LinkReferenceRequest true "关联信息"
+// @Success 200 {object} utils.JSONResponse{data=[]models.CitedReference}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/{id}/references [post]
+func LinkCompoundReference(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
+		return
+	}
+
+	var req LinkReferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	// 确认化合物和文献存在
+	exists, err := compoundRepo.Exists(id)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if !exists {
+		utils.JsonErrorResponse(c, utils.CodeDataNotFound)
+		return
+	}
+	if _, err := services.GetReferenceByID(req.ReferenceID); err != nil {
+		serviceError(c, err, utils.CodeDatabaseError)
+		return
+	}
+
+	if err := services.LinkReference(id, req.ReferenceID, req.Fields); err != nil {
+		if !referenceValidationError(c, err) {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	refs, err := services.GetReferencesByDataIDs([]string{id})
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, refs)
+}
+
+// UnlinkCompoundReference 取消化合物与文献的关联
+// @Summary 取消关联文献
+// @Description 取消化合物与文献的关联，指定field时只取消该字段的关联，需要 data:write 权限
+// @Tags reference
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "数据ID"
+// @Param rid path int true "文献ID"
+// @Param field query string false "数据字段"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/{id}/references/{rid} [delete]
+func UnlinkCompoundReference(c *gin.Context) {
+	id := c.Param("id")
+	rid, ok := parseReferenceID(c)
+	if !ok {
+		return
+	}
+
+	affected, err := services.UnlinkReference(id, rid, c.Query("field"))
+	if err != nil {
+		if !referenceValidationError(c, err) {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+	if affected == 0 {
+		utils.JsonErrorResponse(c, utils.CodeReferenceLinkNotFound)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
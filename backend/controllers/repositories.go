@@ -0,0 +1,19 @@
+package controllers
+
+import "backend/repository"
+
+// 控制器使用的存储，默认为数据库实现，测试时可通过 SetRepositories 替换为内存实现
+var (
+	compoundRepo repository.CompoundRepository = repository.NewGormCompoundRepository()
+	passkeyRepo  repository.PasskeyRepository  = repository.NewGormPasskeyRepository()
+)
+
+// SetRepositories 替换控制器使用的存储，参数为 nil 时保持不变
+func SetRepositories(compounds repository.CompoundRepository, passkeys repository.PasskeyRepository) {
+	if compounds != nil {
+		compoundRepo = compounds
+	}
+	if passkeys != nil {
+		passkeyRepo = passkeys
+	}
+}
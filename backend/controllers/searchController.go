@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"backend/services"
+	"backend/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchCompounds 按名称全文检索化合物
+// @Summary 名称检索
+// @Description 在化合物名称、别名、CAS号、标签和来源中检索，支持前缀匹配和拼写容错，按相关度排序
+// @Tags search
+// @Produce json
+// @Param q query string true "检索词，如 streptomy、50-07-7，最多200个字符、10个词"
+// @Param limit query int false "返回的记录数量，默认为10"
+// @Param offset query int false "从第几条记录开始，默认为0"
+// @Success 200 {object} utils.JSONResponse{data=utils.Page{data=[]services.SearchHit}}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/search [get]
+func SearchCompounds(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "limit"})
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.JsonErrorResponse(c, utils.CodeInvalidNonNegativeInteger, gin.H{"param": "offset"})
+		return
+	}
+
+	// 限制最大查询数量
+	if limit > 100 {
+		limit = 100
+	}
+
+	hits, totalCount, err := services.SearchCompounds(c.Query("q"), limit, offset)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmptySearchQuery):
+			utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "q"})
+		case errors.Is(err, services.ErrSearchQueryTooLong):
+			utils.JsonErrorResponse(c, utils.CodeSearchQueryTooLong, gin.H{"max": services.MaxSearchQueryLength, "max_terms": services.MaxSearchTerms})
+		default:
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	response := utils.NewPage(hits, int64(totalCount), limit, offset)
+
+	utils.JsonSuccessResponse(c, response)
+}
+
+// RebuildSearchIndex 重建名称检索索引
+// @Summary 重建检索索引
+// @Description 从数据库重建名称检索索引，直接修改数据库中的化合物名称后调用，需要 data:write 权限
+// @Tags search
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=services.SearchIndexStats}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/search/rebuild [post]
+func RebuildSearchIndex(c *gin.Context) {
+	stats, err := services.BuildSearchIndex()
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, stats)
+}
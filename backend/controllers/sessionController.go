@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionResponse 会话响应结构
+type SessionResponse struct {
+	models.Session
+	Active  bool `json:"active"`  // 未注销且未过期
+	Current bool `json:"current"` // 是否为当前请求所属的会话
+}
+
+func newSessionResponses(c *gin.Context, sessions []models.Session, now time.Time) []SessionResponse {
+	current := c.GetString("session_id")
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			Session: session,
+			Active:  session.RevokedAt == nil && now.Before(session.ExpiresAt),
+			Current: current != "" && session.ID == current,
+		})
+	}
+	return response
+}
+
+// GetMySessions 获取当前用户的活动会话
+// @Summary 获取当前用户的会话
+// @Description 返回当前 passkey 未注销且未过期的会话（登录设备）
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=[]SessionResponse}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/auth/sessions [get]
+func GetMySessions(c *gin.Context) {
+	now := time.Now()
+	sessions, err := services.ListSessions(c.GetString("passkey"), false, now)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, newSessionResponses(c, sessions, now))
+}
+
+// GetPasskeySessions 获取 passkey 的会话
+// @Summary 获取 passkey 的会话
+// @Description 返回指定 passkey 的活动会话，include_inactive=true 时同时返回已注销和已过期的会话
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Param include_inactive query bool false "是否包含已注销和已过期的会话"
+// @Success 200 {object} utils.JSONResponse{data=[]SessionResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/sessions [get]
+func GetPasskeySessions(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	sessions, err := services.ListSessions(passkey.Passkey, c.Query("include_inactive") == "true", now)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, newSessionResponses(c, sessions, now))
+}
+
+// DeletePasskeySession 结束 passkey 的会话
+// @Summary 结束 passkey 的会话
+// @Description 注销指定会话，会话的刷新令牌和访问令牌立即失效
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Param sid path string true "会话ID"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/sessions/{sid} [delete]
+func DeletePasskeySession(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := services.RevokeSession(passkey.Passkey, c.Param("sid"), models.SessionRevokeAdmin)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if revoked == 0 {
+		utils.JsonErrorResponse(c, utils.CodeSessionNotFound)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
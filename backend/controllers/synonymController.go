@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SynonymRequest 别名请求结构
+type SynonymRequest struct {
+	Name     string `json:"name" binding:"required"`
+	NameType string `json:"name_type"` // trivial、iupac、code、other，默认为 trivial
+}
+
+// GetCompoundSynonyms 获取化合物的别名
+// @Summary 获取化合物别名
+// @Description 返回指定化合物的俗名、IUPAC名和代号等别名
+// @Tags search
+// @Produce json
+// @Param id path string true "数据ID"
+// @Success 200 {object} utils.JSONResponse{data=[]models.Synonym}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/{id}/synonyms [get]
+func GetCompoundSynonyms(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
+		return
+	}
+
+	synonyms, err := services.GetSynonymsByDataID(id)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, synonyms)
+}
+
+// CreateCompoundSynonym 为化合物新增别名
+// @Summary 新增化合物别名
+// @Description 为指定化合物新增别名，并同步更新名称检索索引，需要 data:write 权限
+// @Tags search
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "数据ID"
+// @Param request body SynonymRequest true "别名"
+// @Success 200 {object} utils.JSONResponse{data=models.Synonym}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 409 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/{id}/synonyms [post]
+func CreateCompoundSynonym(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.JsonErrorResponse(c, utils.CodeMissingParameter, gin.H{"param": "id"})
+		return
+	}
+
+	var req SynonymRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	// 确认化合物存在
+	exists, err := compoundRepo.Exists(id)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if !exists {
+		utils.JsonErrorResponse(c, utils.CodeDataNotFound)
+		return
+	}
+
+	synonym := models.Synonym{DataID: id, Name: req.Name, NameType: req.NameType}
+	if err := services.CreateSynonym(&synonym); err != nil {
+		if errors.Is(err, services.ErrInvalidSynonymType) || errors.Is(err, services.ErrEmptySynonym) || errors.Is(err, services.ErrDuplicateSynonym) {
+			serviceError(c, err, utils.CodeInternalError)
+		} else {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	utils.JsonSuccessResponse(c, synonym)
+}
+
+// DeleteCompoundSynonym 删除化合物的别名
+// @Summary 删除化合物别名
+// @Description 删除指定化合物的别名，需要 data:write 权限
+// @Tags search
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "数据ID"
+// @Param sid path int true "别名ID"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/data/{id}/synonyms/{sid} [delete]
+func DeleteCompoundSynonym(c *gin.Context) {
+	sid, err := strconv.ParseUint(c.Param("sid"), 10, 64)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "sid"})
+		return
+	}
+
+	affected, err := services.DeleteSynonym(c.Param("id"), uint(sid))
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+	if affected == 0 {
+		utils.JsonErrorResponse(c, utils.CodeSynonymNotFound)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
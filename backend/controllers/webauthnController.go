@@ -0,0 +1,406 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/services"
+	"backend/utils"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+// WebAuthnEnrollRequest 使用注册码开始首次注册的请求结构
+type WebAuthnEnrollRequest struct {
+	EnrollmentCode string `json:"enrollment_code" binding:"required"` // passkey UUID
+	Name           string `json:"name"`                               // 凭据名称，如"实验室 YubiKey"
+}
+
+// WebAuthnRegisterRequest 已登录用户开始注册新凭据的请求结构
+type WebAuthnRegisterRequest struct {
+	Name string `json:"name"`
+}
+
+// WebAuthnFinishRequest 完成注册或登录的请求结构，credential 为浏览器 navigator.credentials 返回的 PublicKeyCredential（JSON 序列化）
+type WebAuthnFinishRequest struct {
+	CeremonyID string          `json:"ceremony_id" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// WebAuthnRegistrationOptions 注册选项响应结构
+type WebAuthnRegistrationOptions struct {
+	CeremonyID string                       `json:"ceremony_id"`
+	Options    *protocol.CredentialCreation `json:"options"` // 传给 navigator.credentials.create()
+}
+
+// WebAuthnLoginOptions 登录选项响应结构
+type WebAuthnLoginOptions struct {
+	CeremonyID string                        `json:"ceremony_id"`
+	Options    *protocol.CredentialAssertion `json:"options"` // 传给 navigator.credentials.get()
+}
+
+// WebAuthnRegistrationResponse 完成注册的响应结构，首次注册时同时返回登录令牌
+type WebAuthnRegistrationResponse struct {
+	Credential models.WebAuthnCredential `json:"credential"`
+	Login      *LoginResponse            `json:"login,omitempty"`
+}
+
+// webAuthnError 将 WebAuthn 服务错误转换为响应
+func webAuthnError(c *gin.Context, err error) {
+	// 凭据对应的 passkey 已被删除
+	if errors.Is(err, services.ErrPasskeyNotFound) {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPasskey)
+		return
+	}
+	serviceError(c, err, utils.CodeInternalError)
+}
+
+// startWebAuthnSession 记录登录并创建会话，返回登录响应
+func startWebAuthnSession(c *gin.Context, p *models.Passkey, now time.Time) (*LoginResponse, bool) {
+	pair, err := services.LoginPasskey(p, c.ClientIP(), c.Request.UserAgent(), now)
+	if err != nil {
+		webAuthnError(c, err)
+		return nil, false
+	}
+	response := newLoginResponse(p, pair)
+	return &response, true
+}
+
+// BeginWebAuthnEnrollment 使用注册码开始首次注册
+// @Summary 使用注册码绑定安全密钥
+// @Description 使用 passkey UUID 作为一次性注册码开始 WebAuthn 注册，返回传给 navigator.credentials.create() 的选项。完成注册后注册码失效
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param request body WebAuthnEnrollRequest true "注册请求"
+// @Success 200 {object} utils.JSONResponse{data=WebAuthnRegistrationOptions}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/auth/webauthn/enroll/begin [post]
+func BeginWebAuthnEnrollment(c *gin.Context) {
+	var req WebAuthnEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	passkey, err := passkeyRepo.Find(req.EnrollmentCode)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.JsonErrorResponse(c, utils.CodeInvalidEnrollmentCode)
+		} else {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+	// 统一身份认证账户没有注册码
+	if passkey.OIDCSubject != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidEnrollmentCode)
+		return
+	}
+
+	ceremonyID, options, err := services.BeginWebAuthnRegistration(passkey, true, req.Name, time.Now())
+	if err != nil {
+		webAuthnError(c, err)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, WebAuthnRegistrationOptions{CeremonyID: ceremonyID, Options: options})
+}
+
+// BeginWebAuthnRegistration 已登录用户开始注册新的安全密钥
+// @Summary 添加安全密钥
+// @Description 为当前账户注册新的 WebAuthn 凭据（一个账户可以绑定多个安全密钥），返回传给 navigator.credentials.create() 的选项
+// @Tags webauthn
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body WebAuthnRegisterRequest false "注册请求"
+// @Success 200 {object} utils.JSONResponse{data=WebAuthnRegistrationOptions}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/auth/webauthn/register/begin [post]
+func BeginWebAuthnRegistration(c *gin.Context) {
+	var req WebAuthnRegisterRequest
+	// 请求体可以为空
+	_ = c.ShouldBindJSON(&req)
+
+	passkey, err := passkeyRepo.Find(c.GetString("passkey"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.JsonErrorResponse(c, utils.CodeInvalidToken)
+		} else {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		}
+		return
+	}
+
+	// 已登录用户添加凭据时，若账户尚未绑定过安全密钥，视为首次注册并使注册码失效
+	ceremonyID, options, err := services.BeginWebAuthnRegistration(passkey, passkey.EnrolledAt == nil, req.Name, time.Now())
+	if err != nil {
+		webAuthnError(c, err)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, WebAuthnRegistrationOptions{CeremonyID: ceremonyID, Options: options})
+}
+
+// FinishWebAuthnRegistration 完成安全密钥注册
+// @Summary 完成安全密钥注册
+// @Description 校验认证器返回的注册结果并保存凭据。使用注册码首次注册时同时完成登录，返回登录令牌
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param request body WebAuthnFinishRequest true "注册结果"
+// @Success 200 {object} utils.JSONResponse{data=WebAuthnRegistrationResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 409 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/auth/webauthn/register/finish [post]
+func FinishWebAuthnRegistration(c *gin.Context) {
+	var req WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	now := time.Now()
+	passkey, credential, enrolled, err := services.FinishWebAuthnRegistration(req.CeremonyID, req.Credential, now)
+	if err != nil {
+		webAuthnError(c, err)
+		return
+	}
+
+	response := WebAuthnRegistrationResponse{Credential: *credential}
+	if enrolled {
+		// 注册码已失效，使此前用 UUID 登录签发的令牌失效，并直接登录
+		if err := services.RevokeAllTokens(passkey.Passkey); err != nil {
+			utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+			return
+		}
+		// 重新读取令牌版本，期间可能有其他注销
+		passkey, err = passkeyRepo.Find(passkey.Passkey)
+		if err != nil {
+			passkeyLookupError(c, err)
+			return
+		}
+		login, ok := startWebAuthnSession(c, passkey, now)
+		if !ok {
+			return
+		}
+		response.Login = login
+	}
+
+	utils.JsonSuccessResponse(c, response)
+}
+
+// BeginWebAuthnLogin 开始安全密钥登录
+// @Summary 开始安全密钥登录
+// @Description 返回传给 navigator.credentials.get() 的选项，使用可发现凭据，无需输入账户
+// @Tags webauthn
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=WebAuthnLoginOptions}
+// @Failure 429 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/auth/webauthn/login/begin [post]
+func BeginWebAuthnLogin(c *gin.Context) {
+	ceremonyID, options, err := services.BeginWebAuthnLogin(time.Now())
+	if err != nil {
+		webAuthnError(c, err)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, WebAuthnLoginOptions{CeremonyID: ceremonyID, Options: options})
+}
+
+// FinishWebAuthnLogin 完成安全密钥登录
+// @Summary 完成安全密钥登录
+// @Description 校验认证器返回的断言，检查 passkey 的有效期和登录次数后创建会话，响应与 /api/auth/login 相同
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param request body WebAuthnFinishRequest true "登录断言"
+// @Success 200 {object} utils.JSONResponse{data=LoginResponse}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 422 {object} utils.JSONResponse
+// @Failure 429 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Failure 503 {object} utils.JSONResponse
+// @Router /api/auth/webauthn/login/finish [post]
+func FinishWebAuthnLogin(c *gin.Context) {
+	var req WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidRequest)
+		return
+	}
+
+	now := time.Now()
+	passkey, err := services.FinishWebAuthnLogin(req.CeremonyID, req.Credential, now)
+	if err != nil {
+		webAuthnError(c, err)
+		return
+	}
+
+	login, ok := startWebAuthnSession(c, passkey, now)
+	if !ok {
+		return
+	}
+
+	utils.JsonSuccessResponse(c, login)
+}
+
+// GetMyWebAuthnCredentials 获取当前账户的安全密钥
+// @Summary 获取当前账户的安全密钥
+// @Description 返回当前账户绑定的全部 WebAuthn 凭据
+// @Tags webauthn
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.JSONResponse{data=[]models.WebAuthnCredential}
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/auth/webauthn/credentials [get]
+func GetMyWebAuthnCredentials(c *gin.Context) {
+	credentials, err := services.GetWebAuthnCredentials(c.GetString("passkey"))
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, credentials)
+}
+
+// DeleteMyWebAuthnCredential 删除当前账户的安全密钥
+// @Summary 删除当前账户的安全密钥
+// @Description 删除当前账户的 WebAuthn 凭据，不能删除最后一个
+// @Tags webauthn
+// @Security BearerAuth
+// @Produce json
+// @Param cid path int true "凭据ID"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 409 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/auth/webauthn/credentials/{cid} [delete]
+func DeleteMyWebAuthnCredential(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("cid"), 10, 64)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "cid"})
+		return
+	}
+
+	if err := services.DeleteWebAuthnCredential(c.GetString("passkey"), uint(id), true); err != nil {
+		webAuthnError(c, err)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// GetPasskeyWebAuthnCredentials 获取 passkey 账户的安全密钥
+// @Summary 获取 passkey 账户的安全密钥
+// @Description 返回指定 passkey 账户绑定的全部 WebAuthn 凭据
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Success 200 {object} utils.JSONResponse{data=[]models.WebAuthnCredential}
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/credentials [get]
+func GetPasskeyWebAuthnCredentials(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	credentials, err := services.GetWebAuthnCredentials(passkey.Passkey)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, credentials)
+}
+
+// DeletePasskeyWebAuthnCredential 删除 passkey 账户的安全密钥
+// @Summary 删除 passkey 账户的安全密钥
+// @Description 删除指定 passkey 账户的 WebAuthn 凭据（如设备丢失），允许删除最后一个
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Param cid path int true "凭据ID"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 409 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/credentials/{cid} [delete]
+func DeletePasskeyWebAuthnCredential(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("cid"), 10, 64)
+	if err != nil {
+		utils.JsonErrorResponse(c, utils.CodeInvalidPositiveInteger, gin.H{"param": "cid"})
+		return
+	}
+
+	if err := services.DeleteWebAuthnCredential(passkey.Passkey, uint(id), false); err != nil {
+		webAuthnError(c, err)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// ResetPasskeyEnrollment 重置 passkey 账户的安全密钥注册
+// @Summary 重置安全密钥注册
+// @Description 删除账户的全部 WebAuthn 凭据并使已签发的令牌失效，passkey UUID 重新作为一次性注册码可用
+// @Tags passkey
+// @Security BearerAuth
+// @Produce json
+// @Param passkey path string true "passkey UUID"
+// @Success 200 {object} utils.JSONResponse
+// @Failure 400 {object} utils.JSONResponse
+// @Failure 401 {object} utils.JSONResponse
+// @Failure 403 {object} utils.JSONResponse
+// @Failure 404 {object} utils.JSONResponse
+// @Failure 500 {object} utils.JSONResponse
+// @Router /api/passkeys/{passkey}/enrollment/reset [post]
+func ResetPasskeyEnrollment(c *gin.Context) {
+	passkey, ok := findManageablePasskey(c)
+	if !ok {
+		return
+	}
+
+	if err := services.ResetWebAuthnEnrollment(passkey.Passkey); err != nil {
+		utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+		return
+	}
+
+	utils.JsonSuccessResponse(c, nil)
+}
@@ -3,33 +3,113 @@ package database
 import (
 	"backend/config"
 	"fmt"
+	"net/url"
 
-	"github.com/rs/zerolog/log"
-
+	"github.com/glebarez/sqlite"
 	_ "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// 支持的数据库驱动，通过 database.driver 配置
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 var DB *gorm.DB
 
-func Init() {
+// Init 按 database.driver 配置连接数据库，连接失败时返回错误（DB 保持为 nil）
+func Init() error {
+	driver := Driver()
+	dialector, err := dialectorFor(driver)
+	if err != nil {
+		return err
+	}
+
+	// TranslateError 将各数据库的唯一约束冲突统一转换为 gorm.ErrDuplicatedKey
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return fmt.Errorf("连接数据库失败（%s）: %v", driver, err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("连接数据库失败（%s）: %v", driver, err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		return fmt.Errorf("连接数据库失败（%s）: %v", driver, err)
+	}
+
+	if err := registerMetrics(db); err != nil {
+		return fmt.Errorf("注册数据库指标失败: %v", err)
+	}
+
+	DB = db
+	return nil
+}
+
+// Driver 返回配置的数据库驱动，默认为 mysql
+func Driver() string {
+	driver := config.Config.GetString("database.driver")
+	if driver == "" {
+		return DriverMySQL
+	}
+	return driver
+}
+
+func dialectorFor(driver string) (gorm.Dialector, error) {
 	user := config.Config.GetString("database.user")
 	pass := config.Config.GetString("database.pass")
 	port := config.Config.GetString("database.port")
 	host := config.Config.GetString("database.host")
 	name := config.Config.GetString("database.name")
 
-	dsn := fmt.Sprintf("%v:%v@tcp(%v:%v)/%v?charset=utf8&parseTime=True&loc=Local", user, pass, host, port, name)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	switch driver {
+	case DriverMySQL:
+		dsn := fmt.Sprintf("%v:%v@tcp(%v:%v)/%v?charset=utf8&parseTime=True&loc=Local", user, pass, host, port, name)
+		return mysql.Open(dsn), nil
 
-	if err != nil {
-		log.Error().Err(err).Msg("Database connect failed")
-	}
+	case DriverPostgres:
+		sslMode := config.Config.GetString("database.sslmode")
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		dsn := (&url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(user, pass),
+			Host:     fmt.Sprintf("%v:%v", host, port),
+			Path:     name,
+			RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+		}).String()
+		return postgres.Open(dsn), nil
 
-	DB = db
+	case DriverSQLite:
+		path := config.Config.GetString("database.path")
+		if path == "" {
+			path = "./mnplib.db"
+		}
+		// 启用外键；WAL 模式下读写互不阻塞，写入冲突时最多等待 5 秒
+		return sqlite.Open(path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"), nil
+
+	default:
+		return nil, fmt.Errorf("不支持的数据库驱动: %s（可选 mysql、postgres、sqlite）", driver)
+	}
 }
 
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// Close 关闭数据库连接池，未连接时不做任何事
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
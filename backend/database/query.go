@@ -0,0 +1,34 @@
+package database
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// 表结构中的列名区分大小写（如 Passkey、Is_Active），PostgreSQL 会把未加引号的标识符转为小写，
+// 因此 SQL 片段中不直接写列名：等值条件使用 map（如 Where(map[string]interface{}{"Passkey": passkey})），
+// 其余条件和排序使用以下函数或 clause 中的表达式，由 GORM 按数据库方言为列名加引号
+
+// Column 返回列，作为 SQL 片段中 ? 的参数时按数据库方言加引号，name 可以带表名，如 data.ID
+func Column(name string) clause.Column {
+	return clause.Column{Name: name}
+}
+
+// Asc 按列升序排列
+func Asc(column string) clause.OrderByColumn {
+	return clause.OrderByColumn{Column: Column(column)}
+}
+
+// Desc 按列降序排列
+func Desc(column string) clause.OrderByColumn {
+	return clause.OrderByColumn{Column: Column(column), Desc: true}
+}
+
+// NotEmpty 列不为 NULL 且不为空字符串
+func NotEmpty(column string) clause.Expression {
+	return clause.And(clause.Neq{Column: column, Value: nil}, clause.Neq{Column: column, Value: ""})
+}
+
+// LikeFold 不区分大小写的 LIKE 条件
+func LikeFold(column, pattern string) clause.Expression {
+	return clause.Expr{SQL: "LOWER(?) LIKE LOWER(?)", Vars: []interface{}{Column(column), pattern}}
+}
@@ -0,0 +1,17 @@
+// Package docs 提供 OpenAPI 文档和接口文档页面。
+// openapi.json 由 controllers 中的注释生成，修改注释后需执行 go generate ./docs
+package docs
+
+import _ "embed"
+
+//go:generate go run ./gen
+
+// OpenAPI OpenAPI 3 文档
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// UI 接口文档页面，使用 Swagger UI 加载 /api/openapi.json
+//
+//go:embed index.html
+var UI []byte
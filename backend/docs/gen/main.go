@@ -0,0 +1,16 @@
+// gen 从 controllers 的注释生成 docs/openapi.json，由 go generate ./docs 调用
+package main
+
+import (
+	"backend/docs/openapi"
+	"fmt"
+	"os"
+)
+
+func main() {
+	// go generate 在 docs 目录中执行
+	if err := openapi.WriteFile("..", "openapi.json"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,913 @@
+// Package openapi 从 controllers 中 swag 风格的注释生成 OpenAPI 3 文档。
+//
+// 通用信息（@title、@version、@description）写在 main.go 的 main 函数注释中，
+// 每个接口的注释支持 @Summary、@Description、@Tags、@Security、@Accept、@Produce、
+// @Param、@Success、@Failure 和 @Router，写法与 swag 相同。结构体按 json 标签生成
+// schema，字段注释作为说明，binding:"required" 的字段为必填，带类型的常量作为枚举值
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 解析结构体的包，相对于 backend 目录
+var packages = []string{"controllers", "models", "repository", "services", "utils"}
+
+// bearerScheme @Security 使用的认证方式名称
+const bearerScheme = "BearerAuth"
+
+// schema OpenAPI schema 对象
+type schema = map[string]interface{}
+
+// typeDecl 包中声明的类型
+type typeDecl struct {
+	pkg     string
+	imports map[string]string // 导入名 -> 导入路径
+	spec    *ast.TypeSpec
+	doc     string
+}
+
+type generator struct {
+	root    string
+	types   map[string]*typeDecl     // 包名.类型名 -> 声明
+	enums   map[string][]interface{} // 包名.类型名 -> 常量值
+	schemas map[string]interface{}   // components.schemas
+}
+
+// externalTypes 其他模块中的类型对应的 schema
+var externalTypes = map[string]schema{
+	"time.Time":                {"type": "string", "format": "date-time"},
+	"time.Duration":            {"type": "integer", "format": "int64", "description": "纳秒"},
+	"encoding/json.RawMessage": {},
+	"gorm.io/gorm.DeletedAt":   {"type": "string", "format": "date-time", "nullable": true},
+	"gorm.io/datatypes.JSON":   {},
+	"github.com/go-webauthn/webauthn/protocol.CredentialCreation": {
+		"type":        "object",
+		"description": "WebAuthn 注册选项，publicKey 字段传给 navigator.credentials.create()",
+	},
+	"github.com/go-webauthn/webauthn/protocol.CredentialAssertion": {
+		"type":        "object",
+		"description": "WebAuthn 登录选项，publicKey 字段传给 navigator.credentials.get()",
+	},
+}
+
+// Generate 解析 root（backend 目录）下的源码，返回格式化的 OpenAPI 文档
+func Generate(root string) ([]byte, error) {
+	g := &generator{
+		root:    root,
+		types:   map[string]*typeDecl{},
+		enums:   map[string][]interface{}{},
+		schemas: map[string]interface{}{},
+	}
+	for _, pkg := range packages {
+		if err := g.loadPackage(pkg); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := g.parseInfo(filepath.Join(root, "main.go"))
+	if err != nil {
+		return nil, err
+	}
+	paths, err := g.parseOperations(filepath.Join(root, "controllers"))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := schema{
+		"openapi": "3.0.3",
+		"info":    info,
+		"paths":   paths,
+		"components": schema{
+			"schemas": g.schemas,
+			"securitySchemes": schema{
+				bearerScheme: schema{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "登录接口返回的访问令牌，放在 Authorization: Bearer <token> 请求头中",
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// goFiles 返回目录中除测试外的 Go 源文件，按文件名排序
+func goFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, file := range files {
+		if !strings.HasSuffix(file, "_test.go") {
+			result = append(result, file)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// fileImports 返回文件的导入名和导入路径
+func fileImports(file *ast.File) map[string]string {
+	imports := map[string]string{}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := filepath.Base(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// loadPackage 记录包中声明的类型和带类型的常量
+func (g *generator) loadPackage(pkg string) error {
+	files, err := goFiles(filepath.Join(g.root, pkg))
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		imports := fileImports(file)
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			switch gen.Tok {
+			case token.TYPE:
+				for _, spec := range gen.Specs {
+					spec := spec.(*ast.TypeSpec)
+					doc := spec.Doc
+					if doc == nil && len(gen.Specs) == 1 {
+						doc = gen.Doc
+					}
+					// 注释开头的类型名不写入文档
+					text := strings.TrimPrefix(docText(doc), spec.Name.Name+" ")
+					g.types[pkg+"."+spec.Name.Name] = &typeDecl{pkg: pkg, imports: imports, spec: spec, doc: text}
+				}
+			case token.CONST:
+				g.loadEnums(pkg, gen)
+			}
+		}
+	}
+	return nil
+}
+
+// loadEnums 记录常量声明中带类型的字符串和整数常量
+func (g *generator) loadEnums(pkg string, gen *ast.GenDecl) {
+	for _, spec := range gen.Specs {
+		spec := spec.(*ast.ValueSpec)
+		ident, ok := spec.Type.(*ast.Ident)
+		if !ok || len(spec.Values) != len(spec.Names) {
+			continue
+		}
+		for _, value := range spec.Values {
+			lit, ok := value.(*ast.BasicLit)
+			if !ok {
+				continue
+			}
+			var v interface{}
+			switch lit.Kind {
+			case token.STRING:
+				v, _ = strconv.Unquote(lit.Value)
+			case token.INT:
+				v, _ = strconv.Atoi(lit.Value)
+			default:
+				continue
+			}
+			key := pkg + "." + ident.Name
+			g.enums[key] = append(g.enums[key], v)
+		}
+	}
+}
+
+// docText 返回注释的文本，多行合并为一行
+func docText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
+
+// annotations 返回注释中以 @ 开头的行
+func annotations(group *ast.CommentGroup) []string {
+	if group == nil {
+		return nil
+	}
+	var lines []string
+	for _, comment := range group.List {
+		line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if strings.HasPrefix(line, "@") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitAnnotation 将注释行拆分为属性名和其余部分
+func splitAnnotation(line string) (string, string) {
+	name, rest, _ := strings.Cut(line, " ")
+	return strings.ToLower(name), strings.TrimSpace(rest)
+}
+
+// parseInfo 解析 main 函数注释中的通用信息
+func (g *generator) parseInfo(path string) (schema, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	info := schema{}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "main" {
+			continue
+		}
+		var description []string
+		for _, line := range annotations(fn.Doc) {
+			switch name, rest := splitAnnotation(line); name {
+			case "@title":
+				info["title"] = rest
+			case "@version":
+				info["version"] = rest
+			case "@description":
+				description = append(description, rest)
+			}
+		}
+		if len(description) > 0 {
+			info["description"] = strings.Join(description, "\n")
+		}
+	}
+	if info["title"] == nil || info["version"] == nil {
+		return nil, fmt.Errorf("%s: main 函数缺少 @title 或 @version", path)
+	}
+	return info, nil
+}
+
+// parseOperations 解析 controllers 中带 @Router 的函数，返回 paths
+func (g *generator) parseOperations(dir string) (schema, error) {
+	files, err := goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := schema{}
+	fset := token.NewFileSet()
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil {
+				continue
+			}
+			lines := annotations(fn.Doc)
+			if len(lines) == 0 {
+				continue
+			}
+			route, method, op, err := g.parseOperation(fn.Name.Name, lines)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", fset.Position(fn.Pos()), fn.Name.Name, err)
+			}
+			item, _ := paths[route].(schema)
+			if item == nil {
+				item = schema{}
+				paths[route] = item
+			}
+			if existing, ok := item[method].(schema); ok {
+				return nil, fmt.Errorf("%s: %s: %s %s 已由 %s 声明", fset.Position(fn.Pos()), fn.Name.Name, strings.ToUpper(method), route, existing["operationId"])
+			}
+			item[method] = op
+		}
+	}
+	return paths, nil
+}
+
+// mimeTypes swag 中 @Accept 和 @Produce 的简写
+var mimeTypes = map[string]string{
+	"json":                  "application/json",
+	"plain":                 "text/plain",
+	"html":                  "text/html",
+	"csv":                   "text/csv",
+	"mpfd":                  "multipart/form-data",
+	"x-www-form-urlencoded": "application/x-www-form-urlencoded",
+	"octet-stream":          "application/octet-stream",
+}
+
+// parseMimeTypes 解析逗号分隔的 MIME 类型列表
+func parseMimeTypes(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if mime, ok := mimeTypes[item]; ok {
+			item = mime
+		}
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// parseOperation 解析一个接口的注释
+func (g *generator) parseOperation(name string, lines []string) (string, string, schema, error) {
+	op := schema{"operationId": name}
+	var (
+		route, method string
+		description   []string
+		accept        = []string{"application/json"}
+		produce       = []string{"application/json"}
+		params        []string
+		responses     []string
+	)
+	for _, line := range lines {
+		switch key, rest := splitAnnotation(line); key {
+		case "@summary":
+			op["summary"] = rest
+		case "@description":
+			description = append(description, rest)
+		case "@tags":
+			op["tags"] = strings.Split(strings.ReplaceAll(rest, " ", ""), ",")
+		case "@security":
+			if rest != bearerScheme {
+				return "", "", nil, fmt.Errorf("不支持的认证方式 %q", rest)
+			}
+			op["security"] = []schema{{bearerScheme: []string{}}}
+		case "@accept":
+			accept = parseMimeTypes(rest)
+		case "@produce":
+			produce = parseMimeTypes(rest)
+		case "@param":
+			params = append(params, rest)
+		case "@success", "@failure":
+			responses = append(responses, rest)
+		case "@router":
+			fields := strings.Fields(rest)
+			if len(fields) != 2 || !strings.HasPrefix(fields[1], "[") || !strings.HasSuffix(fields[1], "]") {
+				return "", "", nil, fmt.Errorf("无效的 @Router %q", rest)
+			}
+			route, method = fields[0], strings.ToLower(strings.Trim(fields[1], "[]"))
+		default:
+			return "", "", nil, fmt.Errorf("不支持的注释 %s", key)
+		}
+	}
+	if route == "" {
+		return "", "", nil, fmt.Errorf("缺少 @Router")
+	}
+	if len(description) > 0 {
+		op["description"] = strings.Join(description, "\n")
+	}
+
+	pathParams := map[string]bool{}
+	var parameters []schema
+	for _, line := range params {
+		param, body, err := g.parseParam(line, accept)
+		if err != nil {
+			return "", "", nil, err
+		}
+		if body != nil {
+			op["requestBody"] = body
+			continue
+		}
+		if param["in"] == "path" {
+			pathParams[param["name"].(string)] = true
+		}
+		parameters = append(parameters, param)
+	}
+	for _, match := range regexp.MustCompile(`\{(\w+)\}`).FindAllStringSubmatch(route, -1) {
+		if !pathParams[match[1]] {
+			return "", "", nil, fmt.Errorf("路径参数 %s 缺少 @Param", match[1])
+		}
+		delete(pathParams, match[1])
+	}
+	for param := range pathParams {
+		return "", "", nil, fmt.Errorf("路径 %s 中没有参数 %s", route, param)
+	}
+	if len(parameters) > 0 {
+		op["parameters"] = parameters
+	}
+
+	result := schema{}
+	for _, line := range responses {
+		if err := g.parseResponse(line, produce, result); err != nil {
+			return "", "", nil, err
+		}
+	}
+	if len(result) == 0 {
+		return "", "", nil, fmt.Errorf("缺少 @Success")
+	}
+	op["responses"] = result
+	return route, method, op, nil
+}
+
+// tokenize 按空格拆分，双引号中的内容作为一项
+func tokenize(s string) []string {
+	var tokens []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				tokens = append(tokens, s)
+				break
+			}
+			tokens = append(tokens, s[:end+2])
+			s = s[end+2:]
+			continue
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			tokens = append(tokens, s)
+			break
+		}
+		tokens = append(tokens, s[:end])
+		s = s[end:]
+	}
+	return tokens
+}
+
+var attributePattern = regexp.MustCompile(`^(\w+)\((.*)\)$`)
+
+// parseParam 解析 @Param，body 参数作为 requestBody 返回
+func (g *generator) parseParam(line string, accept []string) (schema, schema, error) {
+	tokens := tokenize(line)
+	if len(tokens) < 5 || !strings.HasPrefix(tokens[4], `"`) {
+		return nil, nil, fmt.Errorf("无效的 @Param %q，格式为 名称 位置 类型 是否必填 \"说明\"", line)
+	}
+	name, in, typ := tokens[0], tokens[1], tokens[2]
+	required, err := strconv.ParseBool(tokens[3])
+	if err != nil {
+		return nil, nil, fmt.Errorf("无效的 @Param %q: %w", line, err)
+	}
+	description := strings.Trim(tokens[4], `"`)
+
+	s, err := g.annotationSchema(typ)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, attr := range tokens[5:] {
+		match := attributePattern.FindStringSubmatch(attr)
+		if match == nil {
+			return nil, nil, fmt.Errorf("无效的 @Param 属性 %q", attr)
+		}
+		switch strings.ToLower(match[1]) {
+		case "collectionformat":
+			// 数组参数重复出现，即 OpenAPI 3 的默认写法 style=form、explode=true
+		case "default":
+			s["default"] = literal(s, match[2])
+		case "enums":
+			var values []interface{}
+			for _, value := range strings.Split(match[2], ",") {
+				values = append(values, literal(s, strings.TrimSpace(value)))
+			}
+			s["enum"] = values
+		case "minimum", "maximum":
+			value, err := strconv.ParseFloat(match[2], 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("无效的 @Param 属性 %q", attr)
+			}
+			s[strings.ToLower(match[1])] = value
+		default:
+			return nil, nil, fmt.Errorf("不支持的 @Param 属性 %q", attr)
+		}
+	}
+
+	switch in {
+	case "body":
+		content := schema{}
+		for _, mime := range accept {
+			content[mime] = schema{"schema": s}
+		}
+		return nil, schema{"description": description, "required": required, "content": content}, nil
+	case "path", "query", "header":
+		return schema{
+			"name":        name,
+			"in":          in,
+			"required":    required || in == "path",
+			"description": description,
+			"schema":      s,
+		}, nil, nil
+	}
+	return nil, nil, fmt.Errorf("不支持的参数位置 %q", in)
+}
+
+// literal 按 schema 的类型解析默认值和枚举值
+func literal(s schema, value string) interface{} {
+	switch s["type"] {
+	case "integer":
+		if v, err := strconv.Atoi(value); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+// parseResponse 解析 @Success 和 @Failure，写入 responses
+func (g *generator) parseResponse(line string, produce []string, responses schema) error {
+	tokens := tokenize(line)
+	if len(tokens) == 0 {
+		return fmt.Errorf("无效的响应 %q", line)
+	}
+	status, err := strconv.Atoi(tokens[0])
+	if err != nil || http.StatusText(status) == "" {
+		return fmt.Errorf("无效的 HTTP 状态码 %q", tokens[0])
+	}
+	tokens = tokens[1:]
+
+	var content schema
+	if len(tokens) >= 2 && strings.HasPrefix(tokens[0], "{") {
+		s, err := g.annotationSchema(tokens[1])
+		if err != nil {
+			return err
+		}
+		switch strings.Trim(tokens[0], "{}") {
+		case "object", "string", "integer", "number", "boolean":
+		case "array":
+			s = schema{"type": "array", "items": s}
+		default:
+			return fmt.Errorf("无效的响应类型 %s", tokens[0])
+		}
+		// 失败时总是返回 JSON
+		mimes := produce
+		if status >= http.StatusBadRequest {
+			mimes = []string{"application/json"}
+		}
+		content = schema{}
+		for _, mime := range mimes {
+			content[mime] = schema{"schema": s}
+		}
+		tokens = tokens[2:]
+	}
+
+	description := http.StatusText(status)
+	if len(tokens) > 0 {
+		description = strings.Trim(tokens[0], `"`)
+	}
+	key := strconv.Itoa(status)
+	if existing, ok := responses[key].(schema); ok {
+		// 同一状态码的多条说明合并
+		if description != http.StatusText(status) && description != existing["description"] {
+			existing["description"] = existing["description"].(string) + "；" + description
+		}
+		return nil
+	}
+	response := schema{"description": description}
+	if content != nil {
+		response["content"] = content
+	}
+	responses[key] = response
+	return nil
+}
+
+// splitTopLevel 按不在括号中的分隔符拆分
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// annotationSchema 解析注释中的类型，如 string、[]models.Data、
+// utils.JSONResponse{data=utils.Page{data=[]models.Data}}。没有包名的类型属于 controllers
+func (g *generator) annotationSchema(typ string) (schema, error) {
+	switch {
+	case strings.HasPrefix(typ, "[]"):
+		items, err := g.annotationSchema(typ[2:])
+		if err != nil {
+			return nil, err
+		}
+		return schema{"type": "array", "items": items}, nil
+	case strings.HasPrefix(typ, "map[string]"):
+		value, err := g.annotationSchema(strings.TrimPrefix(typ, "map[string]"))
+		if err != nil {
+			return nil, err
+		}
+		return schema{"type": "object", "additionalProperties": value}, nil
+	case typ == "interface{}" || typ == "any":
+		return schema{}, nil
+	case typ == "object":
+		return schema{"type": "object"}, nil
+	case typ == "file":
+		return schema{"type": "string", "format": "binary"}, nil
+	}
+
+	if open := strings.Index(typ, "{"); open >= 0 {
+		if !strings.HasSuffix(typ, "}") {
+			return nil, fmt.Errorf("无效的类型 %q", typ)
+		}
+		base, err := g.annotationSchema(typ[:open])
+		if err != nil {
+			return nil, err
+		}
+		properties := schema{}
+		for _, field := range splitTopLevel(typ[open+1:len(typ)-1], ',') {
+			name, fieldType, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("无效的类型 %q", typ)
+			}
+			s, err := g.annotationSchema(fieldType)
+			if err != nil {
+				return nil, err
+			}
+			properties[name] = s
+		}
+		return schema{"allOf": []schema{base, {"type": "object", "properties": properties}}}, nil
+	}
+
+	if s, ok := basicSchema(typ); ok {
+		return s, nil
+	}
+	pkg, name, ok := strings.Cut(typ, ".")
+	if !ok {
+		pkg, name = "controllers", typ
+	}
+	return g.namedSchema(pkg, name)
+}
+
+// basicSchema 返回基本类型的 schema
+func basicSchema(name string) (schema, bool) {
+	switch name {
+	case "string":
+		return schema{"type": "string"}, true
+	case "bool", "boolean":
+		return schema{"type": "boolean"}, true
+	case "int", "integer", "int8", "int16", "uint", "uint8", "uint16":
+		return schema{"type": "integer"}, true
+	case "int32", "uint32", "rune":
+		return schema{"type": "integer", "format": "int32"}, true
+	case "int64", "uint64":
+		return schema{"type": "integer", "format": "int64"}, true
+	case "float32":
+		return schema{"type": "number", "format": "float"}, true
+	case "float64", "number":
+		return schema{"type": "number", "format": "double"}, true
+	case "any":
+		return schema{}, true
+	}
+	return nil, false
+}
+
+// namedSchema 返回包中命名类型的 schema。结构体放在 components.schemas 中并返回引用，
+// 其他类型展开为其底层类型
+func (g *generator) namedSchema(pkg, name string) (schema, error) {
+	key := pkg + "." + name
+	decl, ok := g.types[key]
+	if !ok {
+		return nil, fmt.Errorf("未找到类型 %s", key)
+	}
+	ref := schema{"$ref": "#/components/schemas/" + key}
+	if _, ok := g.schemas[key]; ok {
+		return ref, nil
+	}
+
+	if st, ok := decl.spec.Type.(*ast.StructType); ok {
+		// 先占位，支持递归的类型
+		g.schemas[key] = schema{}
+		s, err := g.structSchema(decl, st)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if decl.doc != "" {
+			s["description"] = decl.doc
+		}
+		g.schemas[key] = s
+		return ref, nil
+	}
+
+	s, err := g.exprSchema(decl, decl.spec.Type)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	if values := g.enums[key]; len(values) > 0 {
+		s["enum"] = values
+	}
+	if decl.doc != "" {
+		s["description"] = decl.doc
+	}
+	return s, nil
+}
+
+// exprSchema 返回 Go 类型表达式的 schema
+func (g *generator) exprSchema(decl *typeDecl, expr ast.Expr) (schema, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if s, ok := basicSchema(t.Name); ok {
+			return s, nil
+		}
+		return g.namedSchema(decl.pkg, t.Name)
+	case *ast.StarExpr:
+		s, err := g.exprSchema(decl, t.X)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := s["$ref"]; !ok {
+			s["nullable"] = true
+		}
+		return s, nil
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return schema{"type": "string", "format": "byte"}, nil
+		}
+		items, err := g.exprSchema(decl, t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return schema{"type": "array", "items": items}, nil
+	case *ast.MapType:
+		value, err := g.exprSchema(decl, t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return schema{"type": "object", "additionalProperties": value}, nil
+	case *ast.InterfaceType:
+		return schema{}, nil
+	case *ast.StructType:
+		return g.structSchema(decl, t)
+	case *ast.SelectorExpr:
+		qualifier, ok := t.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		path, ok := decl.imports[qualifier.Name]
+		if !ok {
+			return nil, fmt.Errorf("未导入包 %s", qualifier.Name)
+		}
+		if pkg, ok := strings.CutPrefix(path, "backend/"); ok {
+			return g.namedSchema(pkg, t.Sel.Name)
+		}
+		if s, ok := externalTypes[path+"."+t.Sel.Name]; ok {
+			copied := schema{}
+			for k, v := range s {
+				copied[k] = v
+			}
+			return copied, nil
+		}
+		return nil, fmt.Errorf("不支持的类型 %s.%s，需要添加到 externalTypes", path, t.Sel.Name)
+	}
+	return nil, fmt.Errorf("不支持的类型 %T", expr)
+}
+
+// structSchema 返回结构体的 schema，嵌入的结构体字段展开到外层
+func (g *generator) structSchema(decl *typeDecl, st *ast.StructType) (schema, error) {
+	properties := schema{}
+	var required []string
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			value, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(value)
+		}
+		jsonName, jsonOptions, _ := strings.Cut(tag.Get("json"), ",")
+		if jsonName == "-" && jsonOptions == "" {
+			continue
+		}
+
+		if len(field.Names) == 0 && jsonName == "" {
+			embedded, err := g.embeddedSchema(decl, field.Type)
+			if err != nil {
+				return nil, err
+			}
+			for name, property := range embedded["properties"].(schema) {
+				properties[name] = property
+			}
+			if fields, ok := embedded["required"].([]string); ok {
+				required = append(required, fields...)
+			}
+			continue
+		}
+
+		s, err := g.exprSchema(decl, field.Type)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(jsonOptions, "string") {
+			s = schema{"type": "string"}
+		}
+		if description := docText(field.Doc) + docText(field.Comment); description != "" {
+			if _, ok := s["$ref"]; ok {
+				s = schema{"allOf": []schema{s}}
+			}
+			s["description"] = description
+		}
+
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent(jsonName)}
+		}
+		for _, ident := range names {
+			if len(field.Names) > 0 && !ident.IsExported() {
+				continue
+			}
+			name := ident.Name
+			if jsonName != "" {
+				name = jsonName
+			}
+			properties[name] = s
+			if strings.Contains(tag.Get("binding"), "required") {
+				required = append(required, name)
+			}
+		}
+	}
+
+	result := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		result["required"] = required
+	}
+	return result, nil
+}
+
+// embeddedSchema 返回嵌入字段的结构体 schema
+func (g *generator) embeddedSchema(decl *typeDecl, expr ast.Expr) (schema, error) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	pkg := decl.pkg
+	var name string
+	switch t := expr.(type) {
+	case *ast.Ident:
+		name = t.Name
+	case *ast.SelectorExpr:
+		qualifier, _ := t.X.(*ast.Ident)
+		path := ""
+		if qualifier != nil {
+			path = decl.imports[qualifier.Name]
+		}
+		var ok bool
+		if pkg, ok = strings.CutPrefix(path, "backend/"); !ok {
+			return nil, fmt.Errorf("不支持嵌入其他模块的类型 %s", path)
+		}
+		name = t.Sel.Name
+	default:
+		return nil, fmt.Errorf("不支持的嵌入字段 %T", expr)
+	}
+	embedded, ok := g.types[pkg+"."+name]
+	if !ok {
+		return nil, fmt.Errorf("未找到类型 %s.%s", pkg, name)
+	}
+	st, ok := embedded.spec.Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("嵌入字段 %s.%s 不是结构体", pkg, name)
+	}
+	return g.structSchema(embedded, st)
+}
+
+// WriteFile 生成文档并写入 path，内容未变化时不修改文件
+func WriteFile(root, path string) error {
+	data, err := Generate(root)
+	if err != nil {
+		return err
+	}
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	return os.WriteFile(path, data, 0o644)
+}
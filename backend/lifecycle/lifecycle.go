@@ -0,0 +1,92 @@
+// Package lifecycle 管理 HTTP 服务的运行和停止。收到 SIGINT 或 SIGTERM 后停止接受连接，
+// 在截止时间内等待进行中的请求完成，再按注册顺序执行停止步骤（如关闭 RDKit 进程和数据库连接池）
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// 默认的停止截止时间
+const DefaultShutdownTimeout = 30 * time.Second
+
+// step 停止步骤
+type step struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Manager 管理 HTTP 服务和需要按顺序停止的资源
+type Manager struct {
+	server  *http.Server
+	timeout time.Duration
+	steps   []step
+}
+
+// New 创建管理 server 的 Manager，timeout 为从收到信号到全部停止的截止时间，不大于 0 时使用 DefaultShutdownTimeout
+func New(server *http.Server, timeout time.Duration) *Manager {
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	return &Manager{server: server, timeout: timeout}
+}
+
+// OnShutdown 注册停止步骤，HTTP 服务停止后按注册顺序执行，共用同一个截止时间
+func (m *Manager) OnShutdown(name string, stop func(ctx context.Context) error) {
+	m.steps = append(m.steps, step{name: name, stop: stop})
+}
+
+// Run 启动 HTTP 服务并阻塞，直到收到 SIGINT、SIGTERM 或 ctx 结束后完成停止。
+// 服务启动失败时直接返回错误，停止过程中的错误合并后返回
+func (m *Manager) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- m.server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		// 启动失败（如端口被占用），不执行停止步骤
+		return err
+	case <-ctx.Done():
+	}
+	// 再次收到信号时按默认方式立即退出
+	stop()
+	log.Info().Dur("timeout", m.timeout).Msg("Shutting down")
+
+	return m.Shutdown()
+}
+
+// Shutdown 停止接受连接并等待进行中的请求完成，再按顺序执行停止步骤。
+// 超过截止时间仍未完成的请求会被强制断开
+func (m *Manager) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	var errs []error
+	if err := m.server.Shutdown(ctx); err != nil {
+		m.server.Close()
+		errs = append(errs, fmt.Errorf("http: %w", err))
+	}
+	for _, s := range m.steps {
+		start := time.Now()
+		if err := s.stop(ctx); err != nil {
+			log.Error().Err(err).Str("step", s.name).Msg("Shutdown step failed")
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			continue
+		}
+		log.Info().Str("step", s.name).Dur("took", time.Since(start)).Msg("Stopped")
+	}
+	return errors.Join(errs...)
+}
@@ -3,26 +3,75 @@ package main
 import (
 	"backend/config"
 	"backend/database"
+	"backend/lifecycle"
+	"backend/migrations"
 	"backend/router"
 	"backend/services"
 	"backend/utils"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/gin-gonic/gin"
 )
 
+// @title MNPLib API
+// @version 1.0
+// @description MNPLib 微生物天然产物数据库的后端接口。
+// @description JSON 接口的响应体为 {code, msg, data}，失败时还包含错误代码 error 和可选的 details，全部错误代码见 GET /api/errors。
+// @description 错误消息的语言由查询参数 lang 或请求头 Accept-Language 决定，支持 zh-CN（默认）和 en
 func main() {
-	database.Init()
+	if err := database.Init(); err != nil {
+		log.Fatal().Err(err).Msg("Database connect failed")
+	}
+
+	// 命令行子命令（如 import-taxonomy），执行完毕后退出
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	// 数据库结构落后于程序时拒绝启动，需先执行 migrate up
+	if err := migrations.Check(database.GetDB()); err != nil {
+		log.Fatal().Err(err).Msg("Database schema is not up to date")
+	}
+	if versions, err := migrations.Unknown(database.GetDB()); err == nil && len(versions) > 0 {
+		log.Warn().Ints("versions", versions).Msg("Database schema was migrated by a newer version")
+	}
+
+	// strict 模式下RDKit启动失败时退出，degraded 模式下继续运行，化学计算接口返回 503
+	mode, err := services.StartupMode()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid startup mode")
+	}
+	if err := services.InitRdkit(); err != nil {
+		if mode == services.StartupStrict {
+			log.Fatal().Err(err).Msg("RDKit initialization failed")
+		}
+		log.Warn().Err(err).Msg("RDKit unavailable, serving in degraded mode")
+	}
+
 	// services.InitializeCompoundData()
 	r := gin.Default()
 	router.Init(r)
-	services.InitRdkit()
 
-	err := r.Run(config.Config.GetString("adress_port"))
-	if err != nil {
-		utils.LogError(err)
-		log.Fatal().Err(err).Msg("Failed to start server")
-	}
+	// 收到 SIGINT 或 SIGTERM 后停止接受连接，等待进行中的请求完成，
+	// 再依次停止RDKit进程、写入剩余的审计日志并关闭数据库连接池
+	server := &http.Server{Addr: config.Config.GetString("adress_port"), Handler: r}
+	timeout := time.Duration(config.Config.GetFloat64("shutdown.timeout") * float64(time.Second))
+	manager := lifecycle.New(server, timeout)
+	manager.OnShutdown("rdkit", services.ShutdownRdkit)
+	manager.OnShutdown("audit", services.FlushAuditEvents)
+	manager.OnShutdown("database", func(context.Context) error { return database.Close() })
 
+	if err := manager.Run(context.Background()); err != nil {
+		if !errors.Is(err, http.ErrServerClosed) {
+			utils.LogError(err)
+			log.Fatal().Err(err).Msg("Server stopped with error")
+		}
+	}
+	log.Info().Msg("Server stopped")
 }
@@ -0,0 +1,96 @@
+// Package metrics 定义服务导出的 Prometheus 指标，由 GET /metrics 返回
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 指标名称的前缀
+const namespace = "mnplib"
+
+// Registry 全部指标所在的注册表，包括 Go 运行时和进程指标
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// HTTP 请求
+var (
+	// HTTPRequests 按路由（如 /api/data/:id）、方法和状态码统计的请求数
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration 请求处理耗时（秒）
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// DBQueryDuration 数据库操作耗时（秒），operation 为 create、query、update、delete、row 或 raw，
+// result 为 ok 或 error
+var DBQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "db_query_duration_seconds",
+	Help:      "Database query latency by operation and table.",
+	Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+}, []string{"operation", "table", "result"})
+
+// RDKit 进程
+var (
+	// RdkitDuration 按操作（如 smiles_to_fingerprint）统计的请求耗时（秒），result 为 ok、error 或 timeout
+	RdkitDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rdkit_request_duration_seconds",
+		Help:      "RDKit bridge request latency by action and result.",
+		Buckets:   []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"action", "result"})
+
+	// RdkitTimeouts 超时未响应的请求数
+	RdkitTimeouts = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rdkit_timeouts_total",
+		Help:      "RDKit bridge requests that timed out, by action.",
+	}, []string{"action"})
+
+	// RdkitRestarts RDKit 进程重新启动的次数
+	RdkitRestarts = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rdkit_restarts_total",
+		Help:      "Times the RDKit Python process was replaced by a new one.",
+	})
+)
+
+// ProtectedReads 按 passkey 和审计操作统计的成功读取保护数据的次数。
+// passkey 标签为 passkey 的 SHA-256 前 12 位，不在指标中暴露可用于登录的 passkey
+var ProtectedReads = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "protected_reads_total",
+	Help:      "Successful protected data reads by hashed passkey and action.",
+}, []string{"passkey", "action"})
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// NewGaugeFunc 注册在抓取时调用 fn 取值的指标
+func NewGaugeFunc(name, help string, fn func() float64) {
+	factory.NewGaugeFunc(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help}, fn)
+}
+
+// Handler 以 Prometheus 文本格式导出 Registry 中的指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"backend/models"
+	"backend/services"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audit 将保护数据访问记录到审计日志的中间件，需放在 JWTAuth 之前以便记录未认证的访问。
+// 处理函数通过 c.Set("audit_fields", []string{...}) 记录实际返回的保护字段
+func Audit(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		code := c.GetInt("response_code")
+		services.RecordAuditEvent(models.AuditEvent{
+			PasskeyLabel: services.PasskeyLabel(c.GetString("passkey")),
+			Operator:     c.GetString("operator"),
+			Role:         c.GetString("role"),
+			Action:       action,
+			ResourceID:   c.Param("id"),
+			Fields:       strings.Join(c.GetStringSlice("audit_fields"), ","),
+			ClientIP:     c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+			Outcome:      auditOutcome(c.Writer.Status(), code),
+			Code:         code,
+			Detail:       auditDetail(c.Request.URL),
+		})
+	}
+}
+
+// auditDetail 返回记录到审计日志的查询字符串，其中的 passkey 参数（如按 passkey 导出审计日志）替换为 passkey_label
+func auditDetail(u *url.URL) string {
+	query := u.Query()
+	passkeys, ok := query["passkey"]
+	if !ok {
+		return u.RawQuery
+	}
+	query.Del("passkey")
+	for _, passkey := range passkeys {
+		query.Add("passkey_label", services.PasskeyLabel(passkey))
+	}
+	return query.Encode()
+}
+
+// auditOutcome 根据 HTTP 状态码和响应体中的业务代码（如 200403）判断访问结果
+func auditOutcome(status, code int) string {
+	if code != 0 {
+		status = code % 1000
+	}
+	switch {
+	case status == http.StatusOK:
+		return models.AuditOutcomeSuccess
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return models.AuditOutcomeDenied
+	case status == http.StatusNotFound:
+		return models.AuditOutcomeNotFound
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return models.AuditOutcomeInvalid
+	case status == http.StatusTooManyRequests:
+		return models.AuditOutcomeLimited
+	default:
+		return models.AuditOutcomeError
+	}
+}
@@ -1,13 +1,13 @@
 package middlewares
 
 import (
-	"backend/config"
+	"backend/services"
 	"backend/utils"
-	"net/http"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 // JWTAuth JWT认证中间件
@@ -16,7 +16,7 @@ func JWTAuth() gin.HandlerFunc {
 		// 从请求头中获取token
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			utils.JsonErrorResponse(c, http.StatusUnauthorized, "缺少认证令牌")
+			utils.JsonErrorResponse(c, utils.CodeMissingToken)
 			c.Abort()
 			return
 		}
@@ -24,7 +24,7 @@ func JWTAuth() gin.HandlerFunc {
 		// 检查Bearer token格式
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			utils.JsonErrorResponse(c, http.StatusUnauthorized, "令牌格式错误")
+			utils.JsonErrorResponse(c, utils.CodeMalformedToken)
 			c.Abort()
 			return
 		}
@@ -32,38 +32,54 @@ func JWTAuth() gin.HandlerFunc {
 		tokenString := parts[1]
 
 		// 解析和验证token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// 验证签名方法
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(config.Config.GetString("jwt.secret")), nil
-		})
-
+		claims, err := services.ParseAccessToken(tokenString)
 		if err != nil {
-			utils.JsonErrorResponse(c, http.StatusUnauthorized, "令牌无效或已过期")
+			utils.JsonErrorResponse(c, utils.CodeInvalidToken)
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// 获取用户信息
-			passkey, _ := claims["passkey"].(string)
-			operator, _ := claims["operator"].(string)
-			description, _ := claims["description"].(string)
-
-			// 记录访问日志
-			utils.LogAccess(passkey, operator, description, c.Request.Method, c.Request.URL.Path, c.ClientIP())
-
-			// 将用户信息存储到上下文中
-			c.Set("passkey", passkey)
-			c.Set("operator", operator)
-			c.Set("description", description)
-			c.Next()
-		} else {
-			utils.JsonErrorResponse(c, http.StatusUnauthorized, "令牌无效")
+		// 检查 passkey 是否仍然存在、启用且在有效期内，以及令牌是否已被注销（带缓存）
+		passkey, err := services.ValidateTokenState(claims, time.Now())
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrPasskeyNotFound):
+				utils.JsonErrorResponse(c, utils.CodeInvalidToken)
+			case errors.Is(err, services.ErrPasskeyInactive):
+				utils.JsonErrorResponse(c, utils.CodePasskeyInactive)
+			case errors.Is(err, services.ErrPasskeyNotYetValid):
+				utils.JsonErrorResponse(c, utils.CodePasskeyNotYetValid)
+			case errors.Is(err, services.ErrPasskeyExpired):
+				utils.JsonErrorResponse(c, utils.CodePasskeyExpired)
+			case errors.Is(err, services.ErrTokenRevoked):
+				utils.JsonErrorResponse(c, utils.CodeTokenRevoked)
+			case errors.Is(err, services.ErrSessionRevoked):
+				utils.JsonErrorResponse(c, utils.CodeSessionRevoked)
+			default:
+				utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+			}
 			c.Abort()
 			return
 		}
+
+		// 升级前签发的令牌不含角色，按数据库中的角色处理
+		role := claims.Role
+		if role == "" {
+			role = passkey.Role
+		}
+
+		// 记录访问日志
+		utils.LogAccess(claims.Passkey, claims.Operator, claims.Description, c.Request.Method, c.Request.URL.Path, c.ClientIP())
+
+		// 将用户信息存储到上下文中
+		c.Set("passkey", claims.Passkey)
+		c.Set("operator", claims.Operator)
+		c.Set("description", claims.Description)
+		c.Set("role", role)
+		c.Set("jti", claims.JTI)
+		c.Set("session_id", claims.SessionID)
+		c.Set("token_expires_at", claims.ExpiresAt)
+		c.Set("auth_passkey", passkey) // 缓存中的 passkey 状态和限制设置，只读
+		c.Next()
 	}
 }
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"backend/config"
+	"backend/metrics"
+	"backend/utils"
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 按路由、方法和状态码记录请求数和处理耗时的中间件。
+// 未匹配路由的请求（如静态文件和 404）的 route 为 unmatched，避免标签数量随路径增长
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsAuth 指标接口的认证：请求须带有 metrics.token 配置的 Bearer 令牌，未配置时指标接口不可用。
+// 指标中含有各 passkey 的保护数据读取次数，不能公开
+func MetricsAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := config.Config.GetString("metrics.token")
+		if token == "" {
+			utils.JsonErrorResponse(c, utils.CodeMetricsNotConfigured)
+			c.Abort()
+			return
+		}
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			utils.JsonErrorResponse(c, utils.CodeUnauthenticated)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authPasskey 返回 JWTAuth 写入上下文的 passkey，未认证时为 nil
+func authPasskey(c *gin.Context) *models.Passkey {
+	value, _ := c.Get("auth_passkey")
+	p, _ := value.(*models.Passkey)
+	return p
+}
+
+// setRateLimitHeaders 设置 X-RateLimit-* 响应头
+func setRateLimitHeaders(c *gin.Context, result *services.RateLimitResult) {
+	if result == nil {
+		return
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+}
+
+// IPRateLimit 按客户端 IP 限制请求频率的中间件，不需要认证，用于公开接口和登录接口。
+// scope 为 services.IPScopePublic 或 services.IPScopeLogin，各自独立计数
+func IPRateLimit(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := services.CheckIPRateLimit(scope, c.ClientIP(), time.Now())
+		setRateLimitHeaders(c, result)
+		if err != nil {
+			utils.JsonTooManyRequestsResponse(c, result.RetryAfter, utils.CodeRateLimited, gin.H{})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimit 按客户端 IP 和 passkey 限制请求频率的中间件，需在 JWTAuth 之后使用
+func RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := services.CheckRateLimit(authPasskey(c), c.GetString("role"), c.ClientIP(), time.Now())
+		setRateLimitHeaders(c, result)
+		if err != nil {
+			utils.JsonTooManyRequestsResponse(c, result.RetryAfter, utils.CodeRateLimited, gin.H{})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ProtectedQuota 按每日和每月配额限制保护记录读取次数的中间件，需在 JWTAuth 之后使用。
+// 请求开始时占用一次配额，返回多条记录的接口通过上下文中的 quota_reservation 为其余记录占用配额；
+// 请求未成功返回数据（如数据不存在或无权访问）时退还本次请求占用的全部配额
+func ProtectedQuota() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := authPasskey(c)
+		if p == nil || services.IsQuotaExempt(c.GetString("role")) {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		reservation, err := services.ConsumeQuota(p, now)
+		if err != nil {
+			var exceeded *services.QuotaExceededError
+			if errors.As(err, &exceeded) {
+				utils.JsonTooManyRequestsResponse(c, exceeded.ResetsAt.Sub(now), exceeded.ErrorCode(), gin.H{"limit": exceeded.Limit})
+			} else {
+				utils.JsonErrorResponse(c, utils.CodeDatabaseError)
+				c.Abort()
+			}
+			return
+		}
+		c.Set("quota_reservation", reservation)
+
+		c.Next()
+
+		if c.GetInt("response_code") != 200200 {
+			services.RefundQuota(reservation)
+		}
+	}
+}
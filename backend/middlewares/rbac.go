@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"backend/models"
+	"backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 检查当前用户角色是否拥有指定权限的中间件，需在 JWTAuth 之后使用
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role == "" {
+			utils.JsonErrorResponse(c, utils.CodeUnauthenticated)
+			c.Abort()
+			return
+		}
+
+		if !models.HasPermission(role, permission) {
+			utils.JsonErrorResponse(c, utils.CodeRoleForbidden)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// dataV1 化合物数据表
+type dataV1 struct {
+	ID           string     `gorm:"column:ID;size:12;primaryKey;not null"`
+	Source       *string    `gorm:"column:Source;size:255"`
+	ItemName     *string    `gorm:"column:ItemName;type:text"`
+	ItemType     *string    `gorm:"column:ItemType;type:text"`
+	Formula      *string    `gorm:"column:Formula;size:127"`
+	SMILES       *string    `gorm:"column:SMILES;type:text"`
+	Description  *string    `gorm:"column:Description;size:31"` // KNOWN COMPOUND、NEW NATURAL PRODUCT、NEW ANALOGS
+	CASNumber    *string    `gorm:"column:CAS_number;size:127"`
+	ItemTag      *string    `gorm:"column:ItemTag;size:255"`
+	Structure    *string    `gorm:"column:Structure;type:text"`
+	MS1_H        *float64   `gorm:"column:MS1_H"`
+	MS1_Na       *float64   `gorm:"column:MS1_Na"`
+	MS2          *string    `gorm:"column:MS2;size:512"`
+	MS2_full     *string    `gorm:"column:MS2_full;type:text"`
+	Bioactivity  *string    `gorm:"column:Bioactivity;size:512"`
+	NMR_13C_data *string    `gorm:"column:NMR_13C_data;type:text"`
+	Weight       *float32   `gorm:"column:Weight"`
+	FP           *string    `gorm:"column:FP;size:255"`
+	CreatedAt    *time.Time `gorm:"column:Created_At;default:CURRENT_TIMESTAMP"`
+	UpdatedAt    *time.Time `gorm:"column:Updated_At;default:CURRENT_TIMESTAMP"`
+}
+
+func (dataV1) TableName() string {
+	return "data"
+}
+
+var createData = Migration{
+	Version: 1,
+	Name:    "create_data",
+	Up: func(tx *gorm.DB) error {
+		_, err := ensureTable(tx, &dataV1{})
+		return err
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTablesIfEmpty(tx, "data")
+	},
+}
@@ -0,0 +1,136 @@
+package migrations
+
+import (
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// passkeysV1 用户认证表
+type passkeysV1 struct {
+	Passkey       string     `gorm:"column:Passkey;size:36;primaryKey;not null"`
+	Extends       string     `gorm:"column:Extends;size:255;not null;default:''"`
+	ParentID      *string    `gorm:"column:Parent_ID;size:36;index:idx_passkeys_parent_id"`
+	Description   string     `gorm:"column:Description;size:511;not null;default:''"`
+	Operator      string     `gorm:"column:Operator;size:255;not null;default:''"`
+	IsActive      bool       `gorm:"column:Is_Active;not null;default:true"`
+	CreatedAt     time.Time  `gorm:"column:Created_At;not null;default:CURRENT_TIMESTAMP"`
+	Role          string     `gorm:"column:Role;size:31;not null;default:'protected-reader'"`
+	ValidFrom     *time.Time `gorm:"column:Valid_From"`
+	ValidUntil    *time.Time `gorm:"column:Valid_Until"`
+	MaxLogins     *int       `gorm:"column:Max_Logins"`
+	LoginCount    int        `gorm:"column:Login_Count;not null;default:0"`
+	LastLoginAt   *time.Time `gorm:"column:Last_Login_At"`
+	TokenLifetime *int       `gorm:"column:Token_Lifetime"`
+	TokenVersion  int        `gorm:"column:Token_Version;not null;default:0"`
+	RateLimit     *int       `gorm:"column:Rate_Limit"`
+	DailyQuota    *int       `gorm:"column:Daily_Quota"`
+	MonthlyQuota  *int       `gorm:"column:Monthly_Quota"`
+	WebAuthnID    *string    `gorm:"column:WebAuthn_ID;size:88"`
+	EnrolledAt    *time.Time `gorm:"column:Enrolled_At"`
+	OIDCIssuer    *string    `gorm:"column:OIDC_Issuer;size:255;uniqueIndex:idx_passkeys_oidc"`
+	OIDCSubject   *string    `gorm:"column:OIDC_Subject;size:255;uniqueIndex:idx_passkeys_oidc"`
+}
+
+func (passkeysV1) TableName() string {
+	return "passkeys"
+}
+
+var createPasskeys = Migration{
+	Version: 2,
+	Name:    "create_passkeys",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		// 早期的表名为 Passkeys，而查询统一使用 passkeys（区分大小写的文件系统上是两张不同的表）
+		if m.HasTable("Passkeys") && !m.HasTable("passkeys") {
+			if err := m.RenameTable("Passkeys", "passkeys"); err != nil {
+				return err
+			}
+		}
+
+		// 旧版 OIDC 账户以外的记录可能写入了空字符串，与唯一索引冲突
+		if m.HasColumn("passkeys", "OIDC_Subject") {
+			if err := tx.Table("passkeys").Where(map[string]interface{}{"OIDC_Subject": ""}).
+				Updates(map[string]interface{}{"OIDC_Issuer": nil, "OIDC_Subject": nil}).Error; err != nil {
+				return err
+			}
+		}
+
+		added, err := ensureTable(tx, &passkeysV1{})
+		if err != nil {
+			return err
+		}
+
+		// 添加 Role 列前 Extends 为空的是超级管理员
+		if containsColumn(added, "Role") {
+			if err := tx.Table("passkeys").Where(map[string]interface{}{"Extends": ""}).Update("Role", "superadmin").Error; err != nil {
+				return err
+			}
+		}
+		// 添加 Parent_ID 列前创建者以 operator(passkey) 的格式记录在 Extends 中
+		if containsColumn(added, "Parent_ID") {
+			return backfillParentID(tx)
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTablesIfEmpty(tx, "passkeys")
+	},
+}
+
+// legacyCreator 旧格式 operator(passkey) 末尾的创建者 passkey
+var legacyCreator = regexp.MustCompile(`\(([0-9a-fA-F-]{36})\)$`)
+
+// backfillParentID 从旧格式的 Extends 中提取创建者写入 Parent_ID，并去掉 Extends 和数据授权 Created_By 中的 passkey。
+// 各数据库的正则函数不通用（MySQL 5.7 没有 REGEXP_SUBSTR，SQLite 默认没有 REGEXP），因此读出后在程序中匹配
+func backfillParentID(tx *gorm.DB) error {
+	var accounts []passkeysV1
+	if err := tx.Table("passkeys").Select("Passkey", "Extends").Where(clause.Like{Column: "Extends", Value: "%)"}).Find(&accounts).Error; err != nil {
+		return err
+	}
+	exists := map[string]bool{}
+	for _, a := range accounts {
+		exists[a.Passkey] = true
+	}
+	for _, a := range accounts {
+		match := legacyCreator.FindStringSubmatchIndex(a.Extends)
+		if match == nil {
+			continue
+		}
+		updates := map[string]interface{}{"Extends": a.Extends[:match[0]]}
+		creator := a.Extends[match[2]:match[3]]
+		if !exists[creator] {
+			var count int64
+			if err := tx.Table("passkeys").Where(map[string]interface{}{"Passkey": creator}).Count(&count).Error; err != nil {
+				return err
+			}
+			exists[creator] = count > 0
+		}
+		if exists[creator] {
+			updates["Parent_ID"] = creator
+		}
+		if err := tx.Table("passkeys").Where(map[string]interface{}{"Passkey": a.Passkey}).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	if !tx.Migrator().HasTable("data_grants") {
+		return nil
+	}
+	var creators []string
+	if err := tx.Table("data_grants").Distinct().Where(clause.Like{Column: "Created_By", Value: "%)"}).Pluck("Created_By", &creators).Error; err != nil {
+		return err
+	}
+	for _, createdBy := range creators {
+		match := legacyCreator.FindStringIndex(createdBy)
+		if match == nil {
+			continue
+		}
+		if err := tx.Table("data_grants").Where(map[string]interface{}{"Created_By": createdBy}).Update("Created_By", createdBy[:match[0]]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
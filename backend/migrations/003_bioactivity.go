@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// bioactivityV1 结构化活性数据表
+type bioactivityV1 struct {
+	ID            uint64     `gorm:"column:ID;primaryKey;autoIncrement"`
+	DataID        string     `gorm:"column:Data_ID;size:12;not null;index:idx_bioactivity_data"`
+	AssayType     string     `gorm:"column:Assay_Type;size:127;not null;default:''"`
+	Target        string     `gorm:"column:Target;size:255;not null;default:''"`
+	CellLine      string     `gorm:"column:Cell_Line;size:127;not null;default:''"`
+	Organism      string     `gorm:"column:Organism;size:255;not null;default:''"`
+	Endpoint      string     `gorm:"column:Endpoint;size:31;not null;index:idx_bioactivity_endpoint,priority:1"`
+	Qualifier     string     `gorm:"column:Qualifier;size:2;not null;default:'='"`
+	Value         *float64   `gorm:"column:Value"`
+	Units         string     `gorm:"column:Units;size:31;not null;default:''"`
+	StandardValue *float64   `gorm:"column:Standard_Value;index:idx_bioactivity_endpoint,priority:2"`
+	StandardUnits string     `gorm:"column:Standard_Units;size:31;not null;default:''"`
+	Reference     string     `gorm:"column:Reference;size:511;not null;default:''"`
+	RawText       string     `gorm:"column:Raw_Text;size:512;not null;default:''"`
+	CreatedAt     *time.Time `gorm:"column:Created_At;default:CURRENT_TIMESTAMP"`
+	UpdatedAt     *time.Time `gorm:"column:Updated_At;default:CURRENT_TIMESTAMP"`
+}
+
+func (bioactivityV1) TableName() string {
+	return "bioactivity"
+}
+
+var fkBioactivityData = foreignKey{Name: "fk_bioactivity_data", Table: "bioactivity", Column: "Data_ID", RefTable: "data", RefColumn: "ID", OnDelete: "CASCADE"}
+
+var createBioactivity = Migration{
+	Version: 3,
+	Name:    "create_bioactivity",
+	Up: func(tx *gorm.DB) error {
+		if _, err := ensureTable(tx, &bioactivityV1{}); err != nil {
+			return err
+		}
+		return ensureForeignKey(tx, fkBioactivityData)
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTablesIfEmpty(tx, "bioactivity")
+	},
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// organismsV1 来源生物分类表
+type organismsV1 struct {
+	ID        uint64     `gorm:"column:ID;primaryKey;autoIncrement"`
+	TaxID     *int64     `gorm:"column:Tax_ID;uniqueIndex:uk_organisms_tax_id"`
+	Name      string     `gorm:"column:Name;size:255;not null;uniqueIndex:uk_organisms_name"`
+	Rank      string     `gorm:"column:Tax_Rank;size:31;not null;default:''"`
+	Domain    string     `gorm:"column:Domain;size:127;not null;default:''"`
+	Kingdom   string     `gorm:"column:Kingdom;size:127;not null;default:''"`
+	Phylum    string     `gorm:"column:Phylum;size:127;not null;default:''"`
+	Class     string     `gorm:"column:Class;size:127;not null;default:''"`
+	Order     string     `gorm:"column:Order_Name;size:127;not null;default:''"`
+	Family    string     `gorm:"column:Family;size:127;not null;default:''"`
+	Genus     string     `gorm:"column:Genus;size:127;not null;default:''"`
+	Species   string     `gorm:"column:Species;size:255;not null;default:''"`
+	CreatedAt *time.Time `gorm:"column:Created_At;default:CURRENT_TIMESTAMP"`
+	UpdatedAt *time.Time `gorm:"column:Updated_At;default:CURRENT_TIMESTAMP"`
+}
+
+func (organismsV1) TableName() string {
+	return "organisms"
+}
+
+// dataOrganismV1 data 表关联来源生物的列
+type dataOrganismV1 struct {
+	OrganismID *uint64 `gorm:"column:Organism_ID;index:idx_data_organism"`
+}
+
+func (dataOrganismV1) TableName() string {
+	return "data"
+}
+
+var fkDataOrganism = foreignKey{Name: "fk_data_organism", Table: "data", Column: "Organism_ID", RefTable: "organisms", RefColumn: "ID", OnDelete: "SET NULL"}
+
+var createOrganisms = Migration{
+	Version: 4,
+	Name:    "create_organisms",
+	Up: func(tx *gorm.DB) error {
+		if _, err := ensureTable(tx, &organismsV1{}); err != nil {
+			return err
+		}
+		if _, err := ensureTable(tx, &dataOrganismV1{}); err != nil {
+			return err
+		}
+		return ensureForeignKey(tx, fkDataOrganism)
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := dropForeignKey(tx, fkDataOrganism); err != nil {
+			return err
+		}
+		if m.HasIndex(&dataOrganismV1{}, "idx_data_organism") {
+			if err := m.DropIndex(&dataOrganismV1{}, "idx_data_organism"); err != nil {
+				return err
+			}
+		}
+		if m.HasColumn(&dataOrganismV1{}, "Organism_ID") {
+			if err := m.DropColumn(&dataOrganismV1{}, "Organism_ID"); err != nil {
+				return err
+			}
+		}
+		return m.DropTable("organisms")
+	},
+}
@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// literatureV1 文献表（REFERENCES 为 MySQL 保留字）
+type literatureV1 struct {
+	ID        uint64     `gorm:"column:ID;primaryKey;autoIncrement"`
+	DOI       *string    `gorm:"column:DOI;size:255;uniqueIndex:uk_literature_doi"`
+	PubMedID  *string    `gorm:"column:PubMed_ID;size:15;uniqueIndex:uk_literature_pubmed"`
+	Title     string     `gorm:"column:Title;type:text;not null"`
+	Journal   string     `gorm:"column:Journal;size:255;not null;default:''"`
+	Year      *int16     `gorm:"column:Year"`
+	Volume    string     `gorm:"column:Volume;size:31;not null;default:''"`
+	Pages     string     `gorm:"column:Pages;size:31;not null;default:''"`
+	Authors   string     `gorm:"column:Authors;type:text;not null"`
+	CreatedAt *time.Time `gorm:"column:Created_At;default:CURRENT_TIMESTAMP"`
+	UpdatedAt *time.Time `gorm:"column:Updated_At;default:CURRENT_TIMESTAMP"`
+}
+
+func (literatureV1) TableName() string {
+	return "literature"
+}
+
+// compoundReferencesV1 化合物文献关联表
+type compoundReferencesV1 struct {
+	DataID      string `gorm:"column:Data_ID;size:12;primaryKey"`
+	ReferenceID uint64 `gorm:"column:Reference_ID;primaryKey;autoIncrement:false;index:idx_compound_references_reference"`
+	Field       string `gorm:"column:Field;size:31;primaryKey;default:'general'"`
+}
+
+func (compoundReferencesV1) TableName() string {
+	return "compound_references"
+}
+
+var (
+	fkCompoundReferencesData       = foreignKey{Name: "fk_compound_references_data", Table: "compound_references", Column: "Data_ID", RefTable: "data", RefColumn: "ID", OnDelete: "CASCADE"}
+	fkCompoundReferencesLiterature = foreignKey{Name: "fk_compound_references_literature", Table: "compound_references", Column: "Reference_ID", RefTable: "literature", RefColumn: "ID", OnDelete: "CASCADE"}
+)
+
+var createLiterature = Migration{
+	Version: 5,
+	Name:    "create_literature",
+	Up: func(tx *gorm.DB) error {
+		if _, err := ensureTable(tx, &literatureV1{}); err != nil {
+			return err
+		}
+		if _, err := ensureTable(tx, &compoundReferencesV1{}); err != nil {
+			return err
+		}
+		if err := ensureForeignKey(tx, fkCompoundReferencesData); err != nil {
+			return err
+		}
+		return ensureForeignKey(tx, fkCompoundReferencesLiterature)
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTablesIfEmpty(tx, "compound_references", "literature")
+	},
+}
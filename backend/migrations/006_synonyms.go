@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// synonymsV1 化合物别名表
+type synonymsV1 struct {
+	ID        uint64     `gorm:"column:ID;primaryKey;autoIncrement"`
+	DataID    string     `gorm:"column:Data_ID;size:12;not null;uniqueIndex:uk_synonyms_data_name"`
+	Name      string     `gorm:"column:Name;size:512;not null;uniqueIndex:uk_synonyms_data_name"`
+	NameType  string     `gorm:"column:Name_Type;size:15;not null;default:'trivial'"`
+	CreatedAt *time.Time `gorm:"column:Created_At;default:CURRENT_TIMESTAMP"`
+}
+
+func (synonymsV1) TableName() string {
+	return "synonyms"
+}
+
+var fkSynonymsData = foreignKey{Name: "fk_synonyms_data", Table: "synonyms", Column: "Data_ID", RefTable: "data", RefColumn: "ID", OnDelete: "CASCADE"}
+
+var createSynonyms = Migration{
+	Version: 6,
+	Name:    "create_synonyms",
+	Up: func(tx *gorm.DB) error {
+		if _, err := ensureTable(tx, &synonymsV1{}); err != nil {
+			return err
+		}
+		return ensureForeignKey(tx, fkSynonymsData)
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTablesIfEmpty(tx, "synonyms")
+	},
+}
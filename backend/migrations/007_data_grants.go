@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// dataGrantsV1 数据授权表
+type dataGrantsV1 struct {
+	ID          uint64     `gorm:"column:ID;primaryKey;autoIncrement"`
+	Passkey     string     `gorm:"column:Passkey;size:36;not null;index:idx_data_grants_passkey"`
+	Fields      string     `gorm:"column:Fields;size:255;not null"`
+	ScopeType   string     `gorm:"column:Scope_Type;size:15;not null;default:'all'"`
+	ScopeValues string     `gorm:"column:Scope_Values;type:text;not null"`
+	Note        string     `gorm:"column:Note;size:511;not null;default:''"`
+	CreatedBy   string     `gorm:"column:Created_By;size:255;not null"`
+	CreatedAt   *time.Time `gorm:"column:Created_At;default:CURRENT_TIMESTAMP"`
+}
+
+func (dataGrantsV1) TableName() string {
+	return "data_grants"
+}
+
+var createDataGrants = Migration{
+	Version: 7,
+	Name:    "create_data_grants",
+	Up: func(tx *gorm.DB) error {
+		_, err := ensureTable(tx, &dataGrantsV1{})
+		return err
+	},
+	Down: func(tx *gorm.DB) error {
+		return dropTablesIfEmpty(tx, "data_grants")
+	},
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// revokedTokensV1 已注销令牌表
+type revokedTokensV1 struct {
+	JTI       string     `gorm:"column:JTI;size:36;primaryKey;not null"`
+	Passkey   string     `gorm:"column:Passkey;size:36;not null"`
+	ExpiresAt time.Time  `gorm:"column:Expires_At;not null;index:idx_revoked_tokens_expires"`
+	RevokedAt *time.Time `gorm:"column:Revoked_At;default:CURRENT_TIMESTAMP"`
+}
+
+func (revokedTokensV1) TableName() string {
+	return "revoked_tokens"
+}
+
+var createRevokedTokens = Migration{
+	Version: 8,
+	Name:    "create_revoked_tokens",
+	Up: func(tx *gorm.DB) error {
+		_, err := ensureTable(tx, &revokedTokensV1{})
+		return err
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("revoked_tokens")
+	},
+}
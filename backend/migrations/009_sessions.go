@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sessionsV1 登录会话表
+type sessionsV1 struct {
+	ID                string     `gorm:"column:ID;size:36;primaryKey;not null"`
+	Passkey           string     `gorm:"column:Passkey;size:36;not null;index:idx_sessions_passkey"`
+	RefreshHash       string     `gorm:"column:Refresh_Hash;size:64;not null"`
+	TokenVersion      int        `gorm:"column:Token_Version;not null;default:0"`
+	ClientIP          string     `gorm:"column:Client_IP;size:63;not null;default:''"`
+	UserAgent         string     `gorm:"column:User_Agent;size:511;not null;default:''"`
+	CreatedAt         *time.Time `gorm:"column:Created_At;default:CURRENT_TIMESTAMP"`
+	LastUsedAt        time.Time  `gorm:"column:Last_Used_At;not null"`
+	ExpiresAt         time.Time  `gorm:"column:Expires_At;not null"`
+	AbsoluteExpiresAt time.Time  `gorm:"column:Absolute_Expires_At;not null"`
+	RevokedAt         *time.Time `gorm:"column:Revoked_At"`
+	RevokeReason      string     `gorm:"column:Revoke_Reason;size:31;not null;default:''"`
+}
+
+func (sessionsV1) TableName() string {
+	return "sessions"
+}
+
+var createSessions = Migration{
+	Version: 9,
+	Name:    "create_sessions",
+	Up: func(tx *gorm.DB) error {
+		_, err := ensureTable(tx, &sessionsV1{})
+		return err
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("sessions")
+	},
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// webAuthnCredentialsV1 WebAuthn 凭据表
+type webAuthnCredentialsV1 struct {
+	ID              uint64     `gorm:"column:ID;primaryKey;autoIncrement"`
+	Passkey         string     `gorm:"column:Passkey;size:36;not null;index:idx_webauthn_credentials_passkey"`
+	CredentialID    string     `gorm:"column:Credential_ID;size:255;not null;uniqueIndex:idx_webauthn_credentials_credential_id"`
+	PublicKey       []byte     `gorm:"column:Public_Key;not null"`
+	AttestationType string     `gorm:"column:Attestation_Type;size:31;not null;default:''"`
+	Transports      string     `gorm:"column:Transports;size:127;not null;default:''"`
+	AAGUID          []byte     `gorm:"column:AAGUID;size:16"`
+	SignCount       uint32     `gorm:"column:Sign_Count;not null;default:0"`
+	BackupEligible  bool       `gorm:"column:Backup_Eligible;not null;default:false"`
+	BackupState     bool       `gorm:"column:Backup_State;not null;default:false"`
+	Name            string     `gorm:"column:Name;size:127;not null;default:''"`
+	CreatedAt       *time.Time `gorm:"column:Created_At;default:CURRENT_TIMESTAMP"`
+	LastUsedAt      *time.Time `gorm:"column:Last_Used_At"`
+}
+
+func (webAuthnCredentialsV1) TableName() string {
+	return "webauthn_credentials"
+}
+
+var createWebAuthnCredentials = Migration{
+	Version: 10,
+	Name:    "create_webauthn_credentials",
+	Up: func(tx *gorm.DB) error {
+		_, err := ensureTable(tx, &webAuthnCredentialsV1{})
+		return err
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("webauthn_credentials")
+	},
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// quotaUsageV1 配额使用表
+type quotaUsageV1 struct {
+	Passkey     string `gorm:"column:Passkey;size:36;primaryKey;not null"`
+	Period      string `gorm:"column:Period;size:7;primaryKey;not null"`
+	PeriodStart string `gorm:"column:Period_Start;size:10;primaryKey;not null"`
+	Count       int    `gorm:"column:Count;not null;default:0"`
+}
+
+func (quotaUsageV1) TableName() string {
+	return "quota_usage"
+}
+
+var createQuotaUsage = Migration{
+	Version: 11,
+	Name:    "create_quota_usage",
+	Up: func(tx *gorm.DB) error {
+		_, err := ensureTable(tx, &quotaUsageV1{})
+		return err
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("quota_usage")
+	},
+}
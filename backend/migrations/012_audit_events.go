@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// auditEventsV1 审计日志表
+type auditEventsV1 struct {
+	ID         uint64    `gorm:"column:ID;primaryKey;autoIncrement"`
+	CreatedAt  time.Time `gorm:"column:Created_At;precision:3;not null;index:idx_audit_events_created;index:idx_audit_events_passkey,priority:2;index:idx_audit_events_resource,priority:2"`
+	Passkey    string    `gorm:"column:Passkey;size:36;not null;default:'';index:idx_audit_events_passkey,priority:1"`
+	Operator   string    `gorm:"column:Operator;size:255;not null;default:''"`
+	Role       string    `gorm:"column:Role;size:31;not null;default:''"`
+	Action     string    `gorm:"column:Action;size:63;not null"`
+	ResourceID string    `gorm:"column:Resource_ID;size:63;not null;default:'';index:idx_audit_events_resource,priority:1"`
+	Fields     string    `gorm:"column:Fields;size:255;not null;default:''"`
+	ClientIP   string    `gorm:"column:Client_IP;size:63;not null;default:''"`
+	UserAgent  string    `gorm:"column:User_Agent;size:511;not null;default:''"`
+	Outcome    string    `gorm:"column:Outcome;size:15;not null"`
+	Code       int       `gorm:"column:Code;not null;default:0"`
+	Detail     string    `gorm:"column:Detail;size:1023;not null;default:''"`
+}
+
+func (auditEventsV1) TableName() string {
+	return "audit_events"
+}
+
+var createAuditEvents = Migration{
+	Version: 12,
+	Name:    "create_audit_events",
+	Up: func(tx *gorm.DB) error {
+		_, err := ensureTable(tx, &auditEventsV1{})
+		return err
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("audit_events")
+	},
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// auditEventsV2 审计日志表：不再保存 passkey 原文，只保存其 SHA-256 前 12 位
+type auditEventsV2 struct {
+	ID           uint64    `gorm:"column:ID;primaryKey;autoIncrement"`
+	CreatedAt    time.Time `gorm:"column:Created_At;precision:3;not null;index:idx_audit_events_created;index:idx_audit_events_passkey_label,priority:2;index:idx_audit_events_resource,priority:2"`
+	PasskeyLabel string    `gorm:"column:Passkey_Label;size:12;not null;default:'';index:idx_audit_events_passkey_label,priority:1"`
+	Operator     string    `gorm:"column:Operator;size:255;not null;default:''"`
+	Role         string    `gorm:"column:Role;size:31;not null;default:''"`
+	Action       string    `gorm:"column:Action;size:63;not null"`
+	ResourceID   string    `gorm:"column:Resource_ID;size:63;not null;default:'';index:idx_audit_events_resource,priority:1"`
+	Fields       string    `gorm:"column:Fields;size:255;not null;default:''"`
+	ClientIP     string    `gorm:"column:Client_IP;size:63;not null;default:''"`
+	UserAgent    string    `gorm:"column:User_Agent;size:511;not null;default:''"`
+	Outcome      string    `gorm:"column:Outcome;size:15;not null"`
+	Code         int       `gorm:"column:Code;not null;default:0"`
+	Detail       string    `gorm:"column:Detail;size:1023;not null;default:''"`
+}
+
+func (auditEventsV2) TableName() string {
+	return "audit_events"
+}
+
+// auditPasskeyLabel 与 services.PasskeyLabel 相同，迁移内保留一份以免服务代码变更后改变已发布迁移的结果
+func auditPasskeyLabel(passkey string) string {
+	if passkey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(passkey))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+var hashAuditPasskeys = Migration{
+	Version: 13,
+	Name:    "hash_audit_passkeys",
+	Up: func(tx *gorm.DB) error {
+		if _, err := ensureTable(tx, &auditEventsV2{}); err != nil {
+			return err
+		}
+		m := tx.Migrator()
+		if !m.HasColumn("audit_events", "Passkey") {
+			return nil
+		}
+
+		var passkeys []string
+		if err := tx.Table("audit_events").Where(clause.Neq{Column: "Passkey", Value: ""}).Distinct().Pluck("Passkey", &passkeys).Error; err != nil {
+			return err
+		}
+		for _, passkey := range passkeys {
+			if err := tx.Table("audit_events").Where(map[string]interface{}{"Passkey": passkey}).
+				Update("Passkey_Label", auditPasskeyLabel(passkey)).Error; err != nil {
+				return err
+			}
+		}
+
+		if m.HasIndex(&auditEventsV1{}, "idx_audit_events_passkey") {
+			if err := m.DropIndex(&auditEventsV1{}, "idx_audit_events_passkey"); err != nil {
+				return err
+			}
+		}
+		if err := m.DropColumn(&auditEventsV1{}, "Passkey"); err != nil {
+			return err
+		}
+		// SQLite 删除列时重建表，索引需要重新创建
+		_, err := ensureTable(tx, &auditEventsV2{})
+		return err
+	},
+	Down: func(tx *gorm.DB) error {
+		// passkey 原文无法恢复，回滚只补回空的 Passkey 列，保留 Passkey_Label 列中的记录
+		_, err := ensureTable(tx, &auditEventsV1{})
+		return err
+	},
+}
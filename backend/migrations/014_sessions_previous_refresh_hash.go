@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sessionsV2 登录会话表：保存上一个刷新令牌的摘要，用于区分重复使用已轮换的令牌和伪造的令牌
+type sessionsV2 struct {
+	ID                  string     `gorm:"column:ID;size:36;primaryKey;not null"`
+	Passkey             string     `gorm:"column:Passkey;size:36;not null;index:idx_sessions_passkey"`
+	RefreshHash         string     `gorm:"column:Refresh_Hash;size:64;not null"`
+	PreviousRefreshHash string     `gorm:"column:Previous_Refresh_Hash;size:64;not null;default:''"`
+	TokenVersion        int        `gorm:"column:Token_Version;not null;default:0"`
+	ClientIP            string     `gorm:"column:Client_IP;size:63;not null;default:''"`
+	UserAgent           string     `gorm:"column:User_Agent;size:511;not null;default:''"`
+	CreatedAt           *time.Time `gorm:"column:Created_At;default:CURRENT_TIMESTAMP"`
+	LastUsedAt          time.Time  `gorm:"column:Last_Used_At;not null"`
+	ExpiresAt           time.Time  `gorm:"column:Expires_At;not null"`
+	AbsoluteExpiresAt   time.Time  `gorm:"column:Absolute_Expires_At;not null"`
+	RevokedAt           *time.Time `gorm:"column:Revoked_At"`
+	RevokeReason        string     `gorm:"column:Revoke_Reason;size:31;not null;default:''"`
+}
+
+func (sessionsV2) TableName() string {
+	return "sessions"
+}
+
+var addSessionPreviousRefreshHash = Migration{
+	Version: 14,
+	Name:    "add_session_previous_refresh_hash",
+	Up: func(tx *gorm.DB) error {
+		_, err := ensureTable(tx, &sessionsV2{})
+		return err
+	},
+	Down: func(tx *gorm.DB) error {
+		if !tx.Migrator().HasColumn(&sessionsV2{}, "Previous_Refresh_Hash") {
+			return nil
+		}
+		return tx.Migrator().DropColumn(&sessionsV2{}, "Previous_Refresh_Hash")
+	},
+}
@@ -0,0 +1,220 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一个版本的数据库结构变更
+//
+// Up 和 Down 在事务中执行，但 MySQL 的 DDL 会隐式提交，执行到一半失败时无法回滚，
+// 因此 Up 需要可以重复执行：表已存在时只补充缺少的列、索引和外键。
+// 新的结构变更追加一个版本，不要修改已发布的版本。
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// all 全部迁移，按版本号升序排列
+var all = []Migration{
+	createData,
+	createPasskeys,
+	createBioactivity,
+	createOrganisms,
+	createLiterature,
+	createSynonyms,
+	createDataGrants,
+	createRevokedTokens,
+	createSessions,
+	createWebAuthnCredentials,
+	createQuotaUsage,
+	createAuditEvents,
+	hashAuditPasskeys,
+	addSessionPreviousRefreshHash,
+}
+
+var ErrSchemaBehind = errors.New("数据库结构版本落后")
+
+// SchemaMigration 对应数据库中的 schema_migrations 表，记录已执行的迁移版本
+type SchemaMigration struct {
+	Version   int       `gorm:"column:Version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:Name;size:127;not null;default:''"`
+	AppliedAt time.Time `gorm:"column:Applied_At;not null"`
+}
+
+// TableName 指定表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus 一个迁移版本的执行情况
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // 为空表示未执行
+	Known     bool       // 为 false 表示数据库中记录的版本不在本程序的迁移列表中（由更新的版本执行）
+}
+
+// Latest 返回本程序需要的数据库结构版本
+func Latest() int {
+	return all[len(all)-1].Version
+}
+
+// applied 返回已执行的迁移版本，schema_migrations 表不存在时视为全部未执行
+func applied(db *gorm.DB) (map[int]SchemaMigration, error) {
+	result := map[int]SchemaMigration{}
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return result, nil
+	}
+	var rows []SchemaMigration
+	if err := db.Table("schema_migrations").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询迁移版本失败: %v", err)
+	}
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+	return result, nil
+}
+
+// Status 返回全部迁移版本的执行情况，按版本号升序排列
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(all))
+	for _, m := range all {
+		status := MigrationStatus{Version: m.Version, Name: m.Name, Known: true}
+		if row, ok := done[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+			delete(done, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range done {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Up 按版本号升序执行未执行的迁移，直到 target（含），target 为 0 时执行到最新版本。返回本次执行的迁移
+func Up(db *gorm.DB, target int) ([]Migration, error) {
+	if target == 0 {
+		target = Latest()
+	}
+	if err := db.Migrator().AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("创建 schema_migrations 表失败: %v", err)
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range all {
+		if m.Version > target {
+			break
+		}
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Table("schema_migrations").Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("执行迁移 %d_%s 失败: %v", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Down 按版本号降序回滚版本号大于 target 的已执行迁移。返回本次回滚的迁移
+func Down(db *gorm.DB, target int) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	known := map[int]bool{}
+	for _, m := range all {
+		known[m.Version] = true
+	}
+	for version := range done {
+		if version > target && !known[version] {
+			return nil, fmt.Errorf("版本 %d 由更新的程序执行，无法回滚", version)
+		}
+	}
+
+	var ran []Migration
+	for i := len(all) - 1; i >= 0; i-- {
+		m := all[i]
+		if m.Version <= target {
+			break
+		}
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Table("schema_migrations").Where(map[string]interface{}{"Version": m.Version}).Delete(&SchemaMigration{}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("回滚迁移 %d_%s 失败: %v", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Check 检查数据库结构是否为本程序需要的版本，存在未执行的迁移时返回 ErrSchemaBehind
+func Check(db *gorm.DB) error {
+	if db == nil {
+		return errors.New("数据库未连接")
+	}
+	statuses, err := Status(db)
+	if err != nil {
+		return err
+	}
+	current, pending := 0, 0
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending++
+		} else if s.Known && s.Version > current {
+			current = s.Version
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w：当前版本 %d，需要版本 %d（%d 个迁移未执行），请先执行 migrate up", ErrSchemaBehind, current, Latest(), pending)
+	}
+	return nil
+}
+
+// Unknown 返回数据库中记录的、不在本程序迁移列表中的版本（数据库已由更新的程序迁移）
+func Unknown(db *gorm.DB) ([]int, error) {
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	var versions []int
+	for _, s := range statuses {
+		if !s.Known {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions, nil
+}
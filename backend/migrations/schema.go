@@ -0,0 +1,125 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ensureTable 表不存在时按 model 建表；表已存在时（如按旧版 README 手工建立的表）只补充缺少的列和索引，
+// 不修改已有列的类型。返回补充的列名，建表时为空
+//
+// model 是迁移内固定的结构体，不使用 models 包中的模型，以免模型变更后改变已发布迁移的结果
+func ensureTable(tx *gorm.DB, model interface{}) ([]string, error) {
+	m := tx.Migrator()
+	if !m.HasTable(model) {
+		return nil, m.CreateTable(model)
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	var added []string
+	for _, name := range stmt.Schema.DBNames {
+		if m.HasColumn(model, name) {
+			continue
+		}
+		if err := m.AddColumn(model, name); err != nil {
+			return added, fmt.Errorf("添加列 %s.%s 失败: %v", stmt.Schema.Table, name, err)
+		}
+		added = append(added, name)
+	}
+	for _, idx := range stmt.Schema.ParseIndexes() {
+		if m.HasIndex(model, idx.Name) {
+			continue
+		}
+		if err := m.CreateIndex(model, idx.Name); err != nil {
+			return added, fmt.Errorf("创建索引 %s 失败: %v", idx.Name, err)
+		}
+	}
+	return added, nil
+}
+
+// foreignKey 外键定义
+type foreignKey struct {
+	Name      string
+	Table     string
+	Column    string
+	RefTable  string
+	RefColumn string
+	OnDelete  string // CASCADE、SET NULL 等
+}
+
+// ensureForeignKey 外键不存在时添加。SQLite 不支持为已有的表添加外键，跳过
+func ensureForeignKey(tx *gorm.DB, fk foreignKey) error {
+	if tx.Dialector.Name() == "sqlite" {
+		return nil
+	}
+	if tx.Migrator().HasConstraint(fk.Table, fk.Name) {
+		return nil
+	}
+	err := tx.Exec("ALTER TABLE ? ADD CONSTRAINT ? FOREIGN KEY (?) REFERENCES ? (?) ON DELETE "+fk.OnDelete,
+		clause.Table{Name: fk.Table}, clause.Column{Name: fk.Name}, clause.Column{Name: fk.Column},
+		clause.Table{Name: fk.RefTable}, clause.Column{Name: fk.RefColumn}).Error
+	if err != nil {
+		return fmt.Errorf("添加外键 %s 失败: %v", fk.Name, err)
+	}
+	return nil
+}
+
+// dropForeignKey 外键存在时删除
+func dropForeignKey(tx *gorm.DB, fk foreignKey) error {
+	if tx.Dialector.Name() == "sqlite" {
+		return nil
+	}
+	if !tx.Migrator().HasConstraint(fk.Table, fk.Name) {
+		return nil
+	}
+	// 旧版 MySQL 和 MariaDB 不支持 DROP CONSTRAINT 删除外键
+	if tx.Dialector.Name() == "mysql" {
+		return tx.Exec("ALTER TABLE ? DROP FOREIGN KEY ?", clause.Table{Name: fk.Table}, clause.Column{Name: fk.Name}).Error
+	}
+	return tx.Migrator().DropConstraint(fk.Table, fk.Name)
+}
+
+// containsColumn 判断 ensureTable 是否补充了某一列
+func containsColumn(added []string, name string) bool {
+	for _, c := range added {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrTableNotEmpty 回滚时要删除的表中还有数据
+var ErrTableNotEmpty = errors.New("表中还有数据")
+
+// dropTablesIfEmpty 删除 tables 中的全部表，任一表中还有数据时不删除任何表并返回 ErrTableNotEmpty。
+//
+// 这些表可能在执行迁移前就已存在（ensureTable 只补充了列），保存的是化合物库和账户等无法重新生成的数据，
+// 回滚时不能随表一起删除。确认不再需要后由运维手工删除
+func dropTablesIfEmpty(tx *gorm.DB, tables ...string) error {
+	m := tx.Migrator()
+	var existing []interface{}
+	for _, table := range tables {
+		if !m.HasTable(table) {
+			continue
+		}
+		var rows int64
+		if err := tx.Table(table).Count(&rows).Error; err != nil {
+			return err
+		}
+		if rows > 0 {
+			return fmt.Errorf("%w：表 %s 中有 %d 条记录，回滚不会删除数据，确认不再需要后请手工删除该表", ErrTableNotEmpty, table, rows)
+		}
+		existing = append(existing, table)
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	return m.DropTable(existing...)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// CREATE TABLE audit_events (
+//     ID            BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+//     Created_At    DATETIME(3) NOT NULL,
+//     Passkey_Label VARCHAR(12) NOT NULL DEFAULT '',
+//     Operator      VARCHAR(255) NOT NULL DEFAULT '',
+//     Role          VARCHAR(31) NOT NULL DEFAULT '',
+//     Action        VARCHAR(63) NOT NULL,
+//     Resource_ID   VARCHAR(63) NOT NULL DEFAULT '',
+//     Fields        VARCHAR(255) NOT NULL DEFAULT '',
+//     Client_IP     VARCHAR(63) NOT NULL DEFAULT '',
+//     User_Agent    VARCHAR(511) NOT NULL DEFAULT '',
+//     Outcome       VARCHAR(15) NOT NULL,
+//     Code          INT NOT NULL DEFAULT 0,
+//     Detail        VARCHAR(1023) NOT NULL DEFAULT '',
+//     INDEX idx_audit_events_created (Created_At),
+//     INDEX idx_audit_events_passkey_label (Passkey_Label, Created_At),
+//     INDEX idx_audit_events_resource (Resource_ID, Created_At)
+// );
+
+// 审计动作
+const (
+	AuditActionReadProtected   = "data.read-protected" // 读取化合物保护数据
+	AuditActionReadMS2Full     = "data.read-ms2-full"  // 读取完整MS2数据
+	AuditActionReadBioactivity = "bioactivity.read"    // 读取化合物活性记录
+	AuditActionFilterBioactive = "bioactivity.filter"  // 筛选活性记录
+	AuditActionExportAudit     = "audit.export"        // 导出审计日志
+)
+
+// 审计结果
+const (
+	AuditOutcomeSuccess  = "success"   // 成功返回数据
+	AuditOutcomeDenied   = "denied"    // 未认证或无权访问
+	AuditOutcomeNotFound = "not_found" // 资源不存在
+	AuditOutcomeInvalid  = "invalid"   // 请求参数错误
+	AuditOutcomeLimited  = "limited"   // 超出频率限制或配额
+	AuditOutcomeError    = "error"     // 服务器错误
+)
+
+// AuditEvent 对应数据库中的 audit_events 表，记录一次保护数据访问
+type AuditEvent struct {
+	ID           uint64    `gorm:"column:ID;primaryKey;autoIncrement" json:"id"`
+	CreatedAt    time.Time `gorm:"column:Created_At;not null;index" json:"created_at"`
+	PasskeyLabel string    `gorm:"column:Passkey_Label;type:VARCHAR(12);not null;default:''" json:"passkey_label"` // passkey 的 SHA-256 前 12 位，不保存原文
+	Operator     string    `gorm:"column:Operator;type:VARCHAR(255);not null;default:''" json:"operator"`
+	Role         string    `gorm:"column:Role;type:VARCHAR(31);not null;default:''" json:"role"`
+	Action       string    `gorm:"column:Action;type:VARCHAR(63);not null" json:"action"`
+	ResourceID   string    `gorm:"column:Resource_ID;type:VARCHAR(63);not null;default:''" json:"resource_id"`
+	Fields       string    `gorm:"column:Fields;type:VARCHAR(255);not null;default:''" json:"fields"` // 返回的保护字段，逗号分隔
+	ClientIP     string    `gorm:"column:Client_IP;type:VARCHAR(63);not null;default:''" json:"client_ip"`
+	UserAgent    string    `gorm:"column:User_Agent;type:VARCHAR(511);not null;default:''" json:"user_agent"`
+	Outcome      string    `gorm:"column:Outcome;type:VARCHAR(15);not null" json:"outcome"`
+	Code         int       `gorm:"column:Code;not null;default:0" json:"code"`                         // 响应体中的业务代码
+	Detail       string    `gorm:"column:Detail;type:VARCHAR(1023);not null;default:''" json:"detail"` // 查询条件等附加信息
+}
+
+// TableName 指定表名
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
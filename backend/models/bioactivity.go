@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+// CREATE TABLE bioactivity (
+//     ID             BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+//     Data_ID        VARCHAR(12) NOT NULL,
+//     Assay_Type     VARCHAR(127) NOT NULL DEFAULT '',
+//     Target         VARCHAR(255) NOT NULL DEFAULT '',
+//     Cell_Line      VARCHAR(127) NOT NULL DEFAULT '',
+//     Organism       VARCHAR(255) NOT NULL DEFAULT '',
+//     Endpoint       VARCHAR(31) NOT NULL,
+//     Qualifier      VARCHAR(2) NOT NULL DEFAULT '=',
+//     Value          DOUBLE,
+//     Units          VARCHAR(31) NOT NULL DEFAULT '',
+//     Standard_Value DOUBLE,
+//     Standard_Units VARCHAR(31) NOT NULL DEFAULT '',
+//     Reference      VARCHAR(511) NOT NULL DEFAULT '',
+//     Raw_Text       VARCHAR(512) NOT NULL DEFAULT '',
+//     Created_At     DATETIME DEFAULT CURRENT_TIMESTAMP,
+//     Updated_At     DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+//     INDEX idx_bioactivity_data (Data_ID),
+//     INDEX idx_bioactivity_endpoint (Endpoint, Standard_Value),
+//     FOREIGN KEY (Data_ID) REFERENCES data(ID) ON DELETE CASCADE
+// );
+
+// 活性终点类型
+const (
+	EndpointIC50       = "IC50"
+	EndpointEC50       = "EC50"
+	EndpointGI50       = "GI50"
+	EndpointCC50       = "CC50"
+	EndpointLD50       = "LD50"
+	EndpointED50       = "ED50"
+	EndpointMIC        = "MIC"
+	EndpointMBC        = "MBC"
+	EndpointInhibition = "INHIBITION"
+	EndpointOther      = "OTHER"
+)
+
+// BioactivityEndpoints 所有合法的活性终点
+var BioactivityEndpoints = []string{
+	EndpointIC50, EndpointEC50, EndpointGI50, EndpointCC50, EndpointLD50,
+	EndpointED50, EndpointMIC, EndpointMBC, EndpointInhibition, EndpointOther,
+}
+
+// BioactivityQualifiers 所有合法的数值限定符
+var BioactivityQualifiers = []string{"=", "<", "<=", ">", ">=", "~"}
+
+// Bioactivity 对应数据库中的 bioactivity 表，每条记录为一次活性测定结果
+type Bioactivity struct {
+	ID            uint       `gorm:"column:ID;primaryKey;autoIncrement" json:"id"`
+	DataID        string     `gorm:"column:Data_ID;type:VARCHAR(12);not null;index" json:"data_id"`
+	AssayType     string     `gorm:"column:Assay_Type;type:VARCHAR(127);not null;default:''" json:"assay_type"`
+	Target        string     `gorm:"column:Target;type:VARCHAR(255);not null;default:''" json:"target"`
+	CellLine      string     `gorm:"column:Cell_Line;type:VARCHAR(127);not null;default:''" json:"cell_line"`
+	Organism      string     `gorm:"column:Organism;type:VARCHAR(255);not null;default:''" json:"organism"`
+	Endpoint      string     `gorm:"column:Endpoint;type:VARCHAR(31);not null" json:"endpoint"`
+	Qualifier     string     `gorm:"column:Qualifier;type:VARCHAR(2);not null;default:'='" json:"qualifier"`
+	Value         *float64   `gorm:"column:Value;type:DOUBLE" json:"value,omitempty"`
+	Units         string     `gorm:"column:Units;type:VARCHAR(31);not null;default:''" json:"units"`
+	StandardValue *float64   `gorm:"column:Standard_Value;type:DOUBLE" json:"standard_value,omitempty"`
+	StandardUnits string     `gorm:"column:Standard_Units;type:VARCHAR(31);not null;default:''" json:"standard_units"`
+	Reference     string     `gorm:"column:Reference;type:VARCHAR(511);not null;default:''" json:"reference"`
+	RawText       string     `gorm:"column:Raw_Text;type:VARCHAR(512);not null;default:''" json:"raw_text"`
+	CreatedAt     *time.Time `gorm:"column:Created_At" json:"created_at,omitempty"`
+	UpdatedAt     *time.Time `gorm:"column:Updated_At" json:"updated_at,omitempty"`
+}
+
+// TableName 指定表名
+func (Bioactivity) TableName() string {
+	return "bioactivity"
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 )
 
+// 表结构由 migrations 包维护，以下为当前结构
 // CREATE TABLE data (
 //     ID            VARCHAR(12) PRIMARY KEY NOT NULL,
 //     Source        VARCHAR(255),
@@ -11,16 +12,19 @@ import (
 //     ItemType      TEXT,
 //     Formula       VARCHAR(127),
 //     SMILES        TEXT,
-//     Description   ENUM('KNOWN COMPOUND','NEW NATURAL PRODUCT','NEW ANALOGS'),
+//     Description   VARCHAR(31), -- KNOWN COMPOUND、NEW NATURAL PRODUCT、NEW ANALOGS
 //     CAS_number    VARCHAR(127),
 //     ItemTag       VARCHAR(255),
 //     Structure     TEXT,
-//     MS1           DOUBLE,
+//     MS1_H         DOUBLE,
+//     MS1_Na        DOUBLE,
 //     MS2           VARCHAR(512),
+//     MS2_full      TEXT,
 //     Bioactivity   VARCHAR(512),
 //     NMR_13C_data  TEXT,
 //     Weight        FLOAT,
 //     FP            VARCHAR(255),
+//     Organism_ID   BIGINT UNSIGNED,
 
 //     -- 自动填充时间
 //     Created_At    DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -35,16 +39,19 @@ type Data struct {
 	ItemType     *string    `gorm:"column:ItemType;type:TEXT" json:"item_type,omitempty"`
 	Formula      *string    `gorm:"column:Formula;type:VARCHAR(127)" json:"formula,omitempty"`
 	SMILES       *string    `gorm:"column:SMILES;type:TEXT" json:"smiles,omitempty"`
-	Description  *string    `gorm:"column:Description;type:ENUM('KNOWN COMPOUND','NEW NATURAL PRODUCT','NEW ANALOGS')" json:"description,omitempty"`
+	Description  *string    `gorm:"column:Description;type:VARCHAR(31)" json:"description,omitempty"` // KNOWN COMPOUND、NEW NATURAL PRODUCT、NEW ANALOGS
 	CASNumber    *string    `gorm:"column:CAS_number;type:VARCHAR(127)" json:"cas_number,omitempty"`
 	ItemTag      *string    `gorm:"column:ItemTag;type:VARCHAR(255)" json:"item_tag,omitempty"`
 	Structure    *string    `gorm:"column:Structure;type:TEXT" json:"structure,omitempty"`
-	MS1          *float64   `gorm:"column:MS1;type:DOUBLE" json:"ms1,omitempty"`
+	MS1_H        *float64   `gorm:"column:MS1_H;type:DOUBLE" json:"ms1_h,omitempty"`
+	MS1_Na       *float64   `gorm:"column:MS1_Na;type:DOUBLE" json:"ms1_na,omitempty"`
 	MS2          *string    `gorm:"column:MS2;type:VARCHAR(512)" json:"ms2,omitempty"`
+	MS2_full     *string    `gorm:"column:MS2_full;type:TEXT" json:"-"` // 完整MS2数据，只通过 /api/data/{id}/ms2-full 返回
 	Bioactivity  *string    `gorm:"column:Bioactivity;type:VARCHAR(512)" json:"bioactivity,omitempty"`
 	NMR_13C_data *string    `gorm:"column:NMR_13C_data;type:TEXT" json:"nmr_13c_data,omitempty"`
 	Weight       *float32   `gorm:"column:Weight;type:FLOAT" json:"weight,omitempty"`
 	FP           *string    `gorm:"column:FP;type:VARCHAR(255)" json:"fp,omitempty"`
+	OrganismID   *uint      `gorm:"column:Organism_ID" json:"organism_id,omitempty"`
 	CreatedAt    *time.Time `gorm:"column:Created_At" json:"created_at,omitempty"`
 	UpdatedAt    *time.Time `gorm:"column:Updated_At" json:"updated_at,omitempty"`
 }
@@ -56,7 +63,7 @@ type PublicData struct {
 	ItemType    *string    `gorm:"column:ItemType;type:TEXT" json:"item_type,omitempty"`
 	Formula     *string    `gorm:"column:Formula;type:VARCHAR(127)" json:"formula,omitempty"`
 	SMILES      *string    `gorm:"column:SMILES;type:TEXT" json:"smiles,omitempty"`
-	Description *string    `gorm:"column:Description;type:ENUM('KNOWN COMPOUND','NEW NATURAL PRODUCT','NEW ANALOGS')" json:"description,omitempty"`
+	Description *string    `gorm:"column:Description;type:VARCHAR(31)" json:"description,omitempty"` // KNOWN COMPOUND、NEW NATURAL PRODUCT、NEW ANALOGS
 	CASNumber   *string    `gorm:"column:CAS_number;type:VARCHAR(127)" json:"cas_number,omitempty"`
 	ItemTag     *string    `gorm:"column:ItemTag;type:VARCHAR(255)" json:"item_tag,omitempty"`
 	Structure   *string    `gorm:"column:Structure;type:TEXT" json:"structure,omitempty"`
@@ -64,16 +71,25 @@ type PublicData struct {
 	MS1_Na      *float64   `gorm:"column:MS1_Na;type:DOUBLE" json:"ms1_na,omitempty"`
 	Weight      *float32   `gorm:"column:Weight;type:FLOAT" json:"weight,omitempty"`
 	FP          *string    `gorm:"column:FP;type:VARCHAR(255)" json:"fp,omitempty"`
+	OrganismID  *uint      `gorm:"column:Organism_ID" json:"organism_id,omitempty"`
 	CreatedAt   *time.Time `gorm:"column:Created_At" json:"created_at,omitempty"`
 	UpdatedAt   *time.Time `gorm:"column:Updated_At" json:"updated_at,omitempty"`
+	// 别名，来自 synonyms 表
+	Synonyms []Synonym `gorm:"-" json:"synonyms,omitempty"`
+	// 引用文献，来自 compound_references 表
+	References []CitedReference `gorm:"-" json:"references,omitempty"`
 }
 
 // 定义只包含保护字段的结构
 type ProtectedData struct {
-	MS2 *string `json:"ms2,omitempty"`
+	MS2 *string `gorm:"column:MS2" json:"ms2,omitempty"`
 	//MS2_full     *string `json:"ms2_full,omitempty"`
-	Bioactivity  *string `json:"bioactivity,omitempty"`
-	NMR_13C_data *string `json:"nmr_13c_data,omitempty"`
+	Bioactivity  *string `gorm:"column:Bioactivity" json:"bioactivity,omitempty"`
+	NMR_13C_data *string `gorm:"column:NMR_13C_data" json:"nmr_13c_data,omitempty"`
+	// 结构化活性记录，来自 bioactivity 表
+	BioactivityRecords []Bioactivity `gorm:"-" json:"bioactivity_records,omitempty"`
+	// 当前用户可访问的保护字段
+	GrantedFields []string `gorm:"-" json:"granted_fields"`
 }
 
 // TableName 指定表名
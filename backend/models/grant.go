@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// CREATE TABLE data_grants (
+//     ID           BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+//     Passkey      VARCHAR(36) NOT NULL,
+//     Fields       VARCHAR(255) NOT NULL,
+//     Scope_Type   VARCHAR(15) NOT NULL DEFAULT 'all',
+//     Scope_Values TEXT NOT NULL,
+//     Note         VARCHAR(511) NOT NULL DEFAULT '',
+//     Created_By   TINYTEXT NOT NULL,
+//     Created_At   DATETIME DEFAULT CURRENT_TIMESTAMP,
+//     INDEX idx_data_grants_passkey (Passkey)
+// );
+
+// 可授权的保护数据字段
+const (
+	GrantFieldMS2         = "MS2"
+	GrantFieldMS2Full     = "MS2_full"
+	GrantFieldBioactivity = "Bioactivity"
+	GrantFieldNMR         = "NMR_13C_data"
+)
+
+// GrantFields 所有可授权的保护数据字段
+var GrantFields = []string{GrantFieldMS2, GrantFieldMS2Full, GrantFieldBioactivity, GrantFieldNMR}
+
+// 授权的化合物范围
+const (
+	GrantScopeAll    = "all"    // 全部化合物
+	GrantScopeSource = "source" // Source 等于任一取值的化合物
+	GrantScopeTag    = "tag"    // ItemTag 包含任一取值的化合物
+	GrantScopeIDs    = "ids"    // 指定ID的化合物
+)
+
+// GrantScopes 所有合法的授权范围
+var GrantScopes = []string{GrantScopeAll, GrantScopeSource, GrantScopeTag, GrantScopeIDs}
+
+// DataGrant 对应数据库中的 data_grants 表，授权某个 passkey 访问部分化合物的部分保护字段
+type DataGrant struct {
+	ID          uint       `gorm:"column:ID;primaryKey;autoIncrement" json:"id"`
+	Passkey     string     `gorm:"column:Passkey;type:VARCHAR(36);not null;index" json:"passkey"`
+	Fields      string     `gorm:"column:Fields;type:VARCHAR(255);not null" json:"fields"` // 以逗号分隔，如 "MS2,MS2_full"
+	ScopeType   string     `gorm:"column:Scope_Type;type:VARCHAR(15);not null;default:'all'" json:"scope_type"`
+	ScopeValues string     `gorm:"column:Scope_Values;type:TEXT;not null" json:"scope_values"` // 以换行分隔
+	Note        string     `gorm:"column:Note;type:VARCHAR(511);not null;default:''" json:"note"`
+	CreatedBy   string     `gorm:"column:Created_By;type:TINYTEXT;not null" json:"created_by"`
+	CreatedAt   *time.Time `gorm:"column:Created_At" json:"created_at,omitempty"`
+}
+
+// TableName 指定表名
+func (DataGrant) TableName() string {
+	return "data_grants"
+}
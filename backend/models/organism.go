@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+// CREATE TABLE organisms (
+//     ID          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+//     Tax_ID      BIGINT UNIQUE,
+//     Name        VARCHAR(255) NOT NULL UNIQUE,
+//     Tax_Rank    VARCHAR(31) NOT NULL DEFAULT '',
+//     Domain      VARCHAR(127) NOT NULL DEFAULT '',
+//     Kingdom     VARCHAR(127) NOT NULL DEFAULT '',
+//     Phylum      VARCHAR(127) NOT NULL DEFAULT '',
+//     Class       VARCHAR(127) NOT NULL DEFAULT '',
+//     Order_Name  VARCHAR(127) NOT NULL DEFAULT '',
+//     Family      VARCHAR(127) NOT NULL DEFAULT '',
+//     Genus       VARCHAR(127) NOT NULL DEFAULT '',
+//     Species     VARCHAR(255) NOT NULL DEFAULT '',
+//     Created_At  DATETIME DEFAULT CURRENT_TIMESTAMP,
+//     Updated_At  DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+// );
+//
+// ALTER TABLE data ADD COLUMN Organism_ID BIGINT UNSIGNED NULL,
+//     ADD INDEX idx_data_organism (Organism_ID),
+//     ADD FOREIGN KEY (Organism_ID) REFERENCES organisms(ID) ON DELETE SET NULL;
+
+// TaxonomicRanks 支持浏览和筛选的分类阶元，从高到低排列
+var TaxonomicRanks = []string{"domain", "kingdom", "phylum", "class", "order", "family", "genus", "species"}
+
+// TaxonomicRankColumns 分类阶元对应的列名（ORDER 和 RANK 是 MySQL 保留字，因此使用 Order_Name 和 Tax_Rank）
+var TaxonomicRankColumns = map[string]string{
+	"domain":  "Domain",
+	"kingdom": "Kingdom",
+	"phylum":  "Phylum",
+	"class":   "Class",
+	"order":   "Order_Name",
+	"family":  "Family",
+	"genus":   "Genus",
+	"species": "Species",
+}
+
+// Organism 对应数据库中的 organisms 表，保存来源生物及其分类谱系
+type Organism struct {
+	ID        uint       `gorm:"column:ID;primaryKey;autoIncrement" json:"id"`
+	TaxID     *int64     `gorm:"column:Tax_ID;uniqueIndex" json:"tax_id,omitempty"`
+	Name      string     `gorm:"column:Name;type:VARCHAR(255);not null;uniqueIndex" json:"name"`
+	Rank      string     `gorm:"column:Tax_Rank;type:VARCHAR(31);not null;default:''" json:"rank"`
+	Domain    string     `gorm:"column:Domain;type:VARCHAR(127);not null;default:''" json:"domain"`
+	Kingdom   string     `gorm:"column:Kingdom;type:VARCHAR(127);not null;default:''" json:"kingdom"`
+	Phylum    string     `gorm:"column:Phylum;type:VARCHAR(127);not null;default:''" json:"phylum"`
+	Class     string     `gorm:"column:Class;type:VARCHAR(127);not null;default:''" json:"class"`
+	Order     string     `gorm:"column:Order_Name;type:VARCHAR(127);not null;default:''" json:"order"`
+	Family    string     `gorm:"column:Family;type:VARCHAR(127);not null;default:''" json:"family"`
+	Genus     string     `gorm:"column:Genus;type:VARCHAR(127);not null;default:''" json:"genus"`
+	Species   string     `gorm:"column:Species;type:VARCHAR(255);not null;default:''" json:"species"`
+	CreatedAt *time.Time `gorm:"column:Created_At" json:"created_at,omitempty"`
+	UpdatedAt *time.Time `gorm:"column:Updated_At" json:"updated_at,omitempty"`
+}
+
+// TableName 指定表名
+func (Organism) TableName() string {
+	return "organisms"
+}
@@ -4,17 +4,38 @@ import (
 	"time"
 )
 
-// Passkey 对应数据库中的 Passkeys 表
+// Passkey 对应数据库中的 passkeys 表
 type Passkey struct {
-	Passkey     string    `gorm:"column:Passkey;type:VARCHAR(36);primaryKey;not null;default:uuid()" json:"passkey"`
+	Passkey     string    `gorm:"column:Passkey;type:VARCHAR(36);primaryKey;not null" json:"passkey"`
 	Description string    `gorm:"column:Description;type:VARCHAR(511);not null;default:''" json:"description"`
 	Operator    string    `gorm:"column:Operator;type:TINYTEXT;not null;default:''" json:"operator"`
-	CreatedAt   time.Time `gorm:"column:Created_At;not null;default:NOW()" json:"created_at"`
+	CreatedAt   time.Time `gorm:"column:Created_At;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	IsActive    bool      `gorm:"column:Is_Active;type:TINYINT(1);not null;default:1" json:"is_active"`
-	Extends     string    `gorm:"column:Extends;type:TINYTEXT;not null;default:''" json:"extends"`
+	Extends     string    `gorm:"column:Extends;type:TINYTEXT;not null;default:''" json:"extends"`    // 创建者名称，仅用于显示
+	ParentID    *string   `gorm:"column:Parent_ID;type:VARCHAR(36);index" json:"parent_id,omitempty"` // 创建者的 passkey，为空表示顶层账户
+	Role        string    `gorm:"column:Role;type:VARCHAR(31);not null;default:'protected-reader'" json:"role"`
+	// 可选的有效期和使用限制，为空表示不限制
+	ValidFrom     *time.Time `gorm:"column:Valid_From" json:"valid_from,omitempty"`
+	ValidUntil    *time.Time `gorm:"column:Valid_Until" json:"valid_until,omitempty"`
+	MaxLogins     *int       `gorm:"column:Max_Logins" json:"max_logins,omitempty"`
+	LoginCount    int        `gorm:"column:Login_Count;not null;default:0" json:"login_count"`
+	LastLoginAt   *time.Time `gorm:"column:Last_Login_At" json:"last_login_at,omitempty"`
+	TokenLifetime *int       `gorm:"column:Token_Lifetime" json:"token_lifetime,omitempty"` // 令牌有效期（分钟），为空时使用 jwt.token_lifetime 配置
+	TokenVersion  int        `gorm:"column:Token_Version;not null;default:0" json:"-"`      // 令牌版本，递增后此前签发的令牌全部失效
+	// 可选的访问频率和保护数据读取配额，为空时使用 rate_limit 和 quota 配置，0 表示不限制
+	RateLimit    *int `gorm:"column:Rate_Limit" json:"rate_limit,omitempty"`       // 每分钟请求数
+	DailyQuota   *int `gorm:"column:Daily_Quota" json:"daily_quota,omitempty"`     // 每日保护记录读取次数
+	MonthlyQuota *int `gorm:"column:Monthly_Quota" json:"monthly_quota,omitempty"` // 每月保护记录读取次数
+	// WebAuthn 账户信息：绑定凭据后 passkey UUID 作为一次性注册码失效，只能使用 WebAuthn 登录
+	WebAuthnID string     `gorm:"column:WebAuthn_ID;type:VARCHAR(88)" json:"-"` // WebAuthn 用户句柄（base64url），与 passkey UUID 无关
+	EnrolledAt *time.Time `gorm:"column:Enrolled_At" json:"enrolled_at,omitempty"`
+	// 通过 OpenID Connect 登录自动创建的账户，passkey UUID 不能用于登录，角色在每次登录时按 IdP 声明同步
+	// 两列有唯一索引，其他账户必须为 NULL 而不是空字符串
+	OIDCIssuer  *string `gorm:"column:OIDC_Issuer;type:VARCHAR(255)" json:"oidc_issuer,omitempty"`
+	OIDCSubject *string `gorm:"column:OIDC_Subject;type:VARCHAR(255)" json:"oidc_subject,omitempty"`
 }
 
 // TableName 指定表名
 func (Passkey) TableName() string {
-	return "Passkeys"
+	return "passkeys"
 }
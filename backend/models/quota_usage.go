@@ -0,0 +1,28 @@
+package models
+
+// CREATE TABLE quota_usage (
+//     Passkey      VARCHAR(36) NOT NULL,
+//     Period       VARCHAR(7) NOT NULL,
+//     Period_Start VARCHAR(10) NOT NULL,
+//     Count        INT NOT NULL DEFAULT 0,
+//     PRIMARY KEY (Passkey, Period, Period_Start)
+// );
+
+// 配额周期
+const (
+	QuotaPeriodDay   = "day"
+	QuotaPeriodMonth = "month"
+)
+
+// QuotaUsage 对应数据库中的 quota_usage 表，记录 passkey 在一个配额周期内读取保护记录的次数
+type QuotaUsage struct {
+	Passkey     string `gorm:"column:Passkey;type:VARCHAR(36);primaryKey;not null" json:"passkey"`
+	Period      string `gorm:"column:Period;type:VARCHAR(7);primaryKey;not null" json:"period"`
+	PeriodStart string `gorm:"column:Period_Start;type:VARCHAR(10);primaryKey;not null" json:"period_start"` // 周期起始日期，如 2025-06-01
+	Count       int    `gorm:"column:Count;not null;default:0" json:"count"`
+}
+
+// TableName 指定表名
+func (QuotaUsage) TableName() string {
+	return "quota_usage"
+}
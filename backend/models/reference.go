@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// REFERENCES 是 MySQL 保留字，因此文献表命名为 literature
+//
+// CREATE TABLE literature (
+//     ID          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+//     DOI         VARCHAR(255) UNIQUE,
+//     PubMed_ID   VARCHAR(15) UNIQUE,
+//     Title       TEXT NOT NULL,
+//     Journal     VARCHAR(255) NOT NULL DEFAULT '',
+//     Year        SMALLINT,
+//     Volume      VARCHAR(31) NOT NULL DEFAULT '',
+//     Pages       VARCHAR(31) NOT NULL DEFAULT '',
+//     Authors     TEXT NOT NULL,
+//     Created_At  DATETIME DEFAULT CURRENT_TIMESTAMP,
+//     Updated_At  DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+// );
+//
+// CREATE TABLE compound_references (
+//     Data_ID      VARCHAR(12) NOT NULL,
+//     Reference_ID BIGINT UNSIGNED NOT NULL,
+//     Field        VARCHAR(31) NOT NULL DEFAULT 'general',
+//     PRIMARY KEY (Data_ID, Reference_ID, Field),
+//     INDEX idx_compound_references_reference (Reference_ID),
+//     FOREIGN KEY (Data_ID) REFERENCES data(ID) ON DELETE CASCADE,
+//     FOREIGN KEY (Reference_ID) REFERENCES literature(ID) ON DELETE CASCADE
+// );
+
+// 文献关联的数据字段
+const (
+	ReferenceFieldGeneral     = "general"
+	ReferenceFieldIsolation   = "isolation"
+	ReferenceFieldStructure   = "structure"
+	ReferenceFieldBioactivity = "bioactivity"
+	ReferenceFieldNMR         = "nmr"
+	ReferenceFieldMS2         = "ms2"
+)
+
+// ReferenceFields 所有合法的文献关联字段
+var ReferenceFields = []string{
+	ReferenceFieldGeneral, ReferenceFieldIsolation, ReferenceFieldStructure,
+	ReferenceFieldBioactivity, ReferenceFieldNMR, ReferenceFieldMS2,
+}
+
+// Reference 对应数据库中的 literature 表
+type Reference struct {
+	ID        uint       `gorm:"column:ID;primaryKey;autoIncrement" json:"id"`
+	DOI       *string    `gorm:"column:DOI;type:VARCHAR(255);uniqueIndex" json:"doi,omitempty"`
+	PubMedID  *string    `gorm:"column:PubMed_ID;type:VARCHAR(15);uniqueIndex" json:"pubmed_id,omitempty"`
+	Title     string     `gorm:"column:Title;type:TEXT;not null" json:"title"`
+	Journal   string     `gorm:"column:Journal;type:VARCHAR(255);not null;default:''" json:"journal"`
+	Year      *int       `gorm:"column:Year;type:SMALLINT" json:"year,omitempty"`
+	Volume    string     `gorm:"column:Volume;type:VARCHAR(31);not null;default:''" json:"volume"`
+	Pages     string     `gorm:"column:Pages;type:VARCHAR(31);not null;default:''" json:"pages"`
+	Authors   string     `gorm:"column:Authors;type:TEXT;not null" json:"authors"` // 以"; "分隔，如 "Zhang, San; Li, Si"
+	CreatedAt *time.Time `gorm:"column:Created_At" json:"created_at,omitempty"`
+	UpdatedAt *time.Time `gorm:"column:Updated_At" json:"updated_at,omitempty"`
+}
+
+// TableName 指定表名
+func (Reference) TableName() string {
+	return "literature"
+}
+
+// CompoundReference 对应数据库中的 compound_references 表，记录化合物某一数据字段引用的文献
+type CompoundReference struct {
+	DataID      string `gorm:"column:Data_ID;type:VARCHAR(12);primaryKey" json:"data_id"`
+	ReferenceID uint   `gorm:"column:Reference_ID;primaryKey" json:"reference_id"`
+	Field       string `gorm:"column:Field;type:VARCHAR(31);primaryKey;default:'general'" json:"field"`
+}
+
+// TableName 指定表名
+func (CompoundReference) TableName() string {
+	return "compound_references"
+}
+
+// CitedReference 化合物引用的文献及其关联的数据字段
+type CitedReference struct {
+	Reference
+	Fields []string `gorm:"-" json:"fields"`
+}
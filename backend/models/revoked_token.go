@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// CREATE TABLE revoked_tokens (
+//     JTI         VARCHAR(36) PRIMARY KEY NOT NULL,
+//     Passkey     VARCHAR(36) NOT NULL,
+//     Expires_At  DATETIME NOT NULL,
+//     Revoked_At  DATETIME DEFAULT CURRENT_TIMESTAMP,
+//     INDEX idx_revoked_tokens_expires (Expires_At)
+// );
+
+// RevokedToken 对应数据库中的 revoked_tokens 表，记录在过期前被注销的单个令牌
+type RevokedToken struct {
+	JTI       string     `gorm:"column:JTI;type:VARCHAR(36);primaryKey;not null" json:"jti"`
+	Passkey   string     `gorm:"column:Passkey;type:VARCHAR(36);not null" json:"passkey"`
+	ExpiresAt time.Time  `gorm:"column:Expires_At;not null;index" json:"expires_at"`
+	RevokedAt *time.Time `gorm:"column:Revoked_At" json:"revoked_at,omitempty"`
+}
+
+// TableName 指定表名
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -0,0 +1,74 @@
+package models
+
+// 角色，按权限从低到高排列
+const (
+	RoleViewer          = "viewer"           // 只能访问公开数据
+	RoleProtectedReader = "protected-reader" // 可读取MS2、活性、核磁等保护数据
+	RoleCurator         = "curator"          // 可维护活性、文献、别名和来源生物等数据
+	RolePasskeyAdmin    = "passkey-admin"    // 可管理权限低于自己的 passkey
+	RoleSuperadmin      = "superadmin"       // 拥有全部权限
+)
+
+// Roles 所有合法的角色，按权限从低到高排列
+var Roles = []string{RoleViewer, RoleProtectedReader, RoleCurator, RolePasskeyAdmin, RoleSuperadmin}
+
+// 权限范围
+const (
+	PermissionReadProtected  = "data:read-protected"
+	PermissionWriteData      = "data:write"
+	PermissionManagePasskeys = "passkeys:manage"
+	PermissionReadAudit      = "audit:read" // 只有超级管理员拥有
+)
+
+// RolePermissions 各角色拥有的权限范围，超级管理员拥有全部权限
+var RolePermissions = map[string][]string{
+	RoleViewer:          {},
+	RoleProtectedReader: {PermissionReadProtected},
+	RoleCurator:         {PermissionReadProtected, PermissionWriteData},
+	RolePasskeyAdmin:    {PermissionReadProtected, PermissionManagePasskeys},
+	RoleSuperadmin:      {PermissionReadProtected, PermissionWriteData, PermissionManagePasskeys, PermissionReadAudit},
+}
+
+// RoleLevel 返回角色的权限等级，未知角色返回 -1
+func RoleLevel(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func HasPermission(role, permission string) bool {
+	if role == RoleSuperadmin {
+		return true
+	}
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAssignRole 判断 actor 角色能否创建或修改 target 角色的 passkey：
+// 超级管理员可以分配任意角色，其余角色只能分配低于自己、且权限范围是自己权限子集的角色
+// （如 passkey-admin 没有 data:write，不能分配 curator）
+func CanAssignRole(actor, target string) bool {
+	if RoleLevel(target) < 0 {
+		return false
+	}
+	if actor == RoleSuperadmin {
+		return true
+	}
+	if RoleLevel(target) >= RoleLevel(actor) {
+		return false
+	}
+	for _, permission := range RolePermissions[target] {
+		if !HasPermission(actor, permission) {
+			return false
+		}
+	}
+	return true
+}
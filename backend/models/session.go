@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// CREATE TABLE sessions (
+//     ID             VARCHAR(36) PRIMARY KEY NOT NULL,
+//     Passkey        VARCHAR(36) NOT NULL,
+//     Refresh_Hash   CHAR(64) NOT NULL,
+//     Previous_Refresh_Hash CHAR(64) NOT NULL DEFAULT '',
+//     Token_Version  INT NOT NULL DEFAULT 0,
+//     Client_IP      VARCHAR(63) NOT NULL DEFAULT '',
+//     User_Agent     VARCHAR(511) NOT NULL DEFAULT '',
+//     Created_At     DATETIME DEFAULT CURRENT_TIMESTAMP,
+//     Last_Used_At   DATETIME NOT NULL,
+//     Expires_At     DATETIME NOT NULL,
+//     Absolute_Expires_At DATETIME NOT NULL,
+//     Revoked_At     DATETIME NULL,
+//     Revoke_Reason  VARCHAR(31) NOT NULL DEFAULT '',
+//     INDEX idx_sessions_passkey (Passkey)
+// );
+
+// 会话注销原因
+const (
+	SessionRevokeLogout = "logout"  // 用户退出登录
+	SessionRevokeAdmin  = "admin"   // 管理员结束会话
+	SessionRevokeReuse  = "reuse"   // 检测到刷新令牌被重复使用
+	SessionRevokeAll    = "all"     // 在所有设备上退出登录或 passkey 被禁用
+	SessionRevokeDelete = "deleted" // passkey 被删除
+)
+
+// Session 对应数据库中的 sessions 表，一次登录对应一个会话。
+// 会话持有当前有效的刷新令牌（只保存其 SHA-256 摘要），每次刷新都会轮换
+type Session struct {
+	ID          string `gorm:"column:ID;type:VARCHAR(36);primaryKey;not null" json:"id"`
+	Passkey     string `gorm:"column:Passkey;type:VARCHAR(36);not null;index" json:"passkey"`
+	RefreshHash string `gorm:"column:Refresh_Hash;type:CHAR(64);not null" json:"-"`
+	// 上一个刷新令牌的摘要，再次出现时视为令牌泄露
+	PreviousRefreshHash string     `gorm:"column:Previous_Refresh_Hash;type:CHAR(64);not null;default:''" json:"-"`
+	TokenVersion        int        `gorm:"column:Token_Version;not null;default:0" json:"-"`
+	ClientIP            string     `gorm:"column:Client_IP;type:VARCHAR(63);not null;default:''" json:"client_ip"`
+	UserAgent           string     `gorm:"column:User_Agent;type:VARCHAR(511);not null;default:''" json:"user_agent"`
+	CreatedAt           time.Time  `gorm:"column:Created_At;autoCreateTime" json:"created_at"`
+	LastUsedAt          time.Time  `gorm:"column:Last_Used_At;not null" json:"last_used_at"`
+	ExpiresAt           time.Time  `gorm:"column:Expires_At;not null" json:"expires_at"`                   // 空闲过期时间，每次刷新后顺延
+	AbsoluteExpiresAt   time.Time  `gorm:"column:Absolute_Expires_At;not null" json:"absolute_expires_at"` // 会话最长有效期，不随刷新顺延
+	RevokedAt           *time.Time `gorm:"column:Revoked_At" json:"revoked_at,omitempty"`
+	RevokeReason        string     `gorm:"column:Revoke_Reason;type:VARCHAR(31);not null;default:''" json:"revoke_reason,omitempty"`
+}
+
+// TableName 指定表名
+func (Session) TableName() string {
+	return "sessions"
+}
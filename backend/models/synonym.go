@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// CREATE TABLE synonyms (
+//     ID          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+//     Data_ID     VARCHAR(12) NOT NULL,
+//     Name        VARCHAR(512) NOT NULL,
+//     Name_Type   VARCHAR(15) NOT NULL DEFAULT 'trivial',
+//     Created_At  DATETIME DEFAULT CURRENT_TIMESTAMP,
+//     UNIQUE INDEX uk_synonyms_data_name (Data_ID, Name),
+//     FOREIGN KEY (Data_ID) REFERENCES data(ID) ON DELETE CASCADE
+// );
+
+// 化合物别名类型
+const (
+	SynonymTypeTrivial = "trivial" // 俗名
+	SynonymTypeIUPAC   = "iupac"   // IUPAC系统名
+	SynonymTypeCode    = "code"    // 代号，如 SCSIO-1234
+	SynonymTypeOther   = "other"
+)
+
+// SynonymTypes 所有合法的别名类型
+var SynonymTypes = []string{SynonymTypeTrivial, SynonymTypeIUPAC, SynonymTypeCode, SynonymTypeOther}
+
+// Synonym 对应数据库中的 synonyms 表
+type Synonym struct {
+	ID        uint       `gorm:"column:ID;primaryKey;autoIncrement" json:"id"`
+	DataID    string     `gorm:"column:Data_ID;type:VARCHAR(12);not null;uniqueIndex:uk_synonyms_data_name" json:"data_id"`
+	Name      string     `gorm:"column:Name;type:VARCHAR(512);not null;uniqueIndex:uk_synonyms_data_name" json:"name"`
+	NameType  string     `gorm:"column:Name_Type;type:VARCHAR(15);not null;default:'trivial'" json:"name_type"`
+	CreatedAt *time.Time `gorm:"column:Created_At" json:"created_at,omitempty"`
+}
+
+// TableName 指定表名
+func (Synonym) TableName() string {
+	return "synonyms"
+}
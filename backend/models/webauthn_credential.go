@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// CREATE TABLE webauthn_credentials (
+//     ID               BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+//     Passkey          VARCHAR(36) NOT NULL,
+//     Credential_ID    VARCHAR(255) NOT NULL,
+//     Public_Key       BLOB NOT NULL,
+//     Attestation_Type VARCHAR(31) NOT NULL DEFAULT '',
+//     Transports       VARCHAR(127) NOT NULL DEFAULT '',
+//     AAGUID           VARBINARY(16) NULL,
+//     Sign_Count       INT UNSIGNED NOT NULL DEFAULT 0,
+//     Backup_Eligible  TINYINT(1) NOT NULL DEFAULT 0,
+//     Backup_State     TINYINT(1) NOT NULL DEFAULT 0,
+//     Name             VARCHAR(127) NOT NULL DEFAULT '',
+//     Created_At       DATETIME DEFAULT CURRENT_TIMESTAMP,
+//     Last_Used_At     DATETIME NULL,
+//     UNIQUE INDEX idx_webauthn_credentials_credential_id (Credential_ID),
+//     INDEX idx_webauthn_credentials_passkey (Passkey)
+// );
+
+// WebAuthnCredential 对应数据库中的 webauthn_credentials 表，记录绑定到 passkey 账户的 WebAuthn/FIDO2 凭据，
+// 一个账户可以绑定多个凭据（多个安全密钥或设备）
+type WebAuthnCredential struct {
+	ID              uint       `gorm:"column:ID;primaryKey;autoIncrement" json:"id"`
+	Passkey         string     `gorm:"column:Passkey;type:VARCHAR(36);not null;index" json:"-"`
+	CredentialID    string     `gorm:"column:Credential_ID;type:VARCHAR(255);not null;uniqueIndex" json:"credential_id"` // base64url 编码
+	PublicKey       []byte     `gorm:"column:Public_Key;type:BLOB;not null" json:"-"`
+	AttestationType string     `gorm:"column:Attestation_Type;type:VARCHAR(31);not null;default:''" json:"attestation_type"`
+	Transports      string     `gorm:"column:Transports;type:VARCHAR(127);not null;default:''" json:"transports"` // 以逗号分隔
+	AAGUID          []byte     `gorm:"column:AAGUID;type:VARBINARY(16)" json:"-"`
+	SignCount       uint32     `gorm:"column:Sign_Count;not null;default:0" json:"sign_count"`
+	BackupEligible  bool       `gorm:"column:Backup_Eligible;type:TINYINT(1);not null;default:0" json:"backup_eligible"`
+	BackupState     bool       `gorm:"column:Backup_State;type:TINYINT(1);not null;default:0" json:"backup_state"`
+	Name            string     `gorm:"column:Name;type:VARCHAR(127);not null;default:''" json:"name"`
+	CreatedAt       time.Time  `gorm:"column:Created_At;autoCreateTime" json:"created_at"`
+	LastUsedAt      *time.Time `gorm:"column:Last_Used_At" json:"last_used_at,omitempty"`
+}
+
+// TableName 指定表名
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
@@ -1,7 +1,7 @@
 from rdkit import Chem, DataStructs
 from rdkit.Chem import AllChem, Descriptors
 
-import base64, json
+import base64, json, signal
 
 # smiles式转存pdb
 def smiles_to_pdb(smiles):
@@ -87,6 +87,8 @@ def calculate_molecular_weight(smiles):
     return Descriptors.MolWt(mol)
 
 if __name__=="__main__":
+    # 终端的 Ctrl+C 会同时发给本进程，忽略它，由 Go 端处理完进行中的请求后关闭标准输入
+    signal.signal(signal.SIGINT, signal.SIG_IGN)
     # 模式
     while True:
         try:
@@ -200,6 +202,9 @@ if __name__=="__main__":
                 
             print(json.dumps(response))
                 
+        except EOFError:
+            # 标准输入关闭，Go 端要求退出
+            break
         except Exception as e:
             response = {"id": msg_id if 'msg_id' in locals() else "unknown", "reply": f"error: {str(e)}"}
             print(json.dumps(response))
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CompoundStatistics 化合物数据统计
+type CompoundStatistics struct {
+	Total       int64 // 化合物总数
+	Bioactivity int64 // 有活性数据的化合物数
+	MS          int64 // 有质谱数据（MS1_H 或 MS2）的化合物数
+	NMR         int64 // 有核磁数据的化合物数
+}
+
+// CompoundRepository 化合物数据（data 表）的存储接口
+type CompoundRepository interface {
+	// List 按 ID 顺序分页返回化合物及总数
+	List(offset, limit int) ([]models.Data, int64, error)
+	// FindPublic 返回化合物的公开字段，不存在时返回 ErrNotFound
+	FindPublic(id string) (*models.PublicData, error)
+	// FindProtected 只返回 columns 中的保护字段（MS2、Bioactivity、NMR_13C_data），不存在时返回 ErrNotFound
+	FindProtected(id string, columns []string) (*models.ProtectedData, error)
+	// FindMS2Full 返回完整 MS2 数据，不存在时返回 ErrNotFound
+	FindMS2Full(id string) (string, error)
+	// FindStructure 返回结构数据，不存在时返回 ErrNotFound
+	FindStructure(id string) (string, error)
+	// Exists 判断化合物是否存在
+	Exists(id string) (bool, error)
+	// Statistics 统计化合物总数和各类数据的数量
+	Statistics() (CompoundStatistics, error)
+}
+
+// GormCompoundRepository 基于数据库的化合物存储
+type GormCompoundRepository struct{}
+
+func NewGormCompoundRepository() *GormCompoundRepository {
+	return &GormCompoundRepository{}
+}
+
+// notFound 将 GORM 的记录不存在错误转换为 ErrNotFound，其余错误记录日志
+func notFound(err error, action string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	utils.LogError(err)
+	return fmt.Errorf("%s失败: %v", action, err)
+}
+
+func (r *GormCompoundRepository) List(offset, limit int) ([]models.Data, int64, error) {
+	db := database.GetDB()
+
+	var totalCount int64
+	if err := db.Model(&models.Data{}).Count(&totalCount).Error; err != nil {
+		utils.LogError(err)
+		return nil, 0, fmt.Errorf("获取总记录数失败: %v", err)
+	}
+
+	var data []models.Data
+	if err := db.Order(database.Asc("ID")).Offset(offset).Limit(limit).Find(&data).Error; err != nil {
+		utils.LogError(err)
+		return nil, 0, fmt.Errorf("查询数据失败: %v", err)
+	}
+	return data, totalCount, nil
+}
+
+func (r *GormCompoundRepository) FindPublic(id string) (*models.PublicData, error) {
+	var data models.PublicData
+	if err := database.GetDB().Table("data").Where(map[string]interface{}{"ID": id}).First(&data).Error; err != nil {
+		return nil, notFound(err, "查询数据")
+	}
+	return &data, nil
+}
+
+func (r *GormCompoundRepository) FindProtected(id string, columns []string) (*models.ProtectedData, error) {
+	var data models.ProtectedData
+	if err := database.GetDB().Table("data").Select(columns).Where(map[string]interface{}{"ID": id}).Take(&data).Error; err != nil {
+		return nil, notFound(err, "查询数据")
+	}
+	return &data, nil
+}
+
+func (r *GormCompoundRepository) FindMS2Full(id string) (string, error) {
+	var data struct {
+		MS2_full *string `gorm:"column:MS2_full"`
+	}
+	if err := database.GetDB().Table("data").Select("MS2_full").Where(map[string]interface{}{"ID": id}).Take(&data).Error; err != nil {
+		return "", notFound(err, "查询数据")
+	}
+	return stringValue(data.MS2_full), nil
+}
+
+func (r *GormCompoundRepository) FindStructure(id string) (string, error) {
+	var data struct {
+		Structure *string `gorm:"column:Structure"`
+	}
+	if err := database.GetDB().Table("data").Select("Structure").Where(map[string]interface{}{"ID": id}).Take(&data).Error; err != nil {
+		return "", notFound(err, "查询数据")
+	}
+	return stringValue(data.Structure), nil
+}
+
+func (r *GormCompoundRepository) Exists(id string) (bool, error) {
+	var count int64
+	if err := database.GetDB().Table("data").Where(map[string]interface{}{"ID": id}).Count(&count).Error; err != nil {
+		utils.LogError(err)
+		return false, fmt.Errorf("查询数据失败: %v", err)
+	}
+	return count > 0, nil
+}
+
+func (r *GormCompoundRepository) Statistics() (CompoundStatistics, error) {
+	db := database.GetDB()
+	var stats CompoundStatistics
+	counts := []struct {
+		target *int64
+		where  clause.Expression
+	}{
+		{&stats.Total, nil},
+		{&stats.Bioactivity, database.NotEmpty("Bioactivity")},
+		{&stats.MS, clause.Or(clause.Neq{Column: "MS1_H", Value: nil}, database.NotEmpty("MS2"))},
+		{&stats.NMR, database.NotEmpty("NMR_13C_data")},
+	}
+	for _, count := range counts {
+		query := db.Model(&models.Data{})
+		if count.where != nil {
+			query = query.Where(count.where)
+		}
+		if err := query.Count(count.target).Error; err != nil {
+			utils.LogError(err)
+			return stats, fmt.Errorf("统计数据失败: %v", err)
+		}
+	}
+	return stats, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"backend/models"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryCompoundRepository 内存中的化合物存储，用于测试
+type MemoryCompoundRepository struct {
+	mu   sync.RWMutex
+	data map[string]models.Data
+}
+
+func NewMemoryCompoundRepository(records ...models.Data) *MemoryCompoundRepository {
+	r := &MemoryCompoundRepository{data: make(map[string]models.Data)}
+	for _, record := range records {
+		r.data[record.ID] = record
+	}
+	return r
+}
+
+// Put 新增或替换化合物
+func (r *MemoryCompoundRepository) Put(record models.Data) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data[record.ID] = record
+}
+
+func (r *MemoryCompoundRepository) List(offset, limit int) ([]models.Data, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.data))
+	for id := range r.data {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	data := []models.Data{}
+	for i := offset; i < len(ids) && i < offset+limit; i++ {
+		data = append(data, r.data[ids[i]])
+	}
+	return data, int64(len(ids)), nil
+}
+
+func (r *MemoryCompoundRepository) FindPublic(id string) (*models.PublicData, error) {
+	d, ok := r.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &models.PublicData{
+		ID:          d.ID,
+		Source:      d.Source,
+		ItemName:    d.ItemName,
+		ItemType:    d.ItemType,
+		Formula:     d.Formula,
+		SMILES:      d.SMILES,
+		Description: d.Description,
+		CASNumber:   d.CASNumber,
+		ItemTag:     d.ItemTag,
+		Structure:   d.Structure,
+		MS1_H:       d.MS1_H,
+		MS1_Na:      d.MS1_Na,
+		Weight:      d.Weight,
+		FP:          d.FP,
+		OrganismID:  d.OrganismID,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+	}, nil
+}
+
+func (r *MemoryCompoundRepository) FindProtected(id string, columns []string) (*models.ProtectedData, error) {
+	d, ok := r.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var data models.ProtectedData
+	for _, column := range columns {
+		switch column {
+		case models.GrantFieldMS2:
+			data.MS2 = d.MS2
+		case models.GrantFieldBioactivity:
+			data.Bioactivity = d.Bioactivity
+		case models.GrantFieldNMR:
+			data.NMR_13C_data = d.NMR_13C_data
+		}
+	}
+	return &data, nil
+}
+
+func (r *MemoryCompoundRepository) FindMS2Full(id string) (string, error) {
+	d, ok := r.get(id)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return stringValue(d.MS2_full), nil
+}
+
+func (r *MemoryCompoundRepository) FindStructure(id string) (string, error) {
+	d, ok := r.get(id)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return stringValue(d.Structure), nil
+}
+
+func (r *MemoryCompoundRepository) Exists(id string) (bool, error) {
+	_, ok := r.get(id)
+	return ok, nil
+}
+
+func (r *MemoryCompoundRepository) Statistics() (CompoundStatistics, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stats CompoundStatistics
+	for _, d := range r.data {
+		stats.Total++
+		if stringValue(d.Bioactivity) != "" {
+			stats.Bioactivity++
+		}
+		if d.MS1_H != nil || stringValue(d.MS2) != "" {
+			stats.MS++
+		}
+		if stringValue(d.NMR_13C_data) != "" {
+			stats.NMR++
+		}
+	}
+	return stats, nil
+}
+
+func (r *MemoryCompoundRepository) get(id string) (models.Data, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.data[id]
+	return d, ok
+}
+
+// MemoryPasskeyRepository 内存中的 passkey 存储，用于测试
+type MemoryPasskeyRepository struct {
+	mu       sync.RWMutex
+	passkeys map[string]models.Passkey
+}
+
+func NewMemoryPasskeyRepository(passkeys ...models.Passkey) *MemoryPasskeyRepository {
+	r := &MemoryPasskeyRepository{passkeys: make(map[string]models.Passkey)}
+	for _, p := range passkeys {
+		r.passkeys[p.Passkey] = p
+	}
+	return r
+}
+
+func (r *MemoryPasskeyRepository) Find(passkey string) (*models.Passkey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.passkeys[passkey]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (r *MemoryPasskeyRepository) ListByRoles(roles []string) ([]models.Passkey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	passkeys := []models.Passkey{}
+	for _, p := range r.passkeys {
+		for _, role := range roles {
+			if p.Role == role {
+				passkeys = append(passkeys, p)
+				break
+			}
+		}
+	}
+	sort.SliceStable(passkeys, func(i, j int) bool { return passkeys[i].CreatedAt.After(passkeys[j].CreatedAt) })
+	return passkeys, nil
+}
+
+func (r *MemoryPasskeyRepository) Create(p *models.Passkey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.passkeys[p.Passkey]; ok {
+		return fmt.Errorf("创建passkey失败: %s 已存在", p.Passkey)
+	}
+	// 与数据库的默认值一致
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now()
+	}
+	r.passkeys[p.Passkey] = *p
+	return nil
+}
+
+func (r *MemoryPasskeyRepository) UpdateColumns(p *models.Passkey, columns ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.passkeys[p.Passkey]
+	if !ok {
+		return nil
+	}
+	for _, column := range columns {
+		switch column {
+		case "Description":
+			stored.Description = p.Description
+		case "Operator":
+			stored.Operator = p.Operator
+		case "Is_Active":
+			stored.IsActive = p.IsActive
+		case "Role":
+			stored.Role = p.Role
+		case "Valid_From":
+			stored.ValidFrom = p.ValidFrom
+		case "Valid_Until":
+			stored.ValidUntil = p.ValidUntil
+		case "Max_Logins":
+			stored.MaxLogins = p.MaxLogins
+		case "Login_Count":
+			stored.LoginCount = p.LoginCount
+		case "Token_Lifetime":
+			stored.TokenLifetime = p.TokenLifetime
+		case "Rate_Limit":
+			stored.RateLimit = p.RateLimit
+		case "Daily_Quota":
+			stored.DailyQuota = p.DailyQuota
+		case "Monthly_Quota":
+			stored.MonthlyQuota = p.MonthlyQuota
+		default:
+			return fmt.Errorf("更新passkey失败: 不支持的列 %s", column)
+		}
+	}
+	r.passkeys[p.Passkey] = stored
+	return nil
+}
+
+func (r *MemoryPasskeyRepository) Delete(passkey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.passkeys, passkey)
+	return nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"fmt"
+)
+
+// PasskeyRepository passkey（passkeys 表）的存储接口
+type PasskeyRepository interface {
+	// Find 返回指定的 passkey，不存在时返回 ErrNotFound
+	Find(passkey string) (*models.Passkey, error)
+	// ListByRoles 按创建时间倒序返回指定角色的全部 passkey
+	ListByRoles(roles []string) ([]models.Passkey, error)
+	// Create 新建 passkey
+	Create(p *models.Passkey) error
+	// UpdateColumns 只保存 p 中指定的列。Token_Version、Login_Count 等列由注销和登录并发递增，
+	// 不能用之前读取的副本整行写回
+	UpdateColumns(p *models.Passkey, columns ...string) error
+	// Delete 删除 passkey，不处理其下级、会话和授权
+	Delete(passkey string) error
+}
+
+// GormPasskeyRepository 基于数据库的 passkey 存储
+type GormPasskeyRepository struct{}
+
+func NewGormPasskeyRepository() *GormPasskeyRepository {
+	return &GormPasskeyRepository{}
+}
+
+func (r *GormPasskeyRepository) Find(passkey string) (*models.Passkey, error) {
+	var p models.Passkey
+	if err := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Passkey": passkey}).First(&p).Error; err != nil {
+		return nil, notFound(err, "查询passkey")
+	}
+	return &p, nil
+}
+
+func (r *GormPasskeyRepository) ListByRoles(roles []string) ([]models.Passkey, error) {
+	var passkeys []models.Passkey
+	if err := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Role": roles}).Order(database.Desc("Created_At")).Find(&passkeys).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("查询passkey失败: %v", err)
+	}
+	return passkeys, nil
+}
+
+func (r *GormPasskeyRepository) Create(p *models.Passkey) error {
+	if err := database.GetDB().Table("passkeys").Create(p).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("创建passkey失败: %v", err)
+	}
+	return nil
+}
+
+func (r *GormPasskeyRepository) UpdateColumns(p *models.Passkey, columns ...string) error {
+	if err := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Passkey": p.Passkey}).Select(columns).Updates(p).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("更新passkey失败: %v", err)
+	}
+	return nil
+}
+
+func (r *GormPasskeyRepository) Delete(passkey string) error {
+	if err := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Passkey": passkey}).Delete(&models.Passkey{}).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("删除passkey失败: %v", err)
+	}
+	return nil
+}
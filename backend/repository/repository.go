@@ -0,0 +1,9 @@
+package repository
+
+import "errors"
+
+// 仓储层封装化合物和 passkey 的存储访问，控制器通过接口调用，不直接依赖 GORM。
+// Gorm* 为基于数据库的实现，Memory* 为内存实现，用于测试
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("记录不存在")
@@ -0,0 +1,277 @@
+package router
+
+import (
+	"backend/config"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const oidcTestClientID = "mnplib-test"
+
+// mockIdP 模拟 OIDC IdP：提供发现文档、JWKS 和令牌接口，授权步骤由测试直接调用 authorize 完成
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant // 授权码 -> 授权结果
+}
+
+// mockGrant 一次授权：PKCE challenge、nonce 和 ID 令牌中的声明
+type mockGrant struct {
+	challenge string
+	nonce     string
+	claims    map[string]interface{}
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, codes: map[string]mockGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := idp.server.URL
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer,
+			"authorization_endpoint":                issuer + "/authorize",
+			"token_endpoint":                        issuer + "/token",
+			"jwks_uri":                              issuer + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   b64.EncodeToString(key.PublicKey.N.Bytes()),
+			"e":   b64.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize 模拟用户在 IdP 登录：读取授权地址中的参数，签发授权码，返回回调地址
+func (idp *mockIdP) authorize(t *testing.T, authURL string, claims map[string]interface{}) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authURL, idp.server.URL+"/authorize") {
+		t.Fatalf("login redirects to %s, want the IdP", authURL)
+	}
+	q := u.Query()
+	if q.Get("client_id") != oidcTestClientID || q.Get("code_challenge_method") != "S256" || q.Get("nonce") == "" {
+		t.Fatalf("authorization request = %s", u.RawQuery)
+	}
+	code := uuid.NewString()
+	idp.mu.Lock()
+	idp.codes[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), claims: claims}
+	idp.mu.Unlock()
+
+	callback, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback.Path + "?" + url.Values{"state": {q.Get("state")}, "code": {code}}.Encode()
+}
+
+// token 用授权码换取令牌，校验 PKCE 的 code_verifier，每个授权码只能使用一次
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || b64.EncodeToString(verifier[:]) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":   idp.server.URL,
+		"aud":   oidcTestClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": grant.nonce,
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": uuid.NewString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idp.sign(claims),
+	})
+}
+
+// sign 使用 RS256 签发 JWT
+func (idp *mockIdP) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signingInput + "." + b64.EncodeToString(signature)
+}
+
+// TestOIDCLogin 使用模拟的 IdP 完成授权码登录，检查回调、角色映射、邮箱验证和一次性登录码
+func TestOIDCLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	config.Config.Set("oidc.enabled", true)
+	config.Config.Set("oidc.issuer", idp.server.URL)
+	config.Config.Set("oidc.client_id", oidcTestClientID)
+	config.Config.Set("oidc.redirect_url", "http://localhost/api/auth/oidc/callback")
+	config.Config.Set("oidc.role_mappings", []map[string]interface{}{
+		{"group": "mnplib-curators", "role": "curator"},
+		{"email_domain": "institute.example.org", "role": "protected-reader"},
+	})
+	t.Cleanup(func() {
+		for _, key := range []string{"oidc.enabled", "oidc.issuer", "oidc.client_id", "oidc.redirect_url", "oidc.role_mappings", "oidc.require_verified_email"} {
+			config.Config.Set(key, nil)
+		}
+	})
+	r := newTestEngine(routeStatuses{})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusFound {
+			t.Fatalf("GET %s: status %d, want 302; body: %s", path, w.Code, w.Body.String())
+		}
+		return w
+	}
+	// login 完成一次登录，返回跳转到前端的地址中的参数
+	login := func(claims map[string]interface{}) url.Values {
+		t.Helper()
+		authURL := get("/api/auth/oidc/login?redirect=/compound/MNP001").Header().Get("Location")
+		location, err := url.Parse(get(idp.authorize(t, authURL, claims)).Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(location.String(), "http://localhost/compound/MNP001?") {
+			t.Fatalf("callback redirects to %s", location)
+		}
+		return location.Query()
+	}
+	var response struct {
+		Token    string `json:"token"`
+		Operator string `json:"operator"`
+		Role     string `json:"role"`
+	}
+	exchange := func(code string) {
+		t.Helper()
+		decode(t, postJSON(t, r, "/api/auth/oidc/exchange", map[string]string{"code": code}, http.StatusOK, ""), &response)
+	}
+	expectError := func(claims map[string]interface{}, want string) {
+		t.Helper()
+		if query := login(claims); query.Get("oidc_error_code") != want || query.Get("oidc_code") != "" {
+			t.Errorf("callback query = %v, want oidc_error_code %s", query, want)
+		}
+	}
+
+	// 按用户组映射为 curator，登录码只能领取一次
+	query := login(map[string]interface{}{"sub": "alice", "name": "Alice", "email": "alice@institute.example.org", "email_verified": true, "groups": []string{"mnplib-curators"}})
+	code := query.Get("oidc_code")
+	if code == "" {
+		t.Fatalf("callback query = %v", query)
+	}
+	exchange(code)
+	if response.Role != "curator" || response.Operator != "Alice" || response.Token == "" {
+		t.Fatalf("exchange = %+v", response)
+	}
+	curatorToken := response.Token
+	postJSON(t, r, "/api/auth/oidc/exchange", map[string]string{"code": code}, http.StatusUnauthorized, "OIDC_CODE_INVALID")
+
+	// 同一用户离开用户组后按邮箱域名映射，角色变化使此前的令牌失效
+	exchange(login(map[string]interface{}{"sub": "alice", "name": "Alice", "email": "alice@institute.example.org", "email_verified": true}).Get("oidc_code"))
+	if response.Role != "protected-reader" {
+		t.Errorf("role after leaving the group = %s, want protected-reader", response.Role)
+	}
+	req := httptest.NewRequest("GET", "/api/auth/quota", nil)
+	req.Header.Set("Authorization", "Bearer "+curatorToken)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("token issued before the role change: status %d, want 401", w.Code)
+	}
+
+	// 未配置 oidc.require_verified_email 时要求邮箱已验证
+	unverified := map[string]interface{}{"sub": "bob", "email": "bob@institute.example.org", "email_verified": false}
+	expectError(unverified, "OIDC_EMAIL_NOT_VERIFIED")
+	config.Config.Set("oidc.require_verified_email", false)
+	exchange(login(unverified).Get("oidc_code"))
+	if response.Role != "protected-reader" {
+		t.Errorf("role with an unverified email = %s, want protected-reader", response.Role)
+	}
+	config.Config.Set("oidc.require_verified_email", nil)
+
+	// 过长的显示名按字符截断，不会截断多字节字符
+	exchange(login(map[string]interface{}{"sub": "dave", "name": strings.Repeat("张", 300), "email": "dave@institute.example.org", "email_verified": true}).Get("oidc_code"))
+	if !utf8.ValidString(response.Operator) || utf8.RuneCountInString(response.Operator) != 255 {
+		t.Errorf("long display name stored as %d runes, valid UTF-8 = %v", utf8.RuneCountInString(response.Operator), utf8.ValidString(response.Operator))
+	}
+
+	// 未匹配任何规则，default_role 为空
+	expectError(map[string]interface{}{"sub": "carol", "email": "carol@example.com", "email_verified": true}, "OIDC_NO_ROLE")
+
+	// 授权请求只能回调一次
+	authURL := get("/api/auth/oidc/login").Header().Get("Location")
+	callback := idp.authorize(t, authURL, map[string]interface{}{"sub": "alice", "email_verified": true, "groups": []string{"mnplib-curators"}})
+	get(callback)
+	location, err := url.Parse(get(callback).Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Query().Get("oidc_error_code") != "OIDC_STATE_INVALID" {
+		t.Errorf("second callback redirects to %s", location)
+	}
+
+	// ID 令牌的 nonce 与授权请求不符
+	authURL = get("/api/auth/oidc/login").Header().Get("Location")
+	callback = idp.authorize(t, authURL, map[string]interface{}{"sub": "alice", "nonce": "forged", "groups": []string{"mnplib-curators"}})
+	location, err = url.Parse(get(callback).Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Query().Get("oidc_error_code") != "OIDC_FAILED" {
+		t.Errorf("callback with a forged nonce redirects to %s", location)
+	}
+}
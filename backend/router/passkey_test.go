@@ -0,0 +1,129 @@
+package router
+
+import (
+	"backend/controllers"
+	"backend/database"
+	"backend/models"
+	"backend/repository"
+	"backend/services"
+	"sync"
+	"testing"
+	"time"
+)
+
+// interleavedPasskeyRepository 在第一次读取 passkey 后执行 between，模拟读取和写回之间并发的注销和登录
+type interleavedPasskeyRepository struct {
+	repository.PasskeyRepository
+	once    sync.Once
+	between func()
+}
+
+func (r *interleavedPasskeyRepository) Find(passkey string) (*models.Passkey, error) {
+	p, err := r.PasskeyRepository.Find(passkey)
+	r.once.Do(r.between)
+	return p, err
+}
+
+// createChildPasskey 创建 admin 的下级 passkey
+func createChildPasskey(t *testing.T, key, role string, parent string) {
+	t.Helper()
+	p := models.Passkey{Passkey: key, Operator: key, Role: role, IsActive: true, ParentID: strPtr(parent), Extends: "admin"}
+	if err := database.GetDB().Table("passkeys").Create(&p).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func findPasskey(t *testing.T, key string) models.Passkey {
+	t.Helper()
+	var p models.Passkey
+	if err := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Passkey": key}).Take(&p).Error; err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// TestUpdatePasskeyKeepsConcurrentChanges 修改和启用 passkey 时只写入修改的列，不覆盖期间递增的令牌版本和登录次数
+func TestUpdatePasskeyKeepsConcurrentChanges(t *testing.T) {
+	const key = "00000000-0000-0000-0000-00000000000b"
+	createChildPasskey(t, key, models.RoleViewer, adminKey)
+
+	interleave := func() {
+		controllers.SetRepositories(nil, &interleavedPasskeyRepository{
+			PasskeyRepository: repository.NewGormPasskeyRepository(),
+			between: func() {
+				if err := services.RevokeAllTokens(key); err != nil {
+					t.Error(err)
+				}
+				p := findPasskey(t, key)
+				if p.IsActive {
+					if err := services.RecordLogin(&p, time.Now()); err != nil {
+						t.Error(err)
+					}
+				}
+			},
+		})
+	}
+	t.Cleanup(func() { controllers.SetRepositories(nil, repository.NewGormPasskeyRepository()) })
+	r := newTestEngine(routeStatuses{})
+
+	interleave()
+	runCases(t, r, []routeCase{
+		{name: "update", method: "PUT", path: "/api/passkeys/" + key, token: "admin", body: `{"operator":"renamed","is_active":false}`, want: 200},
+	})
+	// 并发注销和禁用各使令牌版本加1
+	p := findPasskey(t, key)
+	if p.Operator != "renamed" || p.IsActive || p.TokenVersion != 2 || p.LoginCount != 1 {
+		t.Errorf("after update: operator %q, active %v, token version %d, login count %d; want renamed, false, 2, 1", p.Operator, p.IsActive, p.TokenVersion, p.LoginCount)
+	}
+
+	interleave()
+	runCases(t, r, []routeCase{
+		{name: "enable", method: "POST", path: "/api/passkeys/" + key + "/toggle", token: "admin", want: 200},
+	})
+	if p := findPasskey(t, key); !p.IsActive || p.TokenVersion != 3 {
+		t.Errorf("after enable: active %v, token version %d; want true, 3", p.IsActive, p.TokenVersion)
+	}
+}
+
+// TestUpdatePasskeyConstrainsDescendants 修改 passkey 后其下级仍须在新的角色、有效期和限制范围内，禁用时按 cascade 级联
+func TestUpdatePasskeyConstrainsDescendants(t *testing.T) {
+	const (
+		parentKey     = "00000000-0000-0000-0000-00000000000c"
+		childKey      = "00000000-0000-0000-0000-00000000000d"
+		grandchildKey = "00000000-0000-0000-0000-00000000000e"
+	)
+	createChildPasskey(t, parentKey, models.RolePasskeyAdmin, superadminKey)
+	createChildPasskey(t, childKey, models.RoleProtectedReader, parentKey)
+	createChildPasskey(t, grandchildKey, models.RoleViewer, childKey)
+	r := newTestEngine(routeStatuses{})
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	update := func(body string) string {
+		return `{"operator":"parent","is_active":true,` + body + `}`
+	}
+
+	runCases(t, r, []routeCase{
+		// 下级的角色高于修改后的角色能分配的范围
+		{name: "downgrade below child", method: "PUT", path: "/api/passkeys/" + parentKey, token: "superadmin", body: update(`"role":"viewer"`), want: 422},
+		// 未设置有效期的下级沿用新的失效时间
+		{name: "narrow validity", method: "PUT", path: "/api/passkeys/" + parentKey, token: "superadmin", body: update(`"valid_until":"` + until.Format(time.RFC3339) + `"`), want: 200},
+	})
+	if p := findPasskey(t, parentKey); p.Role != models.RolePasskeyAdmin {
+		t.Errorf("role after rejected downgrade = %s", p.Role)
+	}
+	for _, key := range []string{childKey, grandchildKey} {
+		if p := findPasskey(t, key); p.ValidUntil == nil || !p.ValidUntil.Equal(until) {
+			t.Errorf("%s valid until %v, want %v", key, p.ValidUntil, until)
+		}
+	}
+
+	runCases(t, r, []routeCase{
+		// 下级已设置的失效时间晚于新的失效时间
+		{name: "narrow below child", method: "PUT", path: "/api/passkeys/" + parentKey, token: "superadmin", body: update(`"valid_until":"` + until.Add(-time.Minute).Format(time.RFC3339) + `"`), want: 422},
+		{name: "disable with cascade", method: "PUT", path: "/api/passkeys/" + parentKey + "?cascade=true", token: "superadmin", body: `{"operator":"parent","is_active":false}`, want: 200},
+	})
+	for _, key := range []string{parentKey, childKey, grandchildKey} {
+		if p := findPasskey(t, key); p.IsActive || p.TokenVersion != 1 {
+			t.Errorf("%s after disabling the parent: active %v, token version %d", key, p.IsActive, p.TokenVersion)
+		}
+	}
+}
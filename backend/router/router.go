@@ -4,34 +4,104 @@ import (
 	"backend/config"
 	"backend/controllers"
 	"backend/middlewares"
+	"backend/models"
+	"backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 func Init(r *gin.Engine) {
+	// 权限检查中间件，需在 JWTAuth 之后使用
+	readProtected := middlewares.RequirePermission(models.PermissionReadProtected)
+	writeData := middlewares.RequirePermission(models.PermissionWriteData)
+	managePasskeys := middlewares.RequirePermission(models.PermissionManagePasskeys)
+	readAudit := middlewares.RequirePermission(models.PermissionReadAudit)
+	// 保护数据的频率限制和读取配额，需在 JWTAuth 之后使用
+	rateLimit := middlewares.RateLimit()
+	protectedQuota := middlewares.ProtectedQuota()
+	// 登录、刷新令牌和兑换登录码接口按客户端 IP 单独限制频率
+	loginRateLimit := middlewares.IPRateLimit(services.IPScopeLogin)
+
+	// 请求数和耗时指标，需在注册路由之前使用
+	r.Use(middlewares.Metrics())
+
+	// 存活和就绪检查，供部署环境探测
+	r.GET("/healthz", controllers.GetHealthz)
+	r.GET("/readyz", controllers.GetReadyz)
+	// Prometheus 指标，需要 metrics.token 配置的令牌
+	r.GET("/metrics", middlewares.MetricsAuth(), controllers.GetMetrics)
+
 	// API路由组
 	api := r.Group("/api")
+	// 全部接口按客户端 IP 限制频率，包括无需认证的接口
+	api.Use(middlewares.IPRateLimit(services.IPScopePublic))
 	{
 		// 认证相关路由
 		auth := api.Group("/auth")
 		{
-			auth.POST("/login", controllers.Login)
+			auth.POST("/login", loginRateLimit, controllers.Login)
+			// 使用刷新令牌换取新令牌（无需访问令牌）
+			auth.POST("/refresh", loginRateLimit, controllers.RefreshToken)
 			// 验证登录状态（需要JWT认证）
 			auth.GET("/verify", middlewares.JWTAuth(), controllers.VerifyLoginStatus)
-			// 验证是否可以修改passkey（需要JWT认证和Extends为空）
-			auth.GET("/verify-passkey-modifiable", middlewares.JWTAuth(), middlewares.ExtendsCheck(), controllers.VerifyPasskeyModifiable)
+			// 退出登录：注销当前令牌，或注销当前 passkey 的全部令牌
+			auth.POST("/logout", middlewares.JWTAuth(), controllers.Logout)
+			auth.POST("/logout-all", middlewares.JWTAuth(), controllers.LogoutEverywhere)
+			auth.GET("/sessions", middlewares.JWTAuth(), controllers.GetMySessions)
+			// 当前用户的数据授权
+			auth.GET("/grants", middlewares.JWTAuth(), controllers.GetMyDataGrants)
+			// 当前用户的频率限制和配额使用情况
+			auth.GET("/quota", middlewares.JWTAuth(), controllers.GetMyQuota)
+			// WebAuthn 安全密钥：使用注册码首次注册、已登录用户添加凭据、可发现凭据登录
+			webAuthn := auth.Group("/webauthn")
+			{
+				webAuthn.POST("/enroll/begin", loginRateLimit, controllers.BeginWebAuthnEnrollment)
+				webAuthn.POST("/register/begin", middlewares.JWTAuth(), controllers.BeginWebAuthnRegistration)
+				webAuthn.POST("/register/finish", loginRateLimit, controllers.FinishWebAuthnRegistration)
+				webAuthn.POST("/login/begin", loginRateLimit, controllers.BeginWebAuthnLogin)
+				webAuthn.POST("/login/finish", loginRateLimit, controllers.FinishWebAuthnLogin)
+				webAuthn.GET("/credentials", middlewares.JWTAuth(), controllers.GetMyWebAuthnCredentials)
+				webAuthn.DELETE("/credentials/:cid", middlewares.JWTAuth(), controllers.DeleteMyWebAuthnCredential)
+			}
+			// 统一身份认证（OpenID Connect 授权码 + PKCE）
+			oidc := auth.Group("/oidc")
+			{
+				oidc.GET("/config", controllers.GetOIDCConfig)
+				oidc.GET("/login", controllers.BeginOIDCLogin)
+				oidc.GET("/callback", controllers.OIDCCallback)
+				oidc.POST("/exchange", loginRateLimit, controllers.ExchangeOIDCLoginCode)
+			}
+			// 验证是否可以管理passkey（需要 passkeys:manage 权限）
+			auth.GET("/verify-passkey-modifiable", middlewares.JWTAuth(), managePasskeys, controllers.VerifyPasskeyModifiable)
 		}
 
-		// Passkey 管理路由（需要JWT认证和Extends为空）
+		// Passkey 管理路由（需要 passkeys:manage 权限）
 		passkeys := api.Group("/passkeys")
-		passkeys.Use(middlewares.JWTAuth(), middlewares.ExtendsCheck())
+		passkeys.Use(middlewares.JWTAuth(), managePasskeys)
 		{
 			passkeys.GET("", controllers.GetAllPasskeys)
+			passkeys.GET("/roles", controllers.GetRoles)
+			passkeys.GET("/tree", controllers.GetPasskeyTree)
+			passkeys.GET("/quota", controllers.GetPasskeysQuota)
 			passkeys.POST("", controllers.CreatePasskey)
 			passkeys.GET("/:passkey", controllers.GetPasskeyByID)
 			passkeys.PUT("/:passkey", controllers.UpdatePasskey)
 			passkeys.DELETE("/:passkey", controllers.DeletePasskey)
 			passkeys.POST("/:passkey/toggle", controllers.TogglePasskeyStatus)
+			passkeys.POST("/:passkey/logout-all", controllers.LogoutPasskeyEverywhere)
+			// 会话管理
+			passkeys.GET("/:passkey/sessions", controllers.GetPasskeySessions)
+			passkeys.DELETE("/:passkey/sessions/:sid", controllers.DeletePasskeySession)
+			// 安全密钥管理
+			passkeys.GET("/:passkey/credentials", controllers.GetPasskeyWebAuthnCredentials)
+			passkeys.DELETE("/:passkey/credentials/:cid", controllers.DeletePasskeyWebAuthnCredential)
+			passkeys.POST("/:passkey/enrollment/reset", controllers.ResetPasskeyEnrollment)
+			// 配额使用情况
+			passkeys.GET("/:passkey/quota", controllers.GetPasskeyQuota)
+			// 数据授权
+			passkeys.GET("/:passkey/grants", controllers.GetPasskeyDataGrants)
+			passkeys.POST("/:passkey/grants", controllers.CreatePasskeyDataGrant)
+			passkeys.DELETE("/:passkey/grants/:gid", controllers.DeletePasskeyDataGrant)
 		}
 
 		// 数据相关路由
@@ -39,16 +109,67 @@ func Init(r *gin.Engine) {
 		{
 			// data.GET("", controllers.GetDataRecords)
 			data.GET("/:id", controllers.GetDataByID)
-			data.GET("/:id/ms2-full", middlewares.JWTAuth(), controllers.GetMS2FullByID)
+			// 保护数据按角色权限或数据授权返回允许的字段
+			data.GET("/:id/ms2-full", middlewares.Audit(models.AuditActionReadMS2Full), middlewares.JWTAuth(), rateLimit, protectedQuota, controllers.GetMS2FullByID)
 			data.GET("/:id/structure", controllers.GetStructure)
 			data.GET("/statistics", controllers.GetDataStatistics)
 			data.GET("/filter", controllers.FilterCompounds)
+			data.GET("/search", controllers.SearchCompounds)
+			data.POST("/search/rebuild", middlewares.JWTAuth(), writeData, controllers.RebuildSearchIndex)
 			data.GET("/item-types", controllers.GetItemTypes)
 			data.GET("/descriptions", controllers.GetDescriptions)
 			data.GET("/sources", controllers.GetSources)
-			// 受保护的数据路由，需要JWT认证
-			data.GET("/:id/protected", middlewares.JWTAuth(), controllers.GetDataByIDFull)
+			data.GET("/adducts", controllers.GetAdducts)
+			data.GET("/:id/isotope-pattern", controllers.GetIsotopePattern)
+			data.GET("/:id/synonyms", controllers.GetCompoundSynonyms)
+			data.GET("/:id/protected", middlewares.Audit(models.AuditActionReadProtected), middlewares.JWTAuth(), rateLimit, protectedQuota, controllers.GetDataByIDFull)
+			data.GET("/:id/bioactivity", middlewares.Audit(models.AuditActionReadBioactivity), middlewares.JWTAuth(), rateLimit, protectedQuota, controllers.GetCompoundBioactivity)
+			// 数据维护路由，需要 data:write 权限
+			data.POST("/:id/bioactivity", middlewares.JWTAuth(), readProtected, writeData, controllers.CreateCompoundBioactivity)
+			data.POST("/:id/references", middlewares.JWTAuth(), writeData, controllers.LinkCompoundReference)
+			data.DELETE("/:id/references/:rid", middlewares.JWTAuth(), writeData, controllers.UnlinkCompoundReference)
+			data.POST("/:id/synonyms", middlewares.JWTAuth(), writeData, controllers.CreateCompoundSynonym)
+			data.DELETE("/:id/synonyms/:sid", middlewares.JWTAuth(), writeData, controllers.DeleteCompoundSynonym)
+		}
+
+		// 结构化活性数据路由（保护数据，需要 data:read-protected 权限）
+		bioactivity := api.Group("/bioactivity")
+		{
+			bioactivity.GET("/filter", middlewares.Audit(models.AuditActionFilterBioactive), middlewares.JWTAuth(), readProtected, rateLimit, protectedQuota, controllers.FilterBioactivity)
+			// 修改和导入需要 data:write 权限
+			bioactivity.POST("/import", middlewares.JWTAuth(), readProtected, writeData, controllers.ImportBioactivity)
+			bioactivity.PUT("/:bid", middlewares.JWTAuth(), readProtected, writeData, controllers.UpdateBioactivity)
+			bioactivity.DELETE("/:bid", middlewares.JWTAuth(), readProtected, writeData, controllers.DeleteBioactivity)
+		}
+
+		// 审计日志路由（需要 audit:read 权限，即超级管理员）
+		audit := api.Group("/audit")
+		audit.Use(middlewares.JWTAuth(), readAudit)
+		{
+			audit.GET("/events", controllers.GetAuditEvents)
+			audit.GET("/events/export", middlewares.Audit(models.AuditActionExportAudit), controllers.ExportAuditEvents)
+		}
+		// 来源生物分类路由
+		organisms := api.Group("/organisms")
+		{
+			organisms.GET("", controllers.GetOrganisms)
+			organisms.GET("/ranks/:rank", controllers.GetTaxa)
+			organisms.GET("/:oid", controllers.GetOrganismByID)
+			organisms.POST("/link", middlewares.JWTAuth(), writeData, controllers.LinkOrganisms)
+		}
+
+		// 文献路由
+		references := api.Group("/references")
+		{
+			references.GET("", controllers.GetReferences)
+			references.GET("/export", controllers.ExportReferences)
+			references.GET("/:rid", controllers.GetReferenceByID)
+			// 修改需要 data:write 权限
+			references.POST("", middlewares.JWTAuth(), writeData, controllers.CreateReference)
+			references.PUT("/:rid", middlewares.JWTAuth(), writeData, controllers.UpdateReference)
+			references.DELETE("/:rid", middlewares.JWTAuth(), writeData, controllers.DeleteReference)
 		}
+
 		// RDKit相关路由
 		rdkit := api.Group("/rdkit")
 		{
@@ -60,6 +181,12 @@ func Init(r *gin.Engine) {
 			rdkit.GET("/substructure-search", controllers.SubstructureSearch)
 			rdkit.GET("/exact-match", controllers.ExactMatchSearch)
 		}
+
+		// 错误代码列表
+		api.GET("/errors", controllers.GetErrorCatalogue)
+		// OpenAPI 文档和接口文档页面
+		api.GET("/openapi.json", controllers.GetOpenAPISpec)
+		api.GET("/docs", controllers.GetAPIDocs)
 	}
 
 	if config.Config.GetBool("static") {
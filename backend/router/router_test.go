@@ -0,0 +1,1370 @@
+package router
+
+import (
+	"backend/config"
+	"backend/controllers"
+	"backend/database"
+	"backend/docs"
+	"backend/docs/openapi"
+	"backend/migrations"
+	"backend/models"
+	"backend/repository"
+	"backend/services"
+	"backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 测试使用临时目录中的 SQLite 数据库，执行全部迁移后写入固定的测试数据；
+// 化学计算使用 fakeChemistry，不需要 Python 和 RDKit
+
+// 测试用 passkey
+const (
+	superadminKey = "00000000-0000-0000-0000-000000000001"
+	adminKey      = "00000000-0000-0000-0000-000000000002"
+	curatorKey    = "00000000-0000-0000-0000-000000000003"
+	readerKey     = "00000000-0000-0000-0000-000000000004"
+	viewerKey     = "00000000-0000-0000-0000-000000000005"
+	inactiveKey   = "00000000-0000-0000-0000-000000000006"
+	targetKey     = "00000000-0000-0000-0000-000000000007" // 由 passkey-admin 创建，用于修改类接口
+	victimKey     = "00000000-0000-0000-0000-000000000008" // 用于删除接口
+	logoutAllKey  = "00000000-0000-0000-0000-000000000009" // 用于在所有设备上退出登录
+	missingKey    = "00000000-0000-0000-0000-0000000000ff"
+)
+
+// fixtures 写入测试数据后得到的记录 ID 和令牌
+var fixtures struct {
+	tokens          map[string]string // 按名称区分的访问令牌
+	refreshToken    string
+	targetSessionID string
+	referenceID     uint
+	spareReference  uint // 用于删除接口
+	synonymID       uint
+	bioactivityID   uint
+	grantID         uint
+	organismID      uint
+}
+
+// fakeChemistry 按请求中的 action 返回确定的结果，代替 RDKit Python 进程
+type fakeChemistry struct{}
+
+func (fakeChemistry) SendAndWait(msg string) (string, error) {
+	if msg == "init" {
+		return "initialized", nil
+	}
+	var req struct {
+		Action        string `json:"action"`
+		SMILES        string `json:"smiles"`
+		SMARTSPattern string `json:"smarts_pattern"`
+		QFP           string `json:"qfp"`
+		Library       []struct {
+			ID     string `json:"id"`
+			SMILES string `json:"smiles"`
+		} `json:"library"`
+		Data []struct {
+			ID string `json:"id"`
+			FP string `json:"fp"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(msg), &req); err != nil {
+		return "", err
+	}
+
+	var ids []string
+	switch req.Action {
+	case "smiles_to_fingerprint":
+		return "fp:" + req.SMILES, nil
+	case "smiles_to_pdb":
+		return "PDB " + req.SMILES, nil
+	case "calculate_molecular_weight":
+		return "180.16", nil
+	case "is_substructure":
+		return fmt.Sprint(strings.Contains(req.SMILES, req.SMARTSPattern)), nil
+	case "substructure_search":
+		for _, item := range req.Library {
+			if strings.Contains(item.SMILES, req.SMARTSPattern) {
+				ids = append(ids, item.ID)
+			}
+		}
+	case "exact_match_search":
+		for _, item := range req.Library {
+			if item.SMILES == req.SMILES {
+				ids = append(ids, item.ID)
+			}
+		}
+	case "similarity_search":
+		for _, item := range req.Data {
+			if item.FP == req.QFP {
+				ids = append(ids, item.ID)
+			}
+		}
+	default:
+		return "", fmt.Errorf("unknown action %q", req.Action)
+	}
+	result, _ := json.Marshal(ids)
+	return string(result), nil
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	dir, err := os.MkdirTemp("", "mnplib-router-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := 1
+	if err := setup(dir); err != nil {
+		fmt.Fprintln(os.Stderr, "setup:", err)
+	} else {
+		code = m.Run()
+	}
+
+	if sqlDB, err := database.GetDB().DB(); err == nil {
+		sqlDB.Close()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// metricsToken 测试中访问 /metrics 的令牌
+const metricsToken = "router-test-metrics-token"
+
+func setup(dir string) error {
+	config.Config.Set("database.driver", database.DriverSQLite)
+	config.Config.Set("database.path", filepath.Join(dir, "test.db"))
+	config.Config.Set("jwt.secret", "router-test-secret")
+	config.Config.Set("webauthn.rp_id", "localhost")
+	config.Config.Set("webauthn.rp_origins", []string{"http://localhost"})
+	config.Config.Set("webauthn.rp_display_name", "MNPLib")
+	config.Config.Set("oidc.frontend_url", "http://localhost")
+	// 全部请求来自同一个客户端 IP，按 IP 的频率限制由 TestIPRateLimit 单独检查
+	config.Config.Set("rate_limit.public", 0)
+	config.Config.Set("rate_limit.login", 0)
+	config.Config.Set("metrics.token", metricsToken)
+	// 就绪检查的结果不缓存，RDKit 不可用时的检查结果由各用例确定
+	config.Config.Set("health.cache_ttl", 0)
+
+	if err := database.Init(); err != nil {
+		return err
+	}
+	if _, err := migrations.Up(database.GetDB(), 0); err != nil {
+		return err
+	}
+	services.SetChemistryEngine(fakeChemistry{})
+	return seed()
+}
+
+func strPtr(s string) *string     { return &s }
+func floatPtr(f float64) *float64 { return &f }
+
+func seed() error {
+	db := database.GetDB()
+
+	// 化合物：MNP001 数据完整；MNP002 的 MS2 为空字符串；MNP003 只有 MS1_H
+	compounds := []models.Data{
+		{
+			ID:           "MNP001",
+			ItemName:     strPtr("Glucose"),
+			ItemType:     strPtr("Saccharide"),
+			Formula:      strPtr("C6H12O6"),
+			SMILES:       strPtr("OCC1OC(O)C(O)C(O)C1O"),
+			Description:  strPtr("KNOWN COMPOUND"),
+			Source:       strPtr("Streptomyces griseus"),
+			Structure:    strPtr("MOL MNP001"),
+			MS1_H:        floatPtr(181.07),
+			MS2:          strPtr("163.06,145.05"),
+			MS2_full:     strPtr("163.06:100,145.05:40"),
+			Bioactivity:  strPtr("IC50 = 2 uM"),
+			NMR_13C_data: strPtr("92.9,72.4"),
+			FP:           strPtr("fp:OCC1OC(O)C(O)C(O)C1O"),
+		},
+		{
+			ID:          "MNP002",
+			ItemName:    strPtr("Streptomycin"),
+			ItemType:    strPtr("Aminoglycoside"),
+			Formula:     strPtr("C21H39N7O12"),
+			SMILES:      strPtr("CNC1C(O)C(O)C(CO)OC1O"),
+			Description: strPtr("NEW NATURAL PRODUCT"),
+			Source:      strPtr("Streptomyces griseus"),
+			MS2:         strPtr(""),
+			FP:          strPtr("fp:CNC1C(O)C(O)C(CO)OC1O"),
+		},
+		{
+			ID:       "MNP003",
+			ItemName: strPtr("Compound 3"),
+			Formula:  strPtr("C5H10O5"),
+			SMILES:   strPtr("OCC(O)C(O)C(O)C=O"),
+			Source:   strPtr("Aspergillus niger"),
+			MS1_H:    floatPtr(151.06),
+			FP:       strPtr("fp:OCC(O)C(O)C(O)C=O"),
+		},
+	}
+	if err := db.Create(&compounds).Error; err != nil {
+		return err
+	}
+
+	organism := models.Organism{Name: "Streptomyces griseus", Rank: "species", Genus: "Streptomyces", Species: "Streptomyces griseus"}
+	if err := db.Create(&organism).Error; err != nil {
+		return err
+	}
+	fixtures.organismID = organism.ID
+
+	// passkey：每个角色一个，target 和 victim 由 passkey-admin 创建。
+	// passkey-admin 的每日配额为 1000，其创建的 passkey 不能超出
+	now := time.Now()
+	parent := func(key string) *string { return &key }
+	passkeys := []models.Passkey{
+		{Passkey: superadminKey, Operator: "root", Role: models.RoleSuperadmin, IsActive: true},
+		{Passkey: adminKey, Operator: "admin", Role: models.RolePasskeyAdmin, IsActive: true, ParentID: parent(superadminKey), Extends: "root", DailyQuota: intPtr(1000)},
+		{Passkey: curatorKey, Operator: "curator", Role: models.RoleCurator, IsActive: true, ParentID: parent(superadminKey), Extends: "root"},
+		{Passkey: readerKey, Operator: "reader", Role: models.RoleProtectedReader, IsActive: true, ParentID: parent(adminKey), Extends: "admin"},
+		{Passkey: viewerKey, Operator: "viewer", Role: models.RoleViewer, IsActive: true, ParentID: parent(adminKey), Extends: "admin"},
+		{Passkey: inactiveKey, Operator: "inactive", Role: models.RoleViewer, IsActive: true, ParentID: parent(adminKey), Extends: "admin"},
+		{Passkey: targetKey, Operator: "target", Role: models.RoleViewer, IsActive: true, ParentID: parent(adminKey), Extends: "admin"},
+		{Passkey: victimKey, Operator: "victim", Role: models.RoleViewer, IsActive: true, ParentID: parent(adminKey), Extends: "admin"},
+		{Passkey: logoutAllKey, Operator: "logout-all", Role: models.RoleViewer, IsActive: true, ParentID: parent(adminKey), Extends: "admin"},
+	}
+	for i := range passkeys {
+		passkeys[i].CreatedAt = now.Add(time.Duration(i) * time.Second)
+		if err := db.Table("passkeys").Create(&passkeys[i]).Error; err != nil {
+			return err
+		}
+	}
+	// Is_Active 的默认值为 true，创建时写入 false 会被忽略
+	if err := db.Table("passkeys").Where("Passkey = ?", inactiveKey).Update("Is_Active", false).Error; err != nil {
+		return err
+	}
+
+	fixtures.tokens = map[string]string{"metrics": metricsToken}
+	sessions := map[string]string{
+		"superadmin": superadminKey,
+		"admin":      adminKey,
+		"curator":    curatorKey,
+		"reader":     readerKey,
+		"viewer":     viewerKey,
+		"logout":     viewerKey, // 单独的会话，用于退出登录
+		"refresh":    viewerKey, // 单独的会话，重复使用刷新令牌会结束该会话
+		"logout-all": logoutAllKey,
+		"target":     targetKey,
+	}
+	for name, key := range sessions {
+		for i := range passkeys {
+			if passkeys[i].Passkey != key {
+				continue
+			}
+			pair, err := services.StartSession(&passkeys[i], "127.0.0.1", "router-test", now)
+			if err != nil {
+				return err
+			}
+			fixtures.tokens[name] = pair.AccessToken
+			if name == "target" {
+				fixtures.targetSessionID = pair.SessionID
+			}
+			if name == "refresh" {
+				fixtures.refreshToken = pair.RefreshToken
+			}
+		}
+	}
+
+	references := []models.Reference{
+		{Title: "Isolation of glucose", DOI: strPtr("10.1000/glucose"), Year: intPtr(2020)},
+		{Title: "Spare reference"},
+	}
+	for i := range references {
+		if err := services.CreateReference(&references[i]); err != nil {
+			return err
+		}
+	}
+	fixtures.referenceID = references[0].ID
+	fixtures.spareReference = references[1].ID
+	if err := services.LinkReference("MNP001", fixtures.referenceID, nil); err != nil {
+		return err
+	}
+
+	synonym := models.Synonym{DataID: "MNP001", Name: "D-Glucose", NameType: models.SynonymTypeTrivial}
+	if err := services.CreateSynonym(&synonym); err != nil {
+		return err
+	}
+	fixtures.synonymID = synonym.ID
+
+	bioactivity := models.Bioactivity{DataID: "MNP001", Endpoint: models.EndpointIC50, Target: "HeLa", Qualifier: "=", Value: floatPtr(2), Units: "uM"}
+	if err := services.CreateBioactivity(&bioactivity); err != nil {
+		return err
+	}
+	fixtures.bioactivityID = bioactivity.ID
+
+	grant, err := services.NewDataGrant(targetKey, []string{models.GrantFieldMS2}, "", nil, "", "admin")
+	if err != nil {
+		return err
+	}
+	if err := services.CreateDataGrant(grant); err != nil {
+		return err
+	}
+	fixtures.grantID = grant.ID
+	return nil
+}
+
+func intPtr(i int) *int { return &i }
+
+// routeCase 一次请求及其期望的结果
+type routeCase struct {
+	name   string
+	method string
+	path   string
+	token  string // fixtures.tokens 中的名称，"invalid" 为无效令牌，为空时不带令牌
+	body   string
+	want   int // 期望的 HTTP 状态码
+	// check 检查响应中的 data 字段，可为空
+	check func(t *testing.T, data json.RawMessage)
+}
+
+// routeStatuses 路由（如 "GET /api/data/:id"）返回过的 HTTP 状态码
+type routeStatuses map[string]map[int]bool
+
+// newTestEngine 注册全部路由，并记录请求命中的路由及其响应的状态码
+func newTestEngine(covered routeStatuses) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Next()
+		if c.FullPath() == "" {
+			return
+		}
+		key := c.Request.Method + " " + c.FullPath()
+		if covered[key] == nil {
+			covered[key] = map[int]bool{}
+		}
+		covered[key][c.Writer.Status()] = true
+	})
+	Init(r)
+	return r
+}
+
+var openAPIPathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// loadOpenAPI 请求 /api/openapi.json，返回文档中的路由（与 gin 的写法相同）及其声明的状态码
+func loadOpenAPI(t *testing.T, r *gin.Engine) map[string]map[int]bool {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json: status %d", w.Code)
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("decode openapi.json: %v", err)
+	}
+	documented := map[string]map[int]bool{}
+	for path, operations := range spec.Paths {
+		path = openAPIPathParam.ReplaceAllString(path, ":$1")
+		for method, op := range operations {
+			statuses := map[int]bool{}
+			for code := range op.Responses {
+				status, err := strconv.Atoi(code)
+				if err != nil {
+					t.Fatalf("%s %s: invalid response code %q", method, path, code)
+				}
+				statuses[status] = true
+			}
+			documented[strings.ToUpper(method)+" "+path] = statuses
+		}
+	}
+	return documented
+}
+
+// errorCatalogue 错误代码列表，按错误代码索引
+var errorCatalogue = func() map[utils.ErrorCode]utils.ErrorDefinition {
+	catalogue := map[utils.ErrorCode]utils.ErrorDefinition{}
+	for _, def := range utils.ErrorCatalogue(utils.LanguageZH) {
+		catalogue[def.Code] = def
+	}
+	return catalogue
+}()
+
+// checkBody 检查 JSON 响应体与 HTTP 状态码一致：业务代码为 200+状态码，
+// 失败时 error 为错误代码列表中的代码。返回响应中的 data 字段，响应不是 JSON 或
+// 成功返回的不是 {code, msg, data} 格式（如 OpenAPI 文档）时返回 nil
+func checkBody(t *testing.T, w *httptest.ResponseRecorder) json.RawMessage {
+	t.Helper()
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return nil
+	}
+	var body struct {
+		Code  *int            `json:"code"`
+		Error utils.ErrorCode `json:"error"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Code == nil && w.Code < http.StatusBadRequest {
+		return nil
+	}
+	if body.Code == nil {
+		t.Errorf("code missing, want %d", 200000+w.Code)
+	} else if *body.Code != 200000+w.Code {
+		t.Errorf("code = %d, want %d", *body.Code, 200000+w.Code)
+	}
+	if w.Code >= http.StatusBadRequest {
+		if def, ok := errorCatalogue[body.Error]; !ok || def.Status != w.Code {
+			t.Errorf("error = %q, want a catalogued code for status %d", body.Error, w.Code)
+		}
+	}
+	return body.Data
+}
+
+func runCases(t *testing.T, r *gin.Engine, cases []routeCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body *strings.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			} else {
+				body = strings.NewReader("")
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			switch tc.token {
+			case "":
+			case "invalid":
+				req.Header.Set("Authorization", "Bearer not-a-token")
+			default:
+				token, ok := fixtures.tokens[tc.token]
+				if !ok {
+					t.Fatalf("unknown token %q", tc.token)
+				}
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.want {
+				t.Fatalf("%s %s: status %d, want %d; body: %s", tc.method, tc.path, w.Code, tc.want, w.Body.String())
+			}
+			data := checkBody(t, w)
+			if tc.check != nil {
+				tc.check(t, data)
+			}
+		})
+	}
+}
+
+// decode 将响应中的 data 字段解析到 v
+func decode(t *testing.T, data json.RawMessage, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+}
+
+// expectTotal 检查分页响应中的 total
+func expectTotal(want int) func(t *testing.T, data json.RawMessage) {
+	return func(t *testing.T, data json.RawMessage) {
+		t.Helper()
+		var page struct {
+			Total int `json:"total"`
+		}
+		decode(t, data, &page)
+		if page.Total != want {
+			t.Errorf("total = %d, want %d", page.Total, want)
+		}
+	}
+}
+
+// expectString 检查 data 为指定的字符串
+func expectString(want string) func(t *testing.T, data json.RawMessage) {
+	return func(t *testing.T, data json.RawMessage) {
+		t.Helper()
+		var got string
+		decode(t, data, &got)
+		if got != want {
+			t.Errorf("data = %q, want %q", got, want)
+		}
+	}
+}
+
+func authCases() []routeCase {
+	return []routeCase{
+		{name: "login", method: "POST", path: "/api/auth/login", body: `{"passkey":"` + readerKey + `"}`, want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var login struct {
+					Role  string `json:"role"`
+					Token string `json:"token"`
+				}
+				decode(t, data, &login)
+				if login.Role != models.RoleProtectedReader || login.Token == "" {
+					t.Errorf("login response = %s", data)
+				}
+			}},
+		{name: "login missing body", method: "POST", path: "/api/auth/login", want: 400},
+		{name: "login unknown passkey", method: "POST", path: "/api/auth/login", body: `{"passkey":"` + missingKey + `"}`, want: 401},
+		{name: "login inactive passkey", method: "POST", path: "/api/auth/login", body: `{"passkey":"` + inactiveKey + `"}`, want: 401},
+		// 只知道会话ID时伪造的刷新令牌被拒绝，但不注销会话
+		{name: "refresh forged secret", method: "POST", path: "/api/auth/refresh", body: `{"refresh_token":"` + strings.SplitN(fixtures.refreshToken, ".", 2)[0] + `.forged"}`, want: 401},
+		{name: "refresh", method: "POST", path: "/api/auth/refresh", body: `{"refresh_token":"` + fixtures.refreshToken + `"}`, want: 200},
+		{name: "refresh reused token", method: "POST", path: "/api/auth/refresh", body: `{"refresh_token":"` + fixtures.refreshToken + `"}`, want: 401},
+		{name: "refresh missing body", method: "POST", path: "/api/auth/refresh", want: 400},
+		{name: "verify", method: "GET", path: "/api/auth/verify", token: "reader", want: 200},
+		{name: "verify without token", method: "GET", path: "/api/auth/verify", want: 401},
+		{name: "verify invalid token", method: "GET", path: "/api/auth/verify", token: "invalid", want: 401},
+		{name: "sessions", method: "GET", path: "/api/auth/sessions", token: "reader", want: 200},
+		{name: "sessions without token", method: "GET", path: "/api/auth/sessions", want: 401},
+		{name: "grants", method: "GET", path: "/api/auth/grants", token: "target", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var grants []json.RawMessage
+				decode(t, data, &grants)
+				if len(grants) != 1 {
+					t.Errorf("got %d grants, want 1", len(grants))
+				}
+			}},
+		{name: "grants without token", method: "GET", path: "/api/auth/grants", want: 401},
+		{name: "quota", method: "GET", path: "/api/auth/quota", token: "reader", want: 200},
+		{name: "quota without token", method: "GET", path: "/api/auth/quota", want: 401},
+		{name: "verify passkey modifiable", method: "GET", path: "/api/auth/verify-passkey-modifiable", token: "admin", want: 200},
+		{name: "verify passkey modifiable forbidden", method: "GET", path: "/api/auth/verify-passkey-modifiable", token: "viewer", want: 403},
+		{name: "verify passkey modifiable without token", method: "GET", path: "/api/auth/verify-passkey-modifiable", want: 401},
+		{name: "logout", method: "POST", path: "/api/auth/logout", token: "logout", want: 200},
+		{name: "logout token revoked", method: "GET", path: "/api/auth/verify", token: "logout", want: 401},
+		{name: "logout without token", method: "POST", path: "/api/auth/logout", want: 401},
+		{name: "logout all", method: "POST", path: "/api/auth/logout-all", token: "logout-all", want: 200},
+		{name: "logout all tokens revoked", method: "GET", path: "/api/auth/verify", token: "logout-all", want: 401},
+		{name: "logout all without token", method: "POST", path: "/api/auth/logout-all", want: 401},
+	}
+}
+
+func webAuthnCases() []routeCase {
+	return []routeCase{
+		{name: "enroll begin", method: "POST", path: "/api/auth/webauthn/enroll/begin", body: `{"enrollment_code":"` + viewerKey + `"}`, want: 200},
+		{name: "enroll begin unknown code", method: "POST", path: "/api/auth/webauthn/enroll/begin", body: `{"enrollment_code":"` + missingKey + `"}`, want: 401},
+		{name: "enroll begin missing body", method: "POST", path: "/api/auth/webauthn/enroll/begin", want: 400},
+		{name: "register begin", method: "POST", path: "/api/auth/webauthn/register/begin", token: "reader", body: `{"name":"key"}`, want: 200},
+		{name: "register begin without token", method: "POST", path: "/api/auth/webauthn/register/begin", want: 401},
+		{name: "register finish unknown ceremony", method: "POST", path: "/api/auth/webauthn/register/finish", body: `{"ceremony_id":"nope","credential":{}}`, want: 422},
+		{name: "register finish missing body", method: "POST", path: "/api/auth/webauthn/register/finish", want: 400},
+		{name: "login begin", method: "POST", path: "/api/auth/webauthn/login/begin", want: 200},
+		{name: "login finish unknown ceremony", method: "POST", path: "/api/auth/webauthn/login/finish", body: `{"ceremony_id":"nope","credential":{}}`, want: 422},
+		{name: "login finish missing body", method: "POST", path: "/api/auth/webauthn/login/finish", want: 400},
+		{name: "credentials", method: "GET", path: "/api/auth/webauthn/credentials", token: "reader", want: 200},
+		{name: "credentials without token", method: "GET", path: "/api/auth/webauthn/credentials", want: 401},
+		{name: "delete credential invalid id", method: "DELETE", path: "/api/auth/webauthn/credentials/abc", token: "reader", want: 400},
+		{name: "delete credential without token", method: "DELETE", path: "/api/auth/webauthn/credentials/1", want: 401},
+	}
+}
+
+func oidcCases() []routeCase {
+	return []routeCase{
+		{name: "config", method: "GET", path: "/api/auth/oidc/config", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var cfg struct {
+					Enabled bool `json:"enabled"`
+				}
+				decode(t, data, &cfg)
+				if cfg.Enabled {
+					t.Error("oidc should be disabled")
+				}
+			}},
+		{name: "login not configured", method: "GET", path: "/api/auth/oidc/login", want: 503},
+		{name: "callback idp error", method: "GET", path: "/api/auth/oidc/callback?error=access_denied&state=x", want: http.StatusFound},
+		{name: "callback invalid state", method: "GET", path: "/api/auth/oidc/callback?state=x&code=y", want: http.StatusFound},
+		{name: "exchange unknown code", method: "POST", path: "/api/auth/oidc/exchange", body: `{"code":"nope"}`, want: 401},
+		{name: "exchange missing body", method: "POST", path: "/api/auth/oidc/exchange", want: 400},
+	}
+}
+
+func passkeyCases() []routeCase {
+	target := "/api/passkeys/" + targetKey
+	return []routeCase{
+		{name: "list", method: "GET", path: "/api/passkeys", token: "admin", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var passkeys []struct {
+					Passkey string `json:"passkey"`
+				}
+				decode(t, data, &passkeys)
+				for _, p := range passkeys {
+					if p.Passkey == superadminKey || p.Passkey == curatorKey {
+						t.Errorf("passkey-admin can see %s outside its delegation tree", p.Passkey)
+					}
+				}
+			}},
+		{name: "list forbidden", method: "GET", path: "/api/passkeys", token: "viewer", want: 403},
+		{name: "list without token", method: "GET", path: "/api/passkeys", want: 401},
+		{name: "roles", method: "GET", path: "/api/passkeys/roles", token: "admin", want: 200},
+		{name: "roles forbidden", method: "GET", path: "/api/passkeys/roles", token: "curator", want: 403},
+		{name: "tree", method: "GET", path: "/api/passkeys/tree", token: "superadmin", want: 200},
+		{name: "tree without token", method: "GET", path: "/api/passkeys/tree", want: 401},
+		{name: "quota overview", method: "GET", path: "/api/passkeys/quota", token: "admin", want: 200},
+		{name: "quota overview forbidden", method: "GET", path: "/api/passkeys/quota", token: "reader", want: 403},
+		{name: "create", method: "POST", path: "/api/passkeys", token: "admin", body: `{"operator":"new","role":"viewer","is_active":true}`, want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var p struct {
+					ParentID string `json:"parent_id"`
+					Role     string `json:"role"`
+				}
+				decode(t, data, &p)
+				if p.ParentID != adminKey || p.Role != models.RoleViewer {
+					t.Errorf("created passkey = %s", data)
+				}
+			}},
+		{name: "create missing operator", method: "POST", path: "/api/passkeys", token: "admin", body: `{"role":"viewer"}`, want: 400},
+		{name: "create unknown role", method: "POST", path: "/api/passkeys", token: "admin", body: `{"operator":"x","role":"root"}`, want: 422},
+		{name: "create higher role", method: "POST", path: "/api/passkeys", token: "admin", body: `{"operator":"x","role":"superadmin"}`, want: 403},
+		// curator 的 data:write 不在 passkey-admin 的权限范围内
+		{name: "create role outside permissions", method: "POST", path: "/api/passkeys", token: "admin", body: `{"operator":"x","role":"curator"}`, want: 403},
+		{name: "create inherits parent quota", method: "POST", path: "/api/passkeys", token: "admin", body: `{"operator":"limited","role":"viewer"}`, want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var p struct {
+					DailyQuota *int `json:"daily_quota"`
+				}
+				decode(t, data, &p)
+				if p.DailyQuota == nil || *p.DailyQuota != 1000 {
+					t.Errorf("daily_quota = %v, want 1000", p.DailyQuota)
+				}
+			}},
+		{name: "create unlimited quota", method: "POST", path: "/api/passkeys", token: "admin", body: `{"operator":"x","role":"viewer","daily_quota":0}`, want: 422},
+		{name: "create quota above parent", method: "POST", path: "/api/passkeys", token: "admin", body: `{"operator":"x","role":"viewer","daily_quota":2000}`, want: 422},
+		{name: "create forbidden", method: "POST", path: "/api/passkeys", token: "viewer", body: `{"operator":"x"}`, want: 403},
+		{name: "get", method: "GET", path: target, token: "admin", want: 200},
+		{name: "get unknown", method: "GET", path: "/api/passkeys/" + missingKey, token: "admin", want: 404},
+		{name: "get outside scope", method: "GET", path: "/api/passkeys/" + curatorKey, token: "admin", want: 403},
+		{name: "get without token", method: "GET", path: target, want: 401},
+		{name: "update", method: "PUT", path: target, token: "admin", body: `{"operator":"target","description":"updated","is_active":true}`, want: 200},
+		{name: "update self", method: "PUT", path: "/api/passkeys/" + adminKey, token: "admin", body: `{"operator":"admin"}`, want: 403},
+		{name: "update missing body", method: "PUT", path: target, token: "admin", want: 400},
+		{name: "update unknown", method: "PUT", path: "/api/passkeys/" + missingKey, token: "admin", body: `{"operator":"x"}`, want: 404},
+		{name: "sessions", method: "GET", path: target + "/sessions", token: "admin", want: 200},
+		{name: "sessions outside scope", method: "GET", path: "/api/passkeys/" + superadminKey + "/sessions", token: "admin", want: 403},
+		{name: "delete session", method: "DELETE", path: target + "/sessions/" + fixtures.targetSessionID, token: "admin", want: 200},
+		{name: "delete session unknown", method: "DELETE", path: target + "/sessions/nope", token: "admin", want: 404},
+		{name: "credentials", method: "GET", path: target + "/credentials", token: "admin", want: 200},
+		{name: "credentials unknown passkey", method: "GET", path: "/api/passkeys/" + missingKey + "/credentials", token: "admin", want: 404},
+		{name: "delete credential unknown", method: "DELETE", path: target + "/credentials/999", token: "admin", want: 404},
+		{name: "delete credential invalid id", method: "DELETE", path: target + "/credentials/abc", token: "admin", want: 400},
+		{name: "reset enrollment", method: "POST", path: target + "/enrollment/reset", token: "admin", want: 200},
+		{name: "reset enrollment forbidden", method: "POST", path: target + "/enrollment/reset", token: "reader", want: 403},
+		{name: "quota", method: "GET", path: target + "/quota", token: "admin", want: 200},
+		{name: "quota unknown", method: "GET", path: "/api/passkeys/" + missingKey + "/quota", token: "admin", want: 404},
+		{name: "grants", method: "GET", path: target + "/grants", token: "admin", want: 200},
+		{name: "create grant", method: "POST", path: target + "/grants", token: "admin", body: `{"fields":["bioactivity"],"scope_type":"ids","scope_values":["MNP002"]}`, want: 200},
+		{name: "create grant unknown field", method: "POST", path: target + "/grants", token: "admin", body: `{"fields":["Weight"]}`, want: 422},
+		{name: "create grant missing body", method: "POST", path: target + "/grants", token: "admin", want: 400},
+		{name: "delete grant", method: "DELETE", path: fmt.Sprintf("%s/grants/%d", target, fixtures.grantID), token: "admin", want: 200},
+		{name: "delete grant unknown", method: "DELETE", path: target + "/grants/999", token: "admin", want: 404},
+		{name: "delete grant invalid id", method: "DELETE", path: target + "/grants/abc", token: "admin", want: 400},
+		{name: "logout everywhere", method: "POST", path: target + "/logout-all", token: "admin", want: 200},
+		{name: "logout everywhere outside scope", method: "POST", path: "/api/passkeys/" + curatorKey + "/logout-all", token: "admin", want: 403},
+		{name: "toggle", method: "POST", path: target + "/toggle", token: "admin", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var p struct {
+					IsActive bool `json:"is_active"`
+				}
+				decode(t, data, &p)
+				if p.IsActive {
+					t.Error("toggle should disable an active passkey")
+				}
+			}},
+		{name: "toggle self", method: "POST", path: "/api/passkeys/" + adminKey + "/toggle", token: "admin", want: 403},
+		{name: "toggle without token", method: "POST", path: target + "/toggle", want: 401},
+		{name: "delete", method: "DELETE", path: "/api/passkeys/" + victimKey, token: "admin", want: 200},
+		{name: "delete again", method: "DELETE", path: "/api/passkeys/" + victimKey, token: "admin", want: 404},
+		{name: "delete self", method: "DELETE", path: "/api/passkeys/" + adminKey, token: "admin", want: 403},
+		{name: "delete forbidden", method: "DELETE", path: "/api/passkeys/" + viewerKey, token: "curator", want: 403},
+	}
+}
+
+func dataCases() []routeCase {
+	return []routeCase{
+		{name: "get", method: "GET", path: "/api/data/MNP001", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var compound map[string]json.RawMessage
+				decode(t, data, &compound)
+				for _, field := range []string{"ms2", "bioactivity", "nmr_13c_data"} {
+					if _, ok := compound[field]; ok {
+						t.Errorf("public record exposes protected field %s", field)
+					}
+				}
+				if _, ok := compound["synonyms"]; !ok {
+					t.Error("public record should include synonyms")
+				}
+			}},
+		{name: "get unknown", method: "GET", path: "/api/data/NOPE", want: 404},
+		{name: "structure", method: "GET", path: "/api/data/MNP001/structure", want: 200, check: expectString("MOL MNP001")},
+		{name: "structure unknown", method: "GET", path: "/api/data/NOPE/structure", want: 404},
+		{name: "statistics", method: "GET", path: "/api/data/statistics", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var stats struct {
+					TotalCompounds  int `json:"total_compounds"`
+					BioactivityData int `json:"bioactivity_data"`
+					MSData          int `json:"ms_data"`
+					NMRData         int `json:"nmr_data"`
+				}
+				decode(t, data, &stats)
+				// MNP001 有 MS1_H 和 MS2，MNP003 只有 MS1_H，MNP002 的 MS2 为空字符串
+				if stats.TotalCompounds != 3 || stats.BioactivityData != 1 || stats.MSData != 2 || stats.NMRData != 1 {
+					t.Errorf("statistics = %+v", stats)
+				}
+			}},
+		{name: "item types", method: "GET", path: "/api/data/item-types", want: 200},
+		{name: "descriptions", method: "GET", path: "/api/data/descriptions", want: 200},
+		{name: "sources", method: "GET", path: "/api/data/sources", want: 200},
+		{name: "adducts", method: "GET", path: "/api/data/adducts", want: 200},
+		{name: "isotope pattern", method: "GET", path: "/api/data/MNP001/isotope-pattern", want: 200},
+		{name: "isotope pattern unknown adduct", method: "GET", path: "/api/data/MNP001/isotope-pattern?adduct=nope", want: 422},
+		{name: "isotope pattern invalid resolution", method: "GET", path: "/api/data/MNP001/isotope-pattern?resolution=x", want: 400},
+		{name: "isotope pattern NaN resolution", method: "GET", path: "/api/data/MNP001/isotope-pattern?resolution=NaN", want: 400},
+		{name: "isotope pattern infinite resolution", method: "GET", path: "/api/data/MNP001/isotope-pattern?resolution=Inf", want: 400},
+		{name: "isotope pattern NaN min intensity", method: "GET", path: "/api/data/MNP001/isotope-pattern?min_intensity=NaN", want: 400},
+		{name: "isotope pattern unknown", method: "GET", path: "/api/data/NOPE/isotope-pattern", want: 404},
+		{name: "synonyms", method: "GET", path: "/api/data/MNP001/synonyms", want: 200},
+		{name: "create synonym", method: "POST", path: "/api/data/MNP001/synonyms", token: "curator", body: `{"name":"Dextrose"}`, want: 200},
+		{name: "create synonym invalid type", method: "POST", path: "/api/data/MNP001/synonyms", token: "curator", body: `{"name":"x","name_type":"nickname"}`, want: 422},
+		{name: "create synonym unknown compound", method: "POST", path: "/api/data/NOPE/synonyms", token: "curator", body: `{"name":"x"}`, want: 404},
+		{name: "create synonym forbidden", method: "POST", path: "/api/data/MNP001/synonyms", token: "reader", body: `{"name":"x"}`, want: 403},
+		{name: "create synonym without token", method: "POST", path: "/api/data/MNP001/synonyms", body: `{"name":"x"}`, want: 401},
+		{name: "delete synonym", method: "DELETE", path: fmt.Sprintf("/api/data/MNP001/synonyms/%d", fixtures.synonymID), token: "curator", want: 200},
+		{name: "delete synonym unknown", method: "DELETE", path: "/api/data/MNP001/synonyms/999", token: "curator", want: 404},
+		{name: "delete synonym invalid id", method: "DELETE", path: "/api/data/MNP001/synonyms/abc", token: "curator", want: 400},
+		{name: "protected", method: "GET", path: "/api/data/MNP001/protected", token: "reader", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var protected struct {
+					MS2                string            `json:"ms2"`
+					BioactivityRecords []json.RawMessage `json:"bioactivity_records"`
+				}
+				decode(t, data, &protected)
+				if protected.MS2 == "" || len(protected.BioactivityRecords) != 1 {
+					t.Errorf("protected data = %s", data)
+				}
+			}},
+		{name: "protected without grant", method: "GET", path: "/api/data/MNP001/protected", token: "viewer", want: 403},
+		{name: "protected without token", method: "GET", path: "/api/data/MNP001/protected", want: 401},
+		{name: "protected unknown", method: "GET", path: "/api/data/NOPE/protected", token: "reader", want: 404},
+		{name: "ms2 full", method: "GET", path: "/api/data/MNP001/ms2-full", token: "reader", want: 200, check: expectString("163.06:100,145.05:40")},
+		{name: "ms2 full without grant", method: "GET", path: "/api/data/MNP001/ms2-full", token: "viewer", want: 403},
+		{name: "ms2 full without token", method: "GET", path: "/api/data/MNP001/ms2-full", want: 401},
+		{name: "ms2 full unknown", method: "GET", path: "/api/data/NOPE/ms2-full", token: "reader", want: 404},
+		{name: "bioactivity", method: "GET", path: "/api/data/MNP001/bioactivity", token: "reader", want: 200},
+		{name: "bioactivity without grant", method: "GET", path: "/api/data/MNP001/bioactivity", token: "viewer", want: 403},
+		{name: "bioactivity without token", method: "GET", path: "/api/data/MNP001/bioactivity", want: 401},
+		{name: "create bioactivity", method: "POST", path: "/api/data/MNP001/bioactivity", token: "curator", body: `{"endpoint":"MIC","value":4,"units":"ug/mL"}`, want: 200},
+		{name: "create bioactivity invalid endpoint", method: "POST", path: "/api/data/MNP001/bioactivity", token: "curator", body: `{"endpoint":"XYZ"}`, want: 422},
+		{name: "create bioactivity unknown compound", method: "POST", path: "/api/data/NOPE/bioactivity", token: "curator", body: `{"endpoint":"MIC"}`, want: 404},
+		{name: "create bioactivity forbidden", method: "POST", path: "/api/data/MNP001/bioactivity", token: "reader", body: `{"endpoint":"MIC"}`, want: 403},
+		{name: "link reference", method: "POST", path: "/api/data/MNP002/references", token: "curator", body: fmt.Sprintf(`{"reference_id":%d}`, fixtures.referenceID), want: 200},
+		{name: "link unknown reference", method: "POST", path: "/api/data/MNP002/references", token: "curator", body: `{"reference_id":999}`, want: 404},
+		{name: "link reference missing body", method: "POST", path: "/api/data/MNP002/references", token: "curator", want: 400},
+		{name: "link reference forbidden", method: "POST", path: "/api/data/MNP002/references", token: "viewer", body: `{"reference_id":1}`, want: 403},
+		{name: "unlink reference", method: "DELETE", path: fmt.Sprintf("/api/data/MNP002/references/%d", fixtures.referenceID), token: "curator", want: 200},
+		{name: "unlink reference again", method: "DELETE", path: fmt.Sprintf("/api/data/MNP002/references/%d", fixtures.referenceID), token: "curator", want: 404},
+		{name: "unlink reference invalid id", method: "DELETE", path: "/api/data/MNP002/references/abc", token: "curator", want: 400},
+	}
+}
+
+func filterCases() []routeCase {
+	return []routeCase{
+		{name: "all", method: "GET", path: "/api/data/filter", want: 200, check: expectTotal(3)},
+		{name: "description case insensitive", method: "GET", path: "/api/data/filter?description=known%20compound", want: 200, check: expectTotal(1)},
+		{name: "source", method: "GET", path: "/api/data/filter?source=streptomyces", want: 200, check: expectTotal(2)},
+		{name: "weight range", method: "GET", path: "/api/data/filter?min_weight=1000", want: 200, check: expectTotal(0)},
+		{name: "invalid limit", method: "GET", path: "/api/data/filter?limit=0", want: 400},
+		{name: "invalid offset", method: "GET", path: "/api/data/filter?offset=-1", want: 400},
+		{name: "invalid weight", method: "GET", path: "/api/data/filter?min_weight=heavy", want: 400},
+		{name: "rank without taxon", method: "GET", path: "/api/data/filter?taxon_rank=genus", want: 400},
+		{name: "unknown rank", method: "GET", path: "/api/data/filter?taxon_rank=tribe&taxon=x", want: 422},
+		{name: "search", method: "GET", path: "/api/data/search?q=glucose", want: 200, check: expectTotal(1)},
+		{name: "search empty query", method: "GET", path: "/api/data/search", want: 400},
+		{name: "search query too long", method: "GET", path: "/api/data/search?q=" + strings.Repeat("a", 201), want: 400},
+		{name: "search too many terms", method: "GET", path: "/api/data/search?q=" + strings.Repeat("a+", 11), want: 400},
+		{name: "search invalid limit", method: "GET", path: "/api/data/search?q=glucose&limit=x", want: 400},
+		{name: "rebuild search index", method: "POST", path: "/api/data/search/rebuild", token: "curator", want: 200},
+		{name: "rebuild search index forbidden", method: "GET", path: "/api/data/search/rebuild", token: "viewer", want: 404},
+		{name: "rebuild search index not allowed", method: "POST", path: "/api/data/search/rebuild", token: "reader", want: 403},
+		{name: "rebuild search index without token", method: "POST", path: "/api/data/search/rebuild", want: 401},
+		{name: "bioactivity filter", method: "GET", path: "/api/bioactivity/filter?endpoint=IC50", token: "reader", want: 200, check: expectTotal(1)},
+		{name: "bioactivity filter value without units", method: "GET", path: "/api/bioactivity/filter?min_value=1", token: "reader", want: 400},
+		{name: "bioactivity filter NaN min value", method: "GET", path: "/api/bioactivity/filter?min_value=NaN&units=uM", token: "reader", want: 400},
+		{name: "bioactivity filter infinite max value", method: "GET", path: "/api/bioactivity/filter?max_value=Inf&units=uM", token: "reader", want: 400},
+		{name: "bioactivity filter forbidden", method: "GET", path: "/api/bioactivity/filter", token: "viewer", want: 403},
+		{name: "bioactivity filter without token", method: "GET", path: "/api/bioactivity/filter", want: 401},
+	}
+}
+
+func bioactivityCases() []routeCase {
+	record := fmt.Sprintf("/api/bioactivity/%d", fixtures.bioactivityID)
+	return []routeCase{
+		{name: "import", method: "POST", path: "/api/bioactivity/import", token: "curator", want: 200},
+		{name: "import forbidden", method: "POST", path: "/api/bioactivity/import", token: "reader", want: 403},
+		{name: "update", method: "PUT", path: record, token: "curator", body: `{"endpoint":"IC50","target":"A549"}`, want: 200},
+		{name: "update invalid id", method: "PUT", path: "/api/bioactivity/abc", token: "curator", body: `{"endpoint":"IC50"}`, want: 400},
+		{name: "update unknown", method: "PUT", path: "/api/bioactivity/999", token: "curator", body: `{"endpoint":"IC50"}`, want: 404},
+		{name: "update without token", method: "PUT", path: record, body: `{"endpoint":"IC50"}`, want: 401},
+		{name: "delete", method: "DELETE", path: record, token: "curator", want: 200},
+		{name: "delete again", method: "DELETE", path: record, token: "curator", want: 404},
+		{name: "delete forbidden", method: "DELETE", path: record, token: "admin", want: 403},
+	}
+}
+
+func auditCases() []routeCase {
+	return []routeCase{
+		{name: "events", method: "GET", path: "/api/audit/events", token: "superadmin", want: 200},
+		{name: "events invalid from", method: "GET", path: "/api/audit/events?from=yesterday", token: "superadmin", want: 400},
+		{name: "events by field", method: "GET", path: "/api/audit/events?field=ms2_full", token: "superadmin", want: 200},
+		// LIKE 通配符不能作为字段
+		{name: "events wildcard field", method: "GET", path: "/api/audit/events?field=%25", token: "superadmin", want: 400},
+		{name: "events forbidden", method: "GET", path: "/api/audit/events", token: "admin", want: 403},
+		{name: "events without token", method: "GET", path: "/api/audit/events", want: 401},
+		{name: "export", method: "GET", path: "/api/audit/events/export", token: "superadmin", want: 200},
+		{name: "export forbidden", method: "GET", path: "/api/audit/events/export", token: "curator", want: 403},
+	}
+}
+
+func organismCases() []routeCase {
+	return []routeCase{
+		{name: "list", method: "GET", path: "/api/organisms", want: 200, check: expectTotal(1)},
+		{name: "list by rank", method: "GET", path: "/api/organisms?rank=genus&name=Streptomyces", want: 200, check: expectTotal(1)},
+		{name: "list rank without name", method: "GET", path: "/api/organisms?rank=genus", want: 400},
+		{name: "list unknown rank", method: "GET", path: "/api/organisms?rank=tribe&name=x", want: 422},
+		{name: "taxa", method: "GET", path: "/api/organisms/ranks/genus", want: 200},
+		{name: "taxa unknown rank", method: "GET", path: "/api/organisms/ranks/tribe", want: 422},
+		{name: "get", method: "GET", path: fmt.Sprintf("/api/organisms/%d", fixtures.organismID), want: 200},
+		{name: "get unknown", method: "GET", path: "/api/organisms/999", want: 404},
+		{name: "get invalid id", method: "GET", path: "/api/organisms/abc", want: 400},
+		{name: "link", method: "POST", path: "/api/organisms/link", token: "curator", want: 200},
+		{name: "link forbidden", method: "POST", path: "/api/organisms/link", token: "reader", want: 403},
+		{name: "link without token", method: "POST", path: "/api/organisms/link", want: 401},
+	}
+}
+
+func referenceCases() []routeCase {
+	spare := fmt.Sprintf("/api/references/%d", fixtures.spareReference)
+	return []routeCase{
+		{name: "list", method: "GET", path: "/api/references", want: 200, check: expectTotal(2)},
+		{name: "list keyword", method: "GET", path: "/api/references?q=glucose", want: 200, check: expectTotal(1)},
+		{name: "list invalid limit", method: "GET", path: "/api/references?limit=x", want: 400},
+		{name: "get", method: "GET", path: fmt.Sprintf("/api/references/%d", fixtures.referenceID), want: 200},
+		{name: "get unknown", method: "GET", path: "/api/references/999", want: 404},
+		{name: "get invalid id", method: "GET", path: "/api/references/abc", want: 400},
+		{name: "export bibtex", method: "GET", path: "/api/references/export?id=MNP001", want: 200},
+		{name: "export without id", method: "GET", path: "/api/references/export", want: 400},
+		{name: "export unknown format", method: "GET", path: "/api/references/export?id=MNP001&format=csv", want: 400},
+		{name: "create", method: "POST", path: "/api/references", token: "curator", body: `{"title":"New paper","year":2024}`, want: 200},
+		{name: "create missing title", method: "POST", path: "/api/references", token: "curator", body: `{"year":2024}`, want: 400},
+		{name: "create forbidden", method: "POST", path: "/api/references", token: "reader", body: `{"title":"x"}`, want: 403},
+		{name: "create without token", method: "POST", path: "/api/references", body: `{"title":"x"}`, want: 401},
+		{name: "update", method: "PUT", path: spare, token: "curator", body: `{"title":"Renamed"}`, want: 200},
+		{name: "update unknown", method: "PUT", path: "/api/references/999", token: "curator", body: `{"title":"x"}`, want: 404},
+		{name: "update missing body", method: "PUT", path: spare, token: "curator", want: 400},
+		{name: "delete", method: "DELETE", path: spare, token: "curator", want: 200},
+		{name: "delete again", method: "DELETE", path: spare, token: "curator", want: 404},
+		{name: "delete forbidden", method: "DELETE", path: spare, token: "viewer", want: 403},
+	}
+}
+
+func metaCases() []routeCase {
+	return []routeCase{
+		{name: "error catalogue", method: "GET", path: "/api/errors", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var catalogue []utils.ErrorDefinition
+				decode(t, data, &catalogue)
+				if len(catalogue) != len(errorCatalogue) {
+					t.Errorf("got %d error codes, want %d", len(catalogue), len(errorCatalogue))
+				}
+			}},
+		{name: "openapi", method: "GET", path: "/api/openapi.json", want: 200},
+		{name: "docs", method: "GET", path: "/api/docs", want: 200},
+		{name: "healthz", method: "GET", path: "/healthz", want: 200, check: expectString("ok")},
+		{name: "readyz", method: "GET", path: "/readyz", want: 200, check: expectReadiness(false)},
+		{name: "metrics", method: "GET", path: "/metrics", token: "metrics", want: 200},
+		{name: "metrics without token", method: "GET", path: "/metrics", want: 401},
+		// 会话令牌不能访问指标接口
+		{name: "metrics session token", method: "GET", path: "/metrics", token: "superadmin", want: 401},
+	}
+}
+
+// expectReadiness 检查就绪检查结果中的 degraded
+func expectReadiness(degraded bool) func(t *testing.T, data json.RawMessage) {
+	return func(t *testing.T, data json.RawMessage) {
+		t.Helper()
+		var readiness services.Readiness
+		decode(t, data, &readiness)
+		if !readiness.Ready || readiness.Degraded != degraded {
+			t.Errorf("ready = %v, degraded = %v, want ready and degraded = %v", readiness.Ready, readiness.Degraded, degraded)
+		}
+	}
+}
+
+func rdkitCases() []routeCase {
+	return []routeCase{
+		{name: "status", method: "GET", path: "/api/rdkit/status", want: 200},
+		{name: "similarity", method: "GET", path: "/api/rdkit/similarity?qfp=fp:OCC1OC(O)C(O)C(O)C1O", want: 200, check: expectString(`["MNP001"]`)},
+		{name: "similarity missing fingerprint", method: "GET", path: "/api/rdkit/similarity", want: 400},
+		{name: "smiles to fingerprint", method: "GET", path: "/api/rdkit/smiles-to-fingerprint?smiles=CCO", want: 200, check: expectString("fp:CCO")},
+		{name: "smiles to fingerprint missing smiles", method: "GET", path: "/api/rdkit/smiles-to-fingerprint", want: 400},
+		{name: "smiles to pdb", method: "GET", path: "/api/rdkit/smiles-to-pdb?smiles=CCO", want: 200, check: expectString("PDB CCO")},
+		{name: "smiles to pdb missing smiles", method: "GET", path: "/api/rdkit/smiles-to-pdb", want: 400},
+		{name: "is substructure", method: "GET", path: "/api/rdkit/is-substructure?smarts_pattern=CO&smiles=CCO", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var result struct {
+					IsSubstructure bool `json:"is_substructure"`
+				}
+				decode(t, data, &result)
+				if !result.IsSubstructure {
+					t.Error("CO should be a substructure of CCO")
+				}
+			}},
+		{name: "is substructure missing smiles", method: "GET", path: "/api/rdkit/is-substructure?smarts_pattern=CO", want: 400},
+		{name: "substructure search", method: "GET", path: "/api/rdkit/substructure-search?smarts_pattern=C=O", want: 200, check: expectString(`["MNP003"]`)},
+		{name: "substructure search missing pattern", method: "GET", path: "/api/rdkit/substructure-search", want: 400},
+		{name: "exact match", method: "GET", path: "/api/rdkit/exact-match?smiles=CNC1C(O)C(O)C(CO)OC1O", want: 200, check: expectString(`["MNP002"]`)},
+		{name: "exact match missing smiles", method: "GET", path: "/api/rdkit/exact-match", want: 400},
+	}
+}
+
+// TestRoutes 按分组请求每个路由，覆盖成功、参数错误、未认证和无权限等情况，
+// 完整运行时还会检查 router.Init 中注册的每个路由至少被请求过一次
+func TestRoutes(t *testing.T) {
+	covered := routeStatuses{}
+	r := newTestEngine(covered)
+
+	groups := []struct {
+		name  string
+		cases []routeCase
+	}{
+		{"auth", authCases()},
+		{"webauthn", webAuthnCases()},
+		{"oidc", oidcCases()},
+		{"passkeys", passkeyCases()},
+		{"data", dataCases()},
+		{"filter", filterCases()},
+		{"bioactivity", bioactivityCases()},
+		{"audit", auditCases()},
+		{"organisms", organismCases()},
+		{"references", referenceCases()},
+		{"rdkit", rdkitCases()},
+		{"meta", metaCases()},
+	}
+	for _, group := range groups {
+		t.Run(group.name, func(t *testing.T) {
+			runCases(t, r, group.cases)
+		})
+	}
+
+	// RDKit 进程未启动时化学计算接口返回 503，就绪检查在 degraded 模式下标记为降级，在 strict 模式下失败
+	t.Run("rdkit unavailable", func(t *testing.T) {
+		services.SetChemistryEngine(nil)
+		defer services.SetChemistryEngine(fakeChemistry{})
+		runCases(t, r, []routeCase{
+			{name: "smiles to fingerprint", method: "GET", path: "/api/rdkit/smiles-to-fingerprint?smiles=CCO", want: 503},
+			{name: "readyz degraded", method: "GET", path: "/readyz", want: 200, check: expectReadiness(true)},
+		})
+
+		config.Config.Set("startup.mode", services.StartupStrict)
+		defer config.Config.Set("startup.mode", "")
+		runCases(t, r, []routeCase{
+			{name: "readyz strict", method: "GET", path: "/readyz", want: 503},
+		})
+	})
+
+	// 只运行部分用例（-run）时不检查覆盖情况
+	if f := flag.Lookup("test.run"); f != nil && f.Value.String() != "" {
+		return
+	}
+	var missing []string
+	for _, route := range r.Routes() {
+		if len(covered[route.Method+" "+route.Path]) == 0 {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("routes without test cases:\n%s", strings.Join(missing, "\n"))
+	}
+
+	// 用例中出现的每个状态码都应在 OpenAPI 文档中声明
+	documented := loadOpenAPI(t, r)
+	var undocumented []string
+	for route, statuses := range covered {
+		for status := range statuses {
+			if !documented[route][status] {
+				undocumented = append(undocumented, fmt.Sprintf("%s %d", route, status))
+			}
+		}
+	}
+	sort.Strings(undocumented)
+	if len(undocumented) > 0 {
+		t.Errorf("responses missing from openapi.json:\n%s", strings.Join(undocumented, "\n"))
+	}
+}
+
+// TestOpenAPI 检查 OpenAPI 文档包含 router.Init 注册的每个路由且没有多余的路由，
+// 并且 docs/openapi.json 与当前的注释一致
+func TestOpenAPI(t *testing.T) {
+	r := newTestEngine(routeStatuses{})
+	documented := loadOpenAPI(t, r)
+
+	var missing []string
+	for _, route := range r.Routes() {
+		key := route.Method + " " + route.Path
+		if _, ok := documented[key]; !ok {
+			missing = append(missing, key)
+		}
+		delete(documented, key)
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("routes missing from openapi.json:\n%s", strings.Join(missing, "\n"))
+	}
+	for route := range documented {
+		t.Errorf("openapi.json documents unregistered route %s", route)
+	}
+
+	generated, err := openapi.Generate("..")
+	if err != nil {
+		t.Fatalf("generate openapi.json: %v", err)
+	}
+	if !bytes.Equal(generated, docs.OpenAPI) {
+		t.Error("docs/openapi.json is out of date, run go generate ./docs")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/docs", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("GET /api/docs: status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+// TestLocalizedErrors 检查错误消息按查询参数 lang 和请求头 Accept-Language 选择语言，
+// 并且每个错误代码都有全部语言的消息
+func TestLocalizedErrors(t *testing.T) {
+	r := newTestEngine(routeStatuses{})
+	const path = "/api/rdkit/smiles-to-fingerprint"
+
+	cases := []struct {
+		name           string
+		query          string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "default", want: utils.LanguageZH},
+		{name: "lang en", query: "?lang=en", want: utils.LanguageEN},
+		{name: "lang en_us", query: "?lang=en_us", acceptLanguage: "zh-CN", want: utils.LanguageEN},
+		{name: "lang zh_cn", query: "?lang=zh_cn", acceptLanguage: "en", want: utils.LanguageZH},
+		{name: "unsupported lang", query: "?lang=fr", acceptLanguage: "en-GB,en;q=0.8", want: utils.LanguageEN},
+		{name: "accept language", acceptLanguage: "en-US", want: utils.LanguageEN},
+		{name: "accept language weights", acceptLanguage: "fr;q=1, zh;q=0.5, en;q=0.9", want: utils.LanguageEN},
+		{name: "accept language refused", acceptLanguage: "en;q=0, zh-TW;q=0.3", want: utils.LanguageZH},
+		{name: "accept language wildcard", acceptLanguage: "fr, *;q=0.5", want: utils.LanguageZH},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", path+tc.query, nil)
+			if tc.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			var body struct {
+				Msg string `json:"msg"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			want := utils.RenderMessage(utils.Message(utils.CodeMissingParameter, tc.want), map[string]interface{}{"param": "smiles"})
+			if body.Msg != want {
+				t.Errorf("msg = %q, want %q", body.Msg, want)
+			}
+			if got := w.Header().Get("Content-Language"); got != tc.want {
+				t.Errorf("Content-Language = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("catalogue", func(t *testing.T) {
+		zh := utils.ErrorCatalogue(utils.LanguageZH)
+		en := utils.ErrorCatalogue(utils.LanguageEN)
+		for i := range zh {
+			if zh[i].Message == string(zh[i].Code) || en[i].Message == string(en[i].Code) || zh[i].Message == en[i].Message {
+				t.Errorf("%s: missing translation (zh %q, en %q)", zh[i].Code, zh[i].Message, en[i].Message)
+			}
+		}
+	})
+}
+
+// TestMetrics 检查 /metrics 导出请求、数据库和 RDKit 指标
+func TestMetrics(t *testing.T) {
+	r := newTestEngine(routeStatuses{})
+	for _, path := range []string{"/api/data/MNP001", "/api/rdkit/smiles-to-fingerprint?smiles=CCO"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+metricsToken)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics: status %d", w.Code)
+	}
+	for _, want := range []string{
+		`mnplib_http_requests_total{method="GET",route="/api/data/:id",status="200"}`,
+		`mnplib_http_request_duration_seconds_bucket{method="GET",route="/api/data/:id",status="200"`,
+		`mnplib_db_query_duration_seconds_count{operation=`,
+		`mnplib_rdkit_request_duration_seconds_count{action="smiles_to_fingerprint",result="ok"}`,
+		"mnplib_rdkit_queue_depth 0",
+		"mnplib_rdkit_pending_requests 0",
+		"mnplib_rdkit_up 1",
+		"mnplib_compounds ",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
+
+// TestMetricsNotConfigured 未配置 metrics.token 时指标接口不可用
+func TestMetricsNotConfigured(t *testing.T) {
+	config.Config.Set("metrics.token", "")
+	defer config.Config.Set("metrics.token", metricsToken)
+	r := newTestEngine(routeStatuses{})
+	runCases(t, r, []routeCase{
+		{name: "metrics", method: "GET", path: "/metrics", token: "metrics", want: 503},
+	})
+}
+
+// TestReadinessCache 缓存时间内返回最近一次的就绪检查结果
+func TestReadinessCache(t *testing.T) {
+	config.Config.Set("health.cache_ttl", 60)
+	defer config.Config.Set("health.cache_ttl", 0)
+	r := newTestEngine(routeStatuses{})
+	services.SetChemistryEngine(nil)
+	defer services.SetChemistryEngine(fakeChemistry{})
+	runCases(t, r, []routeCase{
+		{name: "readyz degraded", method: "GET", path: "/readyz", want: 200, check: expectReadiness(true)},
+	})
+
+	// strict 模式下重新检查会失败，缓存时间内仍返回上一次的结果
+	config.Config.Set("startup.mode", services.StartupStrict)
+	defer config.Config.Set("startup.mode", "")
+	runCases(t, r, []routeCase{
+		{name: "readyz cached", method: "GET", path: "/readyz", want: 200, check: expectReadiness(true)},
+	})
+}
+
+// TestIPRateLimit 检查无需认证的接口按客户端 IP 限制频率，登录接口的限制单独计数
+func TestIPRateLimit(t *testing.T) {
+	r := newTestEngine(routeStatuses{})
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "198.51.100.7:40000"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	defer config.Config.Set("rate_limit.public", 0)
+	defer config.Config.Set("rate_limit.login", 0)
+
+	config.Config.Set("rate_limit.login", 2)
+	for i := 0; i < 2; i++ {
+		if w := send("POST", "/api/auth/login", `{}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("login %d: status %d, want 400", i+1, w.Code)
+		}
+	}
+	w := send("POST", "/api/auth/login", `{}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("login 3: status %d, want 429", w.Code)
+	}
+	checkBody(t, w)
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After missing")
+	}
+	// 登录接口的限制不影响其他接口
+	if w := send("GET", "/api/data/MNP001", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /api/data/MNP001: status %d, want 200", w.Code)
+	}
+
+	config.Config.Set("rate_limit.public", 1)
+	if w := send("GET", "/api/data/search?q=glucose", ""); w.Code != http.StatusOK {
+		t.Fatalf("search 1: status %d, want 200", w.Code)
+	}
+	if w := send("GET", "/api/data/search?q=glucose", ""); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("search 2: status %d, want 429", w.Code)
+	}
+}
+
+// TestBioactivityFilterQuota 检查活性筛选按返回的记录数计入配额，剩余配额不足时只返回剩余数量的记录
+func TestBioactivityFilterQuota(t *testing.T) {
+	r := newTestEngine(routeStatuses{})
+	for _, id := range []string{"MNP001", "MNP002", "MNP003"} {
+		record := models.Bioactivity{DataID: id, Endpoint: models.EndpointGI50, Target: "HeLa", Qualifier: "=", Value: floatPtr(5), Units: "uM"}
+		if err := services.CreateBioactivity(&record); err != nil {
+			t.Fatal(err)
+		}
+		defer services.DeleteBioactivity(record.ID)
+	}
+	clearUsage := func() {
+		database.GetDB().Table("quota_usage").Where("Passkey = ?", readerKey).Delete(&models.QuotaUsage{})
+	}
+	clearUsage()
+	defer clearUsage()
+	config.Config.Set("quota.daily", 2)
+	defer config.Config.Set("quota.daily", 0)
+
+	used := func(t *testing.T) int {
+		t.Helper()
+		reader, err := services.GetPasskey(readerKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		quotas, err := services.GetQuotaUsage(reader, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return quotas[0].Used
+	}
+
+	runCases(t, r, []routeCase{
+		{name: "no records", method: "GET", path: "/api/bioactivity/filter?endpoint=MBC", token: "reader", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				if got := used(t); got != 0 {
+					t.Errorf("used = %d, want 0", got)
+				}
+			}},
+		{name: "limited to remaining quota", method: "GET", path: "/api/bioactivity/filter?endpoint=GI50&limit=10", token: "reader", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var page struct {
+					Data  []json.RawMessage `json:"data"`
+					Total int               `json:"total"`
+				}
+				decode(t, data, &page)
+				if len(page.Data) != 2 || page.Total != 3 {
+					t.Errorf("returned %d of %d records, want 2 of 3", len(page.Data), page.Total)
+				}
+				if got := used(t); got != 2 {
+					t.Errorf("used = %d, want 2", got)
+				}
+			}},
+		{name: "quota exceeded", method: "GET", path: "/api/bioactivity/filter?endpoint=GI50", token: "reader", want: 429},
+	})
+}
+
+// TestAuditPasskeyLabel 检查审计日志、按 passkey 查询和 CSV 导出中都不出现 passkey 原文
+func TestAuditPasskeyLabel(t *testing.T) {
+	r := newTestEngine(routeStatuses{})
+	send := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+fixtures.tokens[token])
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d, want 200; body: %s", path, w.Code, w.Body.String())
+		}
+		return w
+	}
+	flush := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := services.FlushAuditEvents(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send("/api/data/MNP001/protected", "reader")
+	flush()
+	export := send("/api/audit/events/export?passkey="+readerKey, "superadmin")
+	if strings.Contains(export.Body.String(), readerKey) {
+		t.Error("CSV export contains the passkey")
+	}
+	if !strings.Contains(export.Body.String(), services.PasskeyLabel(readerKey)) {
+		t.Error("CSV export is missing the passkey label")
+	}
+	flush()
+
+	// 导出请求本身记入审计日志，其查询条件中的 passkey 也替换为 passkey_label
+	for _, path := range []string{
+		"/api/audit/events?passkey=" + readerKey,
+		"/api/audit/events?passkey_label=" + services.PasskeyLabel(readerKey),
+		"/api/audit/events?action=" + models.AuditActionExportAudit,
+	} {
+		w := send(path, "superadmin")
+		if strings.Contains(w.Body.String(), readerKey) {
+			t.Errorf("GET %s: response contains the passkey", path)
+		}
+		var page struct {
+			Data []models.AuditEvent `json:"data"`
+		}
+		decode(t, checkBody(t, w), &page)
+		if len(page.Data) == 0 {
+			t.Errorf("GET %s: no events", path)
+		}
+	}
+}
+
+// TestMemoryRepositories 控制器通过仓储接口访问化合物和 passkey，替换为内存实现后不再读取数据库中的记录
+func TestMemoryRepositories(t *testing.T) {
+	enrolledAt := time.Now()
+	controllers.SetRepositories(
+		repository.NewMemoryCompoundRepository(models.Data{
+			ID:        "MEM001",
+			ItemName:  strPtr("Memory compound"),
+			Structure: strPtr("MOL MEM001"),
+			MS1_H:     floatPtr(100),
+		}),
+		repository.NewMemoryPasskeyRepository(
+			models.Passkey{Passkey: "memory-enrolled", Role: models.RoleViewer, IsActive: true, EnrolledAt: &enrolledAt},
+			models.Passkey{Passkey: "memory-inactive", Role: models.RoleViewer},
+		),
+	)
+	t.Cleanup(func() {
+		controllers.SetRepositories(repository.NewGormCompoundRepository(), repository.NewGormPasskeyRepository())
+	})
+
+	runCases(t, newTestEngine(routeStatuses{}), []routeCase{
+		{name: "get", method: "GET", path: "/api/data/MEM001", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var compound models.PublicData
+				decode(t, data, &compound)
+				if compound.ItemName == nil || *compound.ItemName != "Memory compound" {
+					t.Errorf("compound = %s", data)
+				}
+			}},
+		{name: "get database record", method: "GET", path: "/api/data/MNP001", want: 404},
+		{name: "structure", method: "GET", path: "/api/data/MEM001/structure", want: 200, check: expectString("MOL MEM001")},
+		{name: "statistics", method: "GET", path: "/api/data/statistics", want: 200,
+			check: func(t *testing.T, data json.RawMessage) {
+				var stats struct {
+					TotalCompounds int `json:"total_compounds"`
+					MSData         int `json:"ms_data"`
+				}
+				decode(t, data, &stats)
+				if stats.TotalCompounds != 1 || stats.MSData != 1 {
+					t.Errorf("statistics = %+v", stats)
+				}
+			}},
+		{name: "create synonym unknown compound", method: "POST", path: "/api/data/MNP001/synonyms", token: "curator", body: `{"name":"x"}`, want: 404},
+		{name: "login enrolled", method: "POST", path: "/api/auth/login", body: `{"passkey":"memory-enrolled"}`, want: 401},
+		{name: "login inactive", method: "POST", path: "/api/auth/login", body: `{"passkey":"memory-inactive"}`, want: 401},
+		{name: "login database passkey", method: "POST", path: "/api/auth/login", body: fmt.Sprintf(`{"passkey":%q}`, readerKey), want: 401},
+	})
+}
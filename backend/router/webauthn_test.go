@@ -0,0 +1,302 @@
+package router
+
+import (
+	"backend/database"
+	"backend/models"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+)
+
+// softAuthenticator 软件实现的认证器：使用 P-256 密钥、"none" 格式的证明，签名计数由测试指定
+type softAuthenticator struct {
+	origin       string
+	rpID         string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{origin: "http://localhost", rpID: "localhost", key: key, credentialID: id}
+}
+
+var b64 = base64.RawURLEncoding
+
+// ceremonyOptions 注册和登录选项中认证器需要的字段
+type ceremonyOptions struct {
+	CeremonyID string `json:"ceremony_id"`
+	Options    struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			User      struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"publicKey"`
+	} `json:"options"`
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremonyType, challenge string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{"type": ceremonyType, "challenge": challenge, "origin": a.origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// authData 认证器数据：RP ID 摘要、标志（UP、UV，attested 时加 AT）、签名计数，attested 时附带凭据
+func (a *softAuthenticator) authData(t *testing.T, signCount uint32, attested bool) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	var buf bytes.Buffer
+	buf.Write(rpIDHash[:])
+	flags := byte(0x01 | 0x04)
+	if attested {
+		flags |= 0x40
+	}
+	buf.WriteByte(flags)
+	binary.Write(&buf, binary.BigEndian, signCount)
+	if attested {
+		buf.Write(make([]byte, 16)) // AAGUID
+		binary.Write(&buf, binary.BigEndian, uint16(len(a.credentialID)))
+		buf.Write(a.credentialID)
+		coseKey, err := webauthncbor.Marshal(map[int]interface{}{
+			1:  2,  // kty: EC2
+			3:  -7, // alg: ES256
+			-1: 1,  // crv: P-256
+			-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+			-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(coseKey)
+	}
+	return buf.Bytes()
+}
+
+// register 按注册选项创建凭据，返回 navigator.credentials.create() 的 JSON 结果
+func (a *softAuthenticator) register(t *testing.T, options ceremonyOptions) json.RawMessage {
+	t.Helper()
+	userHandle, err := b64.DecodeString(options.Options.PublicKey.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.userHandle = userHandle
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(t, 0, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.credential(t, map[string]interface{}{
+		"clientDataJSON":    b64.EncodeToString(a.clientData(t, "webauthn.create", options.Options.PublicKey.Challenge)),
+		"attestationObject": b64.EncodeToString(attestation),
+		"transports":        []string{"usb"},
+	})
+}
+
+// login 按登录选项生成签名计数为 signCount 的断言，返回 navigator.credentials.get() 的 JSON 结果
+func (a *softAuthenticator) login(t *testing.T, options ceremonyOptions, signCount uint32) json.RawMessage {
+	t.Helper()
+	authData := a.authData(t, signCount, false)
+	clientData := a.clientData(t, "webauthn.get", options.Options.PublicKey.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.credential(t, map[string]interface{}{
+		"clientDataJSON":    b64.EncodeToString(clientData),
+		"authenticatorData": b64.EncodeToString(authData),
+		"signature":         b64.EncodeToString(signature),
+		"userHandle":        b64.EncodeToString(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]interface{}) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       b64.EncodeToString(a.credentialID),
+		"rawId":    b64.EncodeToString(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// postJSON 发送 JSON 请求，检查状态码和错误代码（want 为 200 时 wantError 为空），返回 data 字段
+func postJSON(t *testing.T, r *gin.Engine, path string, body interface{}, want int, wantError string) json.RawMessage {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != want {
+		t.Fatalf("POST %s: status %d, want %d; body: %s", path, w.Code, want, w.Body.String())
+	}
+	var resp struct {
+		Error string          `json:"error"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if resp.Error != wantError {
+		t.Fatalf("POST %s: error %q, want %q", path, resp.Error, wantError)
+	}
+	return resp.Data
+}
+
+// TestWebAuthnCeremonies 使用软件认证器完成首次注册和登录，检查注册码只能使用一次，以及签名计数未增加时拒绝登录
+func TestWebAuthnCeremonies(t *testing.T) {
+	const enrollKey = "00000000-0000-0000-0000-00000000000a"
+	p := models.Passkey{Passkey: enrollKey, Operator: "webauthn", Role: models.RoleViewer, IsActive: true, ParentID: strPtr(adminKey), Extends: "admin"}
+	if err := database.GetDB().Table("passkeys").Create(&p).Error; err != nil {
+		t.Fatal(err)
+	}
+	r := newTestEngine(routeStatuses{})
+	begin := func(path string, body interface{}) ceremonyOptions {
+		t.Helper()
+		var options ceremonyOptions
+		decode(t, postJSON(t, r, path, body, http.StatusOK, ""), &options)
+		return options
+	}
+	enroll := map[string]string{"enrollment_code": enrollKey, "name": "Lab key"}
+
+	// 同一注册码开始两次注册，只有先完成的一次成功
+	first, second := newSoftAuthenticator(t), newSoftAuthenticator(t)
+	firstOptions := begin("/api/auth/webauthn/enroll/begin", enroll)
+	secondOptions := begin("/api/auth/webauthn/enroll/begin", enroll)
+
+	var registration struct {
+		Credential models.WebAuthnCredential `json:"credential"`
+		Login      *struct {
+			Token string `json:"token"`
+			Role  string `json:"role"`
+		} `json:"login"`
+	}
+	decode(t, postJSON(t, r, "/api/auth/webauthn/register/finish", map[string]interface{}{
+		"ceremony_id": firstOptions.CeremonyID,
+		"credential":  first.register(t, firstOptions),
+	}, http.StatusOK, ""), &registration)
+	if registration.Credential.Name != "Lab key" || registration.Login == nil || registration.Login.Token == "" || registration.Login.Role != models.RoleViewer {
+		t.Fatalf("registration = %+v", registration)
+	}
+	// 注册后签发的令牌使用注销后的令牌版本
+	req := httptest.NewRequest("GET", "/api/auth/quota", nil)
+	req.Header.Set("Authorization", "Bearer "+registration.Login.Token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("token issued at enrollment: status %d, want 200", w.Code)
+	}
+
+	postJSON(t, r, "/api/auth/webauthn/register/finish", map[string]interface{}{
+		"ceremony_id": secondOptions.CeremonyID,
+		"credential":  second.register(t, secondOptions),
+	}, http.StatusUnauthorized, "ENROLLMENT_CODE_CONSUMED")
+	postJSON(t, r, "/api/auth/webauthn/enroll/begin", enroll, http.StatusUnauthorized, "PASSKEY_ENROLLED")
+	postJSON(t, r, "/api/auth/login", map[string]string{"passkey": enrollKey}, http.StatusUnauthorized, "PASSKEY_ENROLLED")
+
+	// 同一仪式只能完成一次
+	postJSON(t, r, "/api/auth/webauthn/register/finish", map[string]interface{}{
+		"ceremony_id": firstOptions.CeremonyID,
+		"credential":  first.register(t, firstOptions),
+	}, http.StatusUnprocessableEntity, "CEREMONY_NOT_FOUND")
+
+	// 使用可发现凭据登录，签名计数递增
+	loginWith := func(a *softAuthenticator, signCount uint32, want int, wantError string) json.RawMessage {
+		t.Helper()
+		options := begin("/api/auth/webauthn/login/begin", nil)
+		return postJSON(t, r, "/api/auth/webauthn/login/finish", map[string]interface{}{
+			"ceremony_id": options.CeremonyID,
+			"credential":  a.login(t, options, signCount),
+		}, want, wantError)
+	}
+	var login struct {
+		Token    string `json:"token"`
+		Operator string `json:"operator"`
+	}
+	decode(t, loginWith(first, 1, http.StatusOK, ""), &login)
+	if login.Token == "" || login.Operator != "webauthn" {
+		t.Fatalf("login = %+v", login)
+	}
+	loginWith(first, 5, http.StatusOK, "")
+
+	var credential models.WebAuthnCredential
+	if err := database.GetDB().Table("webauthn_credentials").Where(map[string]interface{}{"Passkey": enrollKey}).Take(&credential).Error; err != nil {
+		t.Fatal(err)
+	}
+	if credential.SignCount != 5 || credential.LastUsedAt == nil {
+		t.Errorf("credential after login: sign count %d, last used %v", credential.SignCount, credential.LastUsedAt)
+	}
+
+	// 签名计数未超过已记录的值，认证器可能已被复制
+	loginWith(first, 5, http.StatusUnauthorized, "CREDENTIAL_CLONED")
+	loginWith(first, 3, http.StatusUnauthorized, "CREDENTIAL_CLONED")
+
+	// 未注册的认证器和被篡改的签名
+	loginWith(second, 10, http.StatusUnauthorized, "WEBAUTHN_FAILED")
+	options := begin("/api/auth/webauthn/login/begin", nil)
+	var assertion map[string]interface{}
+	if err := json.Unmarshal(first.login(t, options, 10), &assertion); err != nil {
+		t.Fatal(err)
+	}
+	// 签名针对计数为 10 的认证器数据
+	assertion["response"].(map[string]interface{})["authenticatorData"] = b64.EncodeToString(first.authData(t, 11, false))
+	postJSON(t, r, "/api/auth/webauthn/login/finish", map[string]interface{}{
+		"ceremony_id": options.CeremonyID,
+		"credential":  assertion,
+	}, http.StatusUnauthorized, "WEBAUTHN_FAILED")
+
+	// 没有操作者名称、且短于 UUID 的注册码；已注册的凭据不能再绑定到其他账户，注册码随之保持可用
+	const shortKey = "short"
+	short := models.Passkey{Passkey: shortKey, Role: models.RoleViewer, IsActive: true, ParentID: strPtr(adminKey), Extends: "admin"}
+	if err := database.GetDB().Table("passkeys").Create(&short).Error; err != nil {
+		t.Fatal(err)
+	}
+	shortEnroll := map[string]string{"enrollment_code": shortKey}
+	duplicateOptions := begin("/api/auth/webauthn/enroll/begin", shortEnroll)
+	postJSON(t, r, "/api/auth/webauthn/register/finish", map[string]interface{}{
+		"ceremony_id": duplicateOptions.CeremonyID,
+		"credential":  first.register(t, duplicateOptions),
+	}, http.StatusConflict, "CREDENTIAL_EXISTS")
+	third := newSoftAuthenticator(t)
+	thirdOptions := begin("/api/auth/webauthn/enroll/begin", shortEnroll)
+	postJSON(t, r, "/api/auth/webauthn/register/finish", map[string]interface{}{
+		"ceremony_id": thirdOptions.CeremonyID,
+		"credential":  third.register(t, thirdOptions),
+	}, http.StatusOK, "")
+}
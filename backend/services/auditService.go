@@ -0,0 +1,256 @@
+package services
+
+import (
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 审计事件异步批量写入：队列满时退化为同步写入，不丢弃事件
+const (
+	auditQueueSize     = 1024
+	auditBatchSize     = 100
+	auditFlushInterval = time.Second
+)
+
+// 默认单次导出的最大记录数，可通过 audit.export_limit 配置
+const defaultAuditExportLimit = 100000
+
+var (
+	auditQueue     = make(chan models.AuditEvent, auditQueueSize)
+	auditFlushReqs = make(chan chan struct{})
+	auditOnce      sync.Once
+)
+
+// RecordAuditEvent 记录一条审计事件，写入失败只记录日志，不影响请求
+func RecordAuditEvent(event models.AuditEvent) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	event.Operator = truncateString(event.Operator, 255)
+	event.ResourceID = truncateString(event.ResourceID, 63)
+	event.Fields = truncateString(event.Fields, 255)
+	event.ClientIP = truncateString(event.ClientIP, 63)
+	event.UserAgent = truncateString(event.UserAgent, 511)
+	event.Detail = truncateString(event.Detail, 1023)
+	recordProtectedRead(event)
+
+	auditOnce.Do(func() { go runAuditWriter() })
+	select {
+	case auditQueue <- event:
+	default:
+		writeAuditEvents([]models.AuditEvent{event})
+	}
+}
+
+// FlushAuditEvents 等待队列中的审计事件全部写入数据库
+func FlushAuditEvents(ctx context.Context) error {
+	auditOnce.Do(func() { go runAuditWriter() })
+	done := make(chan struct{})
+	select {
+	case auditFlushReqs <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func runAuditWriter() {
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+
+	var batch []models.AuditEvent
+	flush := func() {
+		if len(batch) > 0 {
+			writeAuditEvents(batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case event := <-auditQueue:
+			batch = append(batch, event)
+			if len(batch) >= auditBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case done := <-auditFlushReqs:
+			// 取出队列中剩余的事件后一并写入
+			for drained := false; !drained; {
+				select {
+				case event := <-auditQueue:
+					batch = append(batch, event)
+				default:
+					drained = true
+				}
+			}
+			flush()
+			close(done)
+		}
+	}
+}
+
+func writeAuditEvents(events []models.AuditEvent) {
+	if err := database.GetDB().Table("audit_events").CreateInBatches(events, auditBatchSize).Error; err != nil {
+		utils.LogError(fmt.Errorf("写入审计日志失败（%d 条）: %v", len(events), err))
+	}
+}
+
+// AuditFilter 审计日志查询条件，空值表示不限制
+type AuditFilter struct {
+	Passkey      string // passkey 原文，按其 PasskeyLabel 匹配
+	PasskeyLabel string
+	Action       string
+	ResourceID   string
+	Field        string // 返回的字段中包含该字段
+	Outcome      string
+	ClientIP     string
+	From         *time.Time
+	To           *time.Time
+	Limit        int
+	Offset       int
+}
+
+func (f AuditFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Passkey != "" {
+		db = db.Where(map[string]interface{}{"Passkey_Label": PasskeyLabel(f.Passkey)})
+	}
+	if f.PasskeyLabel != "" {
+		db = db.Where(map[string]interface{}{"Passkey_Label": f.PasskeyLabel})
+	}
+	if f.Action != "" {
+		db = db.Where(map[string]interface{}{"Action": f.Action})
+	}
+	if f.ResourceID != "" {
+		db = db.Where(map[string]interface{}{"Resource_ID": f.ResourceID})
+	}
+	if f.Field != "" {
+		// Fields 以逗号分隔，分别匹配唯一、开头、中间和结尾的位置
+		db = db.Where(clause.Or(
+			clause.Eq{Column: "Fields", Value: f.Field},
+			clause.Like{Column: "Fields", Value: f.Field + ",%"},
+			clause.Like{Column: "Fields", Value: "%," + f.Field + ",%"},
+			clause.Like{Column: "Fields", Value: "%," + f.Field},
+		))
+	}
+	if f.Outcome != "" {
+		db = db.Where(map[string]interface{}{"Outcome": f.Outcome})
+	}
+	if f.ClientIP != "" {
+		db = db.Where(map[string]interface{}{"Client_IP": f.ClientIP})
+	}
+	if f.From != nil {
+		db = db.Where(clause.Gte{Column: "Created_At", Value: *f.From})
+	}
+	if f.To != nil {
+		db = db.Where(clause.Lt{Column: "Created_At", Value: *f.To})
+	}
+	return db
+}
+
+// QueryAuditEvents 按条件分页查询审计事件，按时间倒序排列
+func QueryAuditEvents(filter AuditFilter) ([]models.AuditEvent, int64, error) {
+	db := database.GetDB()
+
+	var total int64
+	if err := filter.apply(db.Table("audit_events")).Count(&total).Error; err != nil {
+		utils.LogError(err)
+		return nil, 0, fmt.Errorf("查询审计日志失败: %v", err)
+	}
+
+	events := []models.AuditEvent{}
+	if err := filter.apply(db.Table("audit_events")).Order(database.Desc("Created_At")).Order(database.Desc("ID")).
+		Limit(filter.Limit).Offset(filter.Offset).Find(&events).Error; err != nil {
+		utils.LogError(err)
+		return nil, 0, fmt.Errorf("查询审计日志失败: %v", err)
+	}
+	return events, total, nil
+}
+
+// AuditExportLimit 返回单次导出的最大记录数
+func AuditExportLimit() int {
+	limit := config.Config.GetInt("audit.export_limit")
+	if limit <= 0 {
+		limit = defaultAuditExportLimit
+	}
+	return limit
+}
+
+// auditCSVHeader CSV 导出的表头
+var auditCSVHeader = []string{"id", "created_at", "passkey_label", "operator", "role", "action", "resource_id", "fields", "client_ip", "user_agent", "outcome", "code", "detail"}
+
+// ExportAuditEventsCSV 按条件逐行导出审计事件为 CSV（按时间倒序，最多 AuditExportLimit 条），返回导出的记录数
+func ExportAuditEventsCSV(w io.Writer, filter AuditFilter) (int, error) {
+	rows, err := filter.apply(database.GetDB().Table("audit_events")).
+		Order(database.Desc("Created_At")).Order(database.Desc("ID")).Limit(AuditExportLimit()).Rows()
+	if err != nil {
+		utils.LogError(err)
+		return 0, fmt.Errorf("查询审计日志失败: %v", err)
+	}
+	defer rows.Close()
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(auditCSVHeader); err != nil {
+		return 0, err
+	}
+
+	db := database.GetDB()
+	count := 0
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := db.ScanRows(rows, &e); err != nil {
+			utils.LogError(err)
+			return count, fmt.Errorf("读取审计日志失败: %v", err)
+		}
+		record := []string{
+			strconv.FormatUint(e.ID, 10),
+			e.CreatedAt.Format(time.RFC3339),
+			e.PasskeyLabel,
+			csvSafe(e.Operator),
+			e.Role,
+			e.Action,
+			csvSafe(e.ResourceID),
+			e.Fields,
+			e.ClientIP,
+			csvSafe(e.UserAgent),
+			e.Outcome,
+			strconv.Itoa(e.Code),
+			csvSafe(e.Detail),
+		}
+		if err := writer.Write(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return count, err
+	}
+	return count, rows.Err()
+}
+
+// csvSafe 防止用户可控的值在电子表格中被当作公式执行
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidEndpoint  = errors.New("不支持的活性终点类型")
+	ErrInvalidQualifier = errors.New("不支持的数值限定符")
+	ErrInvalidUnits     = errors.New("不支持的单位")
+
+	ErrBioactivityNotFound = errors.New("活性记录不存在")
+)
+
+// 单位换算：同一类单位统一换算为标准单位（摩尔浓度→µM，质量浓度→µg/mL）
+var unitConversions = map[string]struct {
+	Standard string
+	Factor   float64
+}{
+	"pM":    {"µM", 1e-6},
+	"nM":    {"µM", 1e-3},
+	"µM":    {"µM", 1},
+	"mM":    {"µM", 1e3},
+	"M":     {"µM", 1e6},
+	"ng/mL": {"µg/mL", 1e-3},
+	"µg/mL": {"µg/mL", 1},
+	"mg/mL": {"µg/mL", 1e3},
+	"mg/kg": {"mg/kg", 1},
+	"%":     {"%", 1},
+}
+
+// NormalizeUnits 统一单位写法（μ/u → µ，ml → mL）
+func NormalizeUnits(units string) string {
+	u := strings.TrimSpace(units)
+	u = strings.ReplaceAll(u, "μ", "µ")
+	if strings.HasPrefix(u, "u") {
+		u = "µ" + u[1:]
+	}
+	u = strings.Replace(u, "/ml", "/mL", 1)
+	u = strings.Replace(u, "/ML", "/mL", 1)
+	return u
+}
+
+// StandardizeValue 将数值换算为标准单位，未知单位返回 ErrInvalidUnits
+func StandardizeValue(value float64, units string) (float64, string, error) {
+	conv, ok := unitConversions[NormalizeUnits(units)]
+	if !ok {
+		return 0, "", ErrInvalidUnits
+	}
+	return value * conv.Factor, conv.Standard, nil
+}
+
+// normalizeQualifier 统一限定符写法
+func normalizeQualifier(q string) string {
+	switch strings.TrimSpace(q) {
+	case "", "=":
+		return "="
+	case "≤", "<=":
+		return "<="
+	case "≥", ">=":
+		return ">="
+	case "≈", "~":
+		return "~"
+	default:
+		return strings.TrimSpace(q)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateBioactivity 校验并补全活性记录（统一终点、限定符、单位并计算标准值）
+func ValidateBioactivity(record *models.Bioactivity) error {
+	record.Endpoint = strings.ToUpper(strings.TrimSpace(record.Endpoint))
+	if !containsString(models.BioactivityEndpoints, record.Endpoint) {
+		return ErrInvalidEndpoint
+	}
+	record.Qualifier = normalizeQualifier(record.Qualifier)
+	if !containsString(models.BioactivityQualifiers, record.Qualifier) {
+		return ErrInvalidQualifier
+	}
+	record.Units = NormalizeUnits(record.Units)
+	record.StandardValue = nil
+	record.StandardUnits = ""
+	if record.Value != nil && record.Units != "" {
+		standard, standardUnits, err := StandardizeValue(*record.Value, record.Units)
+		if err != nil {
+			return err
+		}
+		record.StandardValue = &standard
+		record.StandardUnits = standardUnits
+	}
+	return nil
+}
+
+var (
+	// 活性终点，例如 IC50、MIC、抑制率
+	endpointPattern = regexp.MustCompile(`(?i)(IC ?50|IC₅₀|EC ?50|EC₅₀|GI ?50|CC ?50|LD ?50|ED ?50|MIC|MBC|inhibition(?: rate| ratio)?|抑制率)`)
+	// 数值和单位，例如 "< 10.5 μM"、"= 3.2 ± 0.4 µg/mL"、"45%"。
+	// 单位后需为单词边界，避免将 "12 MCF-7" 解析为 12 M、"100 Mm" 解析为 100 M
+	valuePattern = regexp.MustCompile(`(<=|>=|≤|≥|<|>|=|~|≈)?\s*(\d+(?:\.\d+)?)(?:\s*(?:±|\+/-)\s*\d+(?:\.\d+)?)?\s*((?:pM|nM|[µμu]M|mM|M|ng/m[lL]|[µμu]g/m[lL]|mg/m[lL]|mg/kg)\b|%)`)
+	// 数值前的 "at"，表示测试浓度而不是活性值，例如 "45% inhibition at 10 µM"
+	concentrationPattern = regexp.MustCompile(`(?i)\bat\s*$`)
+	// 紧跟在数值后的括号，例如 "2.1 µM (HeLa)"
+	followingBracketPattern = regexp.MustCompile(`^\s*[（(]([^()（）]+)[)）]`)
+	// 作用对象，例如 "against HeLa cells with ..."
+	targetPattern = regexp.MustCompile(`(?i)(?:against|toward|towards)\s+(?:the\s+)?([^,;，；()（）]+?)(?:\s+(?:cells?|cell lines?|with|at|showing|IC ?50|EC ?50|GI ?50|MIC)\b|[,;，；()（）]|$)`)
+	// 中文作用对象，例如 "对HeLa细胞的"
+	targetPatternZh = regexp.MustCompile(`对\s*([^,;，；()（）的]+?)\s*(?:细胞|的|[,;，；()（）]|$)`)
+	// 括号内的对象，例如 "MIC 8 µg/mL (S. aureus)"
+	bracketPattern = regexp.MustCompile(`[（(]([^()（）]+)[)）]`)
+	// 细胞系命名，例如 HeLa、A549、MCF-7、HL-60
+	cellLinePattern = regexp.MustCompile(`^[A-Za-z]{1,6}[- ]?\d{0,4}[A-Za-z0-9/-]*$`)
+	// 分句
+	segmentPattern = regexp.MustCompile(`[;；\n。]`)
+)
+
+// assayKeywords 根据关键词推断测定类型
+var assayKeywords = []struct {
+	Keyword   string
+	AssayType string
+}{
+	{"cytotox", "cytotoxicity"},
+	{"细胞毒", "cytotoxicity"},
+	{"antiproliferat", "cytotoxicity"},
+	{"antibacterial", "antibacterial"},
+	{"抗菌", "antibacterial"},
+	{"antifungal", "antifungal"},
+	{"抗真菌", "antifungal"},
+	{"antiviral", "antiviral"},
+	{"抗病毒", "antiviral"},
+	{"anti-inflammatory", "anti-inflammatory"},
+	{"抗炎", "anti-inflammatory"},
+	{"antifouling", "antifouling"},
+	{"防污", "antifouling"},
+	{"enzyme", "enzyme inhibition"},
+	{"酶", "enzyme inhibition"},
+}
+
+// canonicalEndpoint 将文本中的终点写法统一为 models 中的常量
+func canonicalEndpoint(raw string) string {
+	e := strings.ToUpper(strings.NewReplacer(" ", "", "₅₀", "50").Replace(raw))
+	switch {
+	case strings.HasPrefix(e, "INHIBITION"), e == "抑制率":
+		return models.EndpointInhibition
+	case containsString(models.BioactivityEndpoints, e):
+		return e
+	default:
+		return models.EndpointOther
+	}
+}
+
+// ParseBioactivityText 从自由文本中解析结构化的活性记录，无法解析的片段会被跳过
+func ParseBioactivityText(text string) []models.Bioactivity {
+	var records []models.Bioactivity
+	lastAssayType := ""
+
+	for _, segment := range segmentPattern.Split(text, -1) {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
+		}
+		lower := strings.ToLower(segment)
+		for _, k := range assayKeywords {
+			if strings.Contains(lower, k.Keyword) {
+				lastAssayType = k.AssayType
+				break
+			}
+		}
+
+		endpointMatches := endpointPattern.FindAllStringIndex(segment, -1)
+		if endpointMatches == nil {
+			continue
+		}
+		// 分句中没有逐个数值标注的对象时使用的对象
+		subject := ""
+		if m := targetPattern.FindStringSubmatch(segment); m != nil {
+			subject = strings.TrimSpace(m[1])
+		} else if m := targetPatternZh.FindStringSubmatch(segment); m != nil {
+			subject = strings.TrimSpace(m[1])
+		} else if m := bracketPattern.FindStringSubmatch(segment); m != nil && !valuePattern.MatchString(m[1]) {
+			subject = strings.TrimSpace(m[1])
+		}
+
+		// 数值一般出现在终点之后，属于之前最近的终点
+		// （如 "inhibition of NO production, IC50 12.5 µM" 取 IC50，"IC50 2.1 µM (HeLa), 3.4 µM (A549)" 为两条记录）
+		for i, endpointMatch := range endpointMatches {
+			regionEnd := len(segment)
+			if i+1 < len(endpointMatches) {
+				regionEnd = endpointMatches[i+1][0]
+			}
+			region := segment[endpointMatch[1]:regionEnd]
+			for _, valueIndex := range valuePattern.FindAllStringSubmatchIndex(region, -1) {
+				if concentrationPattern.MatchString(region[:valueIndex[0]]) {
+					continue
+				}
+				// 紧接在下一个终点之前的数值属于下一个终点，例如 "IC50 12.5 µM, 45% inhibition" 中的 45%
+				endpoint := endpointMatch
+				if i+1 < len(endpointMatches) && strings.TrimSpace(region[valueIndex[1]:]) == "" {
+					endpoint = endpointMatches[i+1]
+				}
+				record, ok := parseBioactivityValue(segment[endpoint[0]:endpoint[1]], region, valueIndex)
+				if !ok {
+					continue
+				}
+				record.AssayType = lastAssayType
+				record.RawText = truncateString(segment, 512)
+
+				valueSubject := subject
+				if m := followingBracketPattern.FindStringSubmatch(region[valueIndex[1]:]); m != nil && !valuePattern.MatchString(m[1]) {
+					valueSubject = strings.TrimSpace(m[1])
+				}
+				assignBioactivitySubject(&record, truncateString(valueSubject, 127))
+
+				if err := ValidateBioactivity(&record); err != nil {
+					continue
+				}
+				records = append(records, record)
+			}
+		}
+	}
+	return records
+}
+
+// parseBioactivityValue 按 valuePattern 在 region 中的匹配位置生成活性记录
+func parseBioactivityValue(endpoint, region string, valueIndex []int) (models.Bioactivity, bool) {
+	group := func(i int) string {
+		if valueIndex[2*i] < 0 {
+			return ""
+		}
+		return region[valueIndex[2*i]:valueIndex[2*i+1]]
+	}
+	value, err := strconv.ParseFloat(group(2), 64)
+	if err != nil {
+		return models.Bioactivity{}, false
+	}
+	return models.Bioactivity{
+		Endpoint:  canonicalEndpoint(endpoint),
+		Qualifier: group(1),
+		Value:     &value,
+		Units:     group(3),
+	}, true
+}
+
+// assignBioactivitySubject 按终点和命名推断对象是微生物、细胞系还是靶点
+func assignBioactivitySubject(record *models.Bioactivity, subject string) {
+	switch {
+	case subject == "":
+	case record.Endpoint == models.EndpointMIC || record.Endpoint == models.EndpointMBC:
+		record.Organism = subject
+		if record.AssayType == "" {
+			record.AssayType = "antimicrobial"
+		}
+	case cellLinePattern.MatchString(subject):
+		record.CellLine = subject
+		if record.AssayType == "" {
+			record.AssayType = "cytotoxicity"
+		}
+	default:
+		record.Target = subject
+	}
+}
+
+func truncateString(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
+
+// BioactivityImportResult 文本导入结果统计
+type BioactivityImportResult struct {
+	Compounds int   `json:"compounds"` // 含有活性文本的化合物数
+	Parsed    int   `json:"parsed"`    // 成功解析出记录的化合物数
+	Skipped   int   `json:"skipped"`   // 已有结构化记录而跳过的化合物数
+	Failed    int   `json:"failed"`    // 未能解析的化合物数
+	Records   int64 `json:"records"`   // 写入的记录数
+}
+
+// ImportBioactivityFromText 从 data.Bioactivity 文本列导入结构化活性记录
+// overwrite 为 false 时跳过已存在结构化记录的化合物
+func ImportBioactivityFromText(overwrite bool) (*BioactivityImportResult, error) {
+	db := database.GetDB()
+
+	var compounds []struct {
+		ID          string `gorm:"column:ID"`
+		Bioactivity string `gorm:"column:Bioactivity"`
+	}
+	result := db.Table("data").
+		Select("ID", "Bioactivity").
+		Where(database.NotEmpty("Bioactivity")).
+		Find(&compounds)
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return nil, fmt.Errorf("获取活性数据失败: %v", result.Error)
+	}
+
+	var existing []string
+	if !overwrite {
+		if err := db.Table("bioactivity").Distinct().Pluck("Data_ID", &existing).Error; err != nil {
+			utils.LogError(err)
+			return nil, fmt.Errorf("获取已有活性记录失败: %v", err)
+		}
+	}
+	existingSet := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		existingSet[id] = true
+	}
+
+	stats := &BioactivityImportResult{Compounds: len(compounds)}
+	for _, compound := range compounds {
+		if existingSet[compound.ID] {
+			stats.Skipped++
+			continue
+		}
+		records := ParseBioactivityText(compound.Bioactivity)
+		if len(records) == 0 {
+			stats.Failed++
+			continue
+		}
+		for i := range records {
+			records[i].DataID = compound.ID
+		}
+
+		tx := db.Begin()
+		if overwrite {
+			if err := tx.Table("bioactivity").Where(map[string]interface{}{"Data_ID": compound.ID}).Delete(&models.Bioactivity{}).Error; err != nil {
+				tx.Rollback()
+				utils.LogError(err)
+				return stats, fmt.Errorf("删除旧活性记录失败: %v", err)
+			}
+		}
+		createResult := tx.Table("bioactivity").Create(&records)
+		if createResult.Error != nil {
+			tx.Rollback()
+			utils.LogError(createResult.Error)
+			return stats, fmt.Errorf("写入活性记录失败: %v", createResult.Error)
+		}
+		if err := tx.Commit().Error; err != nil {
+			utils.LogError(err)
+			return stats, fmt.Errorf("写入活性记录失败: %v", err)
+		}
+		stats.Parsed++
+		stats.Records += createResult.RowsAffected
+	}
+
+	utils.Log(fmt.Sprintf("活性数据导入完成: 化合物%d个, 解析%d个, 跳过%d个, 失败%d个, 写入记录%d条",
+		stats.Compounds, stats.Parsed, stats.Skipped, stats.Failed, stats.Records))
+	return stats, nil
+}
+
+// GetBioactivityByDataID 获取指定化合物的全部活性记录
+func GetBioactivityByDataID(dataID string) ([]models.Bioactivity, error) {
+	var records []models.Bioactivity
+	result := database.GetDB().Table("bioactivity").Where(map[string]interface{}{"Data_ID": dataID}).Order(database.Asc("ID")).Find(&records)
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return nil, fmt.Errorf("获取活性记录失败: %v", result.Error)
+	}
+	return records, nil
+}
+
+// BioactivityFilter 活性记录筛选条件
+type BioactivityFilter struct {
+	Endpoints  []string
+	AssayTypes []string
+	Target     string // 同时匹配 Target、Cell_Line 和 Organism
+	CellLine   string
+	Organism   string
+	MinValue   *float64
+	MaxValue   *float64
+	Units      string
+	Limit      int
+	Offset     int
+}
+
+// BioactivityHit 筛选结果，附带化合物名称
+type BioactivityHit struct {
+	models.Bioactivity
+	ItemName *string `gorm:"column:ItemName" json:"item_name,omitempty"`
+}
+
+// FilterBioactivity 按终点、对象和阈值筛选活性记录
+// 阈值按标准单位比较；上限筛选排除 ">" 类限定的记录，下限筛选排除 "<" 类限定的记录
+func FilterBioactivity(filter BioactivityFilter) ([]BioactivityHit, int64, error) {
+	query := database.GetDB().Table("bioactivity").
+		Joins("JOIN data ON ? = ?", database.Column("data.ID"), database.Column("bioactivity.Data_ID"))
+
+	if len(filter.Endpoints) > 0 {
+		endpoints := make([]string, len(filter.Endpoints))
+		for i, e := range filter.Endpoints {
+			endpoints[i] = canonicalEndpoint(e)
+		}
+		query = query.Where(map[string]interface{}{"bioactivity.Endpoint": endpoints})
+	}
+	if len(filter.AssayTypes) > 0 {
+		query = query.Where(map[string]interface{}{"bioactivity.Assay_Type": filter.AssayTypes})
+	}
+	if filter.Target != "" {
+		like := "%" + filter.Target + "%"
+		query = query.Where(clause.Or(
+			database.LikeFold("bioactivity.Target", like),
+			database.LikeFold("bioactivity.Cell_Line", like),
+			database.LikeFold("bioactivity.Organism", like),
+		))
+	}
+	if filter.CellLine != "" {
+		query = query.Where(database.LikeFold("bioactivity.Cell_Line", "%"+filter.CellLine+"%"))
+	}
+	if filter.Organism != "" {
+		query = query.Where(database.LikeFold("bioactivity.Organism", "%"+filter.Organism+"%"))
+	}
+
+	if filter.MinValue != nil || filter.MaxValue != nil {
+		if filter.MinValue != nil {
+			min, standardUnits, err := StandardizeValue(*filter.MinValue, filter.Units)
+			if err != nil {
+				return nil, 0, err
+			}
+			query = query.Where(clause.Eq{Column: "bioactivity.Standard_Units", Value: standardUnits},
+				clause.Gte{Column: "bioactivity.Standard_Value", Value: min},
+				clause.Neq{Column: "bioactivity.Qualifier", Value: []string{"<", "<="}})
+		}
+		if filter.MaxValue != nil {
+			max, standardUnits, err := StandardizeValue(*filter.MaxValue, filter.Units)
+			if err != nil {
+				return nil, 0, err
+			}
+			query = query.Where(clause.Eq{Column: "bioactivity.Standard_Units", Value: standardUnits},
+				clause.Lte{Column: "bioactivity.Standard_Value", Value: max},
+				clause.Neq{Column: "bioactivity.Qualifier", Value: []string{">", ">="}})
+		}
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		utils.LogError(err)
+		return nil, 0, fmt.Errorf("获取总记录数失败: %v", err)
+	}
+
+	var hits []BioactivityHit
+	result := query.Select("?.*, ?", clause.Table{Name: "bioactivity"}, database.Column("data.ItemName")).
+		Order(database.Asc("bioactivity.Standard_Value")).
+		Offset(filter.Offset).Limit(filter.Limit).
+		Find(&hits)
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return nil, 0, fmt.Errorf("数据库查询失败: %v", result.Error)
+	}
+	return hits, totalCount, nil
+}
+
+// CreateBioactivity 新增活性记录
+func CreateBioactivity(record *models.Bioactivity) error {
+	if err := ValidateBioactivity(record); err != nil {
+		return err
+	}
+	if err := database.GetDB().Table("bioactivity").Create(record).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("创建活性记录失败: %v", err)
+	}
+	return nil
+}
+
+// UpdateBioactivity 更新活性记录
+func UpdateBioactivity(record *models.Bioactivity) error {
+	if err := ValidateBioactivity(record); err != nil {
+		return err
+	}
+	if err := database.GetDB().Table("bioactivity").Save(record).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("更新活性记录失败: %v", err)
+	}
+	return nil
+}
+
+// GetBioactivityByID 根据记录ID获取活性记录，不存在时返回 ErrBioactivityNotFound
+func GetBioactivityByID(id uint) (*models.Bioactivity, error) {
+	var record models.Bioactivity
+	if err := database.GetDB().Table("bioactivity").Where(map[string]interface{}{"ID": id}).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBioactivityNotFound
+		}
+		utils.LogError(err)
+		return nil, fmt.Errorf("查询活性记录失败: %v", err)
+	}
+	return &record, nil
+}
+
+// DeleteBioactivity 删除活性记录，返回删除的行数
+func DeleteBioactivity(id uint) (int64, error) {
+	result := database.GetDB().Table("bioactivity").Where(map[string]interface{}{"ID": id}).Delete(&models.Bioactivity{})
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return 0, fmt.Errorf("删除活性记录失败: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"backend/models"
+	"testing"
+)
+
+// parsedActivity ParseBioactivityText 结果中需要检查的字段
+type parsedActivity struct {
+	Endpoint  string
+	Qualifier string
+	Value     float64
+	Units     string
+	CellLine  string
+	Organism  string
+	AssayType string
+}
+
+func TestParseBioactivityText(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want []parsedActivity
+	}{
+		{
+			name: "cell line",
+			text: "IC50 = 2.1 µM against HeLa cells",
+			want: []parsedActivity{{Endpoint: models.EndpointIC50, Qualifier: "=", Value: 2.1, Units: "µM", CellLine: "HeLa", AssayType: "cytotoxicity"}},
+		},
+		{
+			name: "qualifier and unit spelling",
+			text: "cytotoxic against A549 with IC50 < 10 uM",
+			want: []parsedActivity{{Endpoint: models.EndpointIC50, Qualifier: "<", Value: 10, Units: "µM", CellLine: "A549", AssayType: "cytotoxicity"}},
+		},
+		{
+			name: "cell line name is not a unit",
+			text: "IC50 12 MCF-7 cells",
+		},
+		{
+			name: "unknown unit is not molar",
+			text: "IC50 > 100 Mm",
+		},
+		{
+			name: "molar at end of text",
+			text: "EC50 0.5 M",
+			want: []parsedActivity{{Endpoint: models.EndpointEC50, Qualifier: "=", Value: 0.5, Units: "M"}},
+		},
+		{
+			name: "several values for one endpoint",
+			text: "IC50 2.1 µM (HeLa), 3.4 µM (A549)",
+			want: []parsedActivity{
+				{Endpoint: models.EndpointIC50, Qualifier: "=", Value: 2.1, Units: "µM", CellLine: "HeLa", AssayType: "cytotoxicity"},
+				{Endpoint: models.EndpointIC50, Qualifier: "=", Value: 3.4, Units: "µM", CellLine: "A549", AssayType: "cytotoxicity"},
+			},
+		},
+		{
+			name: "value before the next endpoint and test concentration",
+			text: "IC50 12.5 µM, 45% inhibition at 10 µM",
+			want: []parsedActivity{
+				{Endpoint: models.EndpointIC50, Qualifier: "=", Value: 12.5, Units: "µM"},
+				{Endpoint: models.EndpointInhibition, Qualifier: "=", Value: 45, Units: "%"},
+			},
+		},
+		{
+			name: "nearest endpoint before the value",
+			text: "inhibition of NO production, IC50 12.5 µM",
+			want: []parsedActivity{{Endpoint: models.EndpointIC50, Qualifier: "=", Value: 12.5, Units: "µM"}},
+		},
+		{
+			name: "segments",
+			text: "antibacterial: MIC 8 µg/mL (S. aureus); MIC ≥ 64 µg/ml (E. coli)",
+			want: []parsedActivity{
+				{Endpoint: models.EndpointMIC, Qualifier: "=", Value: 8, Units: "µg/mL", Organism: "S. aureus", AssayType: "antibacterial"},
+				{Endpoint: models.EndpointMIC, Qualifier: ">=", Value: 64, Units: "µg/mL", Organism: "E. coli", AssayType: "antibacterial"},
+			},
+		},
+		{
+			name: "no endpoint",
+			text: "Isolated as a white powder, 12 mg",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			records := ParseBioactivityText(tc.text)
+			if len(records) != len(tc.want) {
+				t.Fatalf("ParseBioactivityText(%q) returned %d records, want %d: %+v", tc.text, len(records), len(tc.want), records)
+			}
+			for i, r := range records {
+				got := parsedActivity{
+					Endpoint:  r.Endpoint,
+					Qualifier: r.Qualifier,
+					Units:     r.Units,
+					CellLine:  r.CellLine,
+					Organism:  r.Organism,
+					AssayType: r.AssayType,
+				}
+				if r.Value != nil {
+					got.Value = *r.Value
+				}
+				if got != tc.want[i] {
+					t.Errorf("record %d = %+v, want %+v", i, got, tc.want[i])
+				}
+				if r.RawText == "" {
+					t.Errorf("record %d: raw_text is empty", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	ErrOutsideDelegationScope = errors.New("只能管理自己创建的 passkey 及其下级")
+	ErrExceedsParentValidity  = errors.New("有效期不能超出创建者自身的有效期")
+	ErrExceedsParentLimit     = errors.New("频率限制和配额不能超出创建者自身的限制")
+	ErrDescendantExceeds      = errors.New("下级 passkey 的角色、有效期或限制超出修改后的范围")
+)
+
+// 沿父级向上查找的最大层数，防止异常数据形成环
+const maxDelegationDepth = 64
+
+// IsDescendant 判断 passkey 是否由 ancestor 直接或间接创建
+func IsDescendant(ancestor, passkey string) (bool, error) {
+	current := passkey
+	for depth := 0; depth < maxDelegationDepth; depth++ {
+		var p models.Passkey
+		result := database.GetDB().Table("passkeys").Select("Passkey", "Parent_ID").
+			Where(map[string]interface{}{"Passkey": current}).Limit(1).Find(&p)
+		if result.Error != nil {
+			utils.LogError(result.Error)
+			return false, fmt.Errorf("查询 passkey 失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 || p.ParentID == nil || *p.ParentID == "" {
+			return false, nil
+		}
+		if *p.ParentID == ancestor {
+			return true, nil
+		}
+		current = *p.ParentID
+	}
+	return false, nil
+}
+
+// GetDescendantPasskeys 按层级返回 root 直接或间接创建的全部 passkey（不含 root 本身）
+func GetDescendantPasskeys(root string) ([]models.Passkey, error) {
+	var descendants []models.Passkey
+	seen := map[string]bool{root: true}
+	level := []string{root}
+	for depth := 0; len(level) > 0 && depth < maxDelegationDepth; depth++ {
+		var children []models.Passkey
+		if err := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Parent_ID": level}).
+			Order(database.Asc("Created_At")).Find(&children).Error; err != nil {
+			utils.LogError(err)
+			return nil, fmt.Errorf("查询下级 passkey 失败: %v", err)
+		}
+		level = level[:0]
+		for _, child := range children {
+			if seen[child.Passkey] {
+				continue
+			}
+			seen[child.Passkey] = true
+			descendants = append(descendants, child)
+			level = append(level, child.Passkey)
+		}
+	}
+	return descendants, nil
+}
+
+// ConstrainToParent 将新建或修改的 passkey 的有效期、频率限制和配额限制在创建者自身的范围内：
+// 有效期未设置时沿用创建者的生效和失效时间，设置了更宽的范围时返回 ErrExceedsParentValidity；
+// 频率限制和配额见 constrainLimit
+func ConstrainToParent(p, parent *models.Passkey) error {
+	if parent.ValidFrom != nil {
+		if p.ValidFrom == nil {
+			from := *parent.ValidFrom
+			p.ValidFrom = &from
+		} else if p.ValidFrom.Before(*parent.ValidFrom) {
+			return ErrExceedsParentValidity
+		}
+	}
+	if parent.ValidUntil != nil {
+		if p.ValidUntil == nil {
+			until := *parent.ValidUntil
+			p.ValidUntil = &until
+		} else if p.ValidUntil.After(*parent.ValidUntil) {
+			return ErrExceedsParentValidity
+		}
+	}
+
+	// 创建者不受限制的角色视为不限制
+	parentRate := PasskeyRateLimit(parent)
+	if containsString(config.Config.GetStringSlice("rate_limit.exempt_roles"), parent.Role) {
+		parentRate = 0
+	}
+	parentQuota := QuotaLimits(parent)
+	if IsQuotaExempt(parent.Role) {
+		parentQuota = map[string]int{models.QuotaPeriodDay: 0, models.QuotaPeriodMonth: 0}
+	}
+	limits := []struct {
+		value    **int
+		defaults int
+		parent   int
+	}{
+		{&p.RateLimit, config.Config.GetInt("rate_limit.passkey"), parentRate},
+		{&p.DailyQuota, config.Config.GetInt("quota.daily"), parentQuota[models.QuotaPeriodDay]},
+		{&p.MonthlyQuota, config.Config.GetInt("quota.monthly"), parentQuota[models.QuotaPeriodMonth]},
+	}
+	for _, limit := range limits {
+		if err := constrainLimit(limit.value, limit.defaults, limit.parent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// constrainLimit 将频率限制或配额限制在创建者的限制内，0 表示不限制。创建者不限制时不做检查；
+// 设置为 0 或大于创建者的值时返回 ErrExceedsParentLimit；未设置且全局配置更宽时沿用创建者的值
+func constrainLimit(value **int, defaults, parent int) error {
+	if parent <= 0 {
+		return nil
+	}
+	if *value != nil {
+		if **value == 0 || **value > parent {
+			return ErrExceedsParentLimit
+		}
+		return nil
+	}
+	if defaults == 0 || defaults > parent {
+		inherited := parent
+		*value = &inherited
+	}
+	return nil
+}
+
+// inheritedLimits 下级沿用父级范围时可能改变的字段
+type inheritedLimits struct {
+	ValidFrom    *time.Time
+	ValidUntil   *time.Time
+	RateLimit    *int
+	DailyQuota   *int
+	MonthlyQuota *int
+}
+
+func limitsOf(p *models.Passkey) inheritedLimits {
+	return inheritedLimits{p.ValidFrom, p.ValidUntil, p.RateLimit, p.DailyQuota, p.MonthlyQuota}
+}
+
+// ConstrainDescendants 按修改后的 p 逐层检查其全部下级：角色须能由父级分配，有效期和限制按 ConstrainToParent 处理。
+// 有下级设置了更宽的角色、有效期或限制时返回 ErrDescendantExceeds；否则返回需要沿用父级范围的下级（已修改字段），
+// 由 SaveInheritedLimits 在保存 p 之后写入
+func ConstrainDescendants(p *models.Passkey) ([]models.Passkey, error) {
+	descendants, err := GetDescendantPasskeys(p.Passkey)
+	if err != nil {
+		return nil, err
+	}
+	// GetDescendantPasskeys 按层级返回，处理下级时其父级已更新
+	parents := map[string]*models.Passkey{p.Passkey: p}
+	var changed []models.Passkey
+	for i := range descendants {
+		child := &descendants[i]
+		parent, ok := parents[*child.ParentID]
+		if !ok {
+			continue
+		}
+		if !models.CanAssignRole(parent.Role, child.Role) {
+			return nil, ErrDescendantExceeds
+		}
+		before := limitsOf(child)
+		if err := ConstrainToParent(child, parent); err != nil {
+			return nil, ErrDescendantExceeds
+		}
+		if !reflect.DeepEqual(before, limitsOf(child)) {
+			changed = append(changed, *child)
+		}
+		parents[child.Passkey] = child
+	}
+	return changed, nil
+}
+
+// SaveInheritedLimits 写入 ConstrainDescendants 返回的下级的有效期、频率限制和配额
+func SaveInheritedLimits(passkeys []models.Passkey) error {
+	for i := range passkeys {
+		p := &passkeys[i]
+		if err := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Passkey": p.Passkey}).
+			Select("Valid_From", "Valid_Until", "Rate_Limit", "Daily_Quota", "Monthly_Quota").Updates(p).Error; err != nil {
+			utils.LogError(err)
+			return fmt.Errorf("更新下级 passkey 失败: %v", err)
+		}
+		InvalidatePasskeyCache(p.Passkey)
+	}
+	return nil
+}
+
+// DisablePasskeyTree 禁用 passkey，cascade 为 true 时同时禁用其全部下级，
+// 并使被禁用账户已签发的令牌立即失效。返回被禁用的 passkey（含 root）
+func DisablePasskeyTree(root string, cascade bool) ([]string, error) {
+	targets := []string{root}
+	if cascade {
+		descendants, err := GetDescendantPasskeys(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range descendants {
+			if d.IsActive {
+				targets = append(targets, d.Passkey)
+			}
+		}
+	}
+
+	if err := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Passkey": targets}).
+		Update("Is_Active", false).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("禁用 passkey 失败: %v", err)
+	}
+	for _, passkey := range targets {
+		if err := RevokeAllTokens(passkey); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+// ReparentChildren 将 passkey 的直接下级移交给新的父级（删除 passkey 时使用），newParent 为空时成为顶层账户
+func ReparentChildren(passkey string, newParent *string) error {
+	if err := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Parent_ID": passkey}).
+		Update("Parent_ID", newParent).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("移交下级 passkey 失败: %v", err)
+	}
+	return nil
+}
+
+// PasskeyTreeNode 授权树中的节点
+type PasskeyTreeNode struct {
+	Passkey  models.Passkey
+	Children []*PasskeyTreeNode
+}
+
+// BuildPasskeyTree 将 passkey 列表按 Parent_ID 组织为森林，父级不在列表中的节点作为根节点，
+// 同级节点保持列表中的顺序
+func BuildPasskeyTree(passkeys []models.Passkey) []*PasskeyTreeNode {
+	nodes := make(map[string]*PasskeyTreeNode, len(passkeys))
+	for i := range passkeys {
+		nodes[passkeys[i].Passkey] = &PasskeyTreeNode{Passkey: passkeys[i]}
+	}
+
+	var roots []*PasskeyTreeNode
+	for i := range passkeys {
+		node := nodes[passkeys[i].Passkey]
+		if parentID := passkeys[i].ParentID; parentID != nil {
+			if parent, ok := nodes[*parentID]; ok && parent != node {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// GetPasskey 根据 UUID 获取 passkey
+func GetPasskey(passkey string) (*models.Passkey, error) {
+	var p models.Passkey
+	result := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Passkey": passkey}).Limit(1).Find(&p)
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return nil, fmt.Errorf("查询 passkey 失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrPasskeyNotFound
+	}
+	return &p, nil
+}
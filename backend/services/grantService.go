@@ -0,0 +1,201 @@
+package services
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidGrantField = errors.New("不支持的授权字段")
+	ErrInvalidGrantScope = errors.New("不支持的授权范围")
+	ErrEmptyGrantFields  = errors.New("授权字段不能为空")
+	ErrEmptyGrantScope   = errors.New("授权范围取值不能为空")
+	ErrDataNotFound      = errors.New("化合物不存在")
+)
+
+// SplitGrantValues 拆分授权记录中以逗号或换行分隔的取值
+func SplitGrantValues(s string) []string {
+	var values []string
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// NewDataGrant 校验并生成授权记录，字段名不区分大小写
+func NewDataGrant(passkey string, fields []string, scopeType string, scopeValues []string, note, createdBy string) (*models.DataGrant, error) {
+	var normalized []string
+	for _, field := range fields {
+		matched := ""
+		for _, f := range models.GrantFields {
+			if strings.EqualFold(strings.TrimSpace(field), f) {
+				matched = f
+				break
+			}
+		}
+		if matched == "" {
+			return nil, ErrInvalidGrantField
+		}
+		if !containsString(normalized, matched) {
+			normalized = append(normalized, matched)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, ErrEmptyGrantFields
+	}
+
+	scopeType = strings.ToLower(strings.TrimSpace(scopeType))
+	if scopeType == "" {
+		scopeType = models.GrantScopeAll
+	}
+	if !containsString(models.GrantScopes, scopeType) {
+		return nil, ErrInvalidGrantScope
+	}
+
+	var values []string
+	for _, v := range scopeValues {
+		if v = strings.TrimSpace(v); v != "" && !containsString(values, v) {
+			values = append(values, v)
+		}
+	}
+	if scopeType == models.GrantScopeAll {
+		values = nil
+	} else if len(values) == 0 {
+		return nil, ErrEmptyGrantScope
+	}
+
+	return &models.DataGrant{
+		Passkey:     passkey,
+		Fields:      strings.Join(normalized, ","),
+		ScopeType:   scopeType,
+		ScopeValues: strings.Join(values, "\n"),
+		Note:        strings.TrimSpace(note),
+		CreatedBy:   createdBy,
+	}, nil
+}
+
+// CreateDataGrant 新增授权记录
+func CreateDataGrant(grant *models.DataGrant) error {
+	if err := database.GetDB().Table("data_grants").Create(grant).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("创建授权失败: %v", err)
+	}
+	return nil
+}
+
+// GetDataGrants 获取 passkey 的全部授权记录
+func GetDataGrants(passkey string) ([]models.DataGrant, error) {
+	var grants []models.DataGrant
+	if err := database.GetDB().Table("data_grants").Where(map[string]interface{}{"Passkey": passkey}).Order(database.Asc("ID")).Find(&grants).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取授权失败: %v", err)
+	}
+	return grants, nil
+}
+
+// DeleteDataGrant 删除 passkey 的授权记录，返回删除的行数
+func DeleteDataGrant(passkey string, id uint) (int64, error) {
+	result := database.GetDB().Table("data_grants").Where(map[string]interface{}{"ID": id, "Passkey": passkey}).Delete(&models.DataGrant{})
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return 0, fmt.Errorf("删除授权失败: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// DeleteDataGrantsByPasskey 删除 passkey 的全部授权记录
+func DeleteDataGrantsByPasskey(passkey string) error {
+	if err := database.GetDB().Table("data_grants").Where(map[string]interface{}{"Passkey": passkey}).Delete(&models.DataGrant{}).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("删除授权失败: %v", err)
+	}
+	return nil
+}
+
+// grantMatches 判断授权范围是否覆盖该化合物
+func grantMatches(grant models.DataGrant, id, source, itemTag string) bool {
+	values := SplitGrantValues(grant.ScopeValues)
+	switch grant.ScopeType {
+	case models.GrantScopeAll:
+		return true
+	case models.GrantScopeIDs:
+		return containsString(values, id)
+	case models.GrantScopeSource:
+		for _, v := range values {
+			if strings.EqualFold(v, strings.TrimSpace(source)) {
+				return true
+			}
+		}
+	case models.GrantScopeTag:
+		tags := strings.FieldsFunc(itemTag, func(r rune) bool { return r == ',' || r == ';' || r == '，' || r == '；' })
+		for _, tag := range tags {
+			for _, v := range values {
+				if strings.EqualFold(v, strings.TrimSpace(tag)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// GrantedFields 返回当前用户可以访问的化合物保护字段。
+// 拥有 data:read-protected 权限的角色可以访问全部字段，其余用户按授权记录计算；
+// 化合物不存在时返回 ErrDataNotFound
+func GrantedFields(passkey, role, dataID string) ([]string, error) {
+	var compound struct {
+		ID      string  `gorm:"column:ID"`
+		Source  *string `gorm:"column:Source"`
+		ItemTag *string `gorm:"column:ItemTag"`
+	}
+	if err := database.GetDB().Table("data").Select("ID", "Source", "ItemTag").Where(map[string]interface{}{"ID": dataID}).First(&compound).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDataNotFound
+		}
+		utils.LogError(err)
+		return nil, fmt.Errorf("查询化合物失败: %v", err)
+	}
+
+	if models.HasPermission(role, models.PermissionReadProtected) {
+		return models.GrantFields, nil
+	}
+
+	grants, err := GetDataGrants(passkey)
+	if err != nil {
+		return nil, err
+	}
+
+	var source, itemTag string
+	if compound.Source != nil {
+		source = *compound.Source
+	}
+	if compound.ItemTag != nil {
+		itemTag = *compound.ItemTag
+	}
+
+	allowed := map[string]bool{}
+	for _, grant := range grants {
+		if grantMatches(grant, compound.ID, source, itemTag) {
+			for _, field := range SplitGrantValues(grant.Fields) {
+				allowed[field] = true
+			}
+		}
+	}
+
+	// 按固定顺序返回
+	fields := []string{}
+	for _, field := range models.GrantFields {
+		if allowed[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
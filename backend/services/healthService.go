@@ -0,0 +1,173 @@
+package services
+
+import (
+	"backend/config"
+	"backend/database"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 启动模式，通过 startup.mode 配置
+const (
+	// StartupStrict 数据库或RDKit不可用时拒绝启动，RDKit不可用时就绪检查失败
+	StartupStrict = "strict"
+	// StartupDegraded RDKit不可用时继续运行，化学计算接口返回 503，就绪检查标记为降级
+	StartupDegraded = "degraded"
+)
+
+// 就绪检查的状态
+const (
+	CheckStatusOK     = "ok"
+	CheckStatusFailed = "failed"
+)
+
+// 就绪检查的默认超时时间
+const defaultReadinessTimeout = 3 * time.Second
+
+// 就绪检查结果的默认缓存时间
+const defaultReadinessCacheTTL = 5 * time.Second
+
+// readinessCache 最近一次就绪检查的结果，检查期间持有锁，同时到达的请求等待同一次检查
+var readinessCache struct {
+	sync.Mutex
+	result    Readiness
+	checkedAt time.Time
+}
+
+// StartupMode 返回配置的启动模式，默认为 degraded
+func StartupMode() (string, error) {
+	mode := config.Config.GetString("startup.mode")
+	switch mode {
+	case "":
+		return StartupDegraded, nil
+	case StartupStrict, StartupDegraded:
+		return mode, nil
+	}
+	return "", fmt.Errorf("不支持的启动模式: %s（可选 strict、degraded）", mode)
+}
+
+// ReadinessTimeout 返回就绪检查的超时时间，通过 health.timeout 配置（秒）
+func ReadinessTimeout() time.Duration {
+	if seconds := config.Config.GetFloat64("health.timeout"); seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return defaultReadinessTimeout
+}
+
+// ReadinessCacheTTL 返回就绪检查结果的缓存时间，通过 health.cache_ttl 配置（秒），0 表示不缓存
+func ReadinessCacheTTL() time.Duration {
+	if !config.Config.IsSet("health.cache_ttl") {
+		return defaultReadinessCacheTTL
+	}
+	return time.Duration(config.Config.GetFloat64("health.cache_ttl") * float64(time.Second))
+}
+
+// ReadinessCheck 一项依赖的检查结果
+type ReadinessCheck struct {
+	// 依赖名称：database 或 rdkit
+	Name string `json:"name"`
+	// ok 或 failed
+	Status string `json:"status"`
+	// 检查失败的原因
+	Error string `json:"error,omitempty"`
+	// 检查耗时（毫秒）
+	LatencyMS int64 `json:"latency_ms"`
+	// 检查失败时是否影响就绪状态
+	Required bool `json:"required"`
+}
+
+// Readiness 就绪检查的结果
+type Readiness struct {
+	// 全部必需的依赖可用
+	Ready bool `json:"ready"`
+	// 以降级模式运行且有非必需的依赖不可用
+	Degraded bool `json:"degraded"`
+	// strict 或 degraded
+	Mode   string           `json:"mode"`
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+// Failed 返回检查失败的必需依赖名称
+func (r Readiness) Failed() []string {
+	var names []string
+	for _, check := range r.Checks {
+		if check.Required && check.Status != CheckStatusOK {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
+
+// PingDatabase 检查数据库连接
+func PingDatabase(ctx context.Context) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New("数据库未连接")
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckReadiness 在超时时间内依次检查数据库和RDKit。降级模式下RDKit不可用不影响就绪状态
+func CheckReadiness(ctx context.Context) Readiness {
+	mode, err := StartupMode()
+	if err != nil {
+		mode = StartupStrict
+	}
+	ctx, cancel := context.WithTimeout(ctx, ReadinessTimeout())
+	defer cancel()
+
+	readiness := Readiness{Ready: true, Mode: mode}
+	checks := []struct {
+		name     string
+		required bool
+		ping     func(context.Context) error
+	}{
+		{"database", true, PingDatabase},
+		{"rdkit", mode == StartupStrict, PingRdkit},
+	}
+	for _, item := range checks {
+		start := time.Now()
+		err := item.ping(ctx)
+		check := ReadinessCheck{
+			Name:      item.name,
+			Status:    CheckStatusOK,
+			LatencyMS: time.Since(start).Milliseconds(),
+			Required:  item.required,
+		}
+		if err != nil {
+			check.Status = CheckStatusFailed
+			check.Error = err.Error()
+			if item.required {
+				readiness.Ready = false
+			} else {
+				readiness.Degraded = true
+			}
+		}
+		readiness.Checks = append(readiness.Checks, check)
+	}
+	return readiness
+}
+
+// CachedReadiness 返回缓存时间内最近一次的就绪检查结果，过期后重新检查。
+// 就绪检查无需认证，缓存使频繁的请求不会反复 ping 数据库和占用RDKit进程
+func CachedReadiness(ctx context.Context) Readiness {
+	ttl := ReadinessCacheTTL()
+	if ttl <= 0 {
+		return CheckReadiness(ctx)
+	}
+	readinessCache.Lock()
+	defer readinessCache.Unlock()
+	if !readinessCache.checkedAt.IsZero() && time.Since(readinessCache.checkedAt) < ttl {
+		return readinessCache.result
+	}
+	readinessCache.result = CheckReadiness(ctx)
+	readinessCache.checkedAt = time.Now()
+	return readinessCache.result
+}
@@ -5,6 +5,7 @@ import (
 	"backend/models"
 	"backend/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 )
@@ -12,10 +13,10 @@ import (
 // InitializeCompoundData 初始化化合物数据，计算缺失的FP、Structure和Weight
 func InitializeCompoundData() error {
 	// 检查Python进程是否已初始化
-	if p == nil {
+	if currentEngine() == nil {
 		errMsg := "RDkit进程未初始化，无法计算化合物数据"
 		utils.Log(errMsg)
-		return fmt.Errorf(errMsg)
+		return errors.New(errMsg)
 	}
 
 	// 获取所有化合物数据
@@ -120,7 +121,7 @@ func InitializeCompoundData() error {
 		// 更新数据库
 		if len(updates) > 0 {
 			updateResult := database.GetDB().Table("data").
-				Where("ID = ?", compound.ID).
+				Where(map[string]interface{}{"ID": compound.ID}).
 				Updates(updates)
 			if updateResult.Error != nil {
 				utils.LogError(updateResult.Error)
@@ -147,7 +148,7 @@ func calculateFingerprint(smiles string) (string, error) {
 		return "", fmt.Errorf("请求数据序列化失败: %v", err)
 	}
 
-	res, err := p.SendAndWait(string(requestJSON))
+	res, err := sendRdkit("smiles_to_fingerprint", requestJSON)
 	if err != nil {
 		return "", fmt.Errorf("计算指纹失败: %v", err)
 	}
@@ -167,7 +168,7 @@ func calculateStructure(smiles string) (string, error) {
 		return "", fmt.Errorf("请求数据序列化失败: %v", err)
 	}
 
-	res, err := p.SendAndWait(string(requestJSON))
+	res, err := sendRdkit("smiles_to_pdb", requestJSON)
 	if err != nil {
 		return "", fmt.Errorf("计算结构失败: %v", err)
 	}
@@ -187,7 +188,7 @@ func calculateMolecularWeight(smiles string) (float32, error) {
 		return 0, fmt.Errorf("请求数据序列化失败: %v", err)
 	}
 
-	res, err := p.SendAndWait(string(requestJSON))
+	res, err := sendRdkit("calculate_molecular_weight", requestJSON)
 	if err != nil {
 		return 0, fmt.Errorf("计算分子量失败: %v", err)
 	}
@@ -0,0 +1,481 @@
+package services
+
+import (
+	"backend/database"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// electronMass 电子质量（Da）
+const electronMass = 0.00054857990946
+
+// isotope 单个同位素的精确质量和天然丰度
+type isotope struct {
+	Mass      float64
+	Abundance float64
+}
+
+// elementIsotopes 元素同位素表（NIST/IUPAC 精确质量和天然丰度）
+// 海洋天然产物常见的卤素（Cl、Br、I）以及常见加合离子元素均已包含
+var elementIsotopes = map[string][]isotope{
+	"H":  {{1.00782503207, 0.999885}, {2.0141017778, 0.000115}},
+	"Li": {{6.015122795, 0.0759}, {7.01600455, 0.9241}},
+	"B":  {{10.0129370, 0.199}, {11.0093054, 0.801}},
+	"C":  {{12.0, 0.9893}, {13.0033548378, 0.0107}},
+	"N":  {{14.0030740048, 0.99636}, {15.0001088982, 0.00364}},
+	"O":  {{15.99491461956, 0.99757}, {16.99913170, 0.00038}, {17.9991610, 0.00205}},
+	"F":  {{18.99840322, 1}},
+	"Na": {{22.9897692809, 1}},
+	"Mg": {{23.985041700, 0.7899}, {24.98583692, 0.1000}, {25.982592929, 0.1101}},
+	"Al": {{26.98153863, 1}},
+	"Si": {{27.9769265325, 0.92223}, {28.976494700, 0.04685}, {29.97377017, 0.03092}},
+	"P":  {{30.97376163, 1}},
+	"S":  {{31.97207100, 0.9499}, {32.97145876, 0.0075}, {33.96786690, 0.0425}, {35.96708076, 0.0001}},
+	"Cl": {{34.96885268, 0.7576}, {36.96590259, 0.2424}},
+	"K":  {{38.96370668, 0.932581}, {39.96399848, 0.000117}, {40.96182576, 0.067302}},
+	"Ca": {{39.96259098, 0.96941}, {41.95861801, 0.00647}, {42.9587666, 0.00135}, {43.9554818, 0.02086}, {45.9536926, 0.00004}, {47.952534, 0.00187}},
+	"Mn": {{54.9380451, 1}},
+	"Fe": {{53.9396105, 0.05845}, {55.9349375, 0.91754}, {56.9353940, 0.02119}, {57.9332756, 0.00282}},
+	"Co": {{58.9331950, 1}},
+	"Cu": {{62.9295975, 0.6915}, {64.9277895, 0.3085}},
+	"Zn": {{63.9291422, 0.4917}, {65.9260334, 0.2773}, {66.9271273, 0.0404}, {67.9248442, 0.1845}, {69.9253193, 0.0061}},
+	"As": {{74.9215965, 1}},
+	"Se": {{73.9224764, 0.0089}, {75.9192136, 0.0937}, {76.9199140, 0.0763}, {77.9173091, 0.2377}, {79.9165213, 0.4961}, {81.9166994, 0.0873}},
+	"Br": {{78.9183371, 0.5069}, {80.9162906, 0.4931}},
+	"I":  {{126.904473, 1}},
+}
+
+// Adduct 加合离子定义
+type Adduct struct {
+	Name     string         `json:"name"`
+	Multimer int            `json:"multimer"` // M的个数，如[2M+H]+为2
+	Add      map[string]int `json:"-"`
+	Remove   map[string]int `json:"-"`
+	Charge   int            `json:"charge"`
+}
+
+// adducts 支持的加合离子列表
+var adducts = []Adduct{
+	{Name: "[M]+", Multimer: 1, Charge: 1},
+	{Name: "[M+H]+", Multimer: 1, Add: map[string]int{"H": 1}, Charge: 1},
+	{Name: "[M+Na]+", Multimer: 1, Add: map[string]int{"Na": 1}, Charge: 1},
+	{Name: "[M+K]+", Multimer: 1, Add: map[string]int{"K": 1}, Charge: 1},
+	{Name: "[M+NH4]+", Multimer: 1, Add: map[string]int{"N": 1, "H": 4}, Charge: 1},
+	{Name: "[M+H-H2O]+", Multimer: 1, Add: map[string]int{"H": 1}, Remove: map[string]int{"H": 2, "O": 1}, Charge: 1},
+	{Name: "[M+2H]2+", Multimer: 1, Add: map[string]int{"H": 2}, Charge: 2},
+	{Name: "[M+H+Na]2+", Multimer: 1, Add: map[string]int{"H": 1, "Na": 1}, Charge: 2},
+	{Name: "[2M+H]+", Multimer: 2, Add: map[string]int{"H": 1}, Charge: 1},
+	{Name: "[2M+Na]+", Multimer: 2, Add: map[string]int{"Na": 1}, Charge: 1},
+	{Name: "[M]-", Multimer: 1, Charge: -1},
+	{Name: "[M-H]-", Multimer: 1, Remove: map[string]int{"H": 1}, Charge: -1},
+	{Name: "[M+Cl]-", Multimer: 1, Add: map[string]int{"Cl": 1}, Charge: -1},
+	{Name: "[M+HCOO]-", Multimer: 1, Add: map[string]int{"C": 1, "H": 1, "O": 2}, Charge: -1},
+	{Name: "[M+CH3COO]-", Multimer: 1, Add: map[string]int{"C": 2, "H": 3, "O": 2}, Charge: -1},
+	{Name: "[M-2H]2-", Multimer: 1, Remove: map[string]int{"H": 2}, Charge: -2},
+	{Name: "[2M-H]-", Multimer: 2, Remove: map[string]int{"H": 1}, Charge: -1},
+}
+
+// IsotopePeak 同位素峰
+type IsotopePeak struct {
+	MZ        float64 `json:"mz"`
+	Intensity float64 `json:"intensity"` // 相对强度，最高峰为100
+	Abundance float64 `json:"abundance"` // 占全部同位素分布的比例
+}
+
+// IsotopePattern 同位素分布模拟结果
+type IsotopePattern struct {
+	Formula        string        `json:"formula"`
+	Adduct         string        `json:"adduct"`
+	IonFormula     string        `json:"ion_formula"`
+	Charge         int           `json:"charge"`
+	Resolution     float64       `json:"resolution"`
+	MonoisotopicMZ float64       `json:"monoisotopic_mz"`
+	Peaks          []IsotopePeak `json:"peaks"`
+}
+
+var (
+	ErrInvalidFormula   = errors.New("分子式格式错误")
+	ErrUnknownElement   = errors.New("分子式包含不支持的元素")
+	ErrUnknownAdduct    = errors.New("不支持的加合离子类型")
+	ErrAdductIncomplete = errors.New("分子式中的原子不足以形成该加合离子")
+)
+
+// 计算过程中的剪枝阈值，相对于当前最大丰度
+const isotopePruneThreshold = 1e-9
+
+// GetAdducts 获取支持的加合离子列表
+func GetAdducts() []Adduct {
+	return adducts
+}
+
+// FindAdduct 根据名称查找加合离子，不区分大小写
+// 查询参数中未编码的 "+" 会被解码为空格，因此这里把空格还原为 "+"
+func FindAdduct(name string) (Adduct, error) {
+	key := strings.ToUpper(strings.ReplaceAll(name, " ", "+"))
+	for _, a := range adducts {
+		if strings.ToUpper(a.Name) == key {
+			return a, nil
+		}
+	}
+	return Adduct{}, ErrUnknownAdduct
+}
+
+// ParseFormula 解析分子式，支持括号和水合物写法（如 C20H25BrN2O3、Ca(OH)2、CuSO4·5H2O）
+// 原子个数为0的元素不计入结果，不含任何原子的分子式（如 C0H0）返回 ErrInvalidFormula
+func ParseFormula(formula string) (map[string]int, error) {
+	counts := map[string]int{}
+	normalized := normalizeFormula(formula)
+	if normalized == "" {
+		return nil, ErrInvalidFormula
+	}
+
+	// 水合物等以"."分隔的部分分别解析，部分前的数字为系数
+	for _, part := range strings.Split(normalized, ".") {
+		if part == "" {
+			return nil, ErrInvalidFormula
+		}
+		multiplier := 1
+		i := 0
+		for i < len(part) && part[i] >= '0' && part[i] <= '9' {
+			i++
+		}
+		if i > 0 {
+			multiplier, _ = strconv.Atoi(part[:i])
+		}
+		partCounts, pos, err := parseFormulaGroup(part, i)
+		if err != nil {
+			return nil, err
+		}
+		if pos != len(part) {
+			return nil, ErrInvalidFormula
+		}
+		for el, n := range partCounts {
+			counts[el] += n * multiplier
+		}
+	}
+
+	for el, n := range counts {
+		if n == 0 {
+			delete(counts, el)
+		}
+	}
+	if len(counts) == 0 {
+		return nil, ErrInvalidFormula
+	}
+	return counts, nil
+}
+
+// normalizeFormula 去除空白，统一下标数字、方括号和水合点
+func normalizeFormula(formula string) string {
+	var b strings.Builder
+	for _, r := range formula {
+		switch {
+		case unicode.IsSpace(r):
+			continue
+		case r >= '₀' && r <= '₉':
+			b.WriteRune('0' + (r - '₀'))
+		case r == '·' || r == '•' || r == '*':
+			b.WriteRune('.')
+		case r == '[' || r == '{':
+			b.WriteRune('(')
+		case r == ']' || r == '}':
+			b.WriteRune(')')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseFormulaGroup 从pos开始解析，直到遇到未匹配的右括号或字符串结束
+func parseFormulaGroup(s string, pos int) (map[string]int, int, error) {
+	counts := map[string]int{}
+	for pos < len(s) {
+		ch := s[pos]
+		switch {
+		case ch == '(':
+			inner, next, err := parseFormulaGroup(s, pos+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			if next >= len(s) || s[next] != ')' {
+				return nil, 0, ErrInvalidFormula
+			}
+			n, after := readCount(s, next+1)
+			for el, c := range inner {
+				counts[el] += c * n
+			}
+			pos = after
+		case ch == ')':
+			return counts, pos, nil
+		case ch >= 'A' && ch <= 'Z':
+			end := pos + 1
+			for end < len(s) && s[end] >= 'a' && s[end] <= 'z' {
+				end++
+			}
+			symbol := s[pos:end]
+			if _, ok := elementIsotopes[symbol]; !ok {
+				return nil, 0, fmt.Errorf("%w: %s", ErrUnknownElement, symbol)
+			}
+			n, after := readCount(s, end)
+			counts[symbol] += n
+			pos = after
+		default:
+			return nil, 0, ErrInvalidFormula
+		}
+	}
+	return counts, pos, nil
+}
+
+// readCount 读取元素或括号后的原子个数，缺省为1
+func readCount(s string, pos int) (int, int) {
+	end := pos
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	if end == pos {
+		return 1, pos
+	}
+	n, _ := strconv.Atoi(s[pos:end])
+	return n, end
+}
+
+// FormatFormula 按Hill规则输出分子式（C、H在前，其余元素按字母顺序）
+func FormatFormula(counts map[string]int) string {
+	var elements []string
+	for el, n := range counts {
+		if n > 0 && el != "C" && el != "H" {
+			elements = append(elements, el)
+		}
+	}
+	sort.Strings(elements)
+	if counts["C"] > 0 {
+		prefix := []string{"C"}
+		if counts["H"] > 0 {
+			prefix = append(prefix, "H")
+		}
+		elements = append(prefix, elements...)
+	} else if counts["H"] > 0 {
+		elements = append(elements, "H")
+		sort.Strings(elements)
+	}
+
+	var b strings.Builder
+	for _, el := range elements {
+		b.WriteString(el)
+		if counts[el] > 1 {
+			fmt.Fprintf(&b, "%d", counts[el])
+		}
+	}
+	return b.String()
+}
+
+// ionComposition 根据加合离子计算离子的元素组成
+func ionComposition(counts map[string]int, adduct Adduct) (map[string]int, error) {
+	ion := map[string]int{}
+	for el, n := range counts {
+		ion[el] = n * adduct.Multimer
+	}
+	for el, n := range adduct.Add {
+		ion[el] += n
+	}
+	for el, n := range adduct.Remove {
+		ion[el] -= n
+		if ion[el] < 0 {
+			return nil, ErrAdductIncomplete
+		}
+	}
+	return ion, nil
+}
+
+// convolveIsotopes 卷积两个同位素分布，合并同质量峰并剪除低丰度峰
+func convolveIsotopes(a, b []isotope) []isotope {
+	combined := make([]isotope, 0, len(a)*len(b))
+	for _, x := range a {
+		for _, y := range b {
+			combined = append(combined, isotope{Mass: x.Mass + y.Mass, Abundance: x.Abundance * y.Abundance})
+		}
+	}
+	sort.Slice(combined, func(i, j int) bool { return combined[i].Mass < combined[j].Mass })
+
+	// 不同路径得到的同一组成质量只存在浮点误差，按1e-6 Da合并
+	merged := make([]isotope, 0, len(combined))
+	maxAbundance := 0.0
+	for _, peak := range combined {
+		last := len(merged) - 1
+		if last >= 0 && peak.Mass-merged[last].Mass < 1e-6 {
+			total := merged[last].Abundance + peak.Abundance
+			merged[last].Mass = (merged[last].Mass*merged[last].Abundance + peak.Mass*peak.Abundance) / total
+			merged[last].Abundance = total
+		} else {
+			merged = append(merged, peak)
+		}
+		if merged[len(merged)-1].Abundance > maxAbundance {
+			maxAbundance = merged[len(merged)-1].Abundance
+		}
+	}
+
+	pruned := merged[:0]
+	for _, peak := range merged {
+		if peak.Abundance >= maxAbundance*isotopePruneThreshold {
+			pruned = append(pruned, peak)
+		}
+	}
+	return pruned
+}
+
+// elementDistribution 用快速幂计算n个同种原子的同位素分布
+func elementDistribution(isotopes []isotope, n int) []isotope {
+	result := []isotope{{Mass: 0, Abundance: 1}}
+	base := isotopes
+	for n > 0 {
+		if n&1 == 1 {
+			result = convolveIsotopes(result, base)
+		}
+		n >>= 1
+		if n > 0 {
+			base = convolveIsotopes(base, base)
+		}
+	}
+	return result
+}
+
+// mergeByResolution 按仪器分辨率合并相邻峰（FWHM = m/z / resolution），resolution<=0时按整数质量合并
+func mergeByResolution(peaks []IsotopePeak, resolution float64) []IsotopePeak {
+	var merged []IsotopePeak
+	for _, peak := range peaks {
+		last := len(merged) - 1
+		if last >= 0 {
+			var sameCluster bool
+			if resolution > 0 {
+				sameCluster = peak.MZ-merged[last].MZ <= merged[last].MZ/resolution
+			} else {
+				sameCluster = math.Round(peak.MZ) == math.Round(merged[last].MZ)
+			}
+			if sameCluster {
+				total := merged[last].Abundance + peak.Abundance
+				merged[last].MZ = (merged[last].MZ*merged[last].Abundance + peak.MZ*peak.Abundance) / total
+				merged[last].Abundance = total
+				continue
+			}
+		}
+		merged = append(merged, peak)
+	}
+	return merged
+}
+
+// SimulateIsotopePattern 根据分子式和加合离子模拟指定分辨率下的同位素分布
+// minIntensity 为相对强度下限（%），低于该值的峰不返回
+func SimulateIsotopePattern(formula string, adductName string, resolution float64, minIntensity float64) (*IsotopePattern, error) {
+	counts, err := ParseFormula(formula)
+	if err != nil {
+		return nil, err
+	}
+	adduct, err := FindAdduct(adductName)
+	if err != nil {
+		return nil, err
+	}
+	ion, err := ionComposition(counts, adduct)
+	if err != nil {
+		return nil, err
+	}
+
+	// 逐元素卷积得到精细同位素结构
+	distribution := []isotope{{Mass: 0, Abundance: 1}}
+	monoMass := 0.0
+	var elements []string
+	for el := range ion {
+		elements = append(elements, el)
+	}
+	sort.Strings(elements)
+	for _, el := range elements {
+		n := ion[el]
+		if n == 0 {
+			continue
+		}
+		distribution = convolveIsotopes(distribution, elementDistribution(elementIsotopes[el], n))
+		monoMass += float64(n) * mostAbundant(elementIsotopes[el]).Mass
+	}
+
+	z := float64(adduct.Charge)
+	absZ := math.Abs(z)
+	toMZ := func(mass float64) float64 {
+		return (mass - z*electronMass) / absZ
+	}
+
+	peaks := make([]IsotopePeak, len(distribution))
+	for i, d := range distribution {
+		peaks[i] = IsotopePeak{MZ: toMZ(d.Mass), Abundance: d.Abundance}
+	}
+	peaks = mergeByResolution(peaks, resolution)
+
+	maxAbundance := 0.0
+	for _, peak := range peaks {
+		maxAbundance = math.Max(maxAbundance, peak.Abundance)
+	}
+	result := make([]IsotopePeak, 0, len(peaks))
+	for _, peak := range peaks {
+		intensity := peak.Abundance / maxAbundance * 100
+		if intensity < minIntensity {
+			continue
+		}
+		result = append(result, IsotopePeak{
+			MZ:        roundTo(peak.MZ, 6),
+			Intensity: roundTo(intensity, 4),
+			Abundance: roundTo(peak.Abundance, 8),
+		})
+	}
+
+	return &IsotopePattern{
+		Formula:        FormatFormula(counts),
+		Adduct:         adduct.Name,
+		IonFormula:     FormatFormula(ion),
+		Charge:         adduct.Charge,
+		Resolution:     resolution,
+		MonoisotopicMZ: roundTo(toMZ(monoMass), 6),
+		Peaks:          result,
+	}, nil
+}
+
+// mostAbundant 返回元素丰度最高的同位素（用于单同位素质量计算）
+func mostAbundant(isotopes []isotope) isotope {
+	best := isotopes[0]
+	for _, iso := range isotopes[1:] {
+		if iso.Abundance > best.Abundance {
+			best = iso
+		}
+	}
+	return best
+}
+
+func roundTo(x float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(x*p) / p
+}
+
+// GetCompoundIsotopePattern 根据化合物ID获取其分子式并模拟同位素分布，化合物不存在时返回 ErrDataNotFound
+func GetCompoundIsotopePattern(id string, adductName string, resolution float64, minIntensity float64) (*IsotopePattern, error) {
+	var data struct {
+		Formula *string `gorm:"column:Formula"`
+	}
+	result := database.GetDB().Table("data").Select("Formula").Where(map[string]interface{}{"ID": id}).Take(&data)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrDataNotFound
+		}
+		utils.LogError(result.Error)
+		return nil, fmt.Errorf("查询化合物失败: %v", result.Error)
+	}
+	if data.Formula == nil || *data.Formula == "" {
+		return nil, ErrInvalidFormula
+	}
+	return SimulateIsotopePattern(*data.Formula, adductName, resolution, minIntensity)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseFormula(t *testing.T) {
+	cases := []struct {
+		formula string
+		want    map[string]int
+		err     error
+	}{
+		{formula: "C20H25BrN2O3", want: map[string]int{"C": 20, "H": 25, "Br": 1, "N": 2, "O": 3}},
+		{formula: "Ca(OH)2", want: map[string]int{"Ca": 1, "O": 2, "H": 2}},
+		{formula: "CuSO4·5H2O", want: map[string]int{"Cu": 1, "S": 1, "O": 9, "H": 10}},
+		{formula: "C₆H₁₂O₆", want: map[string]int{"C": 6, "H": 12, "O": 6}},
+		{formula: "C6H12O6N0", want: map[string]int{"C": 6, "H": 12, "O": 6}},
+		{formula: "C0H0", err: ErrInvalidFormula},
+		{formula: "(CH2)0", err: ErrInvalidFormula},
+		{formula: "0H2O", err: ErrInvalidFormula},
+		{formula: "", err: ErrInvalidFormula},
+		{formula: "C6H12O6)", err: ErrInvalidFormula},
+		{formula: "c6h12o6", err: ErrInvalidFormula},
+		{formula: "C6Xx2", err: ErrUnknownElement},
+	}
+	for _, tc := range cases {
+		counts, err := ParseFormula(tc.formula)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("ParseFormula(%q): err = %v, want %v", tc.formula, err, tc.err)
+			continue
+		}
+		if tc.err == nil && !reflect.DeepEqual(counts, tc.want) {
+			t.Errorf("ParseFormula(%q) = %v, want %v", tc.formula, counts, tc.want)
+		}
+	}
+}
+
+// TestSimulateIsotopePatternMZ 单同位素 m/z 与按 NIST 精确质量手工计算的参考值比较
+func TestSimulateIsotopePatternMZ(t *testing.T) {
+	cases := []struct {
+		formula string
+		adduct  string
+		ion     string
+		mz      float64
+	}{
+		{formula: "C6H12O6", adduct: "[M+H]+", ion: "C6H13O6", mz: 181.070665},
+		{formula: "C6H12O6", adduct: "[M+Na]+", ion: "C6H12NaO6", mz: 203.052609},
+		{formula: "C6H12O6", adduct: "[M-H]-", ion: "C6H11O6", mz: 179.056112},
+		{formula: "C6H12O6", adduct: "[M+2H]2+", ion: "C6H14O6", mz: 91.038971},
+		{formula: "C6H12O6", adduct: "[2M+H]+", ion: "C12H25O12", mz: 361.134053},
+		{formula: "C2H6O", adduct: "[M+H-H2O]+", ion: "C2H5", mz: 29.038577},
+		{formula: "CH3Cl", adduct: "[M]+", ion: "CH3Cl", mz: 49.991779},
+	}
+	for _, tc := range cases {
+		pattern, err := SimulateIsotopePattern(tc.formula, tc.adduct, 30000, 0.1)
+		if err != nil {
+			t.Errorf("%s %s: %v", tc.formula, tc.adduct, err)
+			continue
+		}
+		if pattern.IonFormula != tc.ion || math.Abs(pattern.MonoisotopicMZ-tc.mz) > 1e-5 {
+			t.Errorf("%s %s: ion %s m/z %f, want %s m/z %f", tc.formula, tc.adduct, pattern.IonFormula, pattern.MonoisotopicMZ, tc.ion, tc.mz)
+		}
+		if len(pattern.Peaks) == 0 || math.Abs(pattern.Peaks[0].MZ-tc.mz) > 1e-5 || pattern.Peaks[0].Intensity != 100 {
+			t.Errorf("%s %s: first peak %+v, want the monoisotopic peak", tc.formula, tc.adduct, pattern.Peaks)
+		}
+	}
+}
+
+// TestSimulateIsotopePatternIntensities 按整数质量合并后的相对强度与参考同位素簇比较
+func TestSimulateIsotopePatternIntensities(t *testing.T) {
+	cases := []struct {
+		name    string
+		formula string
+		adduct  string
+		want    []float64 // M、M+1、M+2……的相对强度（%）
+	}{
+		// 13C 贡献约 6×1.08%，另有 2H、17O；18O 贡献 M+2
+		{name: "glucose", formula: "C6H12O6", adduct: "[M+H]+", want: []float64{100, 6.87, 1.43}},
+		// 35Cl:37Cl ≈ 3:1
+		{name: "chlorine", formula: "CH3Cl", adduct: "[M]+", want: []float64{100, 1.12, 32.0, 0.36}},
+		// 79Br:81Br ≈ 1:1，两个溴原子为 1:2:1
+		{name: "dibromomethane", formula: "CH2Br2", adduct: "[M]+", want: []float64{51.4, 0.57, 100, 1.1, 48.64, 0.54}},
+	}
+	for _, tc := range cases {
+		pattern, err := SimulateIsotopePattern(tc.formula, tc.adduct, 0, 0.1)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if len(pattern.Peaks) != len(tc.want) {
+			t.Errorf("%s: %d peaks %+v, want %d", tc.name, len(pattern.Peaks), pattern.Peaks, len(tc.want))
+			continue
+		}
+		for i, peak := range pattern.Peaks {
+			if math.Abs(peak.Intensity-tc.want[i]) > 0.05 {
+				t.Errorf("%s: M+%d intensity %.2f, want %.2f", tc.name, i, peak.Intensity, tc.want[i])
+			}
+			if i > 0 && math.Abs(peak.MZ-pattern.Peaks[i-1].MZ-1) > 0.01 {
+				t.Errorf("%s: M+%d at m/z %f, want about 1 Da after the previous peak", tc.name, i, peak.MZ)
+			}
+		}
+	}
+}
+
+// TestSimulateIsotopePatternResolution 高分辨率下 M+1 峰分离为 13C、17O、2H 的精细结构，低分辨率下合并
+func TestSimulateIsotopePatternResolution(t *testing.T) {
+	countM1 := func(resolution float64) int {
+		t.Helper()
+		pattern, err := SimulateIsotopePattern("C6H12O6", "[M+H]+", resolution, 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, peak := range pattern.Peaks {
+			if math.Round(peak.MZ-pattern.MonoisotopicMZ) == 1 {
+				n++
+			}
+		}
+		return n
+	}
+	if n := countM1(1e6); n != 3 {
+		t.Errorf("M+1 peaks at resolution 1e6 = %d, want 3", n)
+	}
+	if n := countM1(30000); n != 1 {
+		t.Errorf("M+1 peaks at resolution 30000 = %d, want 1", n)
+	}
+}
+
+func TestSimulateIsotopePatternErrors(t *testing.T) {
+	cases := []struct {
+		formula string
+		adduct  string
+		err     error
+	}{
+		{formula: "C0H0", adduct: "[M+H]+", err: ErrInvalidFormula},
+		{formula: "C6H12O6", adduct: "[M+Li]+", err: ErrUnknownAdduct},
+		{formula: "C", adduct: "[M+H-H2O]+", err: ErrAdductIncomplete},
+	}
+	for _, tc := range cases {
+		if _, err := SimulateIsotopePattern(tc.formula, tc.adduct, 30000, 0.1); !errors.Is(err, tc.err) {
+			t.Errorf("%s %s: err = %v, want %v", tc.formula, tc.adduct, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"backend/database"
+	"backend/metrics"
+	"backend/models"
+	"backend/utils"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// 化合物总数的缓存时间，避免每次抓取指标都统计整张表
+const compoundCountTTL = time.Minute
+
+// rdkitQueue RDKit 进程的队列状态，由 utils.PythonProcess 实现
+type rdkitQueue interface {
+	QueueDepth() int
+	PendingRequests() int
+}
+
+var compoundCount struct {
+	sync.Mutex
+	value     float64
+	updatedAt time.Time
+}
+
+// protectedReadActions 计入 protected_reads_total 的审计操作
+var protectedReadActions = map[string]bool{
+	models.AuditActionReadProtected:   true,
+	models.AuditActionReadMS2Full:     true,
+	models.AuditActionReadBioactivity: true,
+	models.AuditActionFilterBioactive: true,
+}
+
+func init() {
+	metrics.NewGaugeFunc("rdkit_queue_depth", "Requests waiting to be written to the RDKit process.", func() float64 {
+		if queue, ok := currentEngine().(rdkitQueue); ok {
+			return float64(queue.QueueDepth())
+		}
+		return 0
+	})
+	metrics.NewGaugeFunc("rdkit_pending_requests", "RDKit requests waiting for a reply.", func() float64 {
+		if queue, ok := currentEngine().(rdkitQueue); ok {
+			return float64(queue.PendingRequests())
+		}
+		return 0
+	})
+	metrics.NewGaugeFunc("rdkit_up", "Whether the RDKit process is initialized and not restarting.", func() float64 {
+		if currentEngine() != nil {
+			return 1
+		}
+		return 0
+	})
+	metrics.NewGaugeFunc("compounds", "Compounds in the data table, refreshed at most once a minute.", countCompounds)
+}
+
+// countCompounds 返回化合物总数，统计失败时返回上一次的结果
+func countCompounds() float64 {
+	compoundCount.Lock()
+	defer compoundCount.Unlock()
+	if time.Since(compoundCount.updatedAt) < compoundCountTTL {
+		return compoundCount.value
+	}
+	db := database.GetDB()
+	if db == nil {
+		return compoundCount.value
+	}
+	var total int64
+	if err := db.Table("data").Count(&total).Error; err != nil {
+		utils.LogError(err)
+		return compoundCount.value
+	}
+	compoundCount.value = float64(total)
+	compoundCount.updatedAt = time.Now()
+	return compoundCount.value
+}
+
+// recordProtectedRead 按审计事件统计成功的保护数据读取
+func recordProtectedRead(event models.AuditEvent) {
+	if event.Outcome != models.AuditOutcomeSuccess || !protectedReadActions[event.Action] {
+		return
+	}
+	metrics.ProtectedReads.WithLabelValues(event.PasskeyLabel, event.Action).Inc()
+}
+
+// PasskeyLabel 返回 passkey 的 SHA-256 前 12 位，未认证时为空。审计日志和监控指标用它代替 passkey 原文
+func PasskeyLabel(passkey string) string {
+	if passkey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(passkey))
+	return hex.EncodeToString(sum[:])[:12]
+}
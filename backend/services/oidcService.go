@@ -0,0 +1,434 @@
+package services
+
+import (
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOIDCNotConfigured    = errors.New("统一身份认证未配置")
+	ErrOIDCStateInvalid     = errors.New("登录请求不存在或已过期，请重新登录")
+	ErrOIDCFailed           = errors.New("统一身份认证失败")
+	ErrOIDCEmailNotVerified = errors.New("统一身份认证账户的邮箱未验证")
+	ErrOIDCNoRole           = errors.New("当前统一身份认证账户未被授权访问")
+	ErrOIDCCodeInvalid      = errors.New("登录码无效或已过期")
+)
+
+// 授权请求和登录码的有效期
+const (
+	oidcAuthRequestTTL = 10 * time.Minute
+	oidcLoginCodeTTL   = time.Minute
+)
+
+// OIDCRoleMapping 将 IdP 声明映射为角色的规则，group、email、email_domain 任一匹配即生效，
+// 多条规则匹配时取权限最高的角色
+type OIDCRoleMapping struct {
+	Group       string `mapstructure:"group"`
+	Email       string `mapstructure:"email"`
+	EmailDomain string `mapstructure:"email_domain"`
+	Role        string `mapstructure:"role"`
+}
+
+// OIDCIdentity 从 ID 令牌（及 UserInfo）中取出的用户信息
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// oidcClient 按配置创建的 OIDC 客户端
+type oidcClient struct {
+	provider *oidc.Provider
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+var (
+	oidcMu        sync.Mutex
+	oidcInstance  *oidcClient
+	oidcConfigKey string // 生成 oidcInstance 时的配置，配置修改后重新生成
+)
+
+// getOIDCClient 按 oidc 配置获取 OIDC 客户端，首次使用时从 issuer 读取发现文档
+func getOIDCClient(ctx context.Context) (*oidcClient, error) {
+	if !config.Config.GetBool("oidc.enabled") {
+		return nil, ErrOIDCNotConfigured
+	}
+	issuer := config.Config.GetString("oidc.issuer")
+	clientID := config.Config.GetString("oidc.client_id")
+	clientSecret := config.Config.GetString("oidc.client_secret")
+	redirectURL := config.Config.GetString("oidc.redirect_url")
+	scopes := config.Config.GetStringSlice("oidc.scopes")
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if issuer == "" || clientID == "" || redirectURL == "" {
+		return nil, ErrOIDCNotConfigured
+	}
+
+	key := strings.Join([]string{issuer, clientID, clientSecret, redirectURL, strings.Join(scopes, " ")}, "|")
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcInstance != nil && oidcConfigKey == key {
+		return oidcInstance, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("读取统一身份认证配置失败: %v", err)
+	}
+	if !containsString(scopes, oidc.ScopeOpenID) {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+	oidcInstance = &oidcClient{
+		provider: provider,
+		oauth2: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+	}
+	oidcConfigKey = key
+	return oidcInstance, nil
+}
+
+// oidcAuthRequest 进行中的授权请求，以 state 为键
+type oidcAuthRequest struct {
+	verifier  string // PKCE code_verifier
+	nonce     string
+	redirect  string // 登录完成后前端跳转的路径
+	expiresAt time.Time
+}
+
+// oidcLoginResult 授权完成后等待前端领取的登录结果，以一次性登录码为键
+type oidcLoginResult struct {
+	passkey   *models.Passkey
+	pair      *TokenPair
+	expiresAt time.Time
+}
+
+var (
+	oidcStateMu      sync.Mutex
+	oidcAuthRequests = map[string]oidcAuthRequest{}
+	oidcLoginResults = map[string]oidcLoginResult{}
+)
+
+// SafeRedirectPath 只允许站内相对路径，避免登录完成后跳转到外部网站
+func SafeRedirectPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
+
+// BeginOIDCLogin 生成 state、nonce 和 PKCE 参数，返回 IdP 授权地址
+func BeginOIDCLogin(ctx context.Context, redirect string, now time.Time) (string, error) {
+	client, err := getOIDCClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state := uuid.NewString()
+	nonce := uuid.NewString()
+	verifier := oauth2.GenerateVerifier()
+
+	oidcStateMu.Lock()
+	for key, r := range oidcAuthRequests {
+		if now.After(r.expiresAt) {
+			delete(oidcAuthRequests, key)
+		}
+	}
+	oidcAuthRequests[state] = oidcAuthRequest{
+		verifier:  verifier,
+		nonce:     nonce,
+		redirect:  SafeRedirectPath(redirect),
+		expiresAt: now.Add(oidcAuthRequestTTL),
+	}
+	oidcStateMu.Unlock()
+
+	return client.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// CancelOIDCLogin 丢弃授权请求（如用户在 IdP 取消登录），返回登录后跳转的路径
+func CancelOIDCLogin(state string) string {
+	oidcStateMu.Lock()
+	defer oidcStateMu.Unlock()
+	request, ok := oidcAuthRequests[state]
+	delete(oidcAuthRequests, state)
+	if !ok {
+		return "/"
+	}
+	return request.redirect
+}
+
+// CompleteOIDCLogin 用授权码换取并校验 ID 令牌，返回用户信息和登录后跳转的路径
+func CompleteOIDCLogin(ctx context.Context, state, code string, now time.Time) (*OIDCIdentity, string, error) {
+	oidcStateMu.Lock()
+	request, ok := oidcAuthRequests[state]
+	delete(oidcAuthRequests, state)
+	oidcStateMu.Unlock()
+	if !ok || now.After(request.expiresAt) {
+		return nil, "", ErrOIDCStateInvalid
+	}
+
+	client, err := getOIDCClient(ctx)
+	if err != nil {
+		return nil, request.redirect, err
+	}
+
+	token, err := client.oauth2.Exchange(ctx, code, oauth2.VerifierOption(request.verifier))
+	if err != nil {
+		utils.LogError(err)
+		return nil, request.redirect, ErrOIDCFailed
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, request.redirect, ErrOIDCFailed
+	}
+	idToken, err := client.verifier.Verify(ctx, rawIDToken)
+	if err != nil || idToken.Nonce != request.nonce {
+		if err != nil {
+			utils.LogError(err)
+		}
+		return nil, request.redirect, ErrOIDCFailed
+	}
+
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, request.redirect, ErrOIDCFailed
+	}
+	// 部分 IdP 只在 UserInfo 中返回邮箱和用户组
+	if config.Config.GetBool("oidc.use_userinfo") {
+		userInfo, err := client.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			utils.LogError(err)
+			return nil, request.redirect, ErrOIDCFailed
+		}
+		extra := map[string]interface{}{}
+		if err := userInfo.Claims(&extra); err == nil && extra["sub"] == idToken.Subject {
+			for k, v := range extra {
+				if _, exists := claims[k]; !exists {
+					claims[k] = v
+				}
+			}
+		}
+	}
+
+	return newOIDCIdentity(idToken.Issuer, idToken.Subject, claims), request.redirect, nil
+}
+
+// claimValue 按以点分隔的路径读取声明，如 realm_access.roles
+func claimValue(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[part]
+	}
+	return value
+}
+
+// claimStrings 将字符串或字符串数组声明转换为字符串切片
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func newOIDCIdentity(issuer, subject string, claims map[string]interface{}) *OIDCIdentity {
+	emailClaim := config.Config.GetString("oidc.email_claim")
+	if emailClaim == "" {
+		emailClaim = "email"
+	}
+	groupsClaim := config.Config.GetString("oidc.groups_claim")
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	identity := &OIDCIdentity{Issuer: issuer, Subject: subject}
+	identity.Email, _ = claimValue(claims, emailClaim).(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Groups = claimStrings(claimValue(claims, groupsClaim))
+	for _, key := range []string{"name", "preferred_username", "email"} {
+		if name, ok := claims[key].(string); ok && name != "" {
+			identity.Name = name
+			break
+		}
+	}
+	return identity
+}
+
+// requireVerifiedEmail 是否只信任已验证的邮箱，未配置 oidc.require_verified_email 时默认要求
+func requireVerifiedEmail() bool {
+	if !config.Config.IsSet("oidc.require_verified_email") {
+		return true
+	}
+	return config.Config.GetBool("oidc.require_verified_email")
+}
+
+// MapOIDCRole 按 oidc.role_mappings 计算用户的角色，未匹配时使用 oidc.default_role，
+// 返回空字符串表示不允许登录
+func MapOIDCRole(identity *OIDCIdentity) (string, error) {
+	var mappings []OIDCRoleMapping
+	if err := config.Config.UnmarshalKey("oidc.role_mappings", &mappings); err != nil {
+		utils.LogError(err)
+		return "", fmt.Errorf("统一身份认证角色映射配置错误: %v", err)
+	}
+
+	email := strings.ToLower(identity.Email)
+	// 未验证的邮箱不能用于匹配
+	if !identity.EmailVerified && requireVerifiedEmail() {
+		email = ""
+	}
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain = email[at+1:]
+	}
+
+	role := ""
+	for _, m := range mappings {
+		matched := (m.Group != "" && containsString(identity.Groups, m.Group)) ||
+			(m.Email != "" && email != "" && strings.EqualFold(m.Email, email)) ||
+			(m.EmailDomain != "" && domain != "" && strings.EqualFold(strings.TrimPrefix(m.EmailDomain, "@"), domain))
+		if matched && models.RoleLevel(m.Role) > models.RoleLevel(role) {
+			role = m.Role
+		}
+	}
+	if role == "" {
+		role = config.Config.GetString("oidc.default_role")
+	}
+	if role != "" && models.RoleLevel(role) < 0 {
+		return "", fmt.Errorf("统一身份认证角色映射配置错误: 未知角色 %s", role)
+	}
+	return role, nil
+}
+
+// UpsertOIDCPasskey 获取或创建统一身份认证用户对应的 passkey 账户，并同步角色和名称。
+// 角色变化时使此前签发的令牌失效
+func UpsertOIDCPasskey(identity *OIDCIdentity) (*models.Passkey, error) {
+	if identity.Email != "" && !identity.EmailVerified && requireVerifiedEmail() {
+		return nil, ErrOIDCEmailNotVerified
+	}
+	role, err := MapOIDCRole(identity)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrOIDCNoRole
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = identity.Subject
+	}
+	name = truncateString(name, 255)
+	description := "OIDC"
+	if identity.Email != "" {
+		description = "OIDC: " + identity.Email
+	}
+
+	db := database.GetDB()
+	var p models.Passkey
+	err = db.Table("passkeys").Where(map[string]interface{}{"OIDC_Issuer": identity.Issuer, "OIDC_Subject": identity.Subject}).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		p = models.Passkey{
+			Passkey:     uuid.NewString(),
+			Operator:    name,
+			Description: description,
+			IsActive:    true,
+			Extends:     "oidc(" + identity.Issuer + ")",
+			Role:        role,
+			OIDCIssuer:  &identity.Issuer,
+			OIDCSubject: &identity.Subject,
+		}
+		if err := db.Table("passkeys").Create(&p).Error; err != nil {
+			utils.LogError(err)
+			return nil, fmt.Errorf("创建账户失败: %v", err)
+		}
+		return &p, nil
+	}
+	if err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("查询账户失败: %v", err)
+	}
+
+	roleChanged := p.Role != role
+	if roleChanged || p.Operator != name || p.Description != description {
+		if err := db.Table("passkeys").Where(map[string]interface{}{"Passkey": p.Passkey}).Updates(map[string]interface{}{
+			"Role":        role,
+			"Operator":    name,
+			"Description": description,
+		}).Error; err != nil {
+			utils.LogError(err)
+			return nil, fmt.Errorf("更新账户失败: %v", err)
+		}
+		p.Role, p.Operator, p.Description = role, name, description
+	}
+	if roleChanged {
+		if err := RevokeAllTokens(p.Passkey); err != nil {
+			return nil, err
+		}
+		p.TokenVersion++
+	}
+	InvalidatePasskeyCache(p.Passkey)
+	return &p, nil
+}
+
+// IssueOIDCLoginCode 保存登录结果并返回一次性登录码，前端凭登录码领取令牌，避免令牌出现在跳转地址中
+func IssueOIDCLoginCode(p *models.Passkey, pair *TokenPair, now time.Time) string {
+	code := uuid.NewString()
+
+	oidcStateMu.Lock()
+	defer oidcStateMu.Unlock()
+	for key, r := range oidcLoginResults {
+		if now.After(r.expiresAt) {
+			delete(oidcLoginResults, key)
+		}
+	}
+	oidcLoginResults[code] = oidcLoginResult{passkey: p, pair: pair, expiresAt: now.Add(oidcLoginCodeTTL)}
+	return code
+}
+
+// TakeOIDCLoginCode 领取登录结果，每个登录码只能使用一次
+func TakeOIDCLoginCode(code string, now time.Time) (*models.Passkey, *TokenPair, error) {
+	oidcStateMu.Lock()
+	defer oidcStateMu.Unlock()
+	result, ok := oidcLoginResults[code]
+	delete(oidcLoginResults, code)
+	if !ok || now.After(result.expiresAt) {
+		return nil, nil, ErrOIDCCodeInvalid
+	}
+	return result.passkey, result.pair, nil
+}
@@ -0,0 +1,377 @@
+package services
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidRank      = errors.New("不支持的分类阶元")
+	ErrOrganismNotFound = errors.New("来源生物不存在")
+)
+
+// RankColumn 返回分类阶元对应的列名
+func RankColumn(rank string) (string, error) {
+	column, ok := models.TaxonomicRankColumns[strings.ToLower(strings.TrimSpace(rank))]
+	if !ok {
+		return "", ErrInvalidRank
+	}
+	return column, nil
+}
+
+// OrganismSummary 来源生物及其关联的化合物数量
+type OrganismSummary struct {
+	models.Organism
+	Compounds int64 `gorm:"column:Compounds" json:"compounds"`
+}
+
+// ListOrganisms 按分类阶元浏览来源生物，rank 和 value 为空时返回全部
+func ListOrganisms(rank, value string, limit, offset int) ([]OrganismSummary, int64, error) {
+	query := database.GetDB().Table("organisms")
+	if rank != "" {
+		column, err := RankColumn(rank)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where(map[string]interface{}{"organisms." + column: value})
+	}
+
+	var totalCount int64
+	countQuery := query
+	if err := countQuery.Count(&totalCount).Error; err != nil {
+		utils.LogError(err)
+		return nil, 0, fmt.Errorf("获取总记录数失败: %v", err)
+	}
+
+	var organisms []OrganismSummary
+	result := query.Select("?.*, COUNT(?) AS ?", clause.Table{Name: "organisms"}, database.Column("data.ID"), database.Column("Compounds")).
+		Joins("LEFT JOIN data ON ? = ?", database.Column("data.Organism_ID"), database.Column("organisms.ID")).
+		Group("organisms.ID").
+		Order(database.Asc("organisms.Name")).
+		Offset(offset).Limit(limit).
+		Find(&organisms)
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return nil, 0, fmt.Errorf("数据库查询失败: %v", result.Error)
+	}
+	return organisms, totalCount, nil
+}
+
+// TaxonCount 某分类阶元下的一个分类单元及统计
+type TaxonCount struct {
+	Name      string `gorm:"column:Name" json:"name"`
+	Organisms int64  `gorm:"column:Organisms" json:"organisms"`
+	Compounds int64  `gorm:"column:Compounds" json:"compounds"`
+}
+
+// GetTaxa 列出某一分类阶元下的全部分类单元，可用上级阶元限定（如 phylum 下属于 Bacteria 域的门）
+func GetTaxa(rank, parentRank, parentValue string) ([]TaxonCount, error) {
+	column, err := RankColumn(rank)
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.GetDB().Table("organisms").
+		Joins("LEFT JOIN data ON ? = ?", database.Column("data.Organism_ID"), database.Column("organisms.ID")).
+		Where(clause.Neq{Column: "organisms." + column, Value: ""})
+	if parentRank != "" {
+		parentColumn, err := RankColumn(parentRank)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(map[string]interface{}{"organisms." + parentColumn: parentValue})
+	}
+
+	var taxa []TaxonCount
+	result := query.Select("? AS ?, COUNT(DISTINCT ?) AS ?, COUNT(?) AS ?",
+		database.Column("organisms."+column), database.Column("Name"),
+		database.Column("organisms.ID"), database.Column("Organisms"),
+		database.Column("data.ID"), database.Column("Compounds")).
+		Group("organisms." + column).
+		Order(database.Asc("Name")).
+		Find(&taxa)
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return nil, fmt.Errorf("数据库查询失败: %v", result.Error)
+	}
+	return taxa, nil
+}
+
+// GetOrganismByID 根据ID获取来源生物，不存在时返回 ErrOrganismNotFound
+func GetOrganismByID(id uint) (*models.Organism, error) {
+	var organism models.Organism
+	if err := database.GetDB().Table("organisms").Where(map[string]interface{}{"ID": id}).First(&organism).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrganismNotFound
+		}
+		utils.LogError(err)
+		return nil, fmt.Errorf("查询来源生物失败: %v", err)
+	}
+	return &organism, nil
+}
+
+// CountSpecies 统计化合物来源的物种数
+// 已关联分类信息的按种名计数，未鉴定到种的（如 "Streptomyces sp. XXX"）按生物名计数，未关联的按 Source 计数
+func CountSpecies() (int64, error) {
+	var count int64
+	result := database.GetDB().Table("data").
+		Joins("LEFT JOIN organisms ON ? = ?", database.Column("organisms.ID"), database.Column("data.Organism_ID")).
+		Where(clause.Or(clause.Neq{Column: "organisms.ID", Value: nil}, database.NotEmpty("data.Source"))).
+		Select("COUNT(DISTINCT COALESCE(NULLIF(?, ''), ?, ?))",
+			database.Column("organisms.Species"), database.Column("organisms.Name"), database.Column("data.Source")).
+		Scan(&count)
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return 0, fmt.Errorf("统计物种数失败: %v", result.Error)
+	}
+	return count, nil
+}
+
+// OrganismLinkResult 来源关联结果
+type OrganismLinkResult struct {
+	Created int   `json:"created"` // 新建的来源生物数
+	Linked  int64 `json:"linked"`  // 关联的化合物数
+}
+
+// LinkOrganismsFromSource 根据 data.Source 文本创建来源生物并关联到化合物
+// 只处理尚未关联的化合物，Source 相同（忽略首尾空白）的化合物关联到同一来源生物
+func LinkOrganismsFromSource() (*OrganismLinkResult, error) {
+	db := database.GetDB()
+
+	var sources []string
+	result := db.Table("data").
+		Where(clause.Eq{Column: "Organism_ID", Value: nil}, database.NotEmpty("Source")).
+		Distinct().
+		Pluck("Source", &sources)
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return nil, fmt.Errorf("获取Source失败: %v", result.Error)
+	}
+
+	stats := &OrganismLinkResult{}
+	for _, source := range sources {
+		name := strings.Join(strings.Fields(source), " ")
+		if name == "" {
+			continue
+		}
+
+		var organism models.Organism
+		err := db.Table("organisms").Where(map[string]interface{}{"Name": name}).First(&organism).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			organism = models.Organism{Name: name}
+			if err := db.Table("organisms").Create(&organism).Error; err != nil {
+				utils.LogError(err)
+				return stats, fmt.Errorf("创建来源生物失败: %v", err)
+			}
+			stats.Created++
+		} else if err != nil {
+			utils.LogError(err)
+			return stats, fmt.Errorf("查询来源生物失败: %v", err)
+		}
+
+		update := db.Table("data").
+			Where(map[string]interface{}{"Organism_ID": nil, "Source": source}).
+			Update("Organism_ID", organism.ID)
+		if update.Error != nil {
+			utils.LogError(update.Error)
+			return stats, fmt.Errorf("关联来源生物失败: %v", update.Error)
+		}
+		stats.Linked += update.RowsAffected
+	}
+
+	utils.Log(fmt.Sprintf("来源生物关联完成: 新建%d个, 关联化合物%d个", stats.Created, stats.Linked))
+	return stats, nil
+}
+
+// TaxonomyImportResult 分类谱系导入结果
+type TaxonomyImportResult struct {
+	Organisms     int `json:"organisms"`       // 待处理的来源生物数
+	Matched       int `json:"matched"`         // 按名称精确匹配的数量
+	GenusMatched  int `json:"genus_matched"`   // 仅匹配到属的数量（如 "Streptomyces sp. XXX"）
+	Unmatched     int `json:"unmatched"`       // 未匹配的数量
+	ScannedLines  int `json:"scanned_lines"`   // 扫描的 rankedlineage 行数
+	UpdatedTaxIDs int `json:"updated_tax_ids"` // 写入 Tax_ID 的数量
+}
+
+// taxonomyEntry NCBI rankedlineage.dmp 中的一行
+type taxonomyEntry struct {
+	TaxID   int64
+	Name    string
+	Lineage map[string]string // 祖先阶元 → 名称，不包含自身
+}
+
+// splitDmpLine 拆分 NCBI .dmp 文件的一行（字段以 "\t|\t" 分隔，以 "\t|" 结尾）
+func splitDmpLine(line string) []string {
+	line = strings.TrimSuffix(strings.TrimRight(line, "\r\n"), "\t|")
+	return strings.Split(line, "\t|\t")
+}
+
+// ImportTaxonomyDump 从本地 NCBI taxonomy 数据（new_taxdump）中补全来源生物的分类谱系
+// dumpDir 目录下需要包含 rankedlineage.dmp 和 nodes.dmp；只处理 organisms 表中已有的来源生物
+func ImportTaxonomyDump(dumpDir string) (*TaxonomyImportResult, error) {
+	db := database.GetDB()
+
+	var organisms []models.Organism
+	if err := db.Table("organisms").Find(&organisms).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取来源生物失败: %v", err)
+	}
+	stats := &TaxonomyImportResult{Organisms: len(organisms)}
+	if len(organisms) == 0 {
+		return stats, nil
+	}
+
+	// 需要查找的名称：完整名称，以及作为后备的属名（第一个单词）
+	wanted := map[string]bool{}
+	for _, o := range organisms {
+		wanted[strings.ToLower(o.Name)] = true
+		if genus := strings.Fields(o.Name); len(genus) > 0 {
+			wanted[strings.ToLower(genus[0])] = true
+		}
+	}
+
+	// 第一遍：扫描 rankedlineage.dmp，只保留需要的名称
+	lineageFile, err := os.Open(filepath.Join(dumpDir, "rankedlineage.dmp"))
+	if err != nil {
+		return nil, fmt.Errorf("打开rankedlineage.dmp失败: %v", err)
+	}
+	defer lineageFile.Close()
+
+	entries := map[string]taxonomyEntry{}
+	scanner := bufio.NewScanner(lineageFile)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		stats.ScannedLines++
+		fields := splitDmpLine(scanner.Text())
+		// tax_id, tax_name, species, genus, family, order, class, phylum, kingdom, superkingdom
+		if len(fields) < 10 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(fields[1]))
+		if !wanted[key] {
+			continue
+		}
+		// 同名时保留第一个（NCBI 中同名不同义的情况极少）
+		if _, exists := entries[key]; exists {
+			continue
+		}
+		taxID, err := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64)
+		if err != nil {
+			continue
+		}
+		entries[key] = taxonomyEntry{
+			TaxID: taxID,
+			Name:  strings.TrimSpace(fields[1]),
+			Lineage: map[string]string{
+				"species": strings.TrimSpace(fields[2]),
+				"genus":   strings.TrimSpace(fields[3]),
+				"family":  strings.TrimSpace(fields[4]),
+				"order":   strings.TrimSpace(fields[5]),
+				"class":   strings.TrimSpace(fields[6]),
+				"phylum":  strings.TrimSpace(fields[7]),
+				"kingdom": strings.TrimSpace(fields[8]),
+				"domain":  strings.TrimSpace(fields[9]),
+			},
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取rankedlineage.dmp失败: %v", err)
+	}
+
+	// 第二遍：从 nodes.dmp 获取匹配条目自身的阶元
+	taxIDs := map[int64]string{}
+	for _, e := range entries {
+		taxIDs[e.TaxID] = ""
+	}
+	nodesFile, err := os.Open(filepath.Join(dumpDir, "nodes.dmp"))
+	if err != nil {
+		return nil, fmt.Errorf("打开nodes.dmp失败: %v", err)
+	}
+	defer nodesFile.Close()
+
+	scanner = bufio.NewScanner(nodesFile)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := splitDmpLine(scanner.Text())
+		// tax_id, parent tax_id, rank, ...
+		if len(fields) < 3 {
+			continue
+		}
+		taxID, err := strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, ok := taxIDs[taxID]; ok {
+			taxIDs[taxID] = strings.TrimSpace(fields[2])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取nodes.dmp失败: %v", err)
+	}
+
+	for _, o := range organisms {
+		entry, exact := entries[strings.ToLower(o.Name)]
+		if !exact {
+			words := strings.Fields(o.Name)
+			if len(words) == 0 {
+				stats.Unmatched++
+				continue
+			}
+			genusEntry, ok := entries[strings.ToLower(words[0])]
+			if !ok || taxIDs[genusEntry.TaxID] != "genus" {
+				stats.Unmatched++
+				continue
+			}
+			entry = genusEntry
+		}
+
+		rank := taxIDs[entry.TaxID]
+		lineage := map[string]string{}
+		for r, v := range entry.Lineage {
+			lineage[r] = v
+		}
+		// NCBI 中 superkingdom 对应 domain；自身所在阶元的名称即条目名称
+		if rank == "superkingdom" {
+			rank = "domain"
+		}
+		if _, ok := models.TaxonomicRankColumns[rank]; ok {
+			lineage[rank] = entry.Name
+		}
+
+		updates := map[string]interface{}{}
+		for _, r := range models.TaxonomicRanks {
+			updates[models.TaxonomicRankColumns[r]] = lineage[r]
+		}
+		if exact {
+			stats.Matched++
+			updates["Tax_Rank"] = rank
+			// Tax_ID 唯一，只为精确匹配的来源生物写入
+			updates["Tax_ID"] = entry.TaxID
+			stats.UpdatedTaxIDs++
+		} else {
+			stats.GenusMatched++
+			updates["Tax_Rank"] = ""
+			updates["Species"] = ""
+		}
+
+		if err := db.Table("organisms").Where(map[string]interface{}{"ID": o.ID}).Updates(updates).Error; err != nil {
+			utils.LogError(err)
+			return stats, fmt.Errorf("更新来源生物(%s)分类谱系失败: %v", o.Name, err)
+		}
+	}
+
+	utils.Log(fmt.Sprintf("分类谱系导入完成: 来源生物%d个, 精确匹配%d个, 属匹配%d个, 未匹配%d个",
+		stats.Organisms, stats.Matched, stats.GenusMatched, stats.Unmatched))
+	return stats, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPasskeyInactive    = errors.New("passkey已禁用")
+	ErrPasskeyNotYetValid = errors.New("passkey尚未生效")
+	ErrPasskeyExpired     = errors.New("passkey已过期")
+	ErrLoginLimitReached  = errors.New("passkey登录次数已用完")
+
+	ErrInvalidValidity      = errors.New("失效时间必须晚于生效时间")
+	ErrInvalidMaxLogins     = errors.New("最大登录次数必须是正整数")
+	ErrInvalidTokenLifetime = fmt.Errorf("令牌有效期必须在 %d 到 %d 分钟之间", MinTokenLifetime, MaxTokenLifetime)
+	ErrNegativeLimit        = errors.New("频率限制和配额不能为负数")
+)
+
+// 默认令牌有效期（分钟），可通过 jwt.token_lifetime 配置
+const defaultTokenLifetime = 3 * 24 * 60
+
+// 单个 passkey 可设置的令牌有效期范围（分钟）
+const (
+	MinTokenLifetime = 5
+	MaxTokenLifetime = 30 * 24 * 60
+)
+
+// passkey 状态
+const (
+	PasskeyStatusActive    = "active"
+	PasskeyStatusDisabled  = "disabled"
+	PasskeyStatusPending   = "pending"   // 尚未到生效时间
+	PasskeyStatusExpired   = "expired"   // 已超过失效时间
+	PasskeyStatusExhausted = "exhausted" // 登录次数已用完
+)
+
+// CheckPasskeyValidity 检查 passkey 在指定时间是否可用（启用状态和有效期），不检查登录次数
+func CheckPasskeyValidity(p *models.Passkey, now time.Time) error {
+	if !p.IsActive {
+		return ErrPasskeyInactive
+	}
+	if p.ValidFrom != nil && now.Before(*p.ValidFrom) {
+		return ErrPasskeyNotYetValid
+	}
+	if p.ValidUntil != nil && !now.Before(*p.ValidUntil) {
+		return ErrPasskeyExpired
+	}
+	return nil
+}
+
+// PasskeyStatus 返回 passkey 当前的状态
+func PasskeyStatus(p *models.Passkey, now time.Time) string {
+	switch CheckPasskeyValidity(p, now) {
+	case ErrPasskeyInactive:
+		return PasskeyStatusDisabled
+	case ErrPasskeyNotYetValid:
+		return PasskeyStatusPending
+	case ErrPasskeyExpired:
+		return PasskeyStatusExpired
+	}
+	if p.MaxLogins != nil && p.LoginCount >= *p.MaxLogins {
+		return PasskeyStatusExhausted
+	}
+	return PasskeyStatusActive
+}
+
+// TokenLifetime 计算为 passkey 签发的令牌有效期：优先使用 passkey 自身的设置，
+// 其次为 jwt.token_lifetime 配置，且不会超过 passkey 的失效时间
+func TokenLifetime(p *models.Passkey, now time.Time) time.Duration {
+	minutes := config.Config.GetInt("jwt.token_lifetime")
+	if minutes <= 0 {
+		minutes = defaultTokenLifetime
+	}
+	if p.TokenLifetime != nil && *p.TokenLifetime > 0 {
+		minutes = *p.TokenLifetime
+	}
+	lifetime := time.Duration(minutes) * time.Minute
+	if p.ValidUntil != nil {
+		if remaining := p.ValidUntil.Sub(now); remaining < lifetime {
+			lifetime = remaining
+		}
+	}
+	return lifetime
+}
+
+// ValidatePasskeyLimits 校验 passkey 的有效期、登录次数和令牌有效期设置
+func ValidatePasskeyLimits(p *models.Passkey) error {
+	if p.ValidFrom != nil && p.ValidUntil != nil && !p.ValidUntil.After(*p.ValidFrom) {
+		return ErrInvalidValidity
+	}
+	if p.MaxLogins != nil && *p.MaxLogins < 1 {
+		return ErrInvalidMaxLogins
+	}
+	if p.TokenLifetime != nil && (*p.TokenLifetime < MinTokenLifetime || *p.TokenLifetime > MaxTokenLifetime) {
+		return ErrInvalidTokenLifetime
+	}
+	for _, limit := range []*int{p.RateLimit, p.DailyQuota, p.MonthlyQuota} {
+		if limit != nil && *limit < 0 {
+			return ErrNegativeLimit
+		}
+	}
+	return nil
+}
+
+// RecordLogin 检查 passkey 是否可以登录并记录一次登录。
+// 登录次数在数据库中原子递增，并发登录也不会超过最大登录次数
+func RecordLogin(p *models.Passkey, now time.Time) error {
+	if err := CheckPasskeyValidity(p, now); err != nil {
+		return err
+	}
+
+	result := database.GetDB().Table("passkeys").
+		Where(map[string]interface{}{"Passkey": p.Passkey}).
+		Where(clause.Or(
+			clause.Eq{Column: "Max_Logins", Value: nil},
+			clause.Expr{SQL: "? < ?", Vars: []interface{}{database.Column("Login_Count"), database.Column("Max_Logins")}},
+		)).
+		Updates(map[string]interface{}{
+			"Login_Count":   gorm.Expr("? + 1", database.Column("Login_Count")),
+			"Last_Login_At": now,
+		})
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return fmt.Errorf("记录登录失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrLoginLimitReached
+	}
+
+	p.LoginCount++
+	p.LastLoginAt = &now
+	return nil
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"backend/database"
+	"backend/migrations"
+	"backend/models"
+	"backend/repository"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// PostgreSQL 会把未加引号的标识符转为小写，而表结构中的列名区分大小写（如 Passkey、Is_Active），
+// 查询中的列名必须加引号。以下测试用 PostgreSQL 方言执行主要的查询，记录发送到数据库的 SQL，
+// 检查其中没有未加引号的列名。测试不连接数据库，所有查询都返回空结果
+
+// upperCaseColumns 全大写的列名，无法与 SQL 关键字区分，需要单独列出
+var upperCaseColumns = map[string]bool{
+	"ID":     true,
+	"SMILES": true,
+	"FP":     true,
+	"DOI":    true,
+	"JTI":    true,
+	"AAGUID": true,
+}
+
+// isColumnName 判断单词是否为列名：大小写混合（如 Is_Active）、含大写字母和数字（如 MS2）或在 upperCaseColumns 中。
+// SQL 关键字和函数名在代码中统一大写或小写
+func isColumnName(word string) bool {
+	if upperCaseColumns[word] {
+		return true
+	}
+	var upper, lower, digit bool
+	for i := 0; i < len(word); i++ {
+		switch c := word[i]; {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && (lower || digit)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// unquotedColumns 返回 SQL 中未加引号的列名，跳过字符串常量、已加引号的标识符和数字
+func unquotedColumns(query string) []string {
+	var words []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			// 字符串常量或已加引号的标识符，连续两个引号为转义
+			j := i + 1
+			for j < len(query) {
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j += 2
+						continue
+					}
+					j++
+					break
+				}
+				j++
+			}
+			i = j
+		case c >= '0' && c <= '9':
+			// 数字和 $1 等占位符的序号
+			j := i + 1
+			for j < len(query) && (isIdentPart(query[j]) || query[j] == '.') {
+				j++
+			}
+			i = j
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(query) && isIdentPart(query[j]) {
+				j++
+			}
+			if word := query[i:j]; isColumnName(word) {
+				words = append(words, word)
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return words
+}
+
+func TestUnquotedColumns(t *testing.T) {
+	cases := map[string]string{
+		`SELECT * FROM "passkeys" WHERE "Passkey" = $1`:           "",
+		`SELECT * FROM "passkeys" WHERE Passkey = $1`:             "Passkey",
+		`SELECT "ID" FROM "data" WHERE ItemName LIKE 'Is_Active'`: "ItemName",
+		`SELECT count(*) FROM "data" ORDER BY ID LIMIT 10`:        "ID",
+		`SELECT MS2_full FROM "data" WHERE "FP" IS NOT NULL`:      "MS2_full",
+		`SELECT COUNT(DISTINCT "organisms"."ID") AS "Organisms"`:  "",
+	}
+	for query, want := range cases {
+		if got := strings.Join(unquotedColumns(query), ","); got != want {
+			t.Errorf("unquotedColumns(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+// recordingDriver 记录收到的 SQL 并返回空结果的数据库驱动
+type recordingDriver struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (d *recordingDriver) record(query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, query)
+}
+
+func (d *recordingDriver) take() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	queries := d.queries
+	d.queries = nil
+	return queries
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) {
+	return &recordingConn{driver: d}, nil
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{conn: c, query: query}, nil
+}
+
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *recordingConn) Commit() error             { return nil }
+func (c *recordingConn) Rollback() error           { return nil }
+
+func (c *recordingConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *recordingConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.driver.record(query)
+	return emptyRows{}, nil
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.driver.record(query)
+	return driver.RowsAffected(0), nil
+}
+
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.conn.driver.record(s.query)
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.conn.driver.record(s.query)
+	return emptyRows{}, nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+var (
+	postgresDialectDriver = &recordingDriver{}
+	registerDriverOnce    sync.Once
+)
+
+// usePostgresDialect 将 database.DB 替换为使用 PostgreSQL 方言、连接 recordingDriver 的数据库，测试结束后恢复
+func usePostgresDialect(t *testing.T) *recordingDriver {
+	t.Helper()
+	registerDriverOnce.Do(func() { sql.Register("postgres-dialect-test", postgresDialectDriver) })
+	db, err := gorm.Open(postgres.New(postgres.Config{DriverName: "postgres-dialect-test"}),
+		&gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+	postgresDialectDriver.take()
+	return postgresDialectDriver
+}
+
+func TestPostgresDialectQueries(t *testing.T) {
+	recorder := usePostgresDialect(t)
+	now := time.Now()
+	passkey := "00000000-0000-0000-0000-000000000001"
+	parent := "00000000-0000-0000-0000-000000000002"
+	p := &models.Passkey{Passkey: passkey, Operator: "reader", Role: models.RoleProtectedReader, IsActive: true, DailyQuota: intPtr(10)}
+	value := 5.0
+	doi := "10.1000/xyz"
+	pubmed := "12345"
+
+	steps := []struct {
+		name string
+		run  func()
+	}{
+		{"migrations", func() { migrations.Up(database.GetDB(), 0) }},
+		{"passkeys", func() {
+			GetPasskey(passkey)
+			RecordLogin(p, now)
+			IsDescendant(parent, passkey)
+			GetDescendantPasskeys(parent)
+			DisablePasskeyTree(parent, true)
+			ReparentChildren(passkey, &parent)
+			getAuthPasskey(passkey, now)
+		}},
+		{"tokens and sessions", func() {
+			isTokenRevoked("jti", now)
+			RevokeToken("jti", passkey, now)
+			RevokeAllTokens(passkey)
+			StartSession(p, "127.0.0.1", "test", now)
+			RefreshSession("session.secret", now)
+			ListSessions(passkey, false, now)
+			RevokeSession(passkey, "session", "logout")
+			RevokeSessionsByPasskey(passkey, "all")
+			isSessionRevoked("session", now)
+		}},
+		{"repositories", func() {
+			compounds := repository.NewGormCompoundRepository()
+			compounds.List(0, 10)
+			compounds.FindPublic("MNP001")
+			compounds.FindProtected("MNP001", []string{"MS2", "Bioactivity"})
+			compounds.FindMS2Full("MNP001")
+			compounds.FindStructure("MNP001")
+			compounds.Exists("MNP001")
+			compounds.Statistics()
+			passkeys := repository.NewGormPasskeyRepository()
+			passkeys.Find(passkey)
+			passkeys.ListByRoles([]string{models.RoleSuperadmin})
+			passkeys.UpdateColumns(p, "Operator", "Is_Active", "Max_Logins")
+			passkeys.Delete(passkey)
+		}},
+		{"quota", func() {
+			if reservation, err := ConsumeQuota(p, now); err == nil {
+				ExtendQuota(reservation, now, 2)
+				reservation.Remaining()
+				RefundQuota(reservation)
+			}
+			GetQuotaUsageBatch([]models.Passkey{*p}, now)
+			DeleteQuotaUsageByPasskey(passkey)
+		}},
+		{"grants", func() {
+			CreateDataGrant(&models.DataGrant{Passkey: passkey, Fields: "MS2", ScopeType: models.GrantScopeIDs, ScopeValues: "MNP001"})
+			GetDataGrants(passkey)
+			DeleteDataGrant(passkey, 1)
+			DeleteDataGrantsByPasskey(passkey)
+			GrantedFields(passkey, models.RoleViewer, "MNP001")
+		}},
+		{"audit", func() {
+			filter := AuditFilter{Passkey: passkey, PasskeyLabel: "label", Action: "a", ResourceID: "MNP001", Field: "MS2",
+				Outcome: models.AuditOutcomeSuccess, ClientIP: "127.0.0.1", From: &now, To: &now, Limit: 10}
+			writeAuditEvents([]models.AuditEvent{{CreatedAt: now, Action: "a", Outcome: models.AuditOutcomeSuccess}})
+			QueryAuditEvents(filter)
+			ExportAuditEventsCSV(io.Discard, filter)
+		}},
+		{"compounds", func() {
+			FilterCompounds([]string{"Alkaloid"}, 100, 500, []string{"antibacterial"}, []string{"Streptomyces"}, "genus", "Streptomyces", 10, 0)
+			FilterCompounds([]string{"other"}, 0, 0, nil, nil, "", "", 10, 0)
+			GetItemTypes()
+			GetDescriptions()
+			GetSources()
+			GetCompoundIsotopePattern("MNP001", "[M+H]+", 0, 0)
+			buildSearchIndex()
+			loadSearchDocs("MNP001")
+			PingDatabase(context.Background())
+		}},
+		{"bioactivity", func() {
+			record := models.Bioactivity{ID: 1, DataID: "MNP001", Endpoint: models.EndpointIC50, Qualifier: "=", Value: &value, Units: "µM"}
+			ImportBioactivityFromText(false)
+			GetBioactivityByDataID("MNP001")
+			FilterBioactivity(BioactivityFilter{Endpoints: []string{models.EndpointIC50}, AssayTypes: []string{"cytotoxicity"},
+				Target: "HeLa", CellLine: "HeLa", Organism: "E. coli", MinValue: &value, MaxValue: &value, Units: "µM", Limit: 10})
+			CreateBioactivity(&record)
+			UpdateBioactivity(&record)
+			GetBioactivityByID(1)
+			DeleteBioactivity(1)
+		}},
+		{"organisms", func() {
+			ListOrganisms("genus", "Streptomyces", 10, 0)
+			GetTaxa("species", "genus", "Streptomyces")
+			GetOrganismByID(1)
+			CountSpecies()
+			LinkOrganismsFromSource()
+		}},
+		{"references and synonyms", func() {
+			ref := models.Reference{ID: 1, Title: "Title", DOI: &doi, PubMedID: &pubmed}
+			CreateReference(&ref)
+			UpdateReference(&ref)
+			GetReferenceByID(1)
+			DeleteReference(1)
+			ListReferences("keyword", 10, 0)
+			GetReferencesByDataIDs([]string{"MNP001"})
+			GetReferencedCompounds(1)
+			LinkReference("MNP001", 1, []string{"Structure"})
+			UnlinkReference("MNP001", 1, "Structure")
+			GetSynonymsByDataID("MNP001")
+			CreateSynonym(&models.Synonym{DataID: "MNP001", Name: "name", NameType: "common"})
+			DeleteSynonym("MNP001", 1)
+		}},
+		{"webauthn and oidc", func() {
+			ensureWebAuthnID(p)
+			loadWebAuthnUser(p)
+			GetWebAuthnCredentials(passkey)
+			DeleteWebAuthnCredential(passkey, 1, true)
+			DeleteWebAuthnCredentialsByPasskey(passkey)
+			ResetWebAuthnEnrollment(passkey)
+			UpsertOIDCPasskey(&OIDCIdentity{Issuer: "https://idp.example.com", Subject: "subject", Email: "user@example.com", EmailVerified: true})
+		}},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			step.run()
+			queries := recorder.take()
+			if len(queries) == 0 {
+				t.Fatal("no queries were sent to the database")
+			}
+			for _, query := range queries {
+				if columns := unquotedColumns(query); len(columns) > 0 {
+					t.Errorf("unquoted columns %v in %s", columns, query)
+				}
+			}
+		})
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrQuotaExceeded = errors.New("保护数据读取次数已达上限")
+
+// QuotaExceededError 超出配额时返回，包含被超出的周期和配额重置时间
+type QuotaExceededError struct {
+	Period   string
+	Limit    int
+	ResetsAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	if e.Period == models.QuotaPeriodMonth {
+		return fmt.Sprintf("本月保护数据读取次数已达上限（%d 次）", e.Limit)
+	}
+	return fmt.Sprintf("今日保护数据读取次数已达上限（%d 次）", e.Limit)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// QuotaStatus 一个配额周期的使用情况
+type QuotaStatus struct {
+	Period      string    `json:"period"`       // day 或 month
+	PeriodStart string    `json:"period_start"` // 周期起始日期
+	Limit       int       `json:"limit"`        // 0 表示不限制
+	Used        int       `json:"used"`
+	Remaining   *int      `json:"remaining,omitempty"` // 不限制时为空
+	ResetsAt    time.Time `json:"resets_at"`
+}
+
+// QuotaReservation 一次请求占用的配额，请求失败时通过 RefundQuota 退还
+type QuotaReservation struct {
+	passkey string
+	periods map[string]string // 周期 -> 周期起始日期
+	limits  map[string]int    // 周期 -> 配额
+	count   int               // 每个周期占用的次数
+}
+
+// quotaPeriod 返回配额周期的起始日期和重置时间（服务器本地时间）
+func quotaPeriod(period string, now time.Time) (string, time.Time) {
+	now = now.In(time.Local)
+	if period == models.QuotaPeriodMonth {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+		return start.Format("2006-01-02"), start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	return start.Format("2006-01-02"), start.AddDate(0, 0, 1)
+}
+
+// QuotaLimits 返回 passkey 的每日和每月配额：优先使用 passkey 自身的设置，其次为 quota.daily 和 quota.monthly 配置，0 表示不限制
+func QuotaLimits(p *models.Passkey) map[string]int {
+	limits := map[string]int{
+		models.QuotaPeriodDay:   config.Config.GetInt("quota.daily"),
+		models.QuotaPeriodMonth: config.Config.GetInt("quota.monthly"),
+	}
+	if p.DailyQuota != nil {
+		limits[models.QuotaPeriodDay] = *p.DailyQuota
+	}
+	if p.MonthlyQuota != nil {
+		limits[models.QuotaPeriodMonth] = *p.MonthlyQuota
+	}
+	return limits
+}
+
+// IsQuotaExempt 判断角色是否不受配额限制（quota.exempt_roles）
+func IsQuotaExempt(role string) bool {
+	return containsString(config.Config.GetStringSlice("quota.exempt_roles"), role)
+}
+
+// ErrorCode 返回超出的周期对应的错误代码
+func (e *QuotaExceededError) ErrorCode() utils.ErrorCode {
+	if e.Period == models.QuotaPeriodMonth {
+		return utils.CodeMonthlyQuotaExceeded
+	}
+	return utils.CodeDailyQuotaExceeded
+}
+
+// ConsumeQuota 为一次保护记录读取占用每日和每月配额。计数在数据库中原子递增，
+// 任一周期已用完时退还已占用的配额并返回 *QuotaExceededError
+func ConsumeQuota(p *models.Passkey, now time.Time) (*QuotaReservation, error) {
+	reservation := &QuotaReservation{passkey: p.Passkey, periods: map[string]string{}, limits: QuotaLimits(p), count: 1}
+	db := database.GetDB()
+
+	for _, period := range []string{models.QuotaPeriodDay, models.QuotaPeriodMonth} {
+		limit := reservation.limits[period]
+		if limit <= 0 {
+			continue
+		}
+		start, resetsAt := quotaPeriod(period, now)
+
+		if err := db.Table("quota_usage").Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.QuotaUsage{Passkey: p.Passkey, Period: period, PeriodStart: start}).Error; err != nil {
+			utils.LogError(err)
+			RefundQuota(reservation)
+			return nil, fmt.Errorf("记录配额失败: %v", err)
+		}
+		ok, err := incrementQuota(p.Passkey, period, start, limit, 1)
+		if err != nil {
+			RefundQuota(reservation)
+			return nil, err
+		}
+		if !ok {
+			RefundQuota(reservation)
+			return nil, &QuotaExceededError{Period: period, Limit: limit, ResetsAt: resetsAt}
+		}
+		reservation.periods[period] = start
+	}
+	return reservation, nil
+}
+
+// ExtendQuota 在 reservation 占用的周期中再占用 n 次，用于一次请求返回多条保护记录。
+// 任一周期剩余不足 n 次时不占用并返回 *QuotaExceededError
+func ExtendQuota(reservation *QuotaReservation, now time.Time, n int) error {
+	if reservation == nil || n <= 0 {
+		return nil
+	}
+	extended := map[string]string{}
+	rollback := func() {
+		for period, start := range extended {
+			decrementQuota(reservation.passkey, period, start, n)
+		}
+	}
+	for _, period := range []string{models.QuotaPeriodDay, models.QuotaPeriodMonth} {
+		start, ok := reservation.periods[period]
+		if !ok {
+			continue
+		}
+		limit := reservation.limits[period]
+		ok, err := incrementQuota(reservation.passkey, period, start, limit, n)
+		if err != nil {
+			rollback()
+			return err
+		}
+		if !ok {
+			rollback()
+			_, resetsAt := quotaPeriod(period, now)
+			return &QuotaExceededError{Period: period, Limit: limit, ResetsAt: resetsAt}
+		}
+		extended[period] = start
+	}
+	reservation.count += n
+	return nil
+}
+
+// Remaining 返回 reservation 占用的周期中最少的剩余次数，不受配额限制时第二个返回值为 false
+func (r *QuotaReservation) Remaining() (int, bool, error) {
+	if r == nil || len(r.periods) == 0 {
+		return 0, false, nil
+	}
+	remaining := -1
+	for period, start := range r.periods {
+		var usage models.QuotaUsage
+		if err := database.GetDB().Table("quota_usage").
+			Where(map[string]interface{}{"Passkey": r.passkey, "Period": period, "Period_Start": start}).
+			Take(&usage).Error; err != nil {
+			utils.LogError(err)
+			return 0, true, fmt.Errorf("查询配额失败: %v", err)
+		}
+		left := r.limits[period] - usage.Count
+		if left < 0 {
+			left = 0
+		}
+		if remaining < 0 || left < remaining {
+			remaining = left
+		}
+	}
+	return remaining, true, nil
+}
+
+// incrementQuota 在剩余次数不少于 n 时将计数增加 n，返回是否增加
+func incrementQuota(passkey, period, start string, limit, n int) (bool, error) {
+	result := database.GetDB().Table("quota_usage").
+		Where(map[string]interface{}{"Passkey": passkey, "Period": period, "Period_Start": start}).
+		Where(clause.Lte{Column: "Count", Value: limit - n}).
+		Update("Count", gorm.Expr("? + ?", database.Column("Count"), n))
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return false, fmt.Errorf("记录配额失败: %v", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// decrementQuota 将计数减少 n
+func decrementQuota(passkey, period, start string, n int) {
+	if err := database.GetDB().Table("quota_usage").
+		Where(map[string]interface{}{"Passkey": passkey, "Period": period, "Period_Start": start}).
+		Where(clause.Gte{Column: "Count", Value: n}).
+		Update("Count", gorm.Expr("? - ?", database.Column("Count"), n)).Error; err != nil {
+		utils.LogError(err)
+	}
+}
+
+// RefundQuota 退还占用的配额（如请求的数据不存在或无权访问）
+func RefundQuota(reservation *QuotaReservation) {
+	if reservation == nil {
+		return
+	}
+	for period, start := range reservation.periods {
+		decrementQuota(reservation.passkey, period, start, reservation.count)
+	}
+	reservation.periods = map[string]string{}
+	reservation.count = 0
+}
+
+// GetQuotaUsage 返回 passkey 当前周期的配额使用情况
+func GetQuotaUsage(p *models.Passkey, now time.Time) ([]QuotaStatus, error) {
+	usage, err := GetQuotaUsageBatch([]models.Passkey{*p}, now)
+	if err != nil {
+		return nil, err
+	}
+	return usage[p.Passkey], nil
+}
+
+// GetQuotaUsageBatch 批量返回多个 passkey 当前周期的配额使用情况
+func GetQuotaUsageBatch(passkeys []models.Passkey, now time.Time) (map[string][]QuotaStatus, error) {
+	ids := make([]string, 0, len(passkeys))
+	for _, p := range passkeys {
+		ids = append(ids, p.Passkey)
+	}
+
+	// 周期 -> passkey -> 已用次数
+	used := map[string]map[string]int{}
+	for _, period := range []string{models.QuotaPeriodDay, models.QuotaPeriodMonth} {
+		start, _ := quotaPeriod(period, now)
+		var rows []models.QuotaUsage
+		if len(ids) > 0 {
+			if err := database.GetDB().Table("quota_usage").
+				Where(map[string]interface{}{"Passkey": ids, "Period": period, "Period_Start": start}).
+				Find(&rows).Error; err != nil {
+				utils.LogError(err)
+				return nil, fmt.Errorf("查询配额失败: %v", err)
+			}
+		}
+		used[period] = map[string]int{}
+		for _, row := range rows {
+			used[period][row.Passkey] = row.Count
+		}
+	}
+
+	result := make(map[string][]QuotaStatus, len(passkeys))
+	for i := range passkeys {
+		p := &passkeys[i]
+		limits := QuotaLimits(p)
+		statuses := make([]QuotaStatus, 0, 2)
+		for _, period := range []string{models.QuotaPeriodDay, models.QuotaPeriodMonth} {
+			start, resetsAt := quotaPeriod(period, now)
+			status := QuotaStatus{
+				Period:      period,
+				PeriodStart: start,
+				Limit:       limits[period],
+				Used:        used[period][p.Passkey],
+				ResetsAt:    resetsAt,
+			}
+			if status.Limit > 0 {
+				remaining := status.Limit - status.Used
+				if remaining < 0 {
+					remaining = 0
+				}
+				status.Remaining = &remaining
+			}
+			statuses = append(statuses, status)
+		}
+		result[p.Passkey] = statuses
+	}
+	return result, nil
+}
+
+// DeleteQuotaUsageByPasskey 删除 passkey 的配额记录（删除 passkey 时使用）
+func DeleteQuotaUsageByPasskey(passkey string) error {
+	if err := database.GetDB().Table("quota_usage").Where(map[string]interface{}{"Passkey": passkey}).Delete(&models.QuotaUsage{}).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("删除配额记录失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"backend/config"
+	"backend/models"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("请求过于频繁，请稍后再试")
+
+// 频率限制按每分钟请求数配置，使用令牌桶：允许短时突发，平均速率不超过限制
+const rateLimitWindow = time.Minute
+
+// 令牌桶数量超过该值时清理空闲的令牌桶
+const maxRateBuckets = 100000
+
+type rateBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+var (
+	rateMu      sync.Mutex
+	rateBuckets = make(map[string]*rateBucket)
+)
+
+// RateLimitResult 频率检查结果，用于设置 X-RateLimit-* 和 Retry-After 响应头
+type RateLimitResult struct {
+	Limit      int           // 每分钟请求数
+	Remaining  int           // 当前剩余请求数
+	RetryAfter time.Duration // 被限制时距下一次可请求的时间
+}
+
+// takeRateToken 从 key 对应的令牌桶中取出一个令牌，limit 为每分钟请求数
+func takeRateToken(key string, limit int, now time.Time) RateLimitResult {
+	rate := float64(limit) / rateLimitWindow.Seconds()
+
+	rateMu.Lock()
+	defer rateMu.Unlock()
+
+	bucket, ok := rateBuckets[key]
+	if !ok {
+		if len(rateBuckets) >= maxRateBuckets {
+			pruneRateBuckets(now)
+		}
+		bucket = &rateBucket{tokens: float64(limit), updated: now}
+		rateBuckets[key] = bucket
+	} else {
+		bucket.tokens = math.Min(float64(limit), bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+		bucket.updated = now
+	}
+
+	result := RateLimitResult{Limit: limit}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Remaining = int(bucket.tokens)
+		return result
+	}
+	result.RetryAfter = time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	return result
+}
+
+// pruneRateBuckets 删除空闲超过一个周期的令牌桶（此时已回满，删除不影响限制），调用方需持有 rateMu
+func pruneRateBuckets(now time.Time) {
+	for key, bucket := range rateBuckets {
+		if now.Sub(bucket.updated) >= rateLimitWindow {
+			delete(rateBuckets, key)
+		}
+	}
+}
+
+// PasskeyRateLimit 返回 passkey 每分钟可请求的次数：优先使用 passkey 自身的设置，其次为 rate_limit.passkey 配置，0 表示不限制
+func PasskeyRateLimit(p *models.Passkey) int {
+	if p != nil && p.RateLimit != nil {
+		return *p.RateLimit
+	}
+	return config.Config.GetInt("rate_limit.passkey")
+}
+
+// CheckRateLimit 检查客户端 IP 和 passkey 的请求频率（passkey 为空时只检查 IP），
+// 超过限制时返回 ErrRateLimited。rate_limit.exempt_roles 中的角色不受 passkey 频率限制
+func CheckRateLimit(p *models.Passkey, role, clientIP string, now time.Time) (*RateLimitResult, error) {
+	var result *RateLimitResult
+
+	if limit := config.Config.GetInt("rate_limit.ip"); limit > 0 && clientIP != "" {
+		r := takeRateToken("ip:"+clientIP, limit, now)
+		if r.RetryAfter > 0 {
+			return &r, ErrRateLimited
+		}
+		result = &r
+	}
+
+	if p != nil && !containsString(config.Config.GetStringSlice("rate_limit.exempt_roles"), role) {
+		if limit := PasskeyRateLimit(p); limit > 0 {
+			r := takeRateToken("passkey:"+p.Passkey, limit, now)
+			if r.RetryAfter > 0 {
+				return &r, ErrRateLimited
+			}
+			// 同时受两种限制时返回剩余次数较少的一个
+			if result == nil || r.Remaining < result.Remaining {
+				result = &r
+			}
+		}
+	}
+	return result, nil
+}
+
+// 按客户端 IP 限制频率的接口范围，对应 rate_limit.public 和 rate_limit.login 配置
+const (
+	IPScopePublic = "public" // 全部 /api 接口，包括无需认证的接口
+	IPScopeLogin  = "login"  // 登录、刷新令牌和兑换登录码等可被用于猜测凭据的接口
+)
+
+// 未配置 rate_limit.public 和 rate_limit.login 时使用的每分钟请求数，使无需认证的接口默认也受限制
+var defaultIPRateLimits = map[string]int{
+	IPScopePublic: 300,
+	IPScopeLogin:  10,
+}
+
+// IPRateLimit 返回客户端 IP 在 scope 内每分钟可请求的次数，未配置时使用默认值，0 表示不限制
+func IPRateLimit(scope string) int {
+	key := "rate_limit." + scope
+	if !config.Config.IsSet(key) {
+		return defaultIPRateLimits[scope]
+	}
+	return config.Config.GetInt(key)
+}
+
+// CheckIPRateLimit 检查客户端 IP 在 scope 内的请求频率，超过限制时返回 ErrRateLimited，不限制时返回 nil
+func CheckIPRateLimit(scope, clientIP string, now time.Time) (*RateLimitResult, error) {
+	limit := IPRateLimit(scope)
+	if limit <= 0 || clientIP == "" {
+		return nil, nil
+	}
+	r := takeRateToken(scope+":"+clientIP, limit, now)
+	if r.RetryAfter > 0 {
+		return &r, ErrRateLimited
+	}
+	return &r, nil
+}
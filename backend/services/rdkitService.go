@@ -3,17 +3,58 @@ package services
 import (
 	"backend/config"
 	"backend/database"
+	"backend/metrics"
 	"backend/utils"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ChemistryEngine 化学计算引擎，接收 JSON 格式的请求并返回结果。默认为 InitRdkit 启动的 RDKit Python 进程
+type ChemistryEngine interface {
+	SendAndWait(msg string) (string, error)
+}
+
+// 当前的化学计算引擎。InitRdkit、superviseRdkit 和 ShutdownRdkit 会在处理请求时替换它，读写需持有 engineMu
+var (
+	engineMu     sync.RWMutex
+	rdkitEngine  ChemistryEngine
+	rdkitStopped bool // ShutdownRdkit 后为 true，不再重新启动进程
+)
+
+// ErrRdkitUnavailable RDKit 进程未启动或启动失败
+var ErrRdkitUnavailable = errors.New("RDkit进程未初始化")
+
+// 进程意外退出后重新启动前的等待时间，连续启动失败时加倍，最长为 rdkitRestartMaxDelay
+const (
+	rdkitRestartDelay    = time.Second
+	rdkitRestartMaxDelay = time.Minute
 )
 
-var p *utils.PythonProcess
+// SetChemistryEngine 替换化学计算引擎，测试时可以使用不依赖 Python 的实现
+func SetChemistryEngine(engine ChemistryEngine) {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+	rdkitEngine = engine
+	rdkitStopped = false
+}
+
+// currentEngine 返回当前的化学计算引擎，未初始化、已停用或正在重新启动时为 nil
+func currentEngine() ChemistryEngine {
+	engineMu.RLock()
+	defer engineMu.RUnlock()
+	return rdkitEngine
+}
 
-// InitRdkit 初始化RDKit Python进程
+// InitRdkit 初始化RDKit Python进程，进程意外退出后自动重新启动
 func InitRdkit() error {
 	pwd, err := os.Getwd()
 	if err != nil {
@@ -25,58 +66,185 @@ func InitRdkit() error {
 	if path == "" {
 		errMsg := "RDkit初始化失败: python_path配置为空"
 		utils.Log(errMsg)
-		return fmt.Errorf(errMsg)
+		return errors.New(errMsg)
 	}
 
 	// 使用filepath处理路径，确保跨平台兼容性
 	pythonScriptPath := filepath.Join(pwd, "rdkit_tools.py")
 
-	var initErr error
-	p, initErr = utils.NewPythonProcess(path, pythonScriptPath)
+	// 初始化通信成功后才启用进程，失败时引擎保持为 nil，化学计算接口返回 503
+	process, err := startRdkit(path, pythonScriptPath)
+	if err != nil {
+		return err
+	}
+	SetChemistryEngine(process)
+	go superviseRdkit(process, path, pythonScriptPath)
+	utils.Log("RDkit初始化成功")
+	return nil
+}
+
+// startRdkit 启动RDKit Python进程并完成初始化通信
+func startRdkit(path, script string) (*utils.PythonProcess, error) {
+	process, initErr := utils.NewPythonProcess(path, script)
 	if initErr != nil {
 		utils.LogError(initErr)
-		utils.Log(fmt.Sprintf("RDkit进程启动失败: Python路径=%v, 脚本路径=%v", path, pythonScriptPath))
-		return fmt.Errorf("RDkit进程启动失败: %v", initErr)
+		utils.Log(fmt.Sprintf("RDkit进程启动失败: Python路径=%v, 脚本路径=%v", path, script))
+		return nil, fmt.Errorf("RDkit进程启动失败: %v", initErr)
 	}
 
-	res, err := p.SendAndWait("init")
+	res, err := process.SendAndWait("init")
 	if err != nil {
 		utils.LogError(err)
 		utils.Log("RDkit初始化通信失败")
-		return fmt.Errorf("RDkit初始化通信失败: %v", err)
+		process.Close()
+		return nil, fmt.Errorf("RDkit初始化通信失败: %v", err)
 	}
 	if res != "initialized" {
 		errMsg := fmt.Sprintf("RDkit初始化失败: 响应不正确, 期望='initialized', 实际='%s'", res)
 		utils.Log(errMsg)
-		return fmt.Errorf(errMsg)
+		process.Close()
+		return nil, errors.New(errMsg)
 	}
-	utils.Log("RDkit初始化成功")
-	return nil
+	return process, nil
 }
 
-// GetRdkitStatus 获取RDKit服务状态
-func GetRdkitStatus() map[string]interface{} {
-	status := map[string]interface{}{
-		"initialized": p != nil,
-		"available":   p != nil,
+// superviseRdkit 等待 process 退出。进程不是由 ShutdownRdkit 停止时先停用它（期间化学计算接口返回 503），
+// 再重新启动并计入 rdkit_restarts_total。启动失败时等待后重试，直到成功或 ShutdownRdkit 被调用
+func superviseRdkit(process *utils.PythonProcess, path, script string) {
+	for {
+		<-process.Exited()
+
+		engineMu.Lock()
+		if rdkitStopped || rdkitEngine != ChemistryEngine(process) {
+			engineMu.Unlock()
+			return
+		}
+		rdkitEngine = nil
+		engineMu.Unlock()
+		utils.Log("RDkit进程意外退出，正在重新启动")
+
+		delay := rdkitRestartDelay
+		for {
+			next, err := startRdkit(path, script)
+			if err == nil {
+				engineMu.Lock()
+				if rdkitStopped {
+					engineMu.Unlock()
+					next.Close()
+					return
+				}
+				rdkitEngine = next
+				engineMu.Unlock()
+				metrics.RdkitRestarts.Inc()
+				utils.Log("RDkit进程已重新启动")
+				process = next
+				break
+			}
+
+			time.Sleep(delay)
+			engineMu.RLock()
+			stopped := rdkitStopped
+			engineMu.RUnlock()
+			if stopped {
+				return
+			}
+			if delay *= 2; delay > rdkitRestartMaxDelay {
+				delay = rdkitRestartMaxDelay
+			}
+		}
 	}
+}
+
+// PingRdkit 向RDKit进程发送 init 消息并等待响应，ctx 结束时返回 ErrPythonTimeout
+func PingRdkit(ctx context.Context) error {
+	engine := currentEngine()
+	if engine == nil {
+		return ErrRdkitUnavailable
+	}
+
+	type reply struct {
+		res string
+		err error
+	}
+	done := make(chan reply, 1)
+	go func() {
+		res, err := engine.SendAndWait("init")
+		done <- reply{res, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return r.err
+		}
+		if r.res != "initialized" {
+			return fmt.Errorf("RDkit响应不正确, 期望='initialized', 实际='%s'", r.res)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", utils.ErrPythonTimeout, ctx.Err())
+	}
+}
 
-	if p != nil {
-		status["status"] = "running"
-	} else {
-		status["status"] = "not_initialized"
+// ShutdownRdkit 停用RDKit进程：之后的请求返回 ErrRdkitUnavailable，
+// 等待已提交的请求返回后关闭进程，ctx 结束时强制结束进程
+func ShutdownRdkit(ctx context.Context) error {
+	engineMu.Lock()
+	engine := rdkitEngine
+	rdkitEngine = nil
+	rdkitStopped = true
+	engineMu.Unlock()
+	if process, ok := engine.(interface{ Shutdown(context.Context) error }); ok {
+		return process.Shutdown(ctx)
 	}
+	return nil
+}
+
+// RdkitStatus RDKit服务状态
+type RdkitStatus struct {
+	Initialized bool `json:"initialized"`
+	Available   bool `json:"available"`
+	// running 或 not_initialized
+	Status string `json:"status"`
+}
 
+// GetRdkitStatus 获取RDKit服务状态
+func GetRdkitStatus() RdkitStatus {
+	available := currentEngine() != nil
+	status := RdkitStatus{Initialized: available, Available: available, Status: "not_initialized"}
+	if available {
+		status.Status = "running"
+	}
 	return status
 }
 
+// sendRdkit 向RDKit进程发送请求，按操作记录耗时和超时次数
+func sendRdkit(action string, request []byte) (string, error) {
+	engine := currentEngine()
+	if engine == nil {
+		return "", ErrRdkitUnavailable
+	}
+
+	start := time.Now()
+	res, err := engine.SendAndWait(string(request))
+	result := "ok"
+	switch {
+	case errors.Is(err, utils.ErrPythonTimeout):
+		result = "timeout"
+		metrics.RdkitTimeouts.WithLabelValues(action).Inc()
+	case err != nil || strings.HasPrefix(res, "error"):
+		result = "error"
+	}
+	metrics.RdkitDuration.WithLabelValues(action, result).Observe(time.Since(start).Seconds())
+	return res, err
+}
+
 // SimilaritySearch 相似度搜索
 func SimilaritySearch(fp string, threshold string) (string, error) {
 	// 检查Python进程是否已初始化
-	if p == nil {
-		errMsg := "RDkit进程未初始化"
-		utils.Log(errMsg)
-		return "", fmt.Errorf(errMsg)
+	if currentEngine() == nil {
+		utils.Log(ErrRdkitUnavailable.Error())
+		return "", ErrRdkitUnavailable
 	}
 
 	// 获取数据库中的所有指纹数据
@@ -87,7 +255,7 @@ func SimilaritySearch(fp string, threshold string) (string, error) {
 	}
 
 	// 使用正确的表名和字段名查询
-	result := database.GetDB().Table("data").Select("ID, FP").Find(&compounds)
+	result := database.GetDB().Table("data").Select("ID", "FP").Find(&compounds)
 	if result.Error != nil {
 		utils.LogError(result.Error)
 		return "", fmt.Errorf("数据库查询失败: %v", result.Error)
@@ -99,9 +267,9 @@ func SimilaritySearch(fp string, threshold string) (string, error) {
 			var err error
 			compound.FP, err = SmilesToFingerprint(compound.SMILES)
 			if err != nil {
-				return "", fmt.Errorf("初始化化合物(%v)FP失败： %v", compound.ID, result.Error)
+				return "", fmt.Errorf("初始化化合物(%v)FP失败： %w", compound.ID, err)
 			}
-			database.GetDB().Table("data").Where("ID = ?", compound.ID).Update("FP", compound.FP)
+			database.GetDB().Table("data").Where(map[string]interface{}{"ID": compound.ID}).Update("FP", compound.FP)
 			compounds[i] = compound
 		}
 	}
@@ -129,10 +297,10 @@ func SimilaritySearch(fp string, threshold string) (string, error) {
 		return "", fmt.Errorf("请求数据序列化失败: %v", err)
 	}
 
-	res, err := p.SendAndWait(string(requestJSON))
+	res, err := sendRdkit("similarity_search", requestJSON)
 	if err != nil {
 		utils.LogError(err)
-		return "", fmt.Errorf("相似度搜索失败: %v", err)
+		return "", fmt.Errorf("相似度搜索失败: %w", err)
 	}
 
 	return res, nil
@@ -140,10 +308,9 @@ func SimilaritySearch(fp string, threshold string) (string, error) {
 
 // SmilesToFingerprint SMILES转指纹
 func SmilesToFingerprint(smiles string) (string, error) {
-	if p == nil {
-		errMsg := "RDkit进程未初始化"
-		utils.Log(errMsg)
-		return "", fmt.Errorf(errMsg)
+	if currentEngine() == nil {
+		utils.Log(ErrRdkitUnavailable.Error())
+		return "", ErrRdkitUnavailable
 	}
 
 	requestData := map[string]interface{}{
@@ -157,10 +324,10 @@ func SmilesToFingerprint(smiles string) (string, error) {
 		return "", fmt.Errorf("请求数据序列化失败: %v", err)
 	}
 
-	res, err := p.SendAndWait(string(requestJSON))
+	res, err := sendRdkit("smiles_to_fingerprint", requestJSON)
 	if err != nil {
 		utils.LogError(err)
-		return "", fmt.Errorf("SMILES转指纹失败: %v", err)
+		return "", fmt.Errorf("SMILES转指纹失败: %w", err)
 	}
 
 	return res, nil
@@ -168,10 +335,9 @@ func SmilesToFingerprint(smiles string) (string, error) {
 
 // SmilesToPDB SMILES转PDB
 func SmilesToPDB(smiles string) (string, error) {
-	if p == nil {
-		errMsg := "RDkit进程未初始化"
-		utils.Log(errMsg)
-		return "", fmt.Errorf(errMsg)
+	if currentEngine() == nil {
+		utils.Log(ErrRdkitUnavailable.Error())
+		return "", ErrRdkitUnavailable
 	}
 
 	requestData := map[string]interface{}{
@@ -185,10 +351,10 @@ func SmilesToPDB(smiles string) (string, error) {
 		return "", fmt.Errorf("请求数据序列化失败: %v", err)
 	}
 
-	res, err := p.SendAndWait(string(requestJSON))
+	res, err := sendRdkit("smiles_to_pdb", requestJSON)
 	if err != nil {
 		utils.LogError(err)
-		return "", fmt.Errorf("SMILES转PDB失败: %v", err)
+		return "", fmt.Errorf("SMILES转PDB失败: %w", err)
 	}
 
 	return res, nil
@@ -196,10 +362,9 @@ func SmilesToPDB(smiles string) (string, error) {
 
 // IsSubstructure 子结构匹配
 func IsSubstructure(smartsPattern string, smiles string) (bool, error) {
-	if p == nil {
-		errMsg := "RDkit进程未初始化"
-		utils.Log(errMsg)
-		return false, fmt.Errorf(errMsg)
+	if currentEngine() == nil {
+		utils.Log(ErrRdkitUnavailable.Error())
+		return false, ErrRdkitUnavailable
 	}
 
 	requestData := map[string]interface{}{
@@ -214,10 +379,10 @@ func IsSubstructure(smartsPattern string, smiles string) (bool, error) {
 		return false, fmt.Errorf("请求数据序列化失败: %v", err)
 	}
 
-	res, err := p.SendAndWait(string(requestJSON))
+	res, err := sendRdkit("is_substructure", requestJSON)
 	if err != nil {
 		utils.LogError(err)
-		return false, fmt.Errorf("子结构匹配失败: %v", err)
+		return false, fmt.Errorf("子结构匹配失败: %w", err)
 	}
 
 	// 解析结果为布尔值
@@ -228,10 +393,9 @@ func IsSubstructure(smartsPattern string, smiles string) (bool, error) {
 // SubstructureSearch 子结构搜索 - 根据SMARTS模式在数据库中查找所有匹配的化合物
 func SubstructureSearch(smartsPattern string) (string, error) {
 	// 检查Python进程是否已初始化
-	if p == nil {
-		errMsg := "RDkit进程未初始化"
-		utils.Log(errMsg)
-		return "", fmt.Errorf(errMsg)
+	if currentEngine() == nil {
+		utils.Log(ErrRdkitUnavailable.Error())
+		return "", ErrRdkitUnavailable
 	}
 
 	// 获取数据库中的所有SMILES数据
@@ -241,7 +405,7 @@ func SubstructureSearch(smartsPattern string) (string, error) {
 	}
 
 	// 使用正确的表名和字段名查询
-	result := database.GetDB().Table("data").Select("ID, SMILES").Find(&compounds)
+	result := database.GetDB().Table("data").Select("ID", "SMILES").Find(&compounds)
 	if result.Error != nil {
 		utils.LogError(result.Error)
 		return "", fmt.Errorf("数据库查询失败: %v", result.Error)
@@ -269,10 +433,10 @@ func SubstructureSearch(smartsPattern string) (string, error) {
 		return "", fmt.Errorf("请求数据序列化失败: %v", err)
 	}
 
-	res, err := p.SendAndWait(string(requestJSON))
+	res, err := sendRdkit("substructure_search", requestJSON)
 	if err != nil {
 		utils.LogError(err)
-		return "", fmt.Errorf("子结构搜索失败: %v", err)
+		return "", fmt.Errorf("子结构搜索失败: %w", err)
 	}
 
 	return res, nil
@@ -281,10 +445,9 @@ func SubstructureSearch(smartsPattern string) (string, error) {
 // ExactMatchSearch 精确匹配搜索 - 查找SMILES相同的结构并返回其ID
 func ExactMatchSearch(smiles string) (string, error) {
 	// 检查Python进程是否已初始化
-	if p == nil {
-		errMsg := "RDkit进程未初始化"
-		utils.Log(errMsg)
-		return "", fmt.Errorf(errMsg)
+	if currentEngine() == nil {
+		utils.Log(ErrRdkitUnavailable.Error())
+		return "", ErrRdkitUnavailable
 	}
 
 	// 获取数据库中的所有SMILES数据
@@ -294,7 +457,7 @@ func ExactMatchSearch(smiles string) (string, error) {
 	}
 
 	// 使用正确的表名和字段名查询
-	result := database.GetDB().Table("data").Select("ID, SMILES").Find(&compounds)
+	result := database.GetDB().Table("data").Select("ID", "SMILES").Find(&compounds)
 	if result.Error != nil {
 		utils.LogError(result.Error)
 		return "", fmt.Errorf("数据库查询失败: %v", result.Error)
@@ -322,24 +485,25 @@ func ExactMatchSearch(smiles string) (string, error) {
 		return "", fmt.Errorf("请求数据序列化失败: %v", err)
 	}
 
-	res, err := p.SendAndWait(string(requestJSON))
+	res, err := sendRdkit("exact_match_search", requestJSON)
 	if err != nil {
 		utils.LogError(err)
-		return "", fmt.Errorf("精确匹配搜索失败: %v", err)
+		return "", fmt.Errorf("精确匹配搜索失败: %w", err)
 	}
 
 	return res, nil
 }
 
-type indexData struct {
+// CompoundSummary 筛选结果中的化合物摘要
+type CompoundSummary struct {
 	ID        string  `gorm:"column:ID;type:VARCHAR(12);primaryKey;not null" json:"id"`
 	ItemName  *string `gorm:"column:ItemName;type:TEXT" json:"item_name,omitempty"`
 	SMILES    *string `gorm:"column:SMILES;type:TEXT" json:"smiles,omitempty"`
 	CASNumber *string `gorm:"column:CAS_number;type:VARCHAR(100)" json:"cas_number,omitempty"`
 }
 
-// FilterCompounds 筛选化合物 - 根据ItemType、分子量范围、Description和Source进行筛选，支持数组参数
-func FilterCompounds(itemTypes []string, minWeight, maxWeight float64, descriptions []string, sources []string, limit, offset int) ([]indexData, int64, error) {
+// FilterCompounds 筛选化合物 - 根据ItemType、分子量范围、Description、Source和来源生物分类进行筛选，支持数组参数
+func FilterCompounds(itemTypes []string, minWeight, maxWeight float64, descriptions []string, sources []string, taxonRank, taxon string, limit, offset int) ([]CompoundSummary, int64, error) {
 	// 构建查询条件
 	query := database.GetDB().Table("data")
 
@@ -371,51 +535,57 @@ func FilterCompounds(itemTypes []string, minWeight, maxWeight float64, descripti
 			if len(specificTypes) > 0 {
 				// 用户既选择了"others"又选择了其他具体类别
 				// 查询：不属于6个主要类别，但属于用户选择的具体类别
-				query = query.Where("LOWER(ItemType) NOT IN (?) AND LOWER(ItemType) IN (?)", mainCategories, specificTypes)
+				query = query.Where("LOWER(?) NOT IN ? AND LOWER(?) IN ?", database.Column("ItemType"), mainCategories, database.Column("ItemType"), specificTypes)
 			} else {
 				// 用户只选择了"others"
 				// 查询：不属于6个主要类别的所有化合物
-				query = query.Where("LOWER(ItemType) NOT IN (?)", mainCategories)
+				query = query.Where("LOWER(?) NOT IN ?", database.Column("ItemType"), mainCategories)
 			}
 		} else {
 			// 如果不包含"others"，正常查询用户选择的类别
-			query = query.Where("LOWER(ItemType) IN (?)", lowerItemTypes)
+			query = query.Where("LOWER(?) IN ?", database.Column("ItemType"), lowerItemTypes)
 		}
 	}
 
 	// Description筛选 - 支持数组
 	if len(descriptions) > 0 {
 		// 为每个description创建LIKE条件
-		likeConditions := make([]string, len(descriptions))
-		likeArgs := make([]interface{}, len(descriptions))
+		likeConditions := make([]clause.Expression, len(descriptions))
 		for i, desc := range descriptions {
-			likeConditions[i] = "Description LIKE ?"
-			likeArgs[i] = "%" + desc + "%"
+			likeConditions[i] = database.LikeFold("Description", "%"+desc+"%")
 		}
 		// 使用OR连接多个LIKE条件
-		query = query.Where(strings.Join(likeConditions, " OR "), likeArgs...)
+		query = query.Where(clause.Or(likeConditions...))
 	}
 
 	// Source筛选 - 支持数组
 	if len(sources) > 0 {
 		// 为每个source创建LIKE条件
-		likeConditions := make([]string, len(sources))
-		likeArgs := make([]interface{}, len(sources))
+		likeConditions := make([]clause.Expression, len(sources))
 		for i, source := range sources {
-			likeConditions[i] = "Source LIKE ?"
-			likeArgs[i] = "%" + source + "%"
+			likeConditions[i] = database.LikeFold("Source", "%"+source+"%")
 		}
 		// 使用OR连接多个LIKE条件
-		query = query.Where(strings.Join(likeConditions, " OR "), likeArgs...)
+		query = query.Where(clause.Or(likeConditions...))
+	}
+
+	// 来源生物分类筛选 - 任一分类阶元
+	if taxonRank != "" {
+		column, err := RankColumn(taxonRank)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where("? IN (?)", database.Column("Organism_ID"),
+			database.GetDB().Table("organisms").Select("?", database.Column("ID")).Where(map[string]interface{}{column: taxon}))
 	}
 
 	// 分子量筛选 - 使用Weight字段
 	if minWeight > 0 || maxWeight > 0 {
 		if minWeight > 0 {
-			query = query.Where("Weight >= ?", minWeight)
+			query = query.Where(clause.Gte{Column: "Weight", Value: minWeight})
 		}
 		if maxWeight > 0 {
-			query = query.Where("Weight <= ?", maxWeight)
+			query = query.Where(clause.Lte{Column: "Weight", Value: maxWeight})
 		}
 	}
 
@@ -429,7 +599,7 @@ func FilterCompounds(itemTypes []string, minWeight, maxWeight float64, descripti
 	}
 
 	// 应用分页并获取数据
-	var compounds []indexData
+	var compounds []CompoundSummary
 	result = query.Offset(offset).Limit(limit).Find(&compounds)
 	if result.Error != nil {
 		utils.LogError(result.Error)
@@ -443,8 +613,8 @@ func FilterCompounds(itemTypes []string, minWeight, maxWeight float64, descripti
 func GetItemTypes() ([]string, error) {
 	var itemTypes []string
 	result := database.GetDB().Table("data").
-		Where("ItemType IS NOT NULL AND ItemType != ''").
-		Distinct("ItemType").
+		Where(database.NotEmpty("ItemType")).
+		Distinct().
 		Pluck("ItemType", &itemTypes)
 
 	if result.Error != nil {
@@ -459,8 +629,8 @@ func GetItemTypes() ([]string, error) {
 func GetDescriptions() ([]string, error) {
 	var descriptions []string
 	result := database.GetDB().Table("data").
-		Where("Description IS NOT NULL AND Description != ''").
-		Distinct("Description").
+		Where(database.NotEmpty("Description")).
+		Distinct().
 		Pluck("Description", &descriptions)
 
 	if result.Error != nil {
@@ -475,8 +645,8 @@ func GetDescriptions() ([]string, error) {
 func GetSources() ([]string, error) {
 	var sources []string
 	result := database.GetDB().Table("data").
-		Where("Source IS NOT NULL AND Source != ''").
-		Distinct("Source").
+		Where(database.NotEmpty("Source")).
+		Distinct().
 		Pluck("Source", &sources)
 
 	if result.Error != nil {
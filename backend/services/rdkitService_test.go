@@ -0,0 +1,160 @@
+package services
+
+import (
+	"backend/metrics"
+	"backend/utils"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeRdkitScript 按 rdkit_tools.py 的协议逐行读取请求：init 返回 initialized，exit 不返回结果直接退出
+const fakeRdkitScript = `while read -r line; do
+	id=${line#*\"id\":\"}
+	id=${id%%\"*}
+	case "$line" in
+	*'"msg":"exit"'*) exit 1 ;;
+	esac
+	printf '{"id":"%s","reply":"initialized"}\n' "$id"
+done
+`
+
+func TestSuperviseRdkitRestartsProcess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("每次启动进程需要等待 5 秒")
+	}
+	script := filepath.Join(t.TempDir(), "fake_rdkit.sh")
+	if err := os.WriteFile(script, []byte(fakeRdkitScript), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	process, err := startRdkit("/bin/sh", script)
+	if err != nil {
+		t.Fatalf("startRdkit: %v", err)
+	}
+	SetChemistryEngine(process)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ShutdownRdkit(ctx)
+		SetChemistryEngine(nil)
+	}()
+	go superviseRdkit(process, "/bin/sh", script)
+	restarts := testutil.ToFloat64(metrics.RdkitRestarts)
+
+	// 进程退出时等待中的请求立即返回，不等到超时
+	if _, err := process.SendAndWaitWithTimeout("exit", 10*time.Second); !errors.Is(err, utils.ErrPythonClosed) {
+		t.Fatalf("request to exiting process: err = %v, want ErrPythonClosed", err)
+	}
+
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		engine := currentEngine()
+		if engine != nil && engine != ChemistryEngine(process) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("RDKit process was not restarted")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if got := testutil.ToFloat64(metrics.RdkitRestarts); got != restarts+1 {
+		t.Errorf("rdkit_restarts_total = %v, want %v", got, restarts+1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := PingRdkit(ctx); err != nil {
+		t.Errorf("PingRdkit after restart: %v", err)
+	}
+
+	// ShutdownRdkit 停止的进程不再重新启动
+	restarted := currentEngine()
+	if err := ShutdownRdkit(ctx); err != nil {
+		t.Fatalf("ShutdownRdkit: %v", err)
+	}
+	<-restarted.(*utils.PythonProcess).Exited()
+	time.Sleep(100 * time.Millisecond)
+	if currentEngine() != nil {
+		t.Error("process was restarted after ShutdownRdkit")
+	}
+}
+
+// stalledRdkitScript 回复 init 后不再读取标准输入，管道写满后发送协程阻塞
+const stalledRdkitScript = `read -r line
+id=${line#*\"id\":\"}
+id=${id%%\"*}
+printf '{"id":"%s","reply":"initialized"}\n' "$id"
+exec sleep 60
+`
+
+// TestShutdownRdkitWithStalledWriter 发送协程阻塞、队列已满时，Shutdown 仍在 ctx 的截止时间前返回，
+// 阻塞在入队上的请求按各自的超时返回
+func TestShutdownRdkitWithStalledWriter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("启动进程需要等待 5 秒")
+	}
+	script := filepath.Join(t.TempDir(), "stalled_rdkit.sh")
+	if err := os.WriteFile(script, []byte(stalledRdkitScript), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	process, err := startRdkit("/bin/sh", script)
+	if err != nil {
+		t.Fatalf("startRdkit: %v", err)
+	}
+	defer process.Close()
+
+	// 每个请求都超过管道缓冲区，第一个请求即可使发送协程阻塞，其后的请求填满队列并阻塞在入队上
+	msg := strings.Repeat("x", 128<<10)
+	const requests = 120
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			_, err := process.SendAndWaitWithTimeout(msg, time.Minute)
+			errs <- err
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for process.PendingRequests() < requests {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests submitted, want %d", process.PendingRequests(), requests)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 队列已满时入队超时
+	start := time.Now()
+	if _, err := process.SendAndWaitWithTimeout("ping", 200*time.Millisecond); !errors.Is(err, utils.ErrPythonTimeout) {
+		t.Errorf("request to a full queue: err = %v, want ErrPythonTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request to a full queue returned after %v, want about 200ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if err := process.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown: err = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Shutdown returned after %v, want about 500ms", elapsed)
+	}
+
+	// 进程被强制结束后全部请求返回 ErrPythonClosed
+	for i := 0; i < requests; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, utils.ErrPythonClosed) {
+				t.Errorf("pending request: err = %v, want ErrPythonClosed", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d requests still waiting after Shutdown", requests-i)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidReferenceField = errors.New("不支持的文献关联字段")
+	ErrInvalidDOI            = errors.New("DOI格式错误")
+	ErrInvalidPubMedID       = errors.New("PubMed ID格式错误")
+	ErrDuplicateReference    = errors.New("相同DOI或PubMed ID的文献已存在")
+	ErrReferenceNotFound     = errors.New("文献不存在")
+)
+
+var (
+	doiPattern  = regexp.MustCompile(`^10\.\d{4,9}/\S+$`)
+	pmidPattern = regexp.MustCompile(`^\d{1,10}$`)
+)
+
+// NormalizeDOI 统一DOI写法：去掉 https://doi.org/、doi: 前缀并转为小写
+func NormalizeDOI(doi string) string {
+	d := strings.TrimSpace(doi)
+	lower := strings.ToLower(d)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if strings.HasPrefix(lower, prefix) {
+			d = strings.TrimSpace(d[len(prefix):])
+			break
+		}
+	}
+	return strings.ToLower(d)
+}
+
+// ValidateReference 校验并规范化文献信息
+func ValidateReference(ref *models.Reference) error {
+	ref.Title = strings.TrimSpace(ref.Title)
+	if ref.DOI != nil {
+		doi := NormalizeDOI(*ref.DOI)
+		if doi == "" {
+			ref.DOI = nil
+		} else if !doiPattern.MatchString(doi) {
+			return ErrInvalidDOI
+		} else {
+			ref.DOI = &doi
+		}
+	}
+	if ref.PubMedID != nil {
+		pmid := strings.TrimPrefix(strings.TrimSpace(*ref.PubMedID), "PMID:")
+		pmid = strings.TrimSpace(pmid)
+		if pmid == "" {
+			ref.PubMedID = nil
+		} else if !pmidPattern.MatchString(pmid) {
+			return ErrInvalidPubMedID
+		} else {
+			ref.PubMedID = &pmid
+		}
+	}
+	return nil
+}
+
+// NormalizeReferenceField 校验文献关联字段，空值视为 general
+func NormalizeReferenceField(field string) (string, error) {
+	f := strings.ToLower(strings.TrimSpace(field))
+	if f == "" {
+		return models.ReferenceFieldGeneral, nil
+	}
+	if !containsString(models.ReferenceFields, f) {
+		return "", ErrInvalidReferenceField
+	}
+	return f, nil
+}
+
+// checkDuplicateReference 检查DOI或PubMed ID是否已被其他文献使用
+func checkDuplicateReference(ref *models.Reference) error {
+	if ref.DOI == nil && ref.PubMedID == nil {
+		return nil
+	}
+	query := database.GetDB().Table("literature").Where(clause.Neq{Column: "ID", Value: ref.ID})
+	switch {
+	case ref.DOI != nil && ref.PubMedID != nil:
+		query = query.Where(clause.Or(clause.Eq{Column: "DOI", Value: *ref.DOI}, clause.Eq{Column: "PubMed_ID", Value: *ref.PubMedID}))
+	case ref.DOI != nil:
+		query = query.Where(map[string]interface{}{"DOI": *ref.DOI})
+	default:
+		query = query.Where(map[string]interface{}{"PubMed_ID": *ref.PubMedID})
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("查询文献失败: %v", err)
+	}
+	if count > 0 {
+		return ErrDuplicateReference
+	}
+	return nil
+}
+
+// CreateReference 新增文献
+func CreateReference(ref *models.Reference) error {
+	if err := ValidateReference(ref); err != nil {
+		return err
+	}
+	if err := checkDuplicateReference(ref); err != nil {
+		return err
+	}
+	if err := database.GetDB().Table("literature").Create(ref).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("创建文献失败: %v", err)
+	}
+	return nil
+}
+
+// UpdateReference 更新文献
+func UpdateReference(ref *models.Reference) error {
+	if err := ValidateReference(ref); err != nil {
+		return err
+	}
+	if err := checkDuplicateReference(ref); err != nil {
+		return err
+	}
+	if err := database.GetDB().Table("literature").Save(ref).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("更新文献失败: %v", err)
+	}
+	return nil
+}
+
+// GetReferenceByID 根据ID获取文献，不存在时返回 ErrReferenceNotFound
+func GetReferenceByID(id uint) (*models.Reference, error) {
+	var ref models.Reference
+	if err := database.GetDB().Table("literature").Where(map[string]interface{}{"ID": id}).First(&ref).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReferenceNotFound
+		}
+		utils.LogError(err)
+		return nil, fmt.Errorf("查询文献失败: %v", err)
+	}
+	return &ref, nil
+}
+
+// DeleteReference 删除文献及其全部关联，返回删除的行数
+func DeleteReference(id uint) (int64, error) {
+	var affected int64
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("compound_references").Where(map[string]interface{}{"Reference_ID": id}).Delete(&models.CompoundReference{}).Error; err != nil {
+			return err
+		}
+		result := tx.Table("literature").Where(map[string]interface{}{"ID": id}).Delete(&models.Reference{})
+		affected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		utils.LogError(err)
+		return 0, fmt.Errorf("删除文献失败: %v", err)
+	}
+	return affected, nil
+}
+
+// ListReferences 分页查询文献，keyword 匹配标题、作者、期刊和DOI
+func ListReferences(keyword string, limit, offset int) ([]models.Reference, int64, error) {
+	query := database.GetDB().Table("literature")
+	if keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where(clause.Or(
+			database.LikeFold("Title", like),
+			database.LikeFold("Authors", like),
+			database.LikeFold("Journal", like),
+			clause.Expr{SQL: "? LIKE LOWER(?)", Vars: []interface{}{database.Column("DOI"), like}},
+			clause.Eq{Column: "PubMed_ID", Value: keyword},
+		))
+	}
+
+	var totalCount int64
+	countQuery := query
+	if err := countQuery.Count(&totalCount).Error; err != nil {
+		utils.LogError(err)
+		return nil, 0, fmt.Errorf("获取总记录数失败: %v", err)
+	}
+
+	var refs []models.Reference
+	if err := query.Order(database.Desc("Year")).Order(database.Desc("ID")).Offset(offset).Limit(limit).Find(&refs).Error; err != nil {
+		utils.LogError(err)
+		return nil, 0, fmt.Errorf("数据库查询失败: %v", err)
+	}
+	return refs, totalCount, nil
+}
+
+// GetReferencesByDataIDs 获取一组化合物引用的文献（去重），每篇文献附带关联的数据字段
+func GetReferencesByDataIDs(dataIDs []string) ([]models.CitedReference, error) {
+	if len(dataIDs) == 0 {
+		return []models.CitedReference{}, nil
+	}
+
+	var links []models.CompoundReference
+	if err := database.GetDB().Table("compound_references").Where(map[string]interface{}{"Data_ID": dataIDs}).Find(&links).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取文献关联失败: %v", err)
+	}
+	if len(links) == 0 {
+		return []models.CitedReference{}, nil
+	}
+
+	fields := map[uint][]string{}
+	var refIDs []uint
+	for _, link := range links {
+		if _, ok := fields[link.ReferenceID]; !ok {
+			refIDs = append(refIDs, link.ReferenceID)
+		}
+		if !containsString(fields[link.ReferenceID], link.Field) {
+			fields[link.ReferenceID] = append(fields[link.ReferenceID], link.Field)
+		}
+	}
+
+	var refs []models.Reference
+	if err := database.GetDB().Table("literature").Where(map[string]interface{}{"ID": refIDs}).Order(database.Asc("Year")).Order(database.Asc("ID")).Find(&refs).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取文献失败: %v", err)
+	}
+
+	cited := make([]models.CitedReference, len(refs))
+	for i, ref := range refs {
+		f := fields[ref.ID]
+		sort.Strings(f)
+		cited[i] = models.CitedReference{Reference: ref, Fields: f}
+	}
+	return cited, nil
+}
+
+// GetReferencedCompounds 获取引用某篇文献的化合物及字段
+func GetReferencedCompounds(referenceID uint) ([]models.CompoundReference, error) {
+	var links []models.CompoundReference
+	if err := database.GetDB().Table("compound_references").Where(map[string]interface{}{"Reference_ID": referenceID}).Order(database.Asc("Data_ID")).Order(database.Asc("Field")).Find(&links).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取文献关联失败: %v", err)
+	}
+	return links, nil
+}
+
+// LinkReference 将文献关联到化合物的若干数据字段，已存在的关联会被忽略
+func LinkReference(dataID string, referenceID uint, fields []string) error {
+	if len(fields) == 0 {
+		fields = []string{models.ReferenceFieldGeneral}
+	}
+	links := make([]models.CompoundReference, 0, len(fields))
+	for _, field := range fields {
+		f, err := NormalizeReferenceField(field)
+		if err != nil {
+			return err
+		}
+		links = append(links, models.CompoundReference{DataID: dataID, ReferenceID: referenceID, Field: f})
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, link := range links {
+			var count int64
+			if err := tx.Table("compound_references").
+				Where(map[string]interface{}{"Data_ID": link.DataID, "Reference_ID": link.ReferenceID, "Field": link.Field}).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			if err := tx.Table("compound_references").Create(&link).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("关联文献失败: %v", err)
+	}
+	return nil
+}
+
+// UnlinkReference 取消化合物与文献的关联，field 为空时取消全部字段的关联
+func UnlinkReference(dataID string, referenceID uint, field string) (int64, error) {
+	query := database.GetDB().Table("compound_references").Where(map[string]interface{}{"Data_ID": dataID, "Reference_ID": referenceID})
+	if field != "" {
+		f, err := NormalizeReferenceField(field)
+		if err != nil {
+			return 0, err
+		}
+		query = query.Where(map[string]interface{}{"Field": f})
+	}
+	result := query.Delete(&models.CompoundReference{})
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return 0, fmt.Errorf("取消文献关联失败: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// splitAuthors 拆分以"; "分隔的作者列表
+func splitAuthors(authors string) []string {
+	var list []string
+	for _, a := range strings.Split(authors, ";") {
+		if a = strings.TrimSpace(a); a != "" {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+// bibtexKey 生成BibTeX引用键：第一作者姓 + 年份 + 标题首个实词，如 zhang2021marine
+func bibtexKey(ref models.Reference, used map[string]int) string {
+	var b strings.Builder
+	if authors := splitAuthors(ref.Authors); len(authors) > 0 {
+		// "Zhang, San" 取逗号前的部分，"San Zhang" 取最后一个词
+		last := authors[0]
+		if i := strings.Index(last, ","); i >= 0 {
+			last = last[:i]
+		} else if fields := strings.Fields(last); len(fields) > 0 {
+			last = fields[len(fields)-1]
+		}
+		b.WriteString(keyPart(last))
+	}
+	if ref.Year != nil {
+		b.WriteString(strconv.Itoa(*ref.Year))
+	}
+	for _, word := range strings.Fields(ref.Title) {
+		w := keyPart(word)
+		if len(w) > 3 {
+			b.WriteString(w)
+			break
+		}
+	}
+	key := b.String()
+	if key == "" {
+		key = "ref" + strconv.Itoa(int(ref.ID))
+	}
+	// 重复的键依次追加 a、b、c…
+	used[key]++
+	if n := used[key]; n > 1 {
+		key += string(rune('a' + n - 2))
+	}
+	return key
+}
+
+// keyPart 只保留字母和数字并转为小写
+func keyPart(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// bibtexEscape 转义BibTeX中的特殊字符
+func bibtexEscape(s string) string {
+	return strings.NewReplacer(`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`).Replace(s)
+}
+
+// FormatBibTeX 将文献导出为BibTeX格式
+func FormatBibTeX(refs []models.CitedReference) string {
+	var b strings.Builder
+	used := map[string]int{}
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "@article{%s,\n", bibtexKey(ref.Reference, used))
+		field := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "  %s = {%s},\n", name, value)
+			}
+		}
+		field("author", bibtexEscape(strings.Join(splitAuthors(ref.Authors), " and ")))
+		field("title", bibtexEscape(ref.Title))
+		field("journal", bibtexEscape(ref.Journal))
+		if ref.Year != nil {
+			field("year", strconv.Itoa(*ref.Year))
+		}
+		field("volume", bibtexEscape(ref.Volume))
+		field("pages", strings.ReplaceAll(bibtexEscape(ref.Pages), "-", "--"))
+		if ref.DOI != nil {
+			field("doi", *ref.DOI)
+		}
+		if ref.PubMedID != nil {
+			field("pmid", *ref.PubMedID)
+		}
+		b.WriteString("}\n\n")
+	}
+	return b.String()
+}
+
+// FormatRIS 将文献导出为RIS格式
+func FormatRIS(refs []models.CitedReference) string {
+	var b strings.Builder
+	tag := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s  - %s\r\n", name, value)
+		}
+	}
+	for _, ref := range refs {
+		tag("TY", "JOUR")
+		for _, author := range splitAuthors(ref.Authors) {
+			tag("AU", author)
+		}
+		tag("TI", ref.Title)
+		tag("JO", ref.Journal)
+		if ref.Year != nil {
+			tag("PY", strconv.Itoa(*ref.Year))
+		}
+		tag("VL", ref.Volume)
+		if pages := strings.SplitN(ref.Pages, "-", 2); pages[0] != "" {
+			tag("SP", strings.TrimSpace(pages[0]))
+			if len(pages) == 2 {
+				tag("EP", strings.TrimSpace(pages[1]))
+			}
+		}
+		if ref.DOI != nil {
+			tag("DO", *ref.DOI)
+		}
+		if ref.PubMedID != nil {
+			tag("AN", "PMID:"+*ref.PubMedID)
+		}
+		b.WriteString("ER  - \r\n\r\n")
+	}
+	return b.String()
+}
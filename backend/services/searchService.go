@@ -0,0 +1,521 @@
+package services
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrEmptySearchQuery   = errors.New("检索词不能为空")
+	ErrSearchQueryTooLong = errors.New("检索词过长")
+)
+
+// 检索式的长度上限。检索接口无需认证，每个词项都要与索引中的全部词项比较编辑距离，需限制检索式的规模
+const (
+	MaxSearchQueryLength = 200 // 字符数
+	MaxSearchTerms       = 10  // 词项数
+)
+
+// 参与名称检索的字段及权重
+const (
+	SearchFieldItemName  = "item_name"
+	SearchFieldSynonym   = "synonym"
+	SearchFieldCASNumber = "cas_number"
+	SearchFieldItemTag   = "item_tag"
+	SearchFieldSource    = "source"
+)
+
+var searchFieldWeights = map[string]float64{
+	SearchFieldItemName:  5,
+	SearchFieldSynonym:   4,
+	SearchFieldCASNumber: 4,
+	SearchFieldItemTag:   2,
+	SearchFieldSource:    1,
+}
+
+// 词项匹配得分：完全匹配 > 前缀匹配 > 拼写容错匹配
+const (
+	exactMatchScore  = 1.0
+	prefixMatchBase  = 0.5
+	prefixMatchRange = 0.4
+	phraseBonus      = 2.0
+)
+
+// SearchMatch 命中的字段及原文
+type SearchMatch struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// SearchHit 名称检索结果
+type SearchHit struct {
+	ID        string        `json:"id"`
+	ItemName  string        `json:"item_name,omitempty"`
+	CASNumber string        `json:"cas_number,omitempty"`
+	Source    string        `json:"source,omitempty"`
+	Score     float64       `json:"score"`
+	Matches   []SearchMatch `json:"matches"`
+}
+
+// SearchIndexStats 名称检索索引统计
+type SearchIndexStats struct {
+	Documents int `json:"documents"`
+	Terms     int `json:"terms"`
+}
+
+// termPosting 某个词项在文档中的最佳出处
+type termPosting struct {
+	weight float64
+	field  string
+	text   string
+}
+
+// searchDoc 一个化合物的索引文档
+type searchDoc struct {
+	id        string
+	itemName  string
+	casNumber string
+	source    string
+	terms     map[string]termPosting
+	phrases   map[string]termPosting // 整个字段值规范化后的文本，用于整词命中加分
+}
+
+// nameIndex Go 端倒排索引，词项 -> 化合物ID集合
+type nameIndex struct {
+	docs     map[string]*searchDoc
+	postings map[string]map[string]struct{}
+	terms    []string // 有序词项，用于前缀匹配
+}
+
+var (
+	searchMu    sync.RWMutex
+	searchIdx   *nameIndex
+	searchBuild sync.Mutex
+)
+
+// tokenize 将文本切分为小写词项。数字之间的连字符保留（如 CAS 号 50-07-7），
+// 其余非字母数字字符作为分隔符；单个字符的词项只保留汉字
+func tokenize(text string) []string {
+	runes := []rune(strings.ToLower(text))
+	var tokens []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 1 || (len(cur) == 1 && unicode.Is(unicode.Han, cur[0])) {
+			tokens = append(tokens, string(cur))
+		}
+		cur = cur[:0]
+	}
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur = append(cur, r)
+		case r == '-' && len(cur) > 0 && unicode.IsDigit(cur[len(cur)-1]) && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// normalizePhrase 规范化整个字段值，用于整词比较
+func normalizePhrase(text string) string {
+	return strings.Join(tokenize(text), " ")
+}
+
+// newSearchDoc 由化合物字段和别名生成索引文档
+func newSearchDoc(id, itemName, casNumber, itemTag, source string, synonyms []string) *searchDoc {
+	doc := &searchDoc{
+		id:        id,
+		itemName:  itemName,
+		casNumber: casNumber,
+		source:    source,
+		terms:     map[string]termPosting{},
+		phrases:   map[string]termPosting{},
+	}
+	add := func(field, text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		posting := termPosting{weight: searchFieldWeights[field], field: field, text: text}
+		for _, term := range tokenize(text) {
+			if old, ok := doc.terms[term]; !ok || old.weight < posting.weight {
+				doc.terms[term] = posting
+			}
+		}
+		if phrase := normalizePhrase(text); phrase != "" {
+			if old, ok := doc.phrases[phrase]; !ok || old.weight < posting.weight {
+				doc.phrases[phrase] = posting
+			}
+		}
+	}
+	add(SearchFieldItemName, itemName)
+	for _, synonym := range synonyms {
+		add(SearchFieldSynonym, synonym)
+	}
+	add(SearchFieldCASNumber, casNumber)
+	add(SearchFieldItemTag, itemTag)
+	add(SearchFieldSource, source)
+	return doc
+}
+
+// add 将文档加入索引（不更新有序词项）
+func (idx *nameIndex) add(doc *searchDoc) {
+	idx.docs[doc.id] = doc
+	for term := range doc.terms {
+		ids, ok := idx.postings[term]
+		if !ok {
+			ids = map[string]struct{}{}
+			idx.postings[term] = ids
+		}
+		ids[doc.id] = struct{}{}
+	}
+}
+
+// remove 将文档移出索引（不更新有序词项）
+func (idx *nameIndex) remove(id string) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		if ids, ok := idx.postings[term]; ok {
+			delete(ids, id)
+			if len(ids) == 0 {
+				delete(idx.postings, term)
+			}
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// sortTerms 重建有序词项
+func (idx *nameIndex) sortTerms() {
+	idx.terms = make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+}
+
+// loadSearchDocs 从数据库加载索引文档，ids 为空时加载全部化合物
+func loadSearchDocs(ids ...string) ([]*searchDoc, error) {
+	var rows []struct {
+		ID        string  `gorm:"column:ID"`
+		ItemName  *string `gorm:"column:ItemName"`
+		CASNumber *string `gorm:"column:CAS_number"`
+		ItemTag   *string `gorm:"column:ItemTag"`
+		Source    *string `gorm:"column:Source"`
+	}
+	query := database.GetDB().Table("data").Select("ID", "ItemName", "CAS_number", "ItemTag", "Source")
+	if len(ids) > 0 {
+		query = query.Where(map[string]interface{}{"ID": ids})
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取化合物名称失败: %v", err)
+	}
+
+	var synonyms []models.Synonym
+	synQuery := database.GetDB().Table("synonyms").Select("Data_ID", "Name")
+	if len(ids) > 0 {
+		synQuery = synQuery.Where(map[string]interface{}{"Data_ID": ids})
+	}
+	if err := synQuery.Find(&synonyms).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取别名失败: %v", err)
+	}
+	names := map[string][]string{}
+	for _, s := range synonyms {
+		names[s.DataID] = append(names[s.DataID], s.Name)
+	}
+
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	docs := make([]*searchDoc, len(rows))
+	for i, row := range rows {
+		docs[i] = newSearchDoc(row.ID, deref(row.ItemName), deref(row.CASNumber), deref(row.ItemTag), deref(row.Source), names[row.ID])
+	}
+	return docs, nil
+}
+
+// BuildSearchIndex 从数据库重建名称检索索引
+func BuildSearchIndex() (*SearchIndexStats, error) {
+	searchBuild.Lock()
+	defer searchBuild.Unlock()
+	return buildSearchIndex()
+}
+
+// buildSearchIndex 重建索引，调用方需持有 searchBuild
+func buildSearchIndex() (*SearchIndexStats, error) {
+	docs, err := loadSearchDocs()
+	if err != nil {
+		return nil, err
+	}
+	idx := &nameIndex{docs: map[string]*searchDoc{}, postings: map[string]map[string]struct{}{}}
+	for _, doc := range docs {
+		idx.add(doc)
+	}
+	idx.sortTerms()
+
+	searchMu.Lock()
+	searchIdx = idx
+	searchMu.Unlock()
+
+	utils.Log(fmt.Sprintf("名称检索索引已建立: %d 个化合物, %d 个词项", len(idx.docs), len(idx.terms)))
+	return &SearchIndexStats{Documents: len(idx.docs), Terms: len(idx.terms)}, nil
+}
+
+// RefreshSearchDocument 重新索引单个化合物，索引尚未建立时不做处理
+func RefreshSearchDocument(id string) {
+	searchMu.RLock()
+	built := searchIdx != nil
+	searchMu.RUnlock()
+	if !built {
+		return
+	}
+
+	docs, err := loadSearchDocs(id)
+	if err != nil {
+		return
+	}
+
+	searchMu.Lock()
+	defer searchMu.Unlock()
+	searchIdx.remove(id)
+	for _, doc := range docs {
+		searchIdx.add(doc)
+	}
+	searchIdx.sortTerms()
+}
+
+// ensureSearchIndex 首次检索时建立索引
+func ensureSearchIndex() error {
+	searchMu.RLock()
+	built := searchIdx != nil
+	searchMu.RUnlock()
+	if built {
+		return nil
+	}
+	searchBuild.Lock()
+	defer searchBuild.Unlock()
+	searchMu.RLock()
+	built = searchIdx != nil
+	searchMu.RUnlock()
+	if built {
+		return nil
+	}
+	_, err := buildSearchIndex()
+	return err
+}
+
+// maxTypoDistance 按词长允许的最大编辑距离
+func maxTypoDistance(length int) int {
+	switch {
+	case length >= 8:
+		return 2
+	case length >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// minInt 返回若干整数中的最小值
+func minInt(first int, rest ...int) int {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
+
+// editDistance 计算两个词的编辑距离（相邻字符交换计为一次），超过 limit 时提前返回 limit+1
+func editDistance(a, b []rune, limit int) int {
+	if diff := len(a) - len(b); diff > limit || -diff > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = minInt(cur[j], prev2[j-2]+1)
+			}
+			rowMin = minInt(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// expandTerm 返回与检索词匹配的索引词项及匹配得分
+func (idx *nameIndex) expandTerm(token string) map[string]float64 {
+	matches := map[string]float64{}
+	if _, ok := idx.postings[token]; ok {
+		matches[token] = exactMatchScore
+	}
+
+	// 前缀匹配
+	qLen := len([]rune(token))
+	for i := sort.SearchStrings(idx.terms, token); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], token); i++ {
+		term := idx.terms[i]
+		if term == token {
+			continue
+		}
+		matches[term] = prefixMatchBase + prefixMatchRange*float64(qLen)/float64(len([]rune(term)))
+	}
+
+	// 拼写容错匹配
+	maxDist := maxTypoDistance(qLen)
+	if maxDist == 0 {
+		return matches
+	}
+	q := []rune(token)
+	for _, term := range idx.terms {
+		if _, ok := matches[term]; ok {
+			continue
+		}
+		t := []rune(term)
+		if d := editDistance(q, t, maxDist); d <= maxDist {
+			matches[term] = 0.7 - 0.2*float64(d)
+		}
+	}
+	return matches
+}
+
+// SearchCompounds 按名称、别名、CAS号、标签和来源全文检索化合物，按相关度排序。
+// 每个检索词都必须命中（支持前缀和拼写容错），整个检索式与某字段完全一致时额外加分。
+// 检索式超过 MaxSearchQueryLength 个字符或 MaxSearchTerms 个词项时返回 ErrSearchQueryTooLong
+func SearchCompounds(query string, limit, offset int) ([]SearchHit, int, error) {
+	if utf8.RuneCountInString(query) > MaxSearchQueryLength {
+		return nil, 0, ErrSearchQueryTooLong
+	}
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return nil, 0, ErrEmptySearchQuery
+	}
+	if len(tokens) > MaxSearchTerms {
+		return nil, 0, ErrSearchQueryTooLong
+	}
+	if err := ensureSearchIndex(); err != nil {
+		return nil, 0, err
+	}
+
+	searchMu.RLock()
+	defer searchMu.RUnlock()
+	idx := searchIdx
+
+	type scored struct {
+		score   float64
+		matches map[string]string
+	}
+	var results map[string]*scored
+	seen := map[string]bool{}
+	for _, token := range tokens {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+
+		// 每个化合物取该检索词的最佳命中
+		best := map[string]termPosting{}
+		bestScore := map[string]float64{}
+		for term, m := range idx.expandTerm(token) {
+			for id := range idx.postings[term] {
+				posting := idx.docs[id].terms[term]
+				if s := m * posting.weight; s > bestScore[id] {
+					bestScore[id] = s
+					best[id] = posting
+				}
+			}
+		}
+
+		next := map[string]*scored{}
+		for id, s := range bestScore {
+			var r *scored
+			if results == nil {
+				r = &scored{matches: map[string]string{}}
+			} else if r = results[id]; r == nil {
+				continue
+			}
+			r.score += s
+			if _, ok := r.matches[best[id].field]; !ok {
+				r.matches[best[id].field] = best[id].text
+			}
+			next[id] = r
+		}
+		results = next
+		if len(results) == 0 {
+			break
+		}
+	}
+
+	phrase := normalizePhrase(query)
+	hits := make([]SearchHit, 0, len(results))
+	for id, r := range results {
+		doc := idx.docs[id]
+		if posting, ok := doc.phrases[phrase]; ok {
+			r.score += phraseBonus * posting.weight
+		}
+		hit := SearchHit{
+			ID:        id,
+			ItemName:  doc.itemName,
+			CASNumber: doc.casNumber,
+			Source:    doc.source,
+			Score:     math.Round(r.score*1000) / 1000,
+		}
+		for field, text := range r.matches {
+			hit.Matches = append(hit.Matches, SearchMatch{Field: field, Text: text})
+		}
+		sort.Slice(hit.Matches, func(i, j int) bool {
+			return searchFieldWeights[hit.Matches[i].Field] > searchFieldWeights[hit.Matches[j].Field]
+		})
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	total := len(hits)
+	if offset >= total {
+		return []SearchHit{}, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return hits[offset:end], total, nil
+}
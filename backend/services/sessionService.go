@@ -0,0 +1,360 @@
+package services
+
+import (
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，会话已注销")
+	ErrSessionRevoked      = errors.New("会话已注销")
+	ErrSessionExpired      = errors.New("会话已过期")
+)
+
+// 默认访问令牌有效期（分钟）和会话最长有效期（分钟），
+// 可通过 jwt.access_token_lifetime 和 jwt.session_max_lifetime 配置
+const (
+	defaultAccessTokenLifetime = 15
+	defaultSessionMaxLifetime  = 30 * 24 * 60
+)
+
+// TokenPair 登录或刷新后返回给客户端的令牌
+type TokenPair struct {
+	SessionID        string
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// AccessTokenLifetime 返回访问令牌有效期
+func AccessTokenLifetime() time.Duration {
+	minutes := config.Config.GetInt("jwt.access_token_lifetime")
+	if minutes <= 0 {
+		minutes = defaultAccessTokenLifetime
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// sessionMaxLifetime 返回会话最长有效期，超过后即使一直在刷新也需要重新登录
+func sessionMaxLifetime() time.Duration {
+	minutes := config.Config.GetInt("jwt.session_max_lifetime")
+	if minutes <= 0 {
+		minutes = defaultSessionMaxLifetime
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// newRefreshSecret 生成刷新令牌的随机部分及其摘要，数据库只保存摘要
+func newRefreshSecret() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		utils.LogError(err)
+		return "", "", fmt.Errorf("生成刷新令牌失败: %v", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	return secret, hashRefreshSecret(secret), nil
+}
+
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionExpiresAt 计算会话的空闲过期时间：按 passkey 的令牌有效期顺延，不超过会话最长有效期
+func sessionExpiresAt(p *models.Passkey, absolute, now time.Time) time.Time {
+	expiresAt := now.Add(TokenLifetime(p, now))
+	if absolute.Before(expiresAt) {
+		expiresAt = absolute
+	}
+	return expiresAt
+}
+
+// StartSession 为登录成功的 passkey 创建会话，签发访问令牌和刷新令牌
+func StartSession(p *models.Passkey, clientIP, userAgent string, now time.Time) (*TokenPair, error) {
+	if config.Config.GetString("jwt.secret") == "" {
+		return nil, ErrJWTSecretMissing
+	}
+
+	secret, hash, err := newRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+	userAgent = truncateString(userAgent, 511)
+
+	absolute := now.Add(sessionMaxLifetime())
+	if p.ValidUntil != nil && p.ValidUntil.Before(absolute) {
+		absolute = *p.ValidUntil
+	}
+	session := models.Session{
+		ID:                uuid.NewString(),
+		Passkey:           p.Passkey,
+		RefreshHash:       hash,
+		TokenVersion:      p.TokenVersion,
+		ClientIP:          clientIP,
+		UserAgent:         userAgent,
+		LastUsedAt:        now,
+		AbsoluteExpiresAt: absolute,
+	}
+	session.ExpiresAt = sessionExpiresAt(p, absolute, now)
+	if err := database.GetDB().Table("sessions").Create(&session).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("创建会话失败: %v", err)
+	}
+
+	return issueTokenPair(p, &session, secret, now)
+}
+
+// LoginPasskey 检查 passkey 的有效期和登录次数，记录登录并创建会话。
+// 用于安全密钥和统一身份认证等已完成身份验证的登录方式
+func LoginPasskey(p *models.Passkey, clientIP, userAgent string, now time.Time) (*TokenPair, error) {
+	// 先检查配置，避免配置错误时消耗登录次数
+	if config.Config.GetString("jwt.secret") == "" {
+		return nil, ErrJWTSecretMissing
+	}
+	if err := RecordLogin(p, now); err != nil {
+		return nil, err
+	}
+	return StartSession(p, clientIP, userAgent, now)
+}
+
+// issueTokenPair 为会话签发访问令牌，并组装刷新令牌（会话ID.随机串）
+func issueTokenPair(p *models.Passkey, session *models.Session, secret string, now time.Time) (*TokenPair, error) {
+	accessToken, _, accessExpiresAt, err := IssueAccessToken(p, session.ID, session.ExpiresAt, now)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		SessionID:        session.ID,
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     session.ID + "." + secret,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// RefreshSession 使用刷新令牌换取新的访问令牌和刷新令牌（轮换），并顺延会话有效期。
+// 上一个刷新令牌再次出现时视为泄露，立即注销整个会话；其他不匹配的令牌只返回 ErrRefreshTokenInvalid，
+// 以免只知道会话ID的人注销他人的会话
+func RefreshSession(refreshToken string, now time.Time) (*TokenPair, *models.Passkey, error) {
+	sessionID, secret, ok := strings.Cut(strings.TrimSpace(refreshToken), ".")
+	if !ok || sessionID == "" || secret == "" {
+		return nil, nil, ErrRefreshTokenInvalid
+	}
+
+	db := database.GetDB()
+	var session models.Session
+	if err := db.Table("sessions").Where(map[string]interface{}{"ID": sessionID}).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrRefreshTokenInvalid
+		}
+		utils.LogError(err)
+		return nil, nil, fmt.Errorf("查询会话失败: %v", err)
+	}
+	if session.RevokedAt != nil {
+		return nil, nil, ErrSessionRevoked
+	}
+
+	hash := hashRefreshSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(session.RefreshHash)) != 1 {
+		return nil, nil, rejectRefresh(&session, hash, now)
+	}
+	if !now.Before(session.ExpiresAt) || !now.Before(session.AbsoluteExpiresAt) {
+		return nil, nil, ErrSessionExpired
+	}
+
+	// 刷新时重新读取 passkey，角色、状态和有效期的修改立即生效
+	var p models.Passkey
+	if err := db.Table("passkeys").Where(map[string]interface{}{"Passkey": session.Passkey}).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrPasskeyNotFound
+		}
+		utils.LogError(err)
+		return nil, nil, fmt.Errorf("查询passkey失败: %v", err)
+	}
+	if err := CheckPasskeyValidity(&p, now); err != nil {
+		return nil, nil, err
+	}
+	if p.TokenVersion != session.TokenVersion {
+		if err := revokeSession(session.ID, models.SessionRevokeAll, now); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrTokenRevoked
+	}
+
+	newSecret, newHash, err := newRefreshSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+	expiresAt := sessionExpiresAt(&p, session.AbsoluteExpiresAt, now)
+
+	// 按旧摘要条件更新，并发使用同一刷新令牌时只有一个请求能够成功
+	result := db.Table("sessions").
+		Where(map[string]interface{}{"ID": session.ID, "Refresh_Hash": hash, "Revoked_At": nil}).
+		Updates(map[string]interface{}{
+			"Refresh_Hash":          newHash,
+			"Previous_Refresh_Hash": hash,
+			"Last_Used_At":          now,
+			"Expires_At":            expiresAt,
+		})
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return nil, nil, fmt.Errorf("刷新会话失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// 并发请求已轮换或注销了会话，按最新状态判断
+		if err := db.Table("sessions").Where(map[string]interface{}{"ID": session.ID}).First(&session).Error; err != nil {
+			utils.LogError(err)
+			return nil, nil, fmt.Errorf("查询会话失败: %v", err)
+		}
+		if session.RevokedAt != nil {
+			return nil, nil, ErrSessionRevoked
+		}
+		return nil, nil, rejectRefresh(&session, hash, now)
+	}
+
+	session.PreviousRefreshHash = hash
+	session.RefreshHash = newHash
+	session.LastUsedAt = now
+	session.ExpiresAt = expiresAt
+	pair, err := issueTokenPair(&p, &session, newSecret, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, &p, nil
+}
+
+// rejectRefresh 处理与当前刷新令牌不匹配的摘要：等于上一个刷新令牌时注销会话并返回 ErrRefreshTokenReused，
+// 否则不改动会话，返回 ErrRefreshTokenInvalid
+func rejectRefresh(session *models.Session, hash string, now time.Time) error {
+	if session.PreviousRefreshHash == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(session.PreviousRefreshHash)) != 1 {
+		return ErrRefreshTokenInvalid
+	}
+	if err := revokeSession(session.ID, models.SessionRevokeReuse, now); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// ListSessions 获取 passkey 的会话，默认只返回未注销且未过期的会话
+func ListSessions(passkey string, includeInactive bool, now time.Time) ([]models.Session, error) {
+	query := database.GetDB().Table("sessions").Where(map[string]interface{}{"Passkey": passkey})
+	if !includeInactive {
+		query = query.Where(map[string]interface{}{"Revoked_At": nil}).Where(clause.Gt{Column: "Expires_At", Value: now})
+	}
+	var sessions []models.Session
+	if err := query.Order(database.Desc("Last_Used_At")).Find(&sessions).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取会话失败: %v", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession 注销 passkey 的某个会话，返回注销的会话数
+func RevokeSession(passkey, sessionID, reason string) (int64, error) {
+	result := database.GetDB().Table("sessions").
+		Where(map[string]interface{}{"ID": sessionID, "Passkey": passkey, "Revoked_At": nil}).
+		Updates(map[string]interface{}{"Revoked_At": time.Now(), "Revoke_Reason": reason})
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return 0, fmt.Errorf("注销会话失败: %v", result.Error)
+	}
+	invalidateSessionCache(sessionID)
+	return result.RowsAffected, nil
+}
+
+// RevokeSessionsByPasskey 注销 passkey 的全部会话
+func RevokeSessionsByPasskey(passkey, reason string) error {
+	db := database.GetDB()
+	var ids []string
+	if err := db.Table("sessions").Where(map[string]interface{}{"Passkey": passkey, "Revoked_At": nil}).Pluck("ID", &ids).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("注销会话失败: %v", err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := db.Table("sessions").Where(map[string]interface{}{"ID": ids}).
+		Updates(map[string]interface{}{"Revoked_At": time.Now(), "Revoke_Reason": reason}).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("注销会话失败: %v", err)
+	}
+	for _, id := range ids {
+		invalidateSessionCache(id)
+	}
+	return nil
+}
+
+func revokeSession(sessionID, reason string, now time.Time) error {
+	if err := database.GetDB().Table("sessions").Where(map[string]interface{}{"ID": sessionID, "Revoked_At": nil}).
+		Updates(map[string]interface{}{"Revoked_At": now, "Revoke_Reason": reason}).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("注销会话失败: %v", err)
+	}
+	invalidateSessionCache(sessionID)
+	return nil
+}
+
+// cachedSession 缓存的会话注销状态
+type cachedSession struct {
+	revoked  bool
+	loadedAt time.Time
+}
+
+// 会话缓存达到该数量时清理过期的缓存项
+const maxSessionCacheSize = 4096
+
+var sessionCache = map[string]cachedSession{}
+
+// isSessionRevoked 判断会话是否已注销（或已不存在），结果按认证缓存有效期缓存
+func isSessionRevoked(sessionID string, now time.Time) (bool, error) {
+	authCacheMu.RLock()
+	cached, ok := sessionCache[sessionID]
+	authCacheMu.RUnlock()
+	if ok && now.Sub(cached.loadedAt) < authCacheTTL() {
+		return cached.revoked, nil
+	}
+
+	var session models.Session
+	err := database.GetDB().Table("sessions").Select("ID", "Revoked_At").Where(map[string]interface{}{"ID": sessionID}).First(&session).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogError(err)
+		return false, fmt.Errorf("查询会话失败: %v", err)
+	}
+	revoked := err != nil || session.RevokedAt != nil
+
+	authCacheMu.Lock()
+	// 清理过期的缓存项，避免会话缓存无限增长
+	if len(sessionCache) >= maxSessionCacheSize {
+		for id, entry := range sessionCache {
+			if now.Sub(entry.loadedAt) >= authCacheTTL() {
+				delete(sessionCache, id)
+			}
+		}
+	}
+	sessionCache[sessionID] = cachedSession{revoked: revoked, loadedAt: now}
+	authCacheMu.Unlock()
+	return revoked, nil
+}
+
+func invalidateSessionCache(sessionID string) {
+	authCacheMu.Lock()
+	delete(sessionCache, sessionID)
+	authCacheMu.Unlock()
+}
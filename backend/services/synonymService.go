@@ -0,0 +1,70 @@
+package services
+
+import (
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidSynonymType = errors.New("不支持的别名类型")
+	ErrEmptySynonym       = errors.New("别名不能为空")
+	ErrDuplicateSynonym   = errors.New("该化合物已存在相同别名")
+)
+
+// GetSynonymsByDataID 获取化合物的全部别名
+func GetSynonymsByDataID(dataID string) ([]models.Synonym, error) {
+	var synonyms []models.Synonym
+	if err := database.GetDB().Table("synonyms").Where(map[string]interface{}{"Data_ID": dataID}).Order(database.Asc("Name_Type")).Order(database.Asc("Name")).Find(&synonyms).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取别名失败: %v", err)
+	}
+	return synonyms, nil
+}
+
+// CreateSynonym 为化合物新增别名，并更新名称检索索引
+func CreateSynonym(synonym *models.Synonym) error {
+	synonym.Name = strings.Join(strings.Fields(synonym.Name), " ")
+	if synonym.Name == "" {
+		return ErrEmptySynonym
+	}
+	synonym.NameType = strings.ToLower(strings.TrimSpace(synonym.NameType))
+	if synonym.NameType == "" {
+		synonym.NameType = models.SynonymTypeTrivial
+	}
+	if !containsString(models.SynonymTypes, synonym.NameType) {
+		return ErrInvalidSynonymType
+	}
+
+	var count int64
+	if err := database.GetDB().Table("synonyms").Where(map[string]interface{}{"Data_ID": synonym.DataID, "Name": synonym.Name}).Count(&count).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("查询别名失败: %v", err)
+	}
+	if count > 0 {
+		return ErrDuplicateSynonym
+	}
+
+	if err := database.GetDB().Table("synonyms").Create(synonym).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("创建别名失败: %v", err)
+	}
+	RefreshSearchDocument(synonym.DataID)
+	return nil
+}
+
+// DeleteSynonym 删除化合物的别名，返回删除的行数
+func DeleteSynonym(dataID string, id uint) (int64, error) {
+	result := database.GetDB().Table("synonyms").Where(map[string]interface{}{"ID": id, "Data_ID": dataID}).Delete(&models.Synonym{})
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return 0, fmt.Errorf("删除别名失败: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		RefreshSearchDocument(dataID)
+	}
+	return result.RowsAffected, nil
+}
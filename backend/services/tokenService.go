@@ -0,0 +1,272 @@
+package services
+
+import (
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrJWTSecretMissing = errors.New("JWT密钥未配置")
+	ErrTokenRevoked     = errors.New("令牌已注销")
+	ErrPasskeyNotFound  = errors.New("passkey不存在")
+)
+
+// 默认的认证缓存有效期（秒），可通过 jwt.cache_ttl 配置。
+// 本进程内的注销和 passkey 修改会立即生效，多实例部署时其他实例最迟在缓存过期后生效
+const defaultAuthCacheTTL = 30
+
+// TokenClaims 访问令牌中携带的用户信息
+type TokenClaims struct {
+	JTI         string
+	SessionID   string
+	Passkey     string
+	Operator    string
+	Description string
+	Role        string
+	Version     int
+	ExpiresAt   time.Time
+}
+
+// IssueAccessToken 为会话签发短期访问令牌，过期时间不晚于 notAfter（会话过期时间），
+// 返回令牌、令牌ID和过期时间
+func IssueAccessToken(p *models.Passkey, sessionID string, notAfter, now time.Time) (string, string, time.Time, error) {
+	secret := config.Config.GetString("jwt.secret")
+	if secret == "" {
+		return "", "", time.Time{}, ErrJWTSecretMissing
+	}
+
+	jti := uuid.NewString()
+	expiresAt := now.Add(AccessTokenLifetime())
+	if notAfter.Before(expiresAt) {
+		expiresAt = notAfter
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":         jti,
+		"sid":         sessionID,
+		"passkey":     p.Passkey,
+		"operator":    p.Operator,
+		"description": p.Description,
+		"role":        p.Role,
+		"ver":         p.TokenVersion,
+		"exp":         expiresAt.Unix(),
+		"iat":         now.Unix(),
+	})
+
+	tokenString, err := token.SignedString([]byte(secret))
+	if err != nil {
+		utils.LogError(err)
+		return "", "", time.Time{}, fmt.Errorf("生成token失败: %v", err)
+	}
+	return tokenString, jti, expiresAt, nil
+}
+
+// ParseAccessToken 校验令牌签名和过期时间并取出其中的用户信息
+func ParseAccessToken(tokenString string) (*TokenClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// 验证签名方法
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(config.Config.GetString("jwt.secret")), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	result := &TokenClaims{}
+	result.JTI, _ = claims["jti"].(string)
+	result.SessionID, _ = claims["sid"].(string)
+	result.Passkey, _ = claims["passkey"].(string)
+	result.Operator, _ = claims["operator"].(string)
+	result.Description, _ = claims["description"].(string)
+	result.Role, _ = claims["role"].(string)
+	// JSON 数字解析为 float64；升级前签发的令牌没有 ver，视为版本 0
+	if ver, ok := claims["ver"].(float64); ok {
+		result.Version = int(ver)
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		result.ExpiresAt = exp.Time
+	}
+	return result, nil
+}
+
+// cachedPasskey 缓存的 passkey 认证状态，passkey 为 nil 表示不存在
+type cachedPasskey struct {
+	passkey  *models.Passkey
+	loadedAt time.Time
+}
+
+var (
+	authCacheMu   sync.RWMutex
+	passkeyCache  = map[string]cachedPasskey{}
+	revokedTokens = map[string]time.Time{} // 令牌ID -> 过期时间
+	revokedLoaded time.Time
+)
+
+// authCacheTTL 返回认证缓存有效期
+func authCacheTTL() time.Duration {
+	ttl := config.Config.GetInt("jwt.cache_ttl")
+	if ttl <= 0 {
+		ttl = defaultAuthCacheTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// getAuthPasskey 获取 passkey 的认证状态（角色、启用状态、有效期和令牌版本），优先使用缓存
+func getAuthPasskey(passkey string, now time.Time) (*models.Passkey, error) {
+	authCacheMu.RLock()
+	cached, ok := passkeyCache[passkey]
+	authCacheMu.RUnlock()
+	if ok && now.Sub(cached.loadedAt) < authCacheTTL() {
+		if cached.passkey == nil {
+			return nil, ErrPasskeyNotFound
+		}
+		return cached.passkey, nil
+	}
+
+	var p models.Passkey
+	err := database.GetDB().Table("passkeys").
+		Select("Passkey", "Role", "Is_Active", "Valid_From", "Valid_Until", "Token_Version", "Rate_Limit", "Daily_Quota", "Monthly_Quota").
+		Where(map[string]interface{}{"Passkey": passkey}).First(&p).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogError(err)
+		return nil, fmt.Errorf("查询passkey失败: %v", err)
+	}
+
+	entry := cachedPasskey{loadedAt: now}
+	if err == nil {
+		entry.passkey = &p
+	}
+	authCacheMu.Lock()
+	passkeyCache[passkey] = entry
+	authCacheMu.Unlock()
+
+	if entry.passkey == nil {
+		return nil, ErrPasskeyNotFound
+	}
+	return entry.passkey, nil
+}
+
+// isTokenRevoked 判断令牌是否已被单独注销，注销列表按缓存有效期从数据库重新加载
+func isTokenRevoked(jti string, now time.Time) (bool, error) {
+	authCacheMu.RLock()
+	fresh := now.Sub(revokedLoaded) < authCacheTTL()
+	_, revoked := revokedTokens[jti]
+	authCacheMu.RUnlock()
+	if fresh {
+		return revoked, nil
+	}
+
+	// 清理已过期的记录后重新加载
+	db := database.GetDB()
+	if err := db.Table("revoked_tokens").Where(clause.Lt{Column: "Expires_At", Value: now}).Delete(&models.RevokedToken{}).Error; err != nil {
+		utils.LogError(err)
+	}
+	var rows []models.RevokedToken
+	if err := db.Table("revoked_tokens").Select("JTI", "Expires_At").Find(&rows).Error; err != nil {
+		utils.LogError(err)
+		return false, fmt.Errorf("查询令牌注销列表失败: %v", err)
+	}
+	list := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		list[row.JTI] = row.ExpiresAt
+	}
+
+	authCacheMu.Lock()
+	revokedTokens = list
+	revokedLoaded = now
+	authCacheMu.Unlock()
+
+	_, revoked = list[jti]
+	return revoked, nil
+}
+
+// ValidateTokenState 检查令牌对应的 passkey 当前是否仍可使用：
+// passkey 存在、启用且在有效期内，令牌版本与 passkey 一致，且令牌未被单独注销。
+// 会话签发的令牌还要求会话未被注销。返回 passkey 当前的认证状态
+func ValidateTokenState(claims *TokenClaims, now time.Time) (*models.Passkey, error) {
+	p, err := getAuthPasskey(claims.Passkey, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckPasskeyValidity(p, now); err != nil {
+		return nil, err
+	}
+	if claims.Version != p.TokenVersion {
+		return nil, ErrTokenRevoked
+	}
+	if claims.JTI != "" {
+		revoked, err := isTokenRevoked(claims.JTI, now)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+	if claims.SessionID != "" {
+		revoked, err := isSessionRevoked(claims.SessionID, now)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrSessionRevoked
+		}
+	}
+	return p, nil
+}
+
+// InvalidatePasskeyCache 清除 passkey 的认证缓存，修改 passkey 后调用
+func InvalidatePasskeyCache(passkey string) {
+	authCacheMu.Lock()
+	delete(passkeyCache, passkey)
+	authCacheMu.Unlock()
+}
+
+// RevokeToken 注销单个令牌
+func RevokeToken(jti, passkey string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+	record := models.RevokedToken{JTI: jti, Passkey: passkey, ExpiresAt: expiresAt}
+	if err := database.GetDB().Table("revoked_tokens").Save(&record).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("注销令牌失败: %v", err)
+	}
+
+	authCacheMu.Lock()
+	revokedTokens[jti] = expiresAt
+	authCacheMu.Unlock()
+	return nil
+}
+
+// RevokeAllTokens 递增 passkey 的令牌版本，使此前签发的全部令牌失效，并结束全部会话（在所有设备上退出登录）
+func RevokeAllTokens(passkey string) error {
+	result := database.GetDB().Table("passkeys").Where(map[string]interface{}{"Passkey": passkey}).
+		Update("Token_Version", gorm.Expr("? + 1", database.Column("Token_Version")))
+	if result.Error != nil {
+		utils.LogError(result.Error)
+		return fmt.Errorf("注销令牌失败: %v", result.Error)
+	}
+	InvalidatePasskeyCache(passkey)
+	if result.RowsAffected == 0 {
+		return ErrPasskeyNotFound
+	}
+	return RevokeSessionsByPasskey(passkey, models.SessionRevokeAll)
+}
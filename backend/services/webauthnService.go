@@ -0,0 +1,481 @@
+package services
+
+import (
+	"backend/config"
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWebAuthnNotConfigured  = errors.New("WebAuthn未配置")
+	ErrPasskeyEnrolled        = errors.New("该passkey已绑定安全密钥，请使用安全密钥登录")
+	ErrCeremonyNotFound       = errors.New("认证请求不存在或已过期，请重试")
+	ErrWebAuthnFailed         = errors.New("安全密钥验证失败")
+	ErrCredentialCloned       = errors.New("安全密钥签名计数异常，可能已被复制")
+	ErrCredentialExists       = errors.New("该安全密钥已绑定")
+	ErrCredentialNotFound     = errors.New("凭据不存在")
+	ErrLastCredential         = errors.New("不能删除最后一个安全密钥")
+	ErrEnrollmentCodeConsumed = errors.New("注册码已被使用")
+)
+
+// 注册和登录仪式的有效期，超过后需要重新开始
+const webAuthnCeremonyTTL = 5 * time.Minute
+
+var (
+	webAuthnMu       sync.Mutex
+	webAuthnInstance *webauthn.WebAuthn
+	webAuthnConfig   string // 生成 webAuthnInstance 时的配置，配置修改后重新生成
+)
+
+// getWebAuthn 按 webauthn 配置创建 WebAuthn 实例
+func getWebAuthn() (*webauthn.WebAuthn, error) {
+	rpID := config.Config.GetString("webauthn.rp_id")
+	origins := config.Config.GetStringSlice("webauthn.rp_origins")
+	displayName := config.Config.GetString("webauthn.rp_display_name")
+	if displayName == "" {
+		displayName = "MNPLib"
+	}
+	if rpID == "" || len(origins) == 0 {
+		return nil, ErrWebAuthnNotConfigured
+	}
+
+	key := rpID + "|" + displayName + "|" + strings.Join(origins, ",")
+	webAuthnMu.Lock()
+	defer webAuthnMu.Unlock()
+	if webAuthnInstance != nil && webAuthnConfig == key {
+		return webAuthnInstance, nil
+	}
+
+	instance, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: displayName,
+		RPOrigins:     origins,
+	})
+	if err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("WebAuthn配置错误: %v", err)
+	}
+	webAuthnInstance = instance
+	webAuthnConfig = key
+	return instance, nil
+}
+
+// webAuthnUser 将 passkey 账户适配为 webauthn.User
+type webAuthnUser struct {
+	passkey     *models.Passkey
+	credentials []webauthn.Credential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	id, _ := base64.RawURLEncoding.DecodeString(u.passkey.WebAuthnID)
+	return id
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	if u.passkey.Operator != "" {
+		return u.passkey.Operator
+	}
+	// 手工插入或早期的记录可能短于 UUID
+	id := u.passkey.Passkey
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return "MNPLib " + id
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if u.passkey.Description != "" {
+		return u.WebAuthnName() + " (" + u.passkey.Description + ")"
+	}
+	return u.WebAuthnName()
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// toWebAuthnCredential 将数据库记录转换为 webauthn.Credential
+func toWebAuthnCredential(record models.WebAuthnCredential) webauthn.Credential {
+	id, _ := base64.RawURLEncoding.DecodeString(record.CredentialID)
+	var transports []protocol.AuthenticatorTransport
+	for _, t := range strings.Split(record.Transports, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+	}
+	return webauthn.Credential{
+		ID:              id,
+		PublicKey:       record.PublicKey,
+		AttestationType: record.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			UserPresent:    true,
+			BackupEligible: record.BackupEligible,
+			BackupState:    record.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    record.AAGUID,
+			SignCount: record.SignCount,
+		},
+	}
+}
+
+// loadWebAuthnUser 加载 passkey 账户及其全部凭据
+func loadWebAuthnUser(p *models.Passkey) (*webAuthnUser, error) {
+	records, err := GetWebAuthnCredentials(p.Passkey)
+	if err != nil {
+		return nil, err
+	}
+	user := &webAuthnUser{passkey: p}
+	for _, record := range records {
+		user.credentials = append(user.credentials, toWebAuthnCredential(record))
+	}
+	return user, nil
+}
+
+// webAuthnCeremony 进行中的注册或登录仪式
+type webAuthnCeremony struct {
+	session    webauthn.SessionData
+	passkey    string // 注册仪式对应的 passkey，登录仪式为空
+	enrollment bool   // 是否为使用注册码的首次注册
+	name       string // 凭据名称
+	expiresAt  time.Time
+}
+
+var (
+	ceremonyMu sync.Mutex
+	ceremonies = map[string]webAuthnCeremony{}
+)
+
+// saveCeremony 保存进行中的仪式，返回仪式ID
+func saveCeremony(ceremony webAuthnCeremony, now time.Time) string {
+	id := uuid.NewString()
+	ceremony.expiresAt = now.Add(webAuthnCeremonyTTL)
+
+	ceremonyMu.Lock()
+	defer ceremonyMu.Unlock()
+	for key, c := range ceremonies {
+		if now.After(c.expiresAt) {
+			delete(ceremonies, key)
+		}
+	}
+	ceremonies[id] = ceremony
+	return id
+}
+
+// takeCeremony 取出并删除仪式，每个仪式只能完成一次
+func takeCeremony(id string, now time.Time) (webAuthnCeremony, error) {
+	ceremonyMu.Lock()
+	defer ceremonyMu.Unlock()
+	ceremony, ok := ceremonies[id]
+	delete(ceremonies, id)
+	if !ok || now.After(ceremony.expiresAt) {
+		return webAuthnCeremony{}, ErrCeremonyNotFound
+	}
+	return ceremony, nil
+}
+
+// ensureWebAuthnID 为 passkey 生成随机的 WebAuthn 用户句柄
+func ensureWebAuthnID(p *models.Passkey) error {
+	if p.WebAuthnID != "" {
+		return nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("生成用户句柄失败: %v", err)
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+
+	// 只在尚未生成时写入，并发请求以先写入的为准
+	db := database.GetDB()
+	if err := db.Table("passkeys").Where(map[string]interface{}{"Passkey": p.Passkey}).
+		Where(clause.Or(clause.Eq{Column: "WebAuthn_ID", Value: nil}, clause.Eq{Column: "WebAuthn_ID", Value: ""})).
+		Update("WebAuthn_ID", id).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("保存用户句柄失败: %v", err)
+	}
+	if err := db.Table("passkeys").Select("?", database.Column("WebAuthn_ID")).Where(map[string]interface{}{"Passkey": p.Passkey}).Scan(&p.WebAuthnID).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("查询用户句柄失败: %v", err)
+	}
+	return nil
+}
+
+// BeginWebAuthnRegistration 开始为 passkey 账户注册新的凭据。
+// enrollment 为 true 时表示使用 passkey UUID 作为注册码进行首次注册，完成后注册码失效
+func BeginWebAuthnRegistration(p *models.Passkey, enrollment bool, name string, now time.Time) (string, *protocol.CredentialCreation, error) {
+	wa, err := getWebAuthn()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := CheckPasskeyValidity(p, now); err != nil {
+		return "", nil, err
+	}
+	if enrollment && p.EnrolledAt != nil {
+		return "", nil, ErrPasskeyEnrolled
+	}
+	if err := ensureWebAuthnID(p); err != nil {
+		return "", nil, err
+	}
+	user, err := loadWebAuthnUser(p)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 要求可发现凭据，登录时无需输入账户
+	creation, session, err := wa.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		utils.LogError(err)
+		return "", nil, fmt.Errorf("开始注册失败: %v", err)
+	}
+
+	id := saveCeremony(webAuthnCeremony{
+		session:    *session,
+		passkey:    p.Passkey,
+		enrollment: enrollment,
+		name:       strings.TrimSpace(name),
+	}, now)
+	return id, creation, nil
+}
+
+// FinishWebAuthnRegistration 校验认证器返回的注册结果并保存凭据。
+// 首次注册时同时消耗注册码，返回 passkey 账户、新凭据以及是否为首次注册
+func FinishWebAuthnRegistration(ceremonyID string, response []byte, now time.Time) (*models.Passkey, *models.WebAuthnCredential, bool, error) {
+	ceremony, err := takeCeremony(ceremonyID, now)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	wa, err := getWebAuthn()
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	db := database.GetDB()
+	var p models.Passkey
+	if err := db.Table("passkeys").Where(map[string]interface{}{"Passkey": ceremony.passkey}).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, false, ErrPasskeyNotFound
+		}
+		utils.LogError(err)
+		return nil, nil, false, fmt.Errorf("查询passkey失败: %v", err)
+	}
+	if err := CheckPasskeyValidity(&p, now); err != nil {
+		return nil, nil, false, err
+	}
+	user, err := loadWebAuthnUser(&p)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, nil, false, ErrWebAuthnFailed
+	}
+	credential, err := wa.CreateCredential(user, ceremony.session, parsed)
+	if err != nil {
+		return nil, nil, false, ErrWebAuthnFailed
+	}
+
+	var transports []string
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+	name := ceremony.name
+	if name == "" {
+		name = "安全密钥 " + now.Format("2006-01-02")
+	}
+	record := models.WebAuthnCredential{
+		Passkey:         p.Passkey,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            name,
+	}
+
+	// Credential_ID 有唯一索引，并发注册同一凭据时只有一个能写入，注册码随事务回滚
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if ceremony.enrollment {
+			// 注册码只能使用一次
+			result := tx.Table("passkeys").Where(map[string]interface{}{"Passkey": p.Passkey, "Enrolled_At": nil}).Update("Enrolled_At", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrEnrollmentCodeConsumed
+			}
+		}
+		return tx.Table("webauthn_credentials").Create(&record).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrEnrollmentCodeConsumed) {
+			return nil, nil, false, err
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, nil, false, ErrCredentialExists
+		}
+		utils.LogError(err)
+		return nil, nil, false, fmt.Errorf("保存凭据失败: %v", err)
+	}
+	if ceremony.enrollment {
+		p.EnrolledAt = &now
+	}
+	return &p, &record, ceremony.enrollment, nil
+}
+
+// BeginWebAuthnLogin 开始使用可发现凭据登录，无需提供账户
+func BeginWebAuthnLogin(now time.Time) (string, *protocol.CredentialAssertion, error) {
+	wa, err := getWebAuthn()
+	if err != nil {
+		return "", nil, err
+	}
+	assertion, session, err := wa.BeginDiscoverableLogin()
+	if err != nil {
+		utils.LogError(err)
+		return "", nil, fmt.Errorf("开始登录失败: %v", err)
+	}
+	return saveCeremony(webAuthnCeremony{session: *session}, now), assertion, nil
+}
+
+// FinishWebAuthnLogin 校验认证器返回的断言，返回对应的 passkey 账户。
+// 不检查有效期和登录次数，由调用方通过 RecordLogin 检查
+func FinishWebAuthnLogin(ceremonyID string, response []byte, now time.Time) (*models.Passkey, error) {
+	ceremony, err := takeCeremony(ceremonyID, now)
+	if err != nil {
+		return nil, err
+	}
+	wa, err := getWebAuthn()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, ErrWebAuthnFailed
+	}
+
+	db := database.GetDB()
+	var lookupErr error
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		var p models.Passkey
+		err := db.Table("passkeys").Where(map[string]interface{}{"WebAuthn_ID": base64.RawURLEncoding.EncodeToString(userHandle)}).First(&p).Error
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.LogError(err)
+				lookupErr = fmt.Errorf("查询passkey失败: %v", err)
+			}
+			return nil, err
+		}
+		user, err := loadWebAuthnUser(&p)
+		if err != nil {
+			lookupErr = err
+			return nil, err
+		}
+		return user, nil
+	}
+
+	user, credential, err := wa.ValidatePasskeyLogin(handler, ceremony.session, parsed)
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	if err != nil {
+		return nil, ErrWebAuthnFailed
+	}
+	if credential.Authenticator.CloneWarning {
+		return nil, ErrCredentialCloned
+	}
+
+	p := user.(*webAuthnUser).passkey
+	credentialID := base64.RawURLEncoding.EncodeToString(credential.ID)
+	if err := db.Table("webauthn_credentials").Where(map[string]interface{}{"Passkey": p.Passkey, "Credential_ID": credentialID}).
+		Updates(map[string]interface{}{
+			"Sign_Count":   credential.Authenticator.SignCount,
+			"Backup_State": credential.Flags.BackupState,
+			"Last_Used_At": now,
+		}).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("更新凭据失败: %v", err)
+	}
+	return p, nil
+}
+
+// GetWebAuthnCredentials 获取 passkey 账户的全部凭据
+func GetWebAuthnCredentials(passkey string) ([]models.WebAuthnCredential, error) {
+	var records []models.WebAuthnCredential
+	if err := database.GetDB().Table("webauthn_credentials").Where(map[string]interface{}{"Passkey": passkey}).Order(database.Asc("ID")).Find(&records).Error; err != nil {
+		utils.LogError(err)
+		return nil, fmt.Errorf("获取凭据失败: %v", err)
+	}
+	return records, nil
+}
+
+// DeleteWebAuthnCredential 删除 passkey 账户的凭据。keepLast 为 true 时不允许删除最后一个凭据，
+// 避免已消耗注册码的账户无法再登录
+func DeleteWebAuthnCredential(passkey string, id uint, keepLast bool) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table("webauthn_credentials").Where(map[string]interface{}{"Passkey": passkey}).Count(&count).Error; err != nil {
+			utils.LogError(err)
+			return fmt.Errorf("查询凭据失败: %v", err)
+		}
+		if keepLast && count <= 1 {
+			return ErrLastCredential
+		}
+		result := tx.Table("webauthn_credentials").Where(map[string]interface{}{"ID": id, "Passkey": passkey}).Delete(&models.WebAuthnCredential{})
+		if result.Error != nil {
+			utils.LogError(result.Error)
+			return fmt.Errorf("删除凭据失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrCredentialNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteWebAuthnCredentialsByPasskey 删除 passkey 账户的全部凭据
+func DeleteWebAuthnCredentialsByPasskey(passkey string) error {
+	if err := database.GetDB().Table("webauthn_credentials").Where(map[string]interface{}{"Passkey": passkey}).Delete(&models.WebAuthnCredential{}).Error; err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("删除凭据失败: %v", err)
+	}
+	return nil
+}
+
+// ResetWebAuthnEnrollment 删除账户的全部凭据并恢复 passkey UUID 作为注册码，同时使已签发的令牌失效。
+// 用于安全密钥全部丢失的情况，重置后应通过可信渠道将 UUID 重新发给用户
+func ResetWebAuthnEnrollment(passkey string) error {
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("webauthn_credentials").Where(map[string]interface{}{"Passkey": passkey}).Delete(&models.WebAuthnCredential{}).Error; err != nil {
+			return err
+		}
+		return tx.Table("passkeys").Where(map[string]interface{}{"Passkey": passkey}).Update("Enrolled_At", nil).Error
+	})
+	if err != nil {
+		utils.LogError(err)
+		return fmt.Errorf("重置注册失败: %v", err)
+	}
+	return RevokeAllTokens(passkey)
+}
@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorCode 稳定的机器可读错误代码，随响应体的 error 字段返回。
+// 客户端应根据错误代码而不是 msg 判断错误类型，msg 的措辞可能调整
+type ErrorCode string
+
+// 请求参数错误
+const (
+	CodeInvalidRequest            ErrorCode = "INVALID_REQUEST"
+	CodeMissingParameter          ErrorCode = "MISSING_PARAMETER"
+	CodeParameterRequiredWith     ErrorCode = "PARAMETER_REQUIRED_WITH"
+	CodeInvalidPositiveInteger    ErrorCode = "INVALID_POSITIVE_INTEGER"
+	CodeInvalidNonNegativeInteger ErrorCode = "INVALID_NON_NEGATIVE_INTEGER"
+	CodeInvalidNumber             ErrorCode = "INVALID_NUMBER"
+	CodeInvalidNonNegativeNumber  ErrorCode = "INVALID_NON_NEGATIVE_NUMBER"
+	CodeNumberOutOfRange          ErrorCode = "NUMBER_OUT_OF_RANGE"
+	CodeInvalidTime               ErrorCode = "INVALID_TIME"
+	CodeInvalidChoice             ErrorCode = "INVALID_CHOICE"
+	CodeTooManyExportIDs          ErrorCode = "TOO_MANY_EXPORT_IDS"
+	CodeSearchQueryTooLong        ErrorCode = "SEARCH_QUERY_TOO_LONG"
+)
+
+// 参数格式正确但取值不被接受
+const (
+	CodeUnsupportedRole       ErrorCode = "UNSUPPORTED_ROLE"
+	CodeUnsupportedRank       ErrorCode = "UNSUPPORTED_RANK"
+	CodeUnsupportedAdduct     ErrorCode = "UNSUPPORTED_ADDUCT"
+	CodeInvalidFormula        ErrorCode = "INVALID_FORMULA"
+	CodeUnknownElement        ErrorCode = "UNKNOWN_ELEMENT"
+	CodeAdductIncomplete      ErrorCode = "ADDUCT_INCOMPLETE"
+	CodeInvalidEndpoint       ErrorCode = "INVALID_ENDPOINT"
+	CodeInvalidQualifier      ErrorCode = "INVALID_QUALIFIER"
+	CodeInvalidUnits          ErrorCode = "INVALID_UNITS"
+	CodeInvalidGrantField     ErrorCode = "INVALID_GRANT_FIELD"
+	CodeInvalidGrantScope     ErrorCode = "INVALID_GRANT_SCOPE"
+	CodeEmptyGrantFields      ErrorCode = "EMPTY_GRANT_FIELDS"
+	CodeEmptyGrantScope       ErrorCode = "EMPTY_GRANT_SCOPE"
+	CodeInvalidSynonymType    ErrorCode = "INVALID_SYNONYM_TYPE"
+	CodeEmptySynonym          ErrorCode = "EMPTY_SYNONYM"
+	CodeInvalidReferenceField ErrorCode = "INVALID_REFERENCE_FIELD"
+	CodeInvalidDOI            ErrorCode = "INVALID_DOI"
+	CodeInvalidPubMedID       ErrorCode = "INVALID_PUBMED_ID"
+	CodeInvalidValidity       ErrorCode = "INVALID_VALIDITY"
+	CodeInvalidMaxLogins      ErrorCode = "INVALID_MAX_LOGINS"
+	CodeInvalidTokenLifetime  ErrorCode = "INVALID_TOKEN_LIFETIME"
+	CodeNegativeLimit         ErrorCode = "NEGATIVE_LIMIT"
+	CodeExceedsParentValidity ErrorCode = "EXCEEDS_PARENT_VALIDITY"
+	CodeExceedsParentLimit    ErrorCode = "EXCEEDS_PARENT_LIMIT"
+	CodeDescendantExceeds     ErrorCode = "DESCENDANT_EXCEEDS"
+	CodeDuplicateSynonym      ErrorCode = "DUPLICATE_SYNONYM"
+	CodeDuplicateReference    ErrorCode = "DUPLICATE_REFERENCE"
+	CodeCredentialExists      ErrorCode = "CREDENTIAL_EXISTS"
+	CodeLastCredential        ErrorCode = "LAST_CREDENTIAL"
+	CodeCeremonyNotFound      ErrorCode = "CEREMONY_NOT_FOUND"
+)
+
+// 认证失败
+const (
+	CodeUnauthenticated        ErrorCode = "UNAUTHENTICATED"
+	CodeMissingToken           ErrorCode = "MISSING_TOKEN"
+	CodeMalformedToken         ErrorCode = "MALFORMED_TOKEN"
+	CodeInvalidToken           ErrorCode = "INVALID_TOKEN"
+	CodeTokenRevoked           ErrorCode = "TOKEN_REVOKED"
+	CodeSessionRevoked         ErrorCode = "SESSION_REVOKED"
+	CodeSessionExpired         ErrorCode = "SESSION_EXPIRED"
+	CodeRefreshTokenInvalid    ErrorCode = "REFRESH_TOKEN_INVALID"
+	CodeRefreshTokenReused     ErrorCode = "REFRESH_TOKEN_REUSED"
+	CodeInvalidPasskey         ErrorCode = "INVALID_PASSKEY"
+	CodePasskeyInactive        ErrorCode = "PASSKEY_INACTIVE"
+	CodePasskeyNotYetValid     ErrorCode = "PASSKEY_NOT_YET_VALID"
+	CodePasskeyExpired         ErrorCode = "PASSKEY_EXPIRED"
+	CodeLoginLimitReached      ErrorCode = "LOGIN_LIMIT_REACHED"
+	CodeInvalidEnrollmentCode  ErrorCode = "INVALID_ENROLLMENT_CODE"
+	CodeEnrollmentCodeConsumed ErrorCode = "ENROLLMENT_CODE_CONSUMED"
+	CodePasskeyEnrolled        ErrorCode = "PASSKEY_ENROLLED"
+	CodeEnrollmentRequired     ErrorCode = "ENROLLMENT_REQUIRED"
+	CodeOIDCAccount            ErrorCode = "OIDC_ACCOUNT"
+	CodeWebAuthnFailed         ErrorCode = "WEBAUTHN_FAILED"
+	CodeCredentialCloned       ErrorCode = "CREDENTIAL_CLONED"
+	CodeOIDCStateInvalid       ErrorCode = "OIDC_STATE_INVALID"
+	CodeOIDCFailed             ErrorCode = "OIDC_FAILED"
+	CodeOIDCEmailNotVerified   ErrorCode = "OIDC_EMAIL_NOT_VERIFIED"
+	CodeOIDCCodeInvalid        ErrorCode = "OIDC_CODE_INVALID"
+)
+
+// 无权访问
+const (
+	CodeRoleForbidden          ErrorCode = "ROLE_FORBIDDEN"
+	CodeOutsideDelegationScope ErrorCode = "OUTSIDE_DELEGATION_SCOPE"
+	CodeRoleNotManageable      ErrorCode = "ROLE_NOT_MANAGEABLE"
+	CodeSelfModification       ErrorCode = "SELF_MODIFICATION"
+	CodeNoDataGrant            ErrorCode = "NO_DATA_GRANT"
+	CodeOIDCNoRole             ErrorCode = "OIDC_NO_ROLE"
+)
+
+// 资源不存在
+const (
+	CodeDataNotFound          ErrorCode = "DATA_NOT_FOUND"
+	CodePasskeyNotFound       ErrorCode = "PASSKEY_NOT_FOUND"
+	CodeReferenceNotFound     ErrorCode = "REFERENCE_NOT_FOUND"
+	CodeReferenceLinkNotFound ErrorCode = "REFERENCE_LINK_NOT_FOUND"
+	CodeBioactivityNotFound   ErrorCode = "BIOACTIVITY_NOT_FOUND"
+	CodeOrganismNotFound      ErrorCode = "ORGANISM_NOT_FOUND"
+	CodeSynonymNotFound       ErrorCode = "SYNONYM_NOT_FOUND"
+	CodeGrantNotFound         ErrorCode = "GRANT_NOT_FOUND"
+	CodeSessionNotFound       ErrorCode = "SESSION_NOT_FOUND"
+	CodeCredentialNotFound    ErrorCode = "CREDENTIAL_NOT_FOUND"
+)
+
+// 请求过多
+const (
+	CodeRateLimited          ErrorCode = "RATE_LIMITED"
+	CodeDailyQuotaExceeded   ErrorCode = "DAILY_QUOTA_EXCEEDED"
+	CodeMonthlyQuotaExceeded ErrorCode = "MONTHLY_QUOTA_EXCEEDED"
+)
+
+// 服务端错误
+const (
+	CodeInternalError         ErrorCode = "INTERNAL_ERROR"
+	CodeDatabaseError         ErrorCode = "DATABASE_ERROR"
+	CodeServerMisconfigured   ErrorCode = "SERVER_MISCONFIGURED"
+	CodeChemistryFailed       ErrorCode = "CHEMISTRY_FAILED"
+	CodeChemistryUnavailable  ErrorCode = "CHEMISTRY_UNAVAILABLE"
+	CodeOIDCUnavailable       ErrorCode = "OIDC_UNAVAILABLE"
+	CodeOIDCNotConfigured     ErrorCode = "OIDC_NOT_CONFIGURED"
+	CodeWebAuthnNotConfigured ErrorCode = "WEBAUTHN_NOT_CONFIGURED"
+	CodeMetricsNotConfigured  ErrorCode = "METRICS_NOT_CONFIGURED"
+	CodeNotReady              ErrorCode = "NOT_READY"
+)
+
+// ErrorDefinition 错误代码对应的 HTTP 状态码和消息。
+// 消息中的 {name} 会被替换为错误详情中同名字段的值
+type ErrorDefinition struct {
+	Code    ErrorCode `json:"error"`
+	Status  int       `json:"status"`
+	Message string    `json:"message"`
+}
+
+// errorStatuses 全部错误代码及其 HTTP 状态码，按状态码分组。各语言的消息见 messages.go
+var errorStatuses = []struct {
+	code   ErrorCode
+	status int
+}{
+	{CodeInvalidRequest, http.StatusBadRequest},
+	{CodeMissingParameter, http.StatusBadRequest},
+	{CodeParameterRequiredWith, http.StatusBadRequest},
+	{CodeInvalidPositiveInteger, http.StatusBadRequest},
+	{CodeInvalidNonNegativeInteger, http.StatusBadRequest},
+	{CodeInvalidNumber, http.StatusBadRequest},
+	{CodeInvalidNonNegativeNumber, http.StatusBadRequest},
+	{CodeNumberOutOfRange, http.StatusBadRequest},
+	{CodeInvalidTime, http.StatusBadRequest},
+	{CodeInvalidChoice, http.StatusBadRequest},
+	{CodeTooManyExportIDs, http.StatusBadRequest},
+	{CodeSearchQueryTooLong, http.StatusBadRequest},
+
+	{CodeUnauthenticated, http.StatusUnauthorized},
+	{CodeMissingToken, http.StatusUnauthorized},
+	{CodeMalformedToken, http.StatusUnauthorized},
+	{CodeInvalidToken, http.StatusUnauthorized},
+	{CodeTokenRevoked, http.StatusUnauthorized},
+	{CodeSessionRevoked, http.StatusUnauthorized},
+	{CodeSessionExpired, http.StatusUnauthorized},
+	{CodeRefreshTokenInvalid, http.StatusUnauthorized},
+	{CodeRefreshTokenReused, http.StatusUnauthorized},
+	{CodeInvalidPasskey, http.StatusUnauthorized},
+	{CodePasskeyInactive, http.StatusUnauthorized},
+	{CodePasskeyNotYetValid, http.StatusUnauthorized},
+	{CodePasskeyExpired, http.StatusUnauthorized},
+	{CodeLoginLimitReached, http.StatusUnauthorized},
+	{CodeInvalidEnrollmentCode, http.StatusUnauthorized},
+	{CodePasskeyEnrolled, http.StatusUnauthorized},
+	{CodeEnrollmentCodeConsumed, http.StatusUnauthorized},
+	{CodeEnrollmentRequired, http.StatusUnauthorized},
+	{CodeOIDCAccount, http.StatusUnauthorized},
+	{CodeWebAuthnFailed, http.StatusUnauthorized},
+	{CodeCredentialCloned, http.StatusUnauthorized},
+	{CodeOIDCStateInvalid, http.StatusUnauthorized},
+	{CodeOIDCFailed, http.StatusUnauthorized},
+	{CodeOIDCEmailNotVerified, http.StatusUnauthorized},
+	{CodeOIDCCodeInvalid, http.StatusUnauthorized},
+
+	{CodeRoleForbidden, http.StatusForbidden},
+	{CodeOutsideDelegationScope, http.StatusForbidden},
+	{CodeRoleNotManageable, http.StatusForbidden},
+	{CodeSelfModification, http.StatusForbidden},
+	{CodeNoDataGrant, http.StatusForbidden},
+	{CodeOIDCNoRole, http.StatusForbidden},
+
+	{CodeDataNotFound, http.StatusNotFound},
+	{CodePasskeyNotFound, http.StatusNotFound},
+	{CodeReferenceNotFound, http.StatusNotFound},
+	{CodeReferenceLinkNotFound, http.StatusNotFound},
+	{CodeBioactivityNotFound, http.StatusNotFound},
+	{CodeOrganismNotFound, http.StatusNotFound},
+	{CodeSynonymNotFound, http.StatusNotFound},
+	{CodeGrantNotFound, http.StatusNotFound},
+	{CodeSessionNotFound, http.StatusNotFound},
+	{CodeCredentialNotFound, http.StatusNotFound},
+
+	{CodeDuplicateSynonym, http.StatusConflict},
+	{CodeDuplicateReference, http.StatusConflict},
+	{CodeCredentialExists, http.StatusConflict},
+	{CodeLastCredential, http.StatusConflict},
+
+	{CodeUnsupportedRole, http.StatusUnprocessableEntity},
+	{CodeUnsupportedRank, http.StatusUnprocessableEntity},
+	{CodeUnsupportedAdduct, http.StatusUnprocessableEntity},
+	{CodeInvalidFormula, http.StatusUnprocessableEntity},
+	{CodeUnknownElement, http.StatusUnprocessableEntity},
+	{CodeAdductIncomplete, http.StatusUnprocessableEntity},
+	{CodeInvalidEndpoint, http.StatusUnprocessableEntity},
+	{CodeInvalidQualifier, http.StatusUnprocessableEntity},
+	{CodeInvalidUnits, http.StatusUnprocessableEntity},
+	{CodeInvalidGrantField, http.StatusUnprocessableEntity},
+	{CodeInvalidGrantScope, http.StatusUnprocessableEntity},
+	{CodeEmptyGrantFields, http.StatusUnprocessableEntity},
+	{CodeEmptyGrantScope, http.StatusUnprocessableEntity},
+	{CodeInvalidSynonymType, http.StatusUnprocessableEntity},
+	{CodeEmptySynonym, http.StatusUnprocessableEntity},
+	{CodeInvalidReferenceField, http.StatusUnprocessableEntity},
+	{CodeInvalidDOI, http.StatusUnprocessableEntity},
+	{CodeInvalidPubMedID, http.StatusUnprocessableEntity},
+	{CodeInvalidValidity, http.StatusUnprocessableEntity},
+	{CodeInvalidMaxLogins, http.StatusUnprocessableEntity},
+	{CodeInvalidTokenLifetime, http.StatusUnprocessableEntity},
+	{CodeNegativeLimit, http.StatusUnprocessableEntity},
+	{CodeExceedsParentValidity, http.StatusUnprocessableEntity},
+	{CodeExceedsParentLimit, http.StatusUnprocessableEntity},
+	{CodeDescendantExceeds, http.StatusUnprocessableEntity},
+	{CodeCeremonyNotFound, http.StatusUnprocessableEntity},
+
+	{CodeRateLimited, http.StatusTooManyRequests},
+	{CodeDailyQuotaExceeded, http.StatusTooManyRequests},
+	{CodeMonthlyQuotaExceeded, http.StatusTooManyRequests},
+
+	{CodeInternalError, http.StatusInternalServerError},
+	{CodeDatabaseError, http.StatusInternalServerError},
+	{CodeServerMisconfigured, http.StatusInternalServerError},
+	{CodeChemistryFailed, http.StatusInternalServerError},
+
+	{CodeChemistryUnavailable, http.StatusServiceUnavailable},
+	{CodeOIDCUnavailable, http.StatusServiceUnavailable},
+	{CodeOIDCNotConfigured, http.StatusServiceUnavailable},
+	{CodeWebAuthnNotConfigured, http.StatusServiceUnavailable},
+	{CodeMetricsNotConfigured, http.StatusServiceUnavailable},
+	{CodeNotReady, http.StatusServiceUnavailable},
+}
+
+var statusByCode = func() map[ErrorCode]int {
+	statuses := make(map[ErrorCode]int, len(errorStatuses))
+	for _, item := range errorStatuses {
+		statuses[item.code] = item.status
+	}
+	return statuses
+}()
+
+// ErrorCatalogue 返回全部错误代码及其 HTTP 状态码和指定语言的消息
+func ErrorCatalogue(lang string) []ErrorDefinition {
+	catalogue := make([]ErrorDefinition, 0, len(errorStatuses))
+	for _, item := range errorStatuses {
+		catalogue = append(catalogue, LookupError(item.code, lang))
+	}
+	return catalogue
+}
+
+// LookupError 返回错误代码的定义，消息使用指定的语言。未知的错误代码按服务器内部错误处理
+func LookupError(code ErrorCode, lang string) ErrorDefinition {
+	status, ok := statusByCode[code]
+	if !ok {
+		status = statusByCode[CodeInternalError]
+	}
+	return ErrorDefinition{Code: code, Status: status, Message: Message(code, lang)}
+}
+
+// RenderMessage 将消息中的 {name} 替换为 details 中同名字段的值
+func RenderMessage(message string, details map[string]interface{}) string {
+	if len(details) == 0 || !strings.Contains(message, "{") {
+		return message
+	}
+	pairs := make([]string, 0, len(details)*2)
+	for key, value := range details {
+		pairs = append(pairs, "{"+key+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的语言
+const (
+	LanguageZH = "zh-CN"
+	LanguageEN = "en"
+
+	// DefaultLanguage 没有指定语言或指定的语言不受支持时使用的语言
+	DefaultLanguage = LanguageZH
+)
+
+// NegotiateLanguage 确定响应消息使用的语言。优先使用查询参数 lang，其次是请求头
+// Accept-Language，都不受支持时使用默认语言
+func NegotiateLanguage(ctx *gin.Context) string {
+	if lang, ok := matchLanguage(ctx.Query("lang")); ok {
+		return lang
+	}
+	return parseAcceptLanguage(ctx.GetHeader("Accept-Language"))
+}
+
+// matchLanguage 把语言标签对应到支持的语言，如 en-US、en_us 对应 en，zh、zh-Hans 对应 zh-CN
+func matchLanguage(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, "_", "-")))
+	primary, _, _ := strings.Cut(tag, "-")
+	switch primary {
+	case "zh":
+		return LanguageZH, true
+	case "en":
+		return LanguageEN, true
+	}
+	return "", false
+}
+
+// parseAcceptLanguage 按权重 q 从高到低选择 Accept-Language 中第一个支持的语言，
+// q=0 表示不接受，* 表示任意语言
+func parseAcceptLanguage(header string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{tag: tag, q: q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.tag == "*" {
+			return DefaultLanguage
+		}
+		if lang, ok := matchLanguage(c.tag); ok {
+			return lang
+		}
+	}
+	return DefaultLanguage
+}
@@ -1,23 +1,84 @@
 package utils
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// JSONResponse 全部 JSON 接口的响应体
+type JSONResponse struct {
+	// 六位的业务代码，200+HTTP 状态码，如 200200、200404
+	Code int `json:"code"`
+	// 机器可读的错误代码，只在失败时返回，见 /api/errors
+	Error ErrorCode `json:"error,omitempty"`
+	// 消息，成功时为 success，失败时为错误代码对应的消息
+	Msg string `json:"msg"`
+	// 返回的数据，失败时为 null
+	Data interface{} `json:"data"`
+	// 错误详情，如出错的参数名
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Page 分页查询的结果
+type Page struct {
+	// 当前页的记录
+	Data interface{} `json:"data"`
+	// 符合条件的记录总数
+	Total int64 `json:"total"`
+	Limit int   `json:"limit"`
+	// 当前页第一条记录的偏移量
+	Offset int `json:"offset"`
+	// 是否还有下一页
+	HasMore bool `json:"has_more"`
+	// 下一页的偏移量
+	NextOffset int `json:"next_offset"`
+}
+
+// NewPage 按总记录数和分页参数构造分页结果
+func NewPage(data interface{}, total int64, limit, offset int) Page {
+	return Page{
+		Data:       data,
+		Total:      total,
+		Limit:      limit,
+		Offset:     offset,
+		HasMore:    int64(offset+limit) < total,
+		NextOffset: offset + limit,
+	}
+}
+
 // 基本的响应模板
 func JsonResponse(ctx *gin.Context, httpStatusCode int, code int, msg string, data interface{}) {
-	ctx.JSON(httpStatusCode, gin.H{ // httpsStatusCode是http的状态码，只有200和500之分
-		"code": code, //六位的代码，应该是200+XXX的格式，如果没有出错就200，出错一般200+XXX
-		"data": data, //返回的数据
-		"msg":  msg,  //返回的消息，默认是success
-	})
+	// 记录业务代码，供审计等中间件读取
+	ctx.Set("response_code", code)
+	ctx.JSON(httpStatusCode, JSONResponse{Code: code, Msg: msg, Data: data})
 }
 
-// 操作失败
-func JsonErrorResponse(ctx *gin.Context, code int, msg string) {
-	JsonResponse(ctx, http.StatusBadRequest, code, msg, nil)
+// 操作失败，按错误代码返回对应的 HTTP 状态码和消息。details 为可选的错误详情，
+// 同时用于填充消息中的 {name}。消息的语言见 NegotiateLanguage
+func JsonErrorResponse(ctx *gin.Context, code ErrorCode, details ...gin.H) {
+	lang := NegotiateLanguage(ctx)
+	def := LookupError(code, lang)
+	var detail gin.H
+	if len(details) > 0 {
+		detail = details[0]
+	}
+
+	// 业务代码沿用 200+HTTP 状态码的格式
+	ctx.Set("response_code", 200000+def.Status)
+	ctx.Set("error_code", code)
+	body := JSONResponse{
+		Code:    200000 + def.Status,
+		Error:   code,
+		Msg:     RenderMessage(def.Message, detail),
+		Details: detail,
+	}
+	ctx.Header("Content-Language", lang)
+	ctx.Header("Vary", "Accept-Language")
+	ctx.JSON(def.Status, body)
 }
 
 // 成功操作
@@ -26,7 +87,22 @@ func JsonSuccessResponse(ctx *gin.Context, data interface{}) {
 }
 
 // 未授权的访问
-func JsonUnAuthorizedResponse(ctx *gin.Context, msg string) {
-	JsonResponse(ctx, http.StatusUnauthorized, 200401, msg, nil)
+func JsonUnAuthorizedResponse(ctx *gin.Context, code ErrorCode) {
+	JsonErrorResponse(ctx, code)
 	ctx.Abort() // 终止后续处理
 }
+
+// 请求过于频繁或超出配额，在 Retry-After 和错误详情的 retry_after 中给出需要等待的秒数
+func JsonTooManyRequestsResponse(ctx *gin.Context, retryAfter time.Duration, code ErrorCode, details gin.H) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+	if details == nil {
+		details = gin.H{}
+	}
+	details["retry_after"] = seconds
+	JsonErrorResponse(ctx, code, details)
+	ctx.Abort()
+}
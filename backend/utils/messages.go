@@ -0,0 +1,234 @@
+package utils
+
+// 错误消息，按语言和错误代码索引。新增错误代码时需要同时添加全部语言的消息
+var messageCatalogue = map[string]map[ErrorCode]string{
+	LanguageZH: zhMessages,
+	LanguageEN: enMessages,
+}
+
+// zhMessages 简体中文消息
+var zhMessages = map[ErrorCode]string{
+	CodeInvalidRequest:            "请求参数错误",
+	CodeMissingParameter:          "参数{param}不能为空",
+	CodeParameterRequiredWith:     "使用{with}时参数{param}不能为空",
+	CodeInvalidPositiveInteger:    "参数{param}必须是正整数",
+	CodeInvalidNonNegativeInteger: "参数{param}必须是非负整数",
+	CodeInvalidNumber:             "参数{param}必须是数字",
+	CodeInvalidNonNegativeNumber:  "参数{param}必须是非负数字",
+	CodeNumberOutOfRange:          "参数{param}必须是{min}到{max}之间的数字",
+	CodeInvalidTime:               "参数{param}必须是 RFC 3339 时间或 YYYY-MM-DD 日期",
+	CodeInvalidChoice:             "参数{param}必须是以下取值之一：{choices}",
+	CodeTooManyExportIDs:          "一次最多导出{max}个化合物的文献",
+	CodeSearchQueryTooLong:        "检索词最多{max}个字符、{max_terms}个词",
+
+	CodeUnauthenticated:        "未获取到用户信息",
+	CodeMissingToken:           "缺少认证令牌",
+	CodeMalformedToken:         "令牌格式错误",
+	CodeInvalidToken:           "令牌无效或已过期",
+	CodeTokenRevoked:           "令牌已注销",
+	CodeSessionRevoked:         "会话已注销",
+	CodeSessionExpired:         "会话已过期",
+	CodeRefreshTokenInvalid:    "刷新令牌无效",
+	CodeRefreshTokenReused:     "刷新令牌已被使用，会话已注销",
+	CodeInvalidPasskey:         "无效的passkey或已禁用",
+	CodePasskeyInactive:        "passkey已禁用",
+	CodePasskeyNotYetValid:     "passkey尚未生效",
+	CodePasskeyExpired:         "passkey已过期",
+	CodeLoginLimitReached:      "passkey登录次数已用完",
+	CodeInvalidEnrollmentCode:  "无效的注册码",
+	CodePasskeyEnrolled:        "该passkey已绑定安全密钥，请使用安全密钥登录",
+	CodeEnrollmentCodeConsumed: "注册码已被使用",
+	CodeEnrollmentRequired:     "请先使用注册码绑定安全密钥",
+	CodeOIDCAccount:            "该账户使用统一身份认证登录",
+	CodeWebAuthnFailed:         "安全密钥验证失败",
+	CodeCredentialCloned:       "安全密钥签名计数异常，可能已被复制",
+	CodeOIDCStateInvalid:       "登录请求不存在或已过期，请重新登录",
+	CodeOIDCFailed:             "统一身份认证失败",
+	CodeOIDCEmailNotVerified:   "统一身份认证账户的邮箱未验证",
+	CodeOIDCCodeInvalid:        "登录码无效或已过期",
+
+	CodeRoleForbidden:          "当前角色无权访问此功能",
+	CodeOutsideDelegationScope: "只能管理自己创建的 passkey 及其下级",
+	CodeRoleNotManageable:      "无权管理该角色的 passkey",
+	CodeSelfModification:       "不能修改、禁用或删除自己的 passkey",
+	CodeNoDataGrant:            "无权访问该化合物的保护数据",
+	CodeOIDCNoRole:             "当前统一身份认证账户未被授权访问",
+
+	CodeDataNotFound:          "数据不存在",
+	CodePasskeyNotFound:       "passkey 不存在",
+	CodeReferenceNotFound:     "文献不存在",
+	CodeReferenceLinkNotFound: "关联不存在",
+	CodeBioactivityNotFound:   "活性记录不存在",
+	CodeOrganismNotFound:      "来源生物不存在",
+	CodeSynonymNotFound:       "别名不存在",
+	CodeGrantNotFound:         "授权不存在",
+	CodeSessionNotFound:       "会话不存在或已结束",
+	CodeCredentialNotFound:    "凭据不存在",
+
+	CodeDuplicateSynonym:   "该化合物已存在相同别名",
+	CodeDuplicateReference: "相同DOI或PubMed ID的文献已存在",
+	CodeCredentialExists:   "该安全密钥已绑定",
+	CodeLastCredential:     "不能删除最后一个安全密钥",
+
+	CodeUnsupportedRole:       "不支持的角色",
+	CodeUnsupportedRank:       "不支持的分类阶元",
+	CodeUnsupportedAdduct:     "不支持的加合离子类型",
+	CodeInvalidFormula:        "分子式格式错误",
+	CodeUnknownElement:        "分子式包含不支持的元素",
+	CodeAdductIncomplete:      "分子式中的原子不足以形成该加合离子",
+	CodeInvalidEndpoint:       "不支持的活性终点类型",
+	CodeInvalidQualifier:      "不支持的数值限定符",
+	CodeInvalidUnits:          "不支持的单位",
+	CodeInvalidGrantField:     "不支持的授权字段",
+	CodeInvalidGrantScope:     "不支持的授权范围",
+	CodeEmptyGrantFields:      "授权字段不能为空",
+	CodeEmptyGrantScope:       "授权范围取值不能为空",
+	CodeInvalidSynonymType:    "不支持的别名类型",
+	CodeEmptySynonym:          "别名不能为空",
+	CodeInvalidReferenceField: "不支持的文献关联字段",
+	CodeInvalidDOI:            "DOI格式错误",
+	CodeInvalidPubMedID:       "PubMed ID格式错误",
+	CodeInvalidValidity:       "失效时间必须晚于生效时间",
+	CodeInvalidMaxLogins:      "最大登录次数必须是正整数",
+	CodeInvalidTokenLifetime:  "令牌有效期必须在 {min} 到 {max} 分钟之间",
+	CodeNegativeLimit:         "频率限制和配额不能为负数",
+	CodeExceedsParentValidity: "有效期不能超出创建者自身的有效期",
+	CodeExceedsParentLimit:    "频率限制和配额不能超出创建者自身的限制",
+	CodeDescendantExceeds:     "下级 passkey 的角色、有效期或限制超出修改后的范围，请先修改下级",
+	CodeCeremonyNotFound:      "认证请求不存在或已过期，请重试",
+
+	CodeRateLimited:          "请求过于频繁，请稍后再试",
+	CodeDailyQuotaExceeded:   "今日保护数据读取次数已达上限（{limit} 次）",
+	CodeMonthlyQuotaExceeded: "本月保护数据读取次数已达上限（{limit} 次）",
+
+	CodeInternalError:       "服务器内部错误",
+	CodeDatabaseError:       "数据库操作失败",
+	CodeServerMisconfigured: "服务器配置错误",
+	CodeChemistryFailed:     "化学计算失败",
+
+	CodeChemistryUnavailable:  "化学计算服务不可用",
+	CodeOIDCUnavailable:       "统一身份认证服务不可用",
+	CodeOIDCNotConfigured:     "统一身份认证未配置",
+	CodeWebAuthnNotConfigured: "WebAuthn未配置",
+	CodeMetricsNotConfigured:  "指标接口未配置访问令牌（metrics.token）",
+	CodeNotReady:              "服务未就绪，检查失败的依赖：{failed}",
+}
+
+// enMessages 英文消息
+var enMessages = map[ErrorCode]string{
+	CodeInvalidRequest:            "Invalid request parameters",
+	CodeMissingParameter:          "Parameter {param} must not be empty",
+	CodeParameterRequiredWith:     "Parameter {param} is required when {with} is used",
+	CodeInvalidPositiveInteger:    "Parameter {param} must be a positive integer",
+	CodeInvalidNonNegativeInteger: "Parameter {param} must be a non-negative integer",
+	CodeInvalidNumber:             "Parameter {param} must be a number",
+	CodeInvalidNonNegativeNumber:  "Parameter {param} must be a non-negative number",
+	CodeNumberOutOfRange:          "Parameter {param} must be a number between {min} and {max}",
+	CodeInvalidTime:               "Parameter {param} must be an RFC 3339 time or a YYYY-MM-DD date",
+	CodeInvalidChoice:             "Parameter {param} must be one of: {choices}",
+	CodeTooManyExportIDs:          "References of at most {max} compounds can be exported at once",
+	CodeSearchQueryTooLong:        "Search queries are limited to {max} characters and {max_terms} terms",
+
+	CodeUnauthenticated:        "User information not found",
+	CodeMissingToken:           "Missing authentication token",
+	CodeMalformedToken:         "Malformed authentication token",
+	CodeInvalidToken:           "Token is invalid or has expired",
+	CodeTokenRevoked:           "Token has been revoked",
+	CodeSessionRevoked:         "Session has been signed out",
+	CodeSessionExpired:         "Session has expired",
+	CodeRefreshTokenInvalid:    "Invalid refresh token",
+	CodeRefreshTokenReused:     "Refresh token has already been used; the session has been signed out",
+	CodeInvalidPasskey:         "Invalid or disabled passkey",
+	CodePasskeyInactive:        "Passkey is disabled",
+	CodePasskeyNotYetValid:     "Passkey is not yet valid",
+	CodePasskeyExpired:         "Passkey has expired",
+	CodeLoginLimitReached:      "Passkey has no logins left",
+	CodeInvalidEnrollmentCode:  "Invalid enrollment code",
+	CodePasskeyEnrolled:        "This passkey is bound to a security key; sign in with the security key",
+	CodeEnrollmentCodeConsumed: "Enrollment code has already been used",
+	CodeEnrollmentRequired:     "Bind a security key with your enrollment code first",
+	CodeOIDCAccount:            "This account signs in with single sign-on",
+	CodeWebAuthnFailed:         "Security key verification failed",
+	CodeCredentialCloned:       "Security key signature counter mismatch; the key may have been cloned",
+	CodeOIDCStateInvalid:       "Login request not found or expired; please sign in again",
+	CodeOIDCFailed:             "Single sign-on failed",
+	CodeOIDCEmailNotVerified:   "The single sign-on account's email address is not verified",
+	CodeOIDCCodeInvalid:        "Login code is invalid or has expired",
+
+	CodeRoleForbidden:          "Your role is not allowed to use this feature",
+	CodeOutsideDelegationScope: "You can only manage passkeys you created and their descendants",
+	CodeRoleNotManageable:      "You are not allowed to manage passkeys with this role",
+	CodeSelfModification:       "You cannot modify, disable or delete your own passkey",
+	CodeNoDataGrant:            "You are not allowed to access protected data of this compound",
+	CodeOIDCNoRole:             "This single sign-on account is not authorized to access MNPLib",
+
+	CodeDataNotFound:          "Compound not found",
+	CodePasskeyNotFound:       "Passkey not found",
+	CodeReferenceNotFound:     "Reference not found",
+	CodeReferenceLinkNotFound: "Reference link not found",
+	CodeBioactivityNotFound:   "Bioactivity record not found",
+	CodeOrganismNotFound:      "Organism not found",
+	CodeSynonymNotFound:       "Synonym not found",
+	CodeGrantNotFound:         "Data grant not found",
+	CodeSessionNotFound:       "Session not found or already ended",
+	CodeCredentialNotFound:    "Credential not found",
+
+	CodeDuplicateSynonym:   "The compound already has this synonym",
+	CodeDuplicateReference: "A reference with the same DOI or PubMed ID already exists",
+	CodeCredentialExists:   "This security key is already registered",
+	CodeLastCredential:     "The last security key cannot be deleted",
+
+	CodeUnsupportedRole:       "Unsupported role",
+	CodeUnsupportedRank:       "Unsupported taxonomic rank",
+	CodeUnsupportedAdduct:     "Unsupported adduct type",
+	CodeInvalidFormula:        "Invalid molecular formula",
+	CodeUnknownElement:        "Molecular formula contains an unsupported element",
+	CodeAdductIncomplete:      "The molecular formula does not have enough atoms to form this adduct",
+	CodeInvalidEndpoint:       "Unsupported bioactivity endpoint",
+	CodeInvalidQualifier:      "Unsupported value qualifier",
+	CodeInvalidUnits:          "Unsupported units",
+	CodeInvalidGrantField:     "Unsupported grant field",
+	CodeInvalidGrantScope:     "Unsupported grant scope",
+	CodeEmptyGrantFields:      "Grant fields must not be empty",
+	CodeEmptyGrantScope:       "Grant scope values must not be empty",
+	CodeInvalidSynonymType:    "Unsupported synonym type",
+	CodeEmptySynonym:          "Synonym must not be empty",
+	CodeInvalidReferenceField: "Unsupported reference link field",
+	CodeInvalidDOI:            "Invalid DOI",
+	CodeInvalidPubMedID:       "Invalid PubMed ID",
+	CodeInvalidValidity:       "Expiry time must be later than the start time",
+	CodeInvalidMaxLogins:      "Maximum number of logins must be a positive integer",
+	CodeInvalidTokenLifetime:  "Token lifetime must be between {min} and {max} minutes",
+	CodeNegativeLimit:         "Rate limit and quotas must not be negative",
+	CodeExceedsParentValidity: "Validity period cannot exceed the creator's own validity period",
+	CodeExceedsParentLimit:    "Rate limit and quotas cannot exceed the creator's own limits",
+	CodeDescendantExceeds:     "A descendant passkey has a role, validity period or limit beyond the new settings; update it first",
+	CodeCeremonyNotFound:      "Authentication request not found or expired; please try again",
+
+	CodeRateLimited:          "Too many requests; please try again later",
+	CodeDailyQuotaExceeded:   "Daily protected data read limit reached ({limit} reads)",
+	CodeMonthlyQuotaExceeded: "Monthly protected data read limit reached ({limit} reads)",
+
+	CodeInternalError:       "Internal server error",
+	CodeDatabaseError:       "Database operation failed",
+	CodeServerMisconfigured: "Server configuration error",
+	CodeChemistryFailed:     "Chemistry calculation failed",
+
+	CodeChemistryUnavailable:  "Chemistry service is unavailable",
+	CodeOIDCUnavailable:       "Single sign-on service is unavailable",
+	CodeOIDCNotConfigured:     "Single sign-on is not configured",
+	CodeWebAuthnNotConfigured: "WebAuthn is not configured",
+	CodeMetricsNotConfigured:  "Metrics access token (metrics.token) is not configured",
+	CodeNotReady:              "Service is not ready, failed checks: {failed}",
+}
+
+// Message 返回错误代码在指定语言下的消息，缺少翻译时使用默认语言
+func Message(code ErrorCode, lang string) string {
+	if msg, ok := messageCatalogue[lang][code]; ok {
+		return msg
+	}
+	if msg, ok := messageCatalogue[DefaultLanguage][code]; ok {
+		return msg
+	}
+	return string(code)
+}
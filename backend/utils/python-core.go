@@ -2,18 +2,25 @@ package utils
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
-	"os"
+	"fmt"
+	"io"
 	"os/exec"
-	"os/signal"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrPythonTimeout Python 进程未在超时时间内返回结果
+var ErrPythonTimeout = errors.New("Python进程响应超时")
+
+// ErrPythonClosed Python 进程已关闭，不再接受请求
+var ErrPythonClosed = errors.New("Python进程已关闭")
+
 type Request struct {
 	ID      string
 	Content string
@@ -22,9 +29,20 @@ type Request struct {
 
 type PythonProcess struct {
 	cmd       *exec.Cmd
+	stdin     io.WriteCloser
 	writer    *bufio.Writer
 	sendQueue chan *Request
 	pending   sync.Map // map[id] = chan
+	// 等待响应的请求数，与 pending 中的条目数一致
+	pendingCount atomic.Int64
+	// 停止接受请求后关闭。发送请求时不加锁，停止接受请求不需要等待阻塞在入队上的调用方
+	closing     chan struct{}
+	closingOnce sync.Once
+	// 停止发送后关闭，发送协程写完队列中的请求后关闭标准输入。发送队列本身不关闭，入队时无需担心向已关闭的通道发送
+	sendDone     chan struct{}
+	sendDoneOnce sync.Once
+	// 进程退出后关闭
+	exited chan struct{}
 }
 
 func NewPythonProcess(pythonPath string, pythonFile string) (*PythonProcess, error) {
@@ -40,8 +58,12 @@ func NewPythonProcess(pythonPath string, pythonFile string) (*PythonProcess, err
 
 	p := &PythonProcess{
 		cmd:       cmd,
+		stdin:     stdin,
 		writer:    bufio.NewWriter(stdin),
 		sendQueue: make(chan *Request, 100),
+		closing:   make(chan struct{}),
+		sendDone:  make(chan struct{}),
+		exited:    make(chan struct{}),
 	}
 
 	// 启动 Python 进程
@@ -54,8 +76,10 @@ func NewPythonProcess(pythonPath string, pythonFile string) (*PythonProcess, err
 	startupCheck := make(chan error, 1)
 
 	go func() {
-		// 等待进程启动完成
+		// 等待进程退出，退出后不再接受请求，等待中的请求返回 ErrPythonClosed
 		err := cmd.Wait()
+		close(p.exited)
+		p.stopSending()
 		startupCheck <- err
 	}()
 
@@ -81,45 +105,48 @@ func NewPythonProcess(pythonPath string, pythonFile string) (*PythonProcess, err
 			json.Unmarshal([]byte(line), &resp)
 
 			// 找到对应的 request channel
-			if ch, ok := p.pending.Load(resp["id"]); ok {
+			if ch, ok := p.pending.LoadAndDelete(resp["id"]); ok {
+				p.pendingCount.Add(-1)
 				ch.(chan string) <- resp["reply"] // 回传结果
-				p.pending.Delete(resp["id"])
 			}
 		}
 	}()
 
-	// 后台发送请求
+	// 后台发送请求，停止发送后写完队列中剩余的请求再关闭标准输入，Python 进程读到 EOF 后退出
 	go func() {
-		for req := range p.sendQueue {
-			// 保存等待通道
-			p.pending.Store(req.ID, req.Resp)
-
-			data := map[string]string{
-				"id":  req.ID,
-				"msg": req.Content,
+		for {
+			select {
+			case req := <-p.sendQueue:
+				p.write(req)
+			case <-p.sendDone:
+				for {
+					select {
+					case req := <-p.sendQueue:
+						p.write(req)
+					default:
+						p.stdin.Close()
+						return
+					}
+				}
 			}
-			jsonBytes, _ := json.Marshal(data)
-			p.writer.Write(jsonBytes)
-			p.writer.WriteString("\n")
-			p.writer.Flush()
-		}
-	}()
-
-	// 设置信号处理器，确保关闭
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		<-c
-		if p.IsRunning() {
-			p.Close()
 		}
-		os.Exit(0)
 	}()
 
 	return p, nil
 }
 
+// write 把请求写入 Python 进程的标准输入，进程不读取输入时会阻塞
+func (p *PythonProcess) write(req *Request) {
+	data := map[string]string{
+		"id":  req.ID,
+		"msg": req.Content,
+	}
+	jsonBytes, _ := json.Marshal(data)
+	p.writer.Write(jsonBytes)
+	p.writer.WriteString("\n")
+	p.writer.Flush()
+}
+
 func (p *PythonProcess) SendAndWait(msg string) (string, error) {
 	return p.SendAndWaitWithTimeout(msg, 30*time.Second)
 }
@@ -131,34 +158,132 @@ func (p *PythonProcess) SendAndWaitWithTimeout(msg string, timeout time.Duration
 		Resp:    make(chan string, 1),
 	}
 
-	p.sendQueue <- req
+	select {
+	case <-p.closing:
+		return "", ErrPythonClosed
+	default:
+	}
+	// 入队前保存等待通道，请求在队列中超时时也能清理
+	p.pending.Store(req.ID, req.Resp)
+	p.pendingCount.Add(1)
+
+	// 超时从入队前开始计算：发送协程阻塞在未读取输入的进程上时，队列可能已满
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case p.sendQueue <- req:
+	case <-p.closing:
+		p.forget(req.ID)
+		return "", ErrPythonClosed
+	case <-p.exited:
+		p.forget(req.ID)
+		return "", ErrPythonClosed
+	case <-timer.C:
+		p.forget(req.ID)
+		return "", ErrPythonTimeout
+	}
 
 	select {
 	case res := <-req.Resp:
 		return res, nil
-	case <-time.After(timeout):
+	case <-p.exited:
+		// 进程退出前可能已收到响应
+		select {
+		case res := <-req.Resp:
+			return res, nil
+		default:
+		}
+		p.forget(req.ID)
+		return "", ErrPythonClosed
+	case <-timer.C:
 		// 超时后清理pending状态
-		p.pending.Delete(req.ID)
-		return "", errors.New("Python进程响应超时")
+		p.forget(req.ID)
+		return "", ErrPythonTimeout
 	}
 }
 
-// Close 关闭Python进程
+// forget 不再等待请求的响应
+func (p *PythonProcess) forget(id string) {
+	if _, ok := p.pending.LoadAndDelete(id); ok {
+		p.pendingCount.Add(-1)
+	}
+}
+
+// Exited 返回进程退出后关闭的通道
+func (p *PythonProcess) Exited() <-chan struct{} {
+	return p.exited
+}
+
+// QueueDepth 返回等待写入Python进程的请求数
+func (p *PythonProcess) QueueDepth() int {
+	return len(p.sendQueue)
+}
+
+// PendingRequests 返回已发送或排队中、尚未收到响应的请求数
+func (p *PythonProcess) PendingRequests() int {
+	return int(p.pendingCount.Load())
+}
+
+// stopAccepting 停止接受请求，之后的请求返回 ErrPythonClosed
+func (p *PythonProcess) stopAccepting() {
+	p.closingOnce.Do(func() { close(p.closing) })
+}
+
+// stopSending 停止接受请求和发送，队列中的请求写完后关闭标准输入
+func (p *PythonProcess) stopSending() {
+	p.stopAccepting()
+	p.sendDoneOnce.Do(func() { close(p.sendDone) })
+}
+
+// Shutdown 停止接受请求，等待已提交的请求返回后关闭标准输入并等待 Python 进程退出。
+// ctx 结束时仍未完成则强制结束进程
+func (p *PythonProcess) Shutdown(ctx context.Context) error {
+	p.stopAccepting()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for p.PendingRequests() > 0 {
+		select {
+		case <-ctx.Done():
+			remaining := p.PendingRequests()
+			p.Close()
+			return fmt.Errorf("等待Python进程处理完 %d 个请求超时: %w", remaining, ctx.Err())
+		case <-p.exited:
+			return errors.New("Python进程在处理请求时退出")
+		case <-ticker.C:
+		}
+	}
+
+	// 全部请求已返回后再关闭标准输入，避免进程退出时丢失未读取的输出
+	p.stopSending()
+	select {
+	case <-p.exited:
+		return nil
+	case <-ctx.Done():
+		p.Close()
+		return fmt.Errorf("等待Python进程退出超时: %w", ctx.Err())
+	}
+}
+
+// Close 立即终止Python进程，未返回的请求不再等待结果
 func (p *PythonProcess) Close() error {
 	if p.cmd == nil || p.cmd.Process == nil {
 		return nil
 	}
 
 	// 关闭发送队列
-	close(p.sendQueue)
+	p.stopSending()
 
 	// 清理所有pending的请求
 	p.pending.Range(func(key, value interface{}) bool {
-		p.pending.Delete(key)
+		p.forget(key.(string))
 		return true
 	})
 
 	// 终止Python进程
+	if !p.IsRunning() {
+		return nil
+	}
 	return p.cmd.Process.Kill()
 }
 
@@ -167,14 +292,10 @@ func (p *PythonProcess) IsRunning() bool {
 	if p.cmd == nil || p.cmd.Process == nil {
 		return false
 	}
-
-	// 检查进程状态
-	processState := p.cmd.ProcessState
-	if processState != nil && processState.Exited() {
+	select {
+	case <-p.exited:
 		return false
+	default:
+		return true
 	}
-
-	// 尝试发送信号检查进程是否存活
-	err := p.cmd.Process.Signal(os.Interrupt)
-	return err == nil
 }
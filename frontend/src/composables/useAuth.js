@@ -9,6 +9,52 @@ const authToken = ref(localStorage.getItem('authToken') || '')
 const userInfo = ref(JSON.parse(localStorage.getItem('userInfo') || 'null'))
 const isAuthenticated = computed(() => !!authToken.value)
 
+// 访问令牌有效期较短，在过期前提前刷新（秒）
+const REFRESH_MARGIN = 60
+let refreshTimer = null
+let refreshing = null
+
+// 在访问令牌过期前安排刷新
+const scheduleRefresh = () => {
+  clearTimeout(refreshTimer)
+  const info = userInfo.value
+  if (!info || !info.refresh_token || !info.expires_at) return
+  const delay = Math.max(info.expires_at - REFRESH_MARGIN - Date.now() / 1000, 0) * 1000
+  refreshTimer = setTimeout(() => { refreshSession() }, delay)
+}
+
+// 使用刷新令牌换取新令牌，刷新令牌每次使用后都会轮换
+const refreshSession = async () => {
+  const info = userInfo.value
+  if (!info || !info.refresh_token) return false
+  // 同一时间只发起一次刷新，避免旧的刷新令牌被重复使用导致会话被注销
+  if (!refreshing) {
+    refreshing = (async () => {
+      try {
+        const response = await fetch('/api/auth/refresh', {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ refresh_token: info.refresh_token })
+        })
+        if (!response.ok) {
+          console.warn('会话已失效，自动登出')
+          logout()
+          return false
+        }
+        const data = await response.json()
+        login(data.data.token, { ...info, ...data.data })
+        return true
+      } catch (err) {
+        console.error('刷新令牌失败:', err)
+        return false
+      } finally {
+        refreshing = null
+      }
+    })()
+  }
+  return refreshing
+}
+
 // 登录函数
 const login = (token, userData = null) => {
   authToken.value = token
@@ -17,12 +63,18 @@ const login = (token, userData = null) => {
   if (userData) {
     localStorage.setItem('userInfo', JSON.stringify(userData))
   }
+  scheduleRefresh()
   // 触发自定义事件，通知其他组件
   window.dispatchEvent(new CustomEvent('auth-changed', { detail: { isAuthenticated: true, userInfo: userData } }))
 }
 
 // 登出函数
 const logout = () => {
+  // 通知后端注销当前会话，失败不影响本地登出
+  if (authToken.value) {
+    fetch('/api/auth/logout', { method: 'POST', headers: getAuthHeader() }).catch(() => {})
+  }
+  clearTimeout(refreshTimer)
   authToken.value = ''
   userInfo.value = null
   localStorage.removeItem('authToken')
@@ -84,10 +136,9 @@ const verifyToken = async () => {
       headers
     });
     
-    // 如果token无效，自动清理
+    // 如果token无效，先尝试刷新，刷新失败时自动清理
     if (!response.ok && response.status === 401) {
-      console.warn('Token已过期，自动登出');
-      logout();
+      return await refreshSession();
     }
     
     return response.ok;
@@ -101,7 +152,14 @@ const verifyToken = async () => {
 const initAuthValidation = async () => {
   if (authToken.value) {
     console.log('初始化验证token...');
-    await verifyToken();
+    const info = userInfo.value;
+    // 访问令牌即将过期时直接刷新
+    if (info && info.expires_at && info.expires_at - REFRESH_MARGIN <= Date.now() / 1000) {
+      await refreshSession();
+    } else {
+      await verifyToken();
+      scheduleRefresh();
+    }
   }
 }
 
@@ -120,7 +178,8 @@ export function useAuth() {
     getAuthHeader,
     getUserInfo,
     verifyPasskeyModifiable,
-    verifyToken
+    verifyToken,
+    refreshSession
   }
 }
 
@@ -135,5 +194,6 @@ export const auth = {
   getAuthHeader,
   getUserInfo,
   verifyPasskeyModifiable,
-  verifyToken
+  verifyToken,
+  refreshSession
 }
@@ -0,0 +1,58 @@
+// 统一身份认证（OpenID Connect）登录
+
+// 获取登录配置，未启用时返回 null
+export const fetchOIDCConfig = async () => {
+  try {
+    const response = await fetch('/api/auth/oidc/config')
+    if (!response.ok) {
+      return null
+    }
+    const data = await response.json()
+    return data.data && data.data.enabled ? data.data : null
+  } catch (err) {
+    console.error('Failed to load OIDC config:', err)
+    return null
+  }
+}
+
+// 跳转到 IdP 登录页面，登录完成后回到当前页面
+export const startOIDCLogin = (config) => {
+  const redirect = window.location.pathname + window.location.search
+  window.location.href = `${config.login_url}?redirect=${encodeURIComponent(redirect)}`
+}
+
+// 处理回调跳转附带的 oidc_code / oidc_error 参数，并从地址栏中移除
+// 返回登录结果（与 /api/auth/login 相同），没有参数时返回 null，出错时抛出异常
+export const consumeOIDCCallback = async () => {
+  const url = new URL(window.location.href)
+  const code = url.searchParams.get('oidc_code')
+  const error = url.searchParams.get('oidc_error')
+  if (!code && !error) {
+    return null
+  }
+  url.searchParams.delete('oidc_code')
+  url.searchParams.delete('oidc_error')
+  window.history.replaceState(window.history.state, '', url.pathname + url.search + url.hash)
+
+  if (error) {
+    throw new Error(error)
+  }
+  const response = await fetch('/api/auth/oidc/exchange', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ code })
+  })
+  const data = await response.json().catch(() => ({}))
+  if (!response.ok) {
+    throw new Error(data.msg || response.statusText)
+  }
+  return data.data
+}
+
+export function useOIDC() {
+  return {
+    fetchOIDCConfig,
+    startOIDCLogin,
+    consumeOIDCCallback
+  }
+}
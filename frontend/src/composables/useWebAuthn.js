@@ -0,0 +1,94 @@
+// WebAuthn 安全密钥注册和登录
+
+// base64url 与 ArrayBuffer 互转
+const toBuffer = (value) => {
+  const base64 = value.replace(/-/g, '+').replace(/_/g, '/')
+  const padded = base64 + '='.repeat((4 - (base64.length % 4)) % 4)
+  return Uint8Array.from(atob(padded), c => c.charCodeAt(0)).buffer
+}
+
+const toBase64url = (buffer) => {
+  const bytes = new Uint8Array(buffer)
+  let binary = ''
+  bytes.forEach(b => { binary += String.fromCharCode(b) })
+  return btoa(binary).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '')
+}
+
+const postJSON = async (url, body, headers = {}) => {
+  const response = await fetch(url, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', ...headers },
+    body: JSON.stringify(body || {})
+  })
+  const data = await response.json().catch(() => ({}))
+  if (!response.ok) {
+    throw new Error(data.msg || response.statusText)
+  }
+  return data.data
+}
+
+export const isWebAuthnSupported = () => !!(window.PublicKeyCredential && navigator.credentials)
+
+// 注册安全密钥：enrollmentCode 为 passkey UUID（首次注册），已登录用户添加凭据时传入认证头
+const register = async ({ enrollmentCode, name, authHeader } = {}) => {
+  const begin = enrollmentCode
+    ? await postJSON('/api/auth/webauthn/enroll/begin', { enrollment_code: enrollmentCode, name })
+    : await postJSON('/api/auth/webauthn/register/begin', { name }, authHeader)
+
+  const publicKey = begin.options.publicKey
+  publicKey.challenge = toBuffer(publicKey.challenge)
+  publicKey.user.id = toBuffer(publicKey.user.id)
+  if (publicKey.excludeCredentials) {
+    publicKey.excludeCredentials = publicKey.excludeCredentials.map(c => ({ ...c, id: toBuffer(c.id) }))
+  }
+
+  const credential = await navigator.credentials.create({ publicKey })
+  return postJSON('/api/auth/webauthn/register/finish', {
+    ceremony_id: begin.ceremony_id,
+    credential: {
+      id: credential.id,
+      rawId: toBase64url(credential.rawId),
+      type: credential.type,
+      response: {
+        clientDataJSON: toBase64url(credential.response.clientDataJSON),
+        attestationObject: toBase64url(credential.response.attestationObject),
+        transports: credential.response.getTransports ? credential.response.getTransports() : []
+      }
+    }
+  })
+}
+
+// 使用安全密钥登录，返回与 /api/auth/login 相同的登录信息
+const login = async () => {
+  const begin = await postJSON('/api/auth/webauthn/login/begin')
+
+  const publicKey = begin.options.publicKey
+  publicKey.challenge = toBuffer(publicKey.challenge)
+  if (publicKey.allowCredentials) {
+    publicKey.allowCredentials = publicKey.allowCredentials.map(c => ({ ...c, id: toBuffer(c.id) }))
+  }
+
+  const credential = await navigator.credentials.get({ publicKey })
+  return postJSON('/api/auth/webauthn/login/finish', {
+    ceremony_id: begin.ceremony_id,
+    credential: {
+      id: credential.id,
+      rawId: toBase64url(credential.rawId),
+      type: credential.type,
+      response: {
+        clientDataJSON: toBase64url(credential.response.clientDataJSON),
+        authenticatorData: toBase64url(credential.response.authenticatorData),
+        signature: toBase64url(credential.response.signature),
+        userHandle: credential.response.userHandle ? toBase64url(credential.response.userHandle) : undefined
+      }
+    }
+  })
+}
+
+export function useWebAuthn() {
+  return {
+    isWebAuthnSupported,
+    register,
+    login
+  }
+}